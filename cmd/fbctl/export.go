@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/export"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func exportFeedback(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID")
+	format := flags.String("format", export.FormatNDJSON, "ndjson, csv or parquet")
+	filters := flags.String("filter", "", "search filters as a query string, e.g. source=intercom&from=2024-01-01T00:00:00Z")
+	spec := flags.String("columns", "", "column mapping, e.g. id,body=content.body,app_version=metadata.app.version")
+	output := flags.String("out", "", "output file, standard output when empty")
+	flags.Parse(args)
+
+	query, err := url.ParseQuery(*filters)
+	if err != nil {
+		return fmt.Errorf("invalid filter: %v", err)
+	}
+	query.Set("tenant_id", *tenantID)
+	filter, err := feedback.ParseFeedbackFilter(query)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := export.Formats[*format]; !ok {
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+	var columns []export.Column
+	if *spec != "" {
+		if columns, err = export.ParseColumns(*spec); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	exportService := export.NewExportService(db.NewFeedbackRepository(dbpool))
+	count, err := exportService.Export(ctx, filter, *format, columns, buffered)
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d feedback records\n", count)
+	return nil
+}
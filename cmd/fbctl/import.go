@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/dedup"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/importer"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func importFeedback(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID (required)")
+	mappingFile := flags.String("mapping", "", "JSON column mapping file (required)")
+	format := flags.String("format", "", "csv or ndjson, from the file extension when empty")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows")
+	flags.Parse(args)
+
+	if *tenantID == "" || *mappingFile == "" || flags.NArg() != 1 {
+		return fmt.Errorf("usage: fbctl import -tenant <id> -mapping <mapping.json> [-format csv|ndjson] [-dry-run] <file>")
+	}
+
+	raw, err := os.ReadFile(*mappingFile)
+	if err != nil {
+		return err
+	}
+	var mapping models.ImportMapping
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return fmt.Errorf("invalid mapping: %v", err)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(flags.Arg(0)), ".")
+		if *format == "jsonl" {
+			*format = importer.FormatNDJSON
+		}
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cfg := config.Load()
+	feedbackRepo := db.NewFeedbackRepository(dbpool)
+	tenantRepo := db.NewTenantRepository(dbpool)
+	vaultService, err := redaction.NewVaultService(db.NewPIIVaultRepository(dbpool), cfg.PIIVaultKey)
+	if err != nil {
+		return err
+	}
+	tagRuleService := tagrule.NewTagRuleService(db.NewTagRuleRepository(dbpool), feedbackRepo)
+	pipelineService := pipeline.NewPipelineService(tenantRepo, vaultService, dedup.NewDedupService(feedbackRepo), tagRuleService, cfg.PIIHashKey)
+
+	importService := importer.NewImportService(feedbackRepo, tenantRepo, pipelineService)
+	result, err := importService.Import(ctx, *tenantID, *format, &mapping, file, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, rowError := range result.Errors {
+		if rowError.Field != "" {
+			fmt.Fprintf(os.Stderr, "row %d: %s: %s\n", rowError.Row, rowError.Field, rowError.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "row %d: %s\n", rowError.Row, rowError.Error)
+		}
+	}
+	if *dryRun {
+		fmt.Printf("Validated %d rows: %d valid, %d failed\n", result.Rows, result.Valid, result.Failed)
+		return nil
+	}
+	fmt.Printf("Imported %d rows: %d inserted, %d updated, %d skipped, %d failed\n",
+		result.Rows, result.Inserted, result.Updated, result.Skipped, result.Failed)
+	return nil
+}
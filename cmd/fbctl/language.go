@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/language"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func detectLanguage(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("detect-language", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID (required)")
+	all := flags.Bool("all", false, "also re-detect feedback whose language was detected before")
+	flags.Parse(args)
+
+	if *tenantID == "" {
+		return fmt.Errorf("-tenant is required")
+	}
+
+	languageService := language.NewLanguageService(db.NewFeedbackRepository(dbpool), db.NewTenantRepository(dbpool))
+	updated, err := languageService.Redetect(ctx, *tenantID, *all)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated the language of %d feedback records\n", updated)
+	return nil
+}
@@ -0,0 +1,96 @@
+// fbctl runs maintenance commands against the feedback database.
+//
+//	go run ./cmd/fbctl <command> [flags]
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, dbpool *pgxpool.Pool, args []string) error
+}
+
+var commands = map[string]command{
+	"detect-language": {
+		usage: "re-run language detection over stored feedback",
+		run:   detectLanguage,
+	},
+	"export": {
+		usage: "export the tenant's feedback as NDJSON, CSV or Parquet",
+		run:   exportFeedback,
+	},
+	"import": {
+		usage: "import historical feedback from a CSV or NDJSON file",
+		run:   importFeedback,
+	},
+	"reencrypt": {
+		usage: "encrypt the subscriptions' credentials with the active secret key",
+		run:   reencrypt,
+	},
+	"reprocess": {
+		usage: "map archived raw payloads to feedback again",
+		run:   reprocess,
+	},
+	"retag": {
+		usage: "re-apply the tenant's tag rules to stored feedback",
+		run:   retag,
+	},
+	"rollup": {
+		usage: "refresh the analytics rollups",
+		run:   rollup,
+	},
+	"topics": {
+		usage: "extract the topics of the tenant's feedback over a window",
+		run:   extractTopics,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		printUsage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	dbpool, err := pgxpool.Connect(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer dbpool.Close()
+
+	if err := cmd.run(ctx, dbpool, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		dbpool.Close()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: fbctl <command> [flags]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/integrations"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func reencrypt(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("reencrypt", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "count the subscriptions to encrypt again without saving them")
+	flags.Parse(args)
+
+	cfg := config.Load()
+	subService, err := newSubscriptionService(dbpool, cfg, integrations.NewStrategies(nil))
+	if err != nil {
+		return err
+	}
+
+	scanned, reencrypted, err := subService.Reencrypt(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "encrypted again"
+	if *dryRun {
+		verb = "to encrypt again"
+	}
+	fmt.Printf("%d subscriptions scanned, %d %s\n", scanned, reencrypted, verb)
+	return nil
+}
+
+// newSubscriptionService returns the subscription service reading the
+// secrets of the strategies' subscriptions with the configured keys.
+func newSubscriptionService(dbpool *pgxpool.Pool, cfg *config.Config, strategies map[models.Source]integrations.SourceStrategy) (*subscription.SubscriptionService, error) {
+	keyring, err := secrets.NewKeyring(cfg.SecretKeys)
+	if err != nil {
+		return nil, err
+	}
+	subService := subscription.NewSubscriptionService(db.NewSubscriptionRepository(dbpool), keyring)
+	subService.SetSecretFields(integrations.SecretFieldsOf(strategies))
+	return subService, nil
+}
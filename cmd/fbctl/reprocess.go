@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/archive"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/deadletter"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/dedup"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/inbox"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/integrations"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func reprocess(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID (required)")
+	source := flags.String("source", "", "source of the payloads (required)")
+	from := flags.String("from", "", "received at or after, RFC 3339")
+	to := flags.String("to", "", "received before, RFC 3339")
+	dryRun := flags.Bool("dry-run", false, "print the changes as JSON lines without saving them")
+	flags.Parse(args)
+
+	if *tenantID == "" || *source == "" {
+		return fmt.Errorf("-tenant and -source are required")
+	}
+
+	req := &models.ReprocessRequest{TenantID: *tenantID, Source: models.Source(*source), DryRun: *dryRun}
+	for value, s := range map[*time.Time]string{&req.From: *from, &req.To: *to} {
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q: %v", s, err)
+		}
+		*value = t
+	}
+
+	cfg := config.Load()
+	feedbackRepo := db.NewFeedbackRepository(dbpool)
+	tenantRepo := db.NewTenantRepository(dbpool)
+	vaultService, err := redaction.NewVaultService(db.NewPIIVaultRepository(dbpool), cfg.PIIVaultKey)
+	if err != nil {
+		return err
+	}
+	tagRuleService := tagrule.NewTagRuleService(db.NewTagRuleRepository(dbpool), feedbackRepo)
+	pipelineService := pipeline.NewPipelineService(tenantRepo, vaultService, dedup.NewDedupService(feedbackRepo), tagRuleService, cfg.PIIHashKey)
+
+	strategies := integrations.NewStrategies(integrations.NewSourceClient(integrations.SourceClientConfigFrom(cfg), ratelimit.NewRateLimiter(db.NewRateLimitRepository(dbpool))))
+	subService, err := newSubscriptionService(dbpool, cfg, strategies)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := secrets.NewKeyring(cfg.SecretKeys)
+	if err != nil {
+		return err
+	}
+	if !keyring.Enabled() {
+		return fmt.Errorf("SECRET_KEYS is not set, the archived payloads cannot be decrypted")
+	}
+
+	integrationManager := integrations.NewIntegrationManager(strategies, feedback.NewFeedbackService(feedbackRepo), pipelineService,
+		subService, inbox.NewInboxService(db.NewWebhookInboxRepository(dbpool), keyring),
+		archive.NewArchiveService(db.NewRawPayloadRepository(dbpool), keyring), deadletter.NewDeadLetterService(db.NewDeadLetterRepository(dbpool), keyring), int64(cfg.WebhookMaxBodyBytes))
+
+	result, err := integrationManager.Reprocess(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for _, payloadError := range result.Errors {
+		fmt.Fprintf(os.Stderr, "payload %s: %s\n", payloadError.PayloadID, payloadError.Error)
+	}
+	if *dryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, change := range result.Changes {
+			encoder.Encode(change)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Reprocessed %d payloads into %d feedback records: %d new, %d changed, %d unchanged, %d payloads failed\n",
+		result.Payloads, result.Feedbacks, result.New, result.Changed, result.Unchanged, result.Failed)
+	return nil
+}
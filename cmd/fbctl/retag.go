@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func retag(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("retag", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID (required)")
+	flags.Parse(args)
+
+	if *tenantID == "" {
+		return fmt.Errorf("-tenant is required")
+	}
+
+	tagRuleService := tagrule.NewTagRuleService(db.NewTagRuleRepository(dbpool), db.NewFeedbackRepository(dbpool))
+	processed, tagged, err := tagRuleService.Retag(ctx, *tenantID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Re-tagged %d feedback records, %d tagged by a rule\n", processed, tagged)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/analytics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func rollup(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("rollup", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID, every tenant when empty")
+	full := flags.Bool("full", false, "rebuild the rollups from scratch")
+	flags.Parse(args)
+
+	analyticsService := analytics.NewAnalyticsService(db.NewAnalyticsRepository(dbpool), db.NewTenantRepository(dbpool))
+	if *tenantID != "" {
+		if err := analyticsService.Refresh(ctx, *tenantID, *full); err != nil {
+			return err
+		}
+		fmt.Println("Refreshed the analytics rollups")
+		return nil
+	}
+
+	failed, err := analyticsService.RefreshAll(ctx, *full)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("the rollups of %d tenants failed to refresh", failed)
+	}
+
+	fmt.Println("Refreshed the analytics rollups")
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/topics"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func extractTopics(ctx context.Context, dbpool *pgxpool.Pool, args []string) error {
+	flags := flag.NewFlagSet("topics", flag.ExitOnError)
+	tenantID := flags.String("tenant", "", "tenant ID (required)")
+	from := flags.String("from", "", "start of the window, RFC3339 (default 7 days before -to)")
+	to := flags.String("to", "", "end of the window, RFC3339 (default now)")
+	flags.Parse(args)
+
+	if *tenantID == "" {
+		return fmt.Errorf("-tenant is required")
+	}
+
+	end := time.Now().UTC()
+	if *to != "" {
+		t, err := time.Parse(time.RFC3339, *to)
+		if err != nil {
+			return fmt.Errorf("invalid -to: %v", err)
+		}
+		end = t
+	}
+	start := end.Add(-topics.DefaultWindow)
+	if *from != "" {
+		t, err := time.Parse(time.RFC3339, *from)
+		if err != nil {
+			return fmt.Errorf("invalid -from: %v", err)
+		}
+		start = t
+	}
+
+	topicService := topics.NewTopicService(db.NewTopicRepository(dbpool), db.NewTenantRepository(dbpool))
+	found, members, err := topicService.Run(ctx, *tenantID, start, end)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Extracted %d topics, covering %d feedback records\n", found, members)
+	return nil
+}
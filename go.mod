@@ -18,5 +18,5 @@ require (
 	github.com/jackc/puddle v1.3.0 // indirect
 	github.com/robfig/cron/v3 v3.0.1
 	golang.org/x/crypto v0.20.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/text v0.14.0
 )
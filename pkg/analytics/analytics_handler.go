@@ -0,0 +1,93 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type AnalyticsHandler struct {
+	service *AnalyticsService
+}
+
+func NewAnalyticsHandler(service *AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// CountFeedbackHandler counts the feedback matching the search filters per
+// interval (hour, day by default, or week), grouped by the comma-separated
+// group_by dimensions. The from and to filters apply to when the feedback
+// was written. format=csv returns CSV instead of JSON.
+func (h *AnalyticsHandler) CountFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	filter, err := feedback.ParseFeedbackFilter(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := &models.AnalyticsQuery{Filter: *filter, Interval: params.Get("interval")}
+	if query.Interval == "" {
+		query.Interval = "day"
+	}
+	if !db.AnalyticsIntervals[query.Interval] {
+		http.Error(w, "Invalid interval, expected hour, day or week", http.StatusBadRequest)
+		return
+	}
+	if groupBy := params.Get("group_by"); groupBy != "" {
+		seen := map[string]bool{}
+		for _, dim := range strings.Split(groupBy, ",") {
+			dim = strings.TrimSpace(dim)
+			if _, ok := db.AnalyticsDimensions[dim]; !ok {
+				http.Error(w, fmt.Sprintf("Invalid group_by %q", dim), http.StatusBadRequest)
+				return
+			}
+			if !seen[dim] {
+				seen[dim] = true
+				query.GroupBy = append(query.GroupBy, dim)
+			}
+		}
+	}
+
+	format := params.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	rows, err := h.service.Count(ctx, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count feedback: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writeCSV(w, query.GroupBy, rows)
+		return
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+func writeCSV(w http.ResponseWriter, groupBy []string, rows []*models.AnalyticsRow) {
+	out := csv.NewWriter(w)
+	out.Write(append(append([]string{"bucket"}, groupBy...), "count"))
+	for _, row := range rows {
+		record := []string{row.Bucket.UTC().Format(time.RFC3339)}
+		for _, dim := range groupBy {
+			record = append(record, row.Dimensions[dim])
+		}
+		record = append(record, strconv.Itoa(row.Count))
+		out.Write(record)
+	}
+	out.Flush()
+}
@@ -0,0 +1,46 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type AnalyticsService struct {
+	repo       *db.AnalyticsRepository
+	tenantRepo *db.TenantRepository
+}
+
+func NewAnalyticsService(repo *db.AnalyticsRepository, tenantRepo *db.TenantRepository) *AnalyticsService {
+	return &AnalyticsService{repo: repo, tenantRepo: tenantRepo}
+}
+
+func (s *AnalyticsService) Count(ctx context.Context, query *models.AnalyticsQuery) ([]*models.AnalyticsRow, error) {
+	return s.repo.Count(ctx, query)
+}
+
+// Refresh rolls up the tenant's feedback counts up to the last full hour.
+func (s *AnalyticsService) Refresh(ctx context.Context, tenantID string, full bool) error {
+	return s.repo.Refresh(ctx, tenantID, full)
+}
+
+// RefreshAll refreshes the rollups of every tenant, it returns the number of
+// tenants whose refresh failed.
+func (s *AnalyticsService) RefreshAll(ctx context.Context, full bool) (int, error) {
+	tenantIDs, err := s.tenantRepo.ListIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, tenantID := range tenantIDs {
+		if err := s.repo.Refresh(ctx, tenantID, full); err != nil {
+			fmt.Printf("Failed to refresh the rollups of tenant %s: %v\n", tenantID, err)
+			failed++
+		}
+	}
+
+	return failed, nil
+}
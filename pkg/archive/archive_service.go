@@ -0,0 +1,114 @@
+// Package archive keeps the raw payloads received from the sources, gzip
+// compressed and encrypted with the secret keys, so the feedback mapped from
+// them can be mapped again after a fix to a strategy.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+)
+
+// pageSize is the number of payloads read from the archive at a time
+const pageSize = 100
+
+// payloadNamespace derives the payload IDs from their content, the same
+// payload received twice (a webhook retried, a page pulled again) is
+// archived once
+var payloadNamespace = uuid.MustParse("5b0c8d1e-6a3f-4f57-9a2e-2f4c1d7e8b90")
+
+// ArchiveService archives the payloads. They hold the personal data the
+// pipeline redacts from the feedback, so they are encrypted with the keyring,
+// bound to their ID, when it has a key, and kept for a retention period.
+type ArchiveService struct {
+	repo    *db.RawPayloadRepository
+	keyring *secrets.Keyring
+}
+
+func NewArchiveService(repo *db.RawPayloadRepository, keyring *secrets.Keyring) *ArchiveService {
+	return &ArchiveService{repo: repo, keyring: keyring}
+}
+
+// Store archives the payload and sets its ID.
+func (s *ArchiveService) Store(ctx context.Context, payload *models.RawPayload) error {
+	var key bytes.Buffer
+	fmt.Fprintf(&key, "%s\x00%s\x00%s\x00", payload.TenantID, payload.SubscriptionID, payload.Kind)
+	key.Write(payload.Data)
+	payload.ID = uuid.NewSHA1(payloadNamespace, key.Bytes()).String()
+	payload.Size = len(payload.Data)
+	if payload.ReceivedAt.IsZero() {
+		payload.ReceivedAt = time.Now().UTC()
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(payload.Data); err != nil {
+		return fmt.Errorf("failed to compress payload: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress payload: %v", err)
+	}
+
+	stored := *payload
+	var err error
+	if stored.Data, err = s.keyring.EncryptData(compressed.Bytes(), []byte(payload.ID)); err != nil {
+		return fmt.Errorf("failed to encrypt payload: %v", err)
+	}
+	return s.repo.Save(ctx, &stored)
+}
+
+// Purge deletes the payloads received more than retention ago, it returns
+// the number of payloads deleted.
+func (s *ArchiveService) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// Each calls fn with every archived payload of the request, decompressed,
+// in the order they were received. It stops at the first error of fn.
+func (s *ArchiveService) Each(ctx context.Context, req *models.ReprocessRequest, fn func(*models.RawPayload) error) error {
+	var (
+		afterReceivedAt time.Time
+		afterID         string
+	)
+	for {
+		payloads, err := s.repo.List(ctx, req, afterReceivedAt, afterID, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, payload := range payloads {
+			if payload.Data, err = s.keyring.DecryptData(payload.Data, []byte(payload.ID)); err != nil {
+				return fmt.Errorf("failed to decrypt payload %s: %v", payload.ID, err)
+			}
+			if payload.Data, err = decompress(payload.Data); err != nil {
+				return fmt.Errorf("failed to decompress payload %s: %v", payload.ID, err)
+			}
+			if err := fn(payload); err != nil {
+				return err
+			}
+		}
+
+		if len(payloads) < pageSize {
+			return nil
+		}
+		last := payloads[len(payloads)-1]
+		afterReceivedAt, afterID = last.ReceivedAt, last.ID
+	}
+}
+
+func decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
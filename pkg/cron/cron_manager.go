@@ -3,28 +3,47 @@ package cron
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/analytics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/archive"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/deadletter"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/inbox"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/integrations"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/topics"
 	"github.com/robfig/cron/v3"
 )
 
+// pullRecordTimeout bounds the recording of the outcome of a pull
+const pullRecordTimeout = 30 * time.Second
+
 type CronManager struct {
 	subService         *subscription.SubscriptionService
 	integrationManager *integrations.IntegrationManager
+	topicService       *topics.TopicService
+	analyticsService   *analytics.AnalyticsService
+	deadLetterService  *deadletter.DeadLetterService
+	inboxService       *inbox.InboxService
+	archiveService     *archive.ArchiveService
 	cron               *cron.Cron
 }
 
-func NewCronManager(subService *subscription.SubscriptionService, integrationManager *integrations.IntegrationManager) *CronManager {
+func NewCronManager(subService *subscription.SubscriptionService, integrationManager *integrations.IntegrationManager, topicService *topics.TopicService, analyticsService *analytics.AnalyticsService, deadLetterService *deadletter.DeadLetterService, inboxService *inbox.InboxService, archiveService *archive.ArchiveService) *CronManager {
 	return &CronManager{
 		subService:         subService,
 		integrationManager: integrationManager,
+		topicService:       topicService,
+		analyticsService:   analyticsService,
+		deadLetterService:  deadLetterService,
+		inboxService:       inboxService,
+		archiveService:     archiveService,
 		cron:               cron.New(cron.WithSeconds()),
 	}
 }
 
-func (cm *CronManager) StartGlobalPullJob(ctx context.Context, interval time.Duration) error {
+func (cm *CronManager) StartGlobalPullJob(ctx context.Context, interval time.Duration, disableAfter int) error {
 	jobFunc := func() {
 		subscriptions, err := cm.subService.GetAllActivePullSubscriptions(ctx)
 		if err != nil {
@@ -34,17 +53,26 @@ func (cm *CronManager) StartGlobalPullJob(ctx context.Context, interval time.Dur
 
 		for _, sub := range subscriptions {
 			jobCtx, cancel := context.WithTimeout(ctx, interval)
-			defer cancel()
-
 			_, err := cm.integrationManager.Pull(jobCtx, sub)
+			cancel()
+
+			// the pull may have timed out, its outcome is recorded all the same
+			recordCtx, cancelRecord := context.WithTimeout(context.Background(), pullRecordTimeout)
 			if err != nil {
 				fmt.Printf("Error pulling data for subscription %s: %v\n", sub.ID, err)
+				if err := cm.subService.RecordPullFailure(recordCtx, sub, err, interval, disableAfter); err != nil {
+					fmt.Printf("Failed to record the failure of subscription %s: %v\n", sub.ID, err)
+				}
 			} else {
-				err := cm.subService.UpdateLastPulled(jobCtx, sub.ID)
+				err := cm.subService.UpdateLastPulled(recordCtx, sub.ID)
 				if err != nil {
 					fmt.Printf("Failed to update last pulled time for subscription %s: %v\n", sub.ID, err)
 				}
+				if err := cm.subService.RecordPullSuccess(recordCtx, sub); err != nil {
+					fmt.Printf("Failed to record the success of subscription %s: %v\n", sub.ID, err)
+				}
 			}
+			cancelRecord()
 		}
 	}
 
@@ -58,3 +86,154 @@ func (cm *CronManager) StartGlobalPullJob(ctx context.Context, interval time.Dur
 	cm.cron.Start()
 	return nil
 }
+
+// StartTopicJob extracts the topics of every tenant over the last window,
+// every interval.
+func (cm *CronManager) StartTopicJob(ctx context.Context, interval, window time.Duration) error {
+	jobFunc := func() {
+		if err := cm.topicService.RunAll(ctx, window); err != nil {
+			fmt.Printf("Failed to run topic job: %v\n", err)
+		}
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule topic job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartRollupJob refreshes the analytics rollups of every tenant, every
+// interval.
+func (cm *CronManager) StartRollupJob(ctx context.Context, interval time.Duration) error {
+	jobFunc := func() {
+		if _, err := cm.analyticsService.RefreshAll(ctx, false); err != nil {
+			fmt.Printf("Failed to refresh analytics rollups: %v\n", err)
+		}
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule rollup job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartInboxJob runs workers ingesting the webhook inbox every interval.
+// A run lasts until the inbox has nothing due, runs may overlap.
+func (cm *CronManager) StartInboxJob(ctx context.Context, interval time.Duration, workers int) error {
+	jobFunc := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := cm.integrationManager.ProcessInbox(ctx); err != nil {
+					fmt.Printf("Failed to process the webhook inbox: %v\n", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook inbox job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartInboxPurgeJob deletes the webhook calls done for longer than
+// retention every interval.
+func (cm *CronManager) StartInboxPurgeJob(ctx context.Context, interval, retention time.Duration) error {
+	jobFunc := func() {
+		purged, err := cm.inboxService.Purge(ctx, retention)
+		if err != nil {
+			fmt.Printf("Failed to purge the webhook inbox: %v\n", err)
+			return
+		}
+		if purged > 0 {
+			fmt.Printf("Purged %d webhook calls from the inbox\n", purged)
+		}
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook inbox purge job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartArchivePurgeJob deletes the payloads archived for longer than
+// retention every interval.
+func (cm *CronManager) StartArchivePurgeJob(ctx context.Context, interval, retention time.Duration) error {
+	jobFunc := func() {
+		purged, err := cm.archiveService.Purge(ctx, retention)
+		if err != nil {
+			fmt.Printf("Failed to purge the payload archive: %v\n", err)
+			return
+		}
+		if purged > 0 {
+			fmt.Printf("Purged %d payloads from the archive\n", purged)
+		}
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule archive purge job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartDeadLetterPurgeJob deletes the dead letters not retried for longer
+// than retention every interval.
+func (cm *CronManager) StartDeadLetterPurgeJob(ctx context.Context, interval, retention time.Duration) error {
+	jobFunc := func() {
+		purged, err := cm.deadLetterService.Purge(ctx, retention)
+		if err != nil {
+			fmt.Printf("Failed to purge the dead letter queue: %v\n", err)
+			return
+		}
+		if purged > 0 {
+			fmt.Printf("Purged %d payloads from the dead letter queue\n", purged)
+		}
+	}
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule dead letter purge job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
+
+// StartDeadLetterMetricsJob refreshes the dead letter queue size of every
+// tenant now and every interval.
+func (cm *CronManager) StartDeadLetterMetricsJob(ctx context.Context, interval time.Duration) error {
+	jobFunc := func() {
+		if err := cm.deadLetterService.RefreshMetrics(ctx); err != nil {
+			fmt.Printf("Failed to refresh dead letter metrics: %v\n", err)
+		}
+	}
+
+	go jobFunc()
+
+	_, err := cm.cron.AddFunc(fmt.Sprintf("@every %s", interval.String()), jobFunc)
+	if err != nil {
+		return fmt.Errorf("failed to schedule dead letter metrics job: %v", err)
+	}
+
+	cm.cron.Start()
+	return nil
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// AnalyticsDimensions are the dimensions feedback counts can be grouped by,
+// with their expression over the feedback table (joined with feedback_tag as
+// ft for tags) and over the rollup tables.
+var AnalyticsDimensions = map[string]struct{ raw, rollup string }{
+	"source":        {`feedback.source`, `r.source`},
+	"source_type":   {`feedback.source_type`, `r.source_type`},
+	"sub_source_id": {`feedback.sub_source_id::TEXT`, `r.sub_source_id::TEXT`},
+	"language":      {`feedback.language`, `r.language`},
+	"rating":        {`COALESCE(feedback.rating::TEXT, '')`, `COALESCE(r.rating::TEXT, '')`},
+	"sentiment":     {`feedback.sentiment`, `r.sentiment`},
+	"tag":           {`ft.tag`, `r.tag`},
+}
+
+// AnalyticsIntervals are the supported bucket sizes.
+var AnalyticsIntervals = map[string]bool{"hour": true, "day": true, "week": true}
+
+// the hour of a feedback, rollup buckets are hours in UTC
+const feedbackHour = `date_trunc('hour', ` + feedbackTime + `, 'UTC')`
+
+// AnalyticsRepository counts feedback. Full hours are pre-aggregated into
+// the rollup tables by Refresh, counts are read from the rollups up to the
+// tenant's last refresh and from the feedback table after it.
+type AnalyticsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAnalyticsRepository(db *pgxpool.Pool) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+func (repo *AnalyticsRepository) Count(ctx context.Context, q *models.AnalyticsQuery) ([]*models.AnalyticsRow, error) {
+	if !AnalyticsIntervals[q.Interval] {
+		return nil, fmt.Errorf("unsupported interval %q", q.Interval)
+	}
+	byTag := false
+	for _, dim := range q.GroupBy {
+		if _, ok := AnalyticsDimensions[dim]; !ok {
+			return nil, fmt.Errorf("unsupported group_by %q", dim)
+		}
+		if dim == "tag" {
+			byTag = true
+		}
+	}
+
+	// From and To apply to when the feedback was written, not to source_created_at
+	filter := q.Filter
+	filter.From, filter.To = time.Time{}, time.Time{}
+	filter.Limit, filter.Offset = 0, 0
+
+	where, args := feedbackFilterClause(&filter)
+	add := func(condition string, value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf(condition, len(args))
+	}
+
+	rawFrom := `feedback`
+	if byTag {
+		rawFrom += ` JOIN feedback_tag ft ON ft.tenant_id = feedback.tenant_id AND ft.source = feedback.source AND ft.feedback_id = feedback.id`
+		if filter.Tag != "" {
+			where += " AND " + add("ft.tag = $%d", filter.Tag)
+		}
+	}
+	if !q.Filter.From.IsZero() {
+		where += " AND " + add(feedbackTime+" >= $%d", q.Filter.From)
+	}
+	if !q.Filter.To.IsZero() {
+		where += " AND " + add(feedbackTime+" < $%d", q.Filter.To)
+	}
+
+	rawColumns := []string{feedbackHour + " AS bucket"}
+	rollupColumns := []string{"r.bucket"}
+	groupColumns := []string{"b"}
+	for i, dim := range q.GroupBy {
+		rawColumns = append(rawColumns, fmt.Sprintf("%s AS d%d", AnalyticsDimensions[dim].raw, i))
+		rollupColumns = append(rollupColumns, fmt.Sprintf("%s AS d%d", AnalyticsDimensions[dim].rollup, i))
+		groupColumns = append(groupColumns, fmt.Sprintf("d%d", i))
+	}
+
+	// the rollups only hold the dimensions, the hours and the tenant's feedback up to its watermark
+	// feedbackFilterClause binds the tenant first
+	tenantArg := 1
+	rows := `SELECT ` + strings.Join(rawColumns, ", ") + `, 1 AS n FROM ` + rawFrom + `
+        WHERE ` + where
+	if rollupCompatible(&q.Filter) {
+		rows += ` AND ` + feedbackTime + ` >= (SELECT watermark FROM wm)`
+
+		rollupTable := `feedback_rollup`
+		if byTag || filter.Tag != "" {
+			rollupTable = `feedback_tag_rollup`
+		}
+		conditions := []string{fmt.Sprintf("r.tenant_id = $%d", tenantArg), "r.bucket < (SELECT watermark FROM wm)"}
+		if filter.Source != "" {
+			conditions = append(conditions, add("r.source = $%d", filter.Source))
+		}
+		if filter.SubSourceID != "" {
+			conditions = append(conditions, add("r.sub_source_id = $%d", filter.SubSourceID))
+		}
+		if filter.SourceType != "" {
+			conditions = append(conditions, add("r.source_type = $%d", filter.SourceType))
+		}
+		if filter.Language != "" {
+			conditions = append(conditions, add("r.language = $%d", filter.Language))
+		}
+		if filter.Tag != "" {
+			conditions = append(conditions, add("r.tag = $%d", filter.Tag))
+		}
+		if filter.Sentiment != "" {
+			conditions = append(conditions, add("r.sentiment = $%d", filter.Sentiment))
+		}
+		if filter.MinRating != nil {
+			conditions = append(conditions, add("r.rating >= $%d", *filter.MinRating))
+		}
+		if filter.MaxRating != nil {
+			conditions = append(conditions, add("r.rating <= $%d", *filter.MaxRating))
+		}
+		if !q.Filter.From.IsZero() {
+			conditions = append(conditions, add("r.bucket >= $%d", q.Filter.From))
+		}
+		if !q.Filter.To.IsZero() {
+			conditions = append(conditions, add("r.bucket < $%d", q.Filter.To))
+		}
+
+		rows += `
+        UNION ALL
+        SELECT ` + strings.Join(rollupColumns, ", ") + `, r.count AS n FROM ` + rollupTable + ` r
+        WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	query := `
+        WITH wm AS (
+            SELECT COALESCE((SELECT refreshed_until FROM feedback_rollup_state WHERE tenant_id = $` + fmt.Sprint(tenantArg) + `), '-infinity'::TIMESTAMPTZ) AS watermark
+        ), counts AS (
+            ` + rows + `
+        )
+        SELECT date_trunc(` + add("$%d", q.Interval) + `, bucket, 'UTC') AS b` + strings.Join(append([]string{""}, groupColumns[1:]...), ", ") + `, SUM(n)::BIGINT
+        FROM counts
+        GROUP BY ` + strings.Join(groupColumns, ", ") + `
+        ORDER BY ` + strings.Join(groupColumns, ", ")
+
+	result, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feedback: %v", err)
+	}
+	defer result.Close()
+
+	counts := []*models.AnalyticsRow{}
+	for result.Next() {
+		row := &models.AnalyticsRow{}
+		values := make([]string, len(q.GroupBy))
+		dest := []interface{}{&row.Bucket}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &row.Count)
+		if err := result.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback count: %v", err)
+		}
+		if len(values) > 0 {
+			row.Dimensions = make(map[string]string, len(values))
+			for i, dim := range q.GroupBy {
+				row.Dimensions[dim] = values[i]
+			}
+		}
+		counts = append(counts, row)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return counts, nil
+}
+
+// rollupCompatible reports whether the rollups have everything the filter
+// needs: its dimensions, and a window starting and ending on the hour.
+func rollupCompatible(filter *models.FeedbackFilter) bool {
+	if filter.Author != "" || filter.DuplicateClusterID != "" || filter.CollapseDuplicates ||
+		filter.MinSentiment != nil || filter.MaxSentiment != nil ||
+		filter.Status != "" || filter.Assignee != "" || filter.Priority != "" || filter.HasNotes != nil {
+		return false
+	}
+	onTheHour := func(t time.Time) bool {
+		return t.IsZero() || t.Equal(t.Truncate(time.Hour))
+	}
+	return onTheHour(filter.From) && onTheHour(filter.To)
+}
+
+// Refresh rolls up the tenant's full hours. The hours with feedback ingested,
+// updated or tagged since the last refresh are recomputed, with the hours
+// marked stale in feedback_rollup_stale: the hours feedback was updated in
+// or out of, deleted from, or had a tag removed in. Every hour is recomputed when
+// full is set.
+func (repo *AnalyticsRepository) Refresh(ctx context.Context, tenantID string, full bool) error {
+	watermark := time.Now().UTC().Truncate(time.Hour)
+	startedAt := time.Now().UTC()
+
+	// the stale hours are taken, the ones marked meanwhile are left to the
+	// next refresh
+	changedQuery := `
+        WITH stale AS (
+            DELETE FROM feedback_rollup_stale WHERE tenant_id = $1 AND bucket < $2
+            RETURNING bucket
+        )
+        SELECT COALESCE(array_agg(DISTINCT hour), '{}') FROM (
+            SELECT ` + feedbackHour + ` AS hour FROM feedback
+            WHERE feedback.tenant_id = $1 AND ` + feedbackTime + ` < $2 AND (
+                $3
+                OR ` + feedbackTime + ` >= COALESCE((SELECT refreshed_until FROM feedback_rollup_state WHERE tenant_id = $1), '-infinity'::TIMESTAMPTZ)
+                OR feedback.updated_at >= (SELECT refreshed_at FROM feedback_rollup_state WHERE tenant_id = $1)
+                OR EXISTS (SELECT 1 FROM feedback_tag ft WHERE ft.tenant_id = feedback.tenant_id AND ft.source = feedback.source AND ft.feedback_id = feedback.id
+                             AND ft.created_at >= (SELECT refreshed_at FROM feedback_rollup_state WHERE tenant_id = $1))
+            )
+            UNION
+            SELECT bucket FROM stale
+        ) changed
+    `
+	rollupQuery := `
+        INSERT INTO feedback_rollup (tenant_id, bucket, source, source_type, sub_source_id, language, rating, sentiment, count)
+        SELECT feedback.tenant_id, ` + feedbackHour + `, feedback.source, feedback.source_type, feedback.sub_source_id, feedback.language, feedback.rating, feedback.sentiment, COUNT(*)
+        FROM feedback
+        WHERE feedback.tenant_id = $1 AND ` + feedbackHour + ` = ANY($2)
+        GROUP BY 1, 2, 3, 4, 5, 6, 7, 8
+    `
+	tagRollupQuery := `
+        INSERT INTO feedback_tag_rollup (tenant_id, bucket, source, source_type, sub_source_id, language, rating, sentiment, tag, count)
+        SELECT feedback.tenant_id, ` + feedbackHour + `, feedback.source, feedback.source_type, feedback.sub_source_id, feedback.language, feedback.rating, feedback.sentiment, ft.tag, COUNT(*)
+        FROM feedback
+        JOIN feedback_tag ft ON ft.tenant_id = feedback.tenant_id AND ft.source = feedback.source AND ft.feedback_id = feedback.id
+        WHERE feedback.tenant_id = $1 AND ` + feedbackHour + ` = ANY($2)
+        GROUP BY 1, 2, 3, 4, 5, 6, 7, 8, 9
+    `
+	stateQuery := `
+        INSERT INTO feedback_rollup_state (tenant_id, refreshed_until, refreshed_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (tenant_id) DO UPDATE SET refreshed_until = EXCLUDED.refreshed_until, refreshed_at = EXCLUDED.refreshed_at
+    `
+
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var hours []time.Time
+		if err := tx.QueryRow(ctx, changedQuery, tenantID, watermark, full).Scan(&hours); err != nil {
+			return fmt.Errorf("failed to find changed hours: %v", err)
+		}
+
+		for _, table := range []string{"feedback_rollup", "feedback_tag_rollup"} {
+			var err error
+			if full {
+				_, err = tx.Exec(ctx, `DELETE FROM `+table+` WHERE tenant_id = $1`, tenantID)
+			} else {
+				_, err = tx.Exec(ctx, `DELETE FROM `+table+` WHERE tenant_id = $1 AND bucket = ANY($2)`, tenantID, hours)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to clear %s: %v", table, err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, rollupQuery, tenantID, hours); err != nil {
+			return fmt.Errorf("failed to roll up feedback: %v", err)
+		}
+		if _, err := tx.Exec(ctx, tagRollupQuery, tenantID, hours); err != nil {
+			return fmt.Errorf("failed to roll up feedback tags: %v", err)
+		}
+		if _, err := tx.Exec(ctx, stateQuery, tenantID, watermark, startedAt); err != nil {
+			return fmt.Errorf("failed to save rollup state: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refresh feedback rollups: %v", err)
+	}
+
+	return nil
+}
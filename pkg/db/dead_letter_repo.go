@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const deadLetterColumns = `id, tenant_id, subscription_id, source, kind, payload, stage, error, attempts, created_at, last_attempt_at`
+
+type DeadLetterRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDeadLetterRepository(db *pgxpool.Pool) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+func (repo *DeadLetterRepository) Add(ctx context.Context, entry *models.DeadLetter) error {
+	query := `
+        INSERT INTO dead_letter (` + deadLetterColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	entry.CreatedAt = time.Now().UTC()
+	entry.LastAttemptAt = entry.CreatedAt
+	if entry.Attempts == 0 {
+		entry.Attempts = 1
+	}
+
+	_, err := repo.db.Exec(ctx, query, entry.ID, entry.TenantID, entry.SubscriptionID, entry.Source, entry.Kind, entry.Payload,
+		entry.Stage, entry.Error, entry.Attempts, entry.CreatedAt, entry.LastAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *DeadLetterRepository) Get(ctx context.Context, id string) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letter WHERE id = $1`
+
+	entry, err := scanDeadLetter(repo.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %v", err)
+	}
+	return entry, nil
+}
+
+// List returns the entries matching the filter, the last added first.
+func (repo *DeadLetterRepository) List(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	where, args := deadLetterFilterClause(filter)
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letter WHERE ` + where + ` ORDER BY created_at DESC`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.DeadLetter
+	for rows.Next() {
+		entry, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return entries, nil
+}
+
+// RecordAttempt records a failed retry of the entry.
+func (repo *DeadLetterRepository) RecordAttempt(ctx context.Context, entry *models.DeadLetter) error {
+	query := `
+        UPDATE dead_letter SET stage = $2, error = $3, attempts = attempts + 1, last_attempt_at = NOW()
+        WHERE id = $1
+        RETURNING attempts, last_attempt_at
+    `
+
+	err := repo.db.QueryRow(ctx, query, entry.ID, entry.Stage, entry.Error).Scan(&entry.Attempts, &entry.LastAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to record dead letter attempt: %v", err)
+	}
+	return nil
+}
+
+func (repo *DeadLetterRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM dead_letter WHERE id = $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no dead letter found with ID %s", id)
+	}
+	return nil
+}
+
+// Purge deletes the entries last attempted before the time, it returns the
+// number of entries deleted.
+func (repo *DeadLetterRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM dead_letter WHERE last_attempt_at < $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %v", err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+// DeleteMatching removes the entries matching the filter, its limit and
+// offset ignored, and returns how many were removed.
+func (repo *DeadLetterRepository) DeleteMatching(ctx context.Context, filter *models.DeadLetterFilter) (int, error) {
+	where, args := deadLetterFilterClause(filter)
+	query := `DELETE FROM dead_letter WHERE ` + where
+
+	cmdTag, err := repo.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dead letters: %v", err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+// CountByTenant returns the number of entries of each tenant having some,
+// or of the given tenant only.
+func (repo *DeadLetterRepository) CountByTenant(ctx context.Context, tenantID string) (map[string]int, error) {
+	query := `SELECT tenant_id, COUNT(*) FROM dead_letter WHERE ($1 = '' OR tenant_id::text = $1) GROUP BY tenant_id`
+
+	rows, err := repo.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			tenant string
+			count  int
+		)
+		if err := rows.Scan(&tenant, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter count: %v", err)
+		}
+		counts[tenant] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return counts, nil
+}
+
+func deadLetterFilterClause(filter *models.DeadLetterFilter) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{filter.TenantID}
+
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if filter.SubscriptionID != "" {
+		add("subscription_id", filter.SubscriptionID)
+	}
+	if filter.Source != "" {
+		add("source", filter.Source)
+	}
+	if filter.Stage != "" {
+		add("stage", filter.Stage)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func scanDeadLetter(row pgx.Row) (*models.DeadLetter, error) {
+	entry := &models.DeadLetter{}
+	err := row.Scan(&entry.ID, &entry.TenantID, &entry.SubscriptionID, &entry.Source, &entry.Kind, &entry.Payload,
+		&entry.Stage, &entry.Error, &entry.Attempts, &entry.CreatedAt, &entry.LastAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -3,13 +3,42 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const feedbackColumns = `id, tenant_id, source, sub_source_id, source_type, language, language_confidence, author, url, rating, source_created_at, ingested_at, created_at, updated_at, metadata, content, minhash, duplicate_cluster_id, sentiment_score, sentiment, raw_payload_id`
+
+// feedbackSelectColumns are the feedback columns followed by its tags, the
+// size of its duplicate cluster, its triage and the number of its notes
+const feedbackSelectColumns = feedbackColumns + `,
+        ARRAY(SELECT t.tag FROM feedback_tag t WHERE t.tenant_id = feedback.tenant_id AND t.source = feedback.source AND t.feedback_id = feedback.id ORDER BY t.tag) AS tags,
+        (SELECT COUNT(*) FROM feedback d WHERE d.tenant_id = feedback.tenant_id AND d.duplicate_cluster_id = feedback.duplicate_cluster_id) AS duplicate_count,
+        ` + triageStatusExpr + ` AS status,
+        ` + triageAssigneeExpr + ` AS assignee,
+        ` + triagePriorityExpr + ` AS priority,
+        (SELECT COUNT(*) FROM feedback_note n WHERE n.tenant_id = feedback.tenant_id AND n.source = feedback.source AND n.feedback_id = feedback.id) AS note_count`
+
+// the triage fields of the feedback, defaulting to those of untriaged feedback
+const (
+	triageMatch        = `FROM feedback_triage tr WHERE tr.tenant_id = feedback.tenant_id AND tr.source = feedback.source AND tr.feedback_id = feedback.id`
+	triageStatusExpr   = `COALESCE((SELECT tr.status ` + triageMatch + `), 'new')`
+	triageAssigneeExpr = `COALESCE((SELECT tr.assignee ` + triageMatch + `), '')`
+	triagePriorityExpr = `COALESCE((SELECT tr.priority ` + triageMatch + `), '')`
+)
+
+// Tag origins, kept with each tag to tell apart where it comes from
+const (
+	TagOriginPipeline = "pipeline"
+	TagOriginRule     = "rule"
+	TagOriginManual   = "manual"
+)
+
 type FeedbackRepository struct {
 	db *pgxpool.Pool
 }
@@ -20,33 +49,206 @@ func NewFeedbackRepository(db *pgxpool.Pool) *FeedbackRepository {
 
 func (repo *FeedbackRepository) Save(ctx context.Context, feedback *models.Feedback) error {
 	query := `
-        INSERT INTO feedback (id, tenant_id, source, sub_source_id, source_type, created_at, updated_at, metadata, content)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        INSERT INTO feedback (` + feedbackColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+    `
+
+	setSaveDefaults(feedback)
+
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, query, feedbackArgs(feedback)...); err != nil {
+			return err
+		}
+		if err := addSignatureBands(ctx, tx, feedback); err != nil {
+			return err
+		}
+		// rule tags first, a tag both added by a rule and by the pipeline is kept as the rule's
+		if err := addRuleTags(ctx, tx, feedback); err != nil {
+			return err
+		}
+		return addTags(ctx, tx, feedback, TagOriginPipeline, feedback.Tags)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feedback %v", err)
+	}
+
+	return nil
+}
+
+// Upsert saves the feedback in one transaction, replacing the stored
+// feedback with the same ID, tenant and source. The creation and ingestion
+// times, the triage, the notes and the manual tags of replaced feedback are
+// kept. It returns the number of feedback inserted and replaced.
+func (repo *FeedbackRepository) Upsert(ctx context.Context, feedbacks []*models.Feedback) (int, int, error) {
+	query := `
+        INSERT INTO feedback (` + feedbackColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+        ON CONFLICT (id, tenant_id, source) DO UPDATE SET
+            sub_source_id = EXCLUDED.sub_source_id,
+            source_type = EXCLUDED.source_type,
+            language = EXCLUDED.language,
+            language_confidence = EXCLUDED.language_confidence,
+            author = EXCLUDED.author,
+            url = EXCLUDED.url,
+            rating = EXCLUDED.rating,
+            source_created_at = EXCLUDED.source_created_at,
+            updated_at = EXCLUDED.updated_at,
+            metadata = EXCLUDED.metadata,
+            content = EXCLUDED.content,
+            minhash = EXCLUDED.minhash,
+            duplicate_cluster_id = COALESCE(EXCLUDED.duplicate_cluster_id, feedback.duplicate_cluster_id),
+            sentiment_score = EXCLUDED.sentiment_score,
+            sentiment = EXCLUDED.sentiment,
+            raw_payload_id = COALESCE(EXCLUDED.raw_payload_id, feedback.raw_payload_id)
+        RETURNING (xmax = 0) AS inserted
     `
+	clearBands := `DELETE FROM feedback_minhash_band WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3`
+	clearTags := `DELETE FROM feedback_tag WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3 AND origin <> $4`
+
+	inserted, updated := 0, 0
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		inserted, updated = 0, 0
+		for _, feedback := range feedbacks {
+			setSaveDefaults(feedback)
+
+			var isNew bool
+			if err := tx.QueryRow(ctx, query, feedbackArgs(feedback)...).Scan(&isNew); err != nil {
+				return err
+			}
+			if isNew {
+				inserted++
+			} else {
+				updated++
+				if _, err := tx.Exec(ctx, clearBands, feedback.TenantID, feedback.Source, feedback.ID); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, clearTags, feedback.TenantID, feedback.Source, feedback.ID, TagOriginManual); err != nil {
+					return err
+				}
+			}
+
+			if err := addSignatureBands(ctx, tx, feedback); err != nil {
+				return err
+			}
+			if err := addRuleTags(ctx, tx, feedback); err != nil {
+				return err
+			}
+			if err := addTags(ctx, tx, feedback, TagOriginPipeline, feedback.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to upsert feedback: %v", err)
+	}
+
+	return inserted, updated, nil
+}
 
+func setSaveDefaults(feedback *models.Feedback) {
 	if feedback.ID == "" {
 		feedback.ID = uuid.New().String()
 	}
 	if feedback.CreatedAt.IsZero() {
 		feedback.CreatedAt = time.Now().UTC()
 	}
+	if feedback.IngestedAt.IsZero() {
+		feedback.IngestedAt = feedback.CreatedAt
+	}
 	feedback.UpdatedAt = time.Now().UTC()
+}
+
+// feedbackArgs are the values of feedbackColumns
+func feedbackArgs(feedback *models.Feedback) []interface{} {
+	return []interface{}{feedback.ID, feedback.TenantID, feedback.Source, feedback.SubSourceID, feedback.SourceType,
+		feedback.Language, feedback.LanguageConfidence, feedback.Author, feedback.URL, feedback.Rating, feedback.SourceCreatedAt, feedback.IngestedAt,
+		feedback.CreatedAt, feedback.UpdatedAt, feedback.Metadata, feedback.Content, feedback.Signature, nullIfEmpty(feedback.DuplicateClusterID),
+		feedback.SentimentScore, feedback.Sentiment, nullIfEmpty(feedback.RawPayloadID)}
+}
+
+func addSignatureBands(ctx context.Context, tx pgx.Tx, feedback *models.Feedback) error {
+	query := `
+        INSERT INTO feedback_minhash_band (tenant_id, band, hash, source, feedback_id)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT DO NOTHING
+    `
+	for band, hash := range feedback.SignatureBands {
+		if _, err := tx.Exec(ctx, query, feedback.TenantID, band, hash, feedback.Source, feedback.ID); err != nil {
+			return fmt.Errorf("failed to index feedback signature: %v", err)
+		}
+	}
+	return nil
+}
 
-	_, err := repo.db.Exec(ctx, query, feedback.ID, feedback.TenantID, feedback.Source, feedback.SubSourceID, feedback.SourceType, feedback.CreatedAt, feedback.UpdatedAt, feedback.Metadata, feedback.Content)
+func addTags(ctx context.Context, tx pgx.Tx, feedback *models.Feedback, origin string, tags []string) error {
+	query := `
+        INSERT INTO feedback_tag (tenant_id, source, feedback_id, tag, origin)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (tenant_id, source, feedback_id, tag) DO NOTHING
+    `
+	for _, tag := range tags {
+		if _, err := tx.Exec(ctx, query, feedback.TenantID, feedback.Source, feedback.ID, tag, origin); err != nil {
+			return fmt.Errorf("failed to tag feedback: %v", err)
+		}
+	}
+	return nil
+}
+
+func addRuleTags(ctx context.Context, tx pgx.Tx, feedback *models.Feedback) error {
+	query := `
+        INSERT INTO feedback_tag (tenant_id, source, feedback_id, tag, origin, rule_id)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (tenant_id, source, feedback_id, tag) DO NOTHING
+    `
+	for _, ruleTag := range feedback.RuleTags {
+		if _, err := tx.Exec(ctx, query, feedback.TenantID, feedback.Source, feedback.ID, ruleTag.Tag, TagOriginRule, ruleTag.RuleID); err != nil {
+			return fmt.Errorf("failed to tag feedback: %v", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceRuleTags replaces the tags added by tag rules to each feedback by
+// its RuleTags.
+func (repo *FeedbackRepository) ReplaceRuleTags(ctx context.Context, feedbacks []*models.Feedback) error {
+	query := `DELETE FROM feedback_tag WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3 AND origin = $4`
+
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		for _, feedback := range feedbacks {
+			if _, err := tx.Exec(ctx, query, feedback.TenantID, feedback.Source, feedback.ID, TagOriginRule); err != nil {
+				return fmt.Errorf("failed to remove rule tags: %v", err)
+			}
+			if err := addRuleTags(ctx, tx, feedback); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to save feedback %v", err)
+		return fmt.Errorf("failed to replace rule tags: %v", err)
 	}
 
 	return nil
 }
 
 func (repo *FeedbackRepository) Get(ctx context.Context, feedbackID string) (*models.Feedback, error) {
-	query := `SELECT id, tenant_id, content, created_at, updated_at FROM feedback WHERE id = $1`
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE id = $1`
 
-	record := &models.Feedback{}
-	row := repo.db.QueryRow(ctx, query, feedbackID)
+	record, err := scanFeedback(repo.db.QueryRow(ctx, query, feedbackID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback record: %v", err)
+	}
+
+	return record, nil
+}
+
+// GetByKey returns the feedback by its full primary key, feedback IDs being
+// only unique per tenant and source.
+func (repo *FeedbackRepository) GetByKey(ctx context.Context, tenantID string, source models.Source, feedbackID string) (*models.Feedback, error) {
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE tenant_id = $1 AND source = $2 AND id = $3`
 
-	err := row.Scan(&record.ID, &record.TenantID, &record.Content, &record.CreatedAt, &record.UpdatedAt)
+	record, err := scanFeedback(repo.db.QueryRow(ctx, query, tenantID, source, feedbackID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get feedback record: %v", err)
 	}
@@ -54,6 +256,29 @@ func (repo *FeedbackRepository) Get(ctx context.Context, feedbackID string) (*mo
 	return record, nil
 }
 
+// GetByKeys returns the feedback of the tenant and source with the given
+// IDs, by ID. IDs without feedback are left out.
+func (repo *FeedbackRepository) GetByKeys(ctx context.Context, tenantID string, source models.Source, feedbackIDs []string) (map[string]*models.Feedback, error) {
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE tenant_id = $1 AND source = $2 AND id = ANY($3)`
+
+	rows, err := repo.db.Query(ctx, query, tenantID, source, feedbackIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	records, err := collectFeedback(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.Feedback, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+	return byID, nil
+}
+
 func (repo *FeedbackRepository) Update(ctx context.Context, feedback *models.Feedback) error {
 	query := `
         UPDATE feedback
@@ -88,7 +313,7 @@ func (repo *FeedbackRepository) Delete(ctx context.Context, feedbackID string) e
 }
 
 func (repo *FeedbackRepository) ListByTenant(ctx context.Context, tenantID string) ([]*models.Feedback, error) {
-	query := `SELECT id, tenant_id, content, created_at, updated_at FROM feedback WHERE tenant_id = $1 ORDER BY created_at DESC`
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE tenant_id = $1 ORDER BY created_at DESC`
 
 	rows, err := repo.db.Query(ctx, query, tenantID)
 	if err != nil {
@@ -96,12 +321,329 @@ func (repo *FeedbackRepository) ListByTenant(ctx context.Context, tenantID strin
 	}
 	defer rows.Close()
 
+	return collectFeedback(rows)
+}
+
+func (repo *FeedbackRepository) Search(ctx context.Context, filter *models.FeedbackFilter) ([]*models.Feedback, error) {
+	where, args := feedbackFilterClause(filter)
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE ` + where + ` ORDER BY COALESCE(source_created_at, created_at) DESC`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	return collectFeedback(rows)
+}
+
+// Stream calls fn with each feedback matching the filter, in (source, id)
+// order, as rows are read from the database: memory use does not depend on
+// the number of records.
+func (repo *FeedbackRepository) Stream(ctx context.Context, filter *models.FeedbackFilter, fn func(*models.Feedback) error) error {
+	where, args := feedbackFilterClause(filter)
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE ` + where + ` ORDER BY feedback.source, feedback.id`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to stream feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanFeedback(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan feedback record: %v", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return nil
+}
+
+// feedbackFilterClause builds the WHERE clause (without the keyword) and its
+// positional arguments for the given filter. Columns are qualified, the
+// clause can be used in queries joining feedback with other tables.
+func feedbackFilterClause(filter *models.FeedbackFilter) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	add("feedback.tenant_id = $%d", filter.TenantID)
+	if filter.Source != "" {
+		add("feedback.source = $%d", filter.Source)
+	}
+	if filter.SubSourceID != "" {
+		add("feedback.sub_source_id = $%d", filter.SubSourceID)
+	}
+	if filter.SourceType != "" {
+		add("feedback.source_type = $%d", filter.SourceType)
+	}
+	if filter.Language != "" {
+		add("feedback.language = $%d", filter.Language)
+	}
+	if filter.Author != "" {
+		add("feedback.author = $%d", filter.Author)
+	}
+	if filter.DuplicateClusterID != "" {
+		add("feedback.duplicate_cluster_id = $%d", filter.DuplicateClusterID)
+	}
+	if filter.CollapseDuplicates {
+		// keep the first ingested feedback of each cluster
+		conditions = append(conditions, `(feedback.duplicate_cluster_id IS NULL OR NOT EXISTS (
+            SELECT 1 FROM feedback d WHERE d.tenant_id = feedback.tenant_id AND d.duplicate_cluster_id = feedback.duplicate_cluster_id
+              AND (d.created_at, d.source, d.id) < (feedback.created_at, feedback.source, feedback.id)))`)
+	}
+	if filter.Tag != "" {
+		add("EXISTS (SELECT 1 FROM feedback_tag t WHERE t.tenant_id = feedback.tenant_id AND t.source = feedback.source AND t.feedback_id = feedback.id AND t.tag = $%d)", filter.Tag)
+	}
+	if !filter.From.IsZero() {
+		add(feedbackTime+" >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add(feedbackTime+" < $%d", filter.To)
+	}
+	if filter.MinRating != nil {
+		add("feedback.rating >= $%d", *filter.MinRating)
+	}
+	if filter.MaxRating != nil {
+		add("feedback.rating <= $%d", *filter.MaxRating)
+	}
+	if filter.Sentiment != "" {
+		add("feedback.sentiment = $%d", filter.Sentiment)
+	}
+	if filter.MinSentiment != nil {
+		add("feedback.sentiment_score >= $%d", *filter.MinSentiment)
+	}
+	if filter.MaxSentiment != nil {
+		add("feedback.sentiment_score <= $%d", *filter.MaxSentiment)
+	}
+	if filter.Status != "" {
+		add(triageStatusExpr+" = $%d", filter.Status)
+	}
+	if filter.Assignee != "" {
+		add(triageAssigneeExpr+" = $%d", filter.Assignee)
+	}
+	if filter.Priority != "" {
+		add(triagePriorityExpr+" = $%d", filter.Priority)
+	}
+	if filter.HasNotes != nil {
+		exists := "EXISTS (SELECT 1 FROM feedback_note n WHERE n.tenant_id = feedback.tenant_id AND n.source = feedback.source AND n.feedback_id = feedback.id)"
+		if !*filter.HasNotes {
+			exists = "NOT " + exists
+		}
+		conditions = append(conditions, exists)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// sentimentGroups maps the supported group_by values of the sentiment
+// summary to the expression grouped on.
+var sentimentGroups = map[string]string{
+	"":              `''`,
+	"source":        `source`,
+	"sub_source_id": `sub_source_id::TEXT`,
+	"source_type":   `source_type`,
+	"language":      `language`,
+	"day":           `to_char(date_trunc('day', COALESCE(source_created_at, created_at)), 'YYYY-MM-DD')`,
+	"week":          `to_char(date_trunc('week', COALESCE(source_created_at, created_at)), 'YYYY-MM-DD')`,
+	"month":         `to_char(date_trunc('month', COALESCE(source_created_at, created_at)), 'YYYY-MM')`,
+}
+
+// SentimentSummary counts the feedback matching the filter by sentiment,
+// grouped by groupBy (see sentimentGroups). Limit and offset of the filter
+// are ignored.
+func (repo *FeedbackRepository) SentimentSummary(ctx context.Context, filter *models.FeedbackFilter, groupBy string) ([]*models.SentimentSummary, error) {
+	group, ok := sentimentGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+	}
+
+	where, args := feedbackFilterClause(filter)
+	query := `
+        SELECT ` + group + ` AS grp,
+               COUNT(*),
+               COUNT(*) FILTER (WHERE sentiment = 'positive'),
+               COUNT(*) FILTER (WHERE sentiment = 'neutral'),
+               COUNT(*) FILTER (WHERE sentiment = 'negative'),
+               AVG(sentiment_score)
+        FROM feedback
+        WHERE ` + where + `
+        GROUP BY grp
+        ORDER BY grp`
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize feedback sentiment: %v", err)
+	}
+	defer rows.Close()
+
+	summaries := []*models.SentimentSummary{}
+	for rows.Next() {
+		summary := &models.SentimentSummary{}
+		if err := rows.Scan(&summary.Group, &summary.Count, &summary.Positive, &summary.Neutral, &summary.Negative, &summary.AverageScore); err != nil {
+			return nil, fmt.Errorf("failed to scan sentiment summary: %v", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return summaries, nil
+}
+
+func scanFeedback(row pgx.Row) (*models.Feedback, error) {
+	record := &models.Feedback{}
+	var duplicateClusterID, rawPayloadID *string
+	err := row.Scan(&record.ID, &record.TenantID, &record.Source, &record.SubSourceID, &record.SourceType,
+		&record.Language, &record.LanguageConfidence, &record.Author, &record.URL, &record.Rating, &record.SourceCreatedAt, &record.IngestedAt,
+		&record.CreatedAt, &record.UpdatedAt, &record.Metadata, &record.Content, &record.Signature, &duplicateClusterID,
+		&record.SentimentScore, &record.Sentiment, &rawPayloadID,
+		&record.Tags, &record.DuplicateCount, &record.Status, &record.Assignee, &record.Priority, &record.NoteCount)
+	if err != nil {
+		return nil, err
+	}
+	if duplicateClusterID != nil {
+		record.DuplicateClusterID = *duplicateClusterID
+	}
+	if rawPayloadID != nil {
+		record.RawPayloadID = *rawPayloadID
+	}
+	return record, nil
+}
+
+// nullIfEmpty stores empty strings as NULL, for nullable UUID columns.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func collectFeedback(rows pgx.Rows) ([]*models.Feedback, error) {
+	var records []*models.Feedback
+	for rows.Next() {
+		record, err := scanFeedback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feedback record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return records, nil
+}
+
+// ListForLanguageDetection pages, in (source, id) order, through the tenant's
+// feedback without a language. With redetect, previously detected languages
+// are listed too; languages declared by the source never are.
+func (repo *FeedbackRepository) ListForLanguageDetection(ctx context.Context, tenantID string, redetect bool, afterSource models.Source, afterID string, limit int) ([]*models.Feedback, error) {
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback
+        WHERE tenant_id = $1 AND (language = '' OR ($2 AND language_confidence > 0)) AND (source, id) > ($3, $4)
+        ORDER BY source, id
+        LIMIT $5`
+
+	rows, err := repo.db.Query(ctx, query, tenantID, redetect, afterSource, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	return collectFeedback(rows)
+}
+
+// ListAfter pages, in (source, id) order, through the tenant's feedback.
+func (repo *FeedbackRepository) ListAfter(ctx context.Context, tenantID string, afterSource models.Source, afterID string, limit int) ([]*models.Feedback, error) {
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback
+        WHERE tenant_id = $1 AND (source, id) > ($2, $3)
+        ORDER BY source, id
+        LIMIT $4`
+
+	rows, err := repo.db.Query(ctx, query, tenantID, afterSource, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	return collectFeedback(rows)
+}
+
+func (repo *FeedbackRepository) UpdateLanguage(ctx context.Context, feedback *models.Feedback) error {
+	query := `
+        UPDATE feedback
+        SET language = $4, language_confidence = $5, updated_at = $6
+        WHERE id = $1 AND tenant_id = $2 AND source = $3
+    `
+	feedback.UpdatedAt = time.Now().UTC()
+
+	_, err := repo.db.Exec(ctx, query, feedback.ID, feedback.TenantID, feedback.Source, feedback.Language, feedback.LanguageConfidence, feedback.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update feedback language: %v", err)
+	}
+
+	return nil
+}
+
+// FindBySignatureBands returns the tenant's feedback sharing at least one
+// signature band with the given ones, the candidates for near-duplicates.
+// Only the fields needed to compare them are loaded.
+func (repo *FeedbackRepository) FindBySignatureBands(ctx context.Context, tenantID string, bands []int64) ([]*models.Feedback, error) {
+	query := `
+        SELECT DISTINCT f.id, f.source, f.minhash, f.duplicate_cluster_id
+        FROM feedback_minhash_band b
+        JOIN feedback f ON f.tenant_id = b.tenant_id AND f.source = b.source AND f.id = b.feedback_id
+        WHERE b.tenant_id = $1 AND (b.band, b.hash) IN (SELECT (ordinality - 1)::SMALLINT, hash FROM unnest($2::BIGINT[]) WITH ORDINALITY AS u(hash, ordinality))
+    `
+
+	rows, err := repo.db.Query(ctx, query, tenantID, bands)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar feedback: %v", err)
+	}
+	defer rows.Close()
+
 	var records []*models.Feedback
 	for rows.Next() {
-		record := &models.Feedback{}
-		if err := rows.Scan(&record.ID, &record.TenantID, &record.Content, &record.CreatedAt, &record.UpdatedAt); err != nil {
+		record := &models.Feedback{TenantID: tenantID}
+		var duplicateClusterID *string
+		if err := rows.Scan(&record.ID, &record.Source, &record.Signature, &duplicateClusterID); err != nil {
 			return nil, fmt.Errorf("failed to scan feedback record: %v", err)
 		}
+		if duplicateClusterID != nil {
+			record.DuplicateClusterID = *duplicateClusterID
+		}
 		records = append(records, record)
 	}
 
@@ -111,3 +653,14 @@ func (repo *FeedbackRepository) ListByTenant(ctx context.Context, tenantID strin
 
 	return records, nil
 }
+
+func (repo *FeedbackRepository) SetDuplicateCluster(ctx context.Context, feedback *models.Feedback) error {
+	query := `UPDATE feedback SET duplicate_cluster_id = $4 WHERE id = $1 AND tenant_id = $2 AND source = $3`
+
+	_, err := repo.db.Exec(ctx, query, feedback.ID, feedback.TenantID, feedback.Source, nullIfEmpty(feedback.DuplicateClusterID))
+	if err != nil {
+		return fmt.Errorf("failed to update feedback duplicate cluster: %v", err)
+	}
+
+	return nil
+}
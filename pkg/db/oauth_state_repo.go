@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type OAuthStateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOAuthStateRepository(db *pgxpool.Pool) *OAuthStateRepository {
+	return &OAuthStateRepository{db: db}
+}
+
+func (repo *OAuthStateRepository) Save(ctx context.Context, state *models.OAuthState) error {
+	query := `
+        INSERT INTO oauth_state (state, subscription_id, source, code_verifier, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `
+
+	_, err := repo.db.Exec(ctx, query, state.State, state.SubscriptionID, state.Source, state.CodeVerifier, state.CreatedAt, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save OAuth state: %v", err)
+	}
+	return nil
+}
+
+// Consume removes the unexpired state and returns it, a state can only be
+// used once. Expired states are removed along the way.
+func (repo *OAuthStateRepository) Consume(ctx context.Context, state string) (*models.OAuthState, error) {
+	if _, err := repo.db.Exec(ctx, `DELETE FROM oauth_state WHERE expires_at < NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to remove expired OAuth states: %v", err)
+	}
+
+	query := `
+        DELETE FROM oauth_state WHERE state = $1
+        RETURNING state, subscription_id, source, code_verifier, created_at, expires_at
+    `
+
+	s := &models.OAuthState{}
+	err := repo.db.QueryRow(ctx, query, state).Scan(&s.State, &s.SubscriptionID, &s.Source, &s.CodeVerifier, &s.CreatedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth state: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type PIIVaultRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPIIVaultRepository(db *pgxpool.Pool) *PIIVaultRepository {
+	return &PIIVaultRepository{db: db}
+}
+
+func (repo *PIIVaultRepository) SaveAll(ctx context.Context, entries []*models.PIIVaultEntry) error {
+	query := `
+        INSERT INTO pii_vault (id, tenant_id, source, feedback_id, kind, replacement, ciphertext, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+
+	batch := &pgx.Batch{}
+	for _, entry := range entries {
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now().UTC()
+		}
+		batch.Queue(query, entry.ID, entry.TenantID, entry.Source, entry.FeedbackID, entry.Kind, entry.Replacement, entry.Ciphertext, entry.CreatedAt)
+	}
+
+	results := repo.db.SendBatch(ctx, batch)
+	defer results.Close()
+	for range entries {
+		if _, err := results.Exec(); err != nil {
+			return fmt.Errorf("failed to save PII vault entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// List returns the tenant's entries of a feedback, or of a replacement
+// (hash or token) when feedbackID is empty.
+func (repo *PIIVaultRepository) List(ctx context.Context, tenantID string, source models.Source, feedbackID, replacement string) ([]*models.PIIVaultEntry, error) {
+	query := `
+        SELECT id, tenant_id, source, feedback_id, kind, replacement, ciphertext, created_at FROM pii_vault
+        WHERE tenant_id = $1
+          AND ($2 = '' OR source = $2)
+          AND ($3 = '' OR feedback_id = $3)
+          AND ($4 = '' OR replacement = $4)
+        ORDER BY created_at
+    `
+
+	rows, err := repo.db.Query(ctx, query, tenantID, string(source), feedbackID, replacement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PII vault entries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.PIIVaultEntry
+	for rows.Next() {
+		entry := &models.PIIVaultEntry{}
+		if err := rows.Scan(&entry.ID, &entry.TenantID, &entry.Source, &entry.FeedbackID, &entry.Kind, &entry.Replacement, &entry.Ciphertext, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan PII vault entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// RateLimitRepository keeps the token buckets in the database, the buckets
+// are refilled as they are read.
+type RateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take takes a token from the bucket and returns the tokens left, which are
+// negative when the bucket was empty: the token is then owed, the caller
+// must wait for it to be refilled.
+func (repo *RateLimitRepository) Take(ctx context.Context, key string, limit models.RateLimit) (float64, error) {
+	query := `
+        INSERT INTO rate_limit_bucket (key, tokens, updated_at)
+        VALUES ($1, $2::float8 - 1, clock_timestamp())
+        ON CONFLICT (key) DO UPDATE SET
+            tokens = LEAST($2::float8, rate_limit_bucket.tokens
+                + EXTRACT(EPOCH FROM clock_timestamp() - rate_limit_bucket.updated_at) * $3::float8) - 1,
+            updated_at = clock_timestamp()
+        RETURNING tokens
+    `
+
+	var tokens float64
+	if err := repo.db.QueryRow(ctx, query, key, float64(limit.Burst), limit.RequestsPerSecond).Scan(&tokens); err != nil {
+		return 0, fmt.Errorf("failed to take a token: %v", err)
+	}
+	return tokens, nil
+}
+
+// Drain empties the bucket for the given time, no token is handed out
+// before it is over.
+func (repo *RateLimitRepository) Drain(ctx context.Context, key string, limit models.RateLimit, wait time.Duration) error {
+	query := `
+        INSERT INTO rate_limit_bucket (key, tokens, updated_at)
+        VALUES ($1, $2::float8, clock_timestamp())
+        ON CONFLICT (key) DO UPDATE SET
+            tokens = LEAST(EXCLUDED.tokens, $3::float8, rate_limit_bucket.tokens
+                + EXTRACT(EPOCH FROM clock_timestamp() - rate_limit_bucket.updated_at) * $4::float8),
+            updated_at = clock_timestamp()
+    `
+
+	tokens := -wait.Seconds() * limit.RequestsPerSecond
+	if _, err := repo.db.Exec(ctx, query, key, tokens, float64(limit.Burst), limit.RequestsPerSecond); err != nil {
+		return fmt.Errorf("failed to drain the bucket: %v", err)
+	}
+	return nil
+}
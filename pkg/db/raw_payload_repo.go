@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type RawPayloadRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRawPayloadRepository(db *pgxpool.Pool) *RawPayloadRepository {
+	return &RawPayloadRepository{db: db}
+}
+
+// Save stores the payload, its data as given. A payload with the same ID
+// is stored once.
+func (repo *RawPayloadRepository) Save(ctx context.Context, payload *models.RawPayload) error {
+	query := `
+        INSERT INTO raw_payload (id, tenant_id, subscription_id, source, kind, data, size, received_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (id) DO NOTHING
+    `
+
+	_, err := repo.db.Exec(ctx, query, payload.ID, payload.TenantID, payload.SubscriptionID, payload.Source, payload.Kind,
+		payload.Data, payload.Size, payload.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to archive payload: %v", err)
+	}
+
+	return nil
+}
+
+// List returns up to limit payloads of the request received after the
+// (afterReceivedAt, afterID) position, in the order they were received.
+func (repo *RawPayloadRepository) List(ctx context.Context, req *models.ReprocessRequest, afterReceivedAt time.Time, afterID string, limit int) ([]*models.RawPayload, error) {
+	query := `
+        SELECT id, tenant_id, subscription_id, source, kind, data, size, received_at
+        FROM raw_payload
+        WHERE tenant_id = $1 AND source = $2 AND received_at >= $3 AND ($4::timestamptz IS NULL OR received_at < $4)
+          AND (received_at, id) > ($5, $6)
+        ORDER BY received_at, id
+        LIMIT $7
+    `
+
+	var to *time.Time
+	if !req.To.IsZero() {
+		to = &req.To
+	}
+	if afterID == "" {
+		afterID = "00000000-0000-0000-0000-000000000000"
+	}
+
+	rows, err := repo.db.Query(ctx, query, req.TenantID, req.Source, req.From, to, afterReceivedAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archived payloads: %v", err)
+	}
+	defer rows.Close()
+
+	var payloads []*models.RawPayload
+	for rows.Next() {
+		payload := &models.RawPayload{}
+		err := rows.Scan(&payload.ID, &payload.TenantID, &payload.SubscriptionID, &payload.Source, &payload.Kind,
+			&payload.Data, &payload.Size, &payload.ReceivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan archived payload: %v", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return payloads, nil
+}
+
+// Purge deletes the payloads received before the time, it returns the number
+// of payloads deleted. The feedback mapped from them loses its reference.
+func (repo *RawPayloadRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM raw_payload WHERE received_at < $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge archived payloads: %v", err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
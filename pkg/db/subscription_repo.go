@@ -3,13 +3,18 @@ package db
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const subscriptionColumns = `id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at, last_pulled, active, webhook_secret, needs_reauth, deleted_at,
+    health, consecutive_failures, last_error, last_success_at, next_pull_at, disabled_reason`
+
 type SubscriptionRepository struct {
 	db *pgxpool.Pool
 }
@@ -20,8 +25,8 @@ func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepository {
 
 func (repo *SubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
 	query := `
-        INSERT INTO subscription (id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at, active)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        INSERT INTO subscription (id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at, active, webhook_secret)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
     `
 	if sub.ID == "" {
 		sub.ID = uuid.New().String()
@@ -33,7 +38,7 @@ func (repo *SubscriptionRepository) Create(ctx context.Context, sub *models.Subs
 		sub.LastPulled = time.Now().UTC()
 	}
 
-	_, err := repo.db.Exec(ctx, query, sub.ID, sub.TenantID, sub.SubSourceId, sub.Source, sub.SubscriptionMode, sub.Configuration, sub.CreatedAt, true)
+	_, err := repo.db.Exec(ctx, query, sub.ID, sub.TenantID, sub.SubSourceId, sub.Source, sub.SubscriptionMode, sub.Configuration, sub.CreatedAt, true, sub.WebhookSecret)
 	if err != nil {
 		return fmt.Errorf("failed to create subscription: %v", err)
 	}
@@ -42,12 +47,9 @@ func (repo *SubscriptionRepository) Create(ctx context.Context, sub *models.Subs
 }
 
 func (repo *SubscriptionRepository) Get(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
-	query := `SELECT id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at FROM subscription WHERE id = $1`
-
-	sub := &models.Subscription{}
-	row := repo.db.QueryRow(ctx, query, subscriptionID)
+	query := `SELECT ` + subscriptionColumns + ` FROM subscription WHERE id = $1`
 
-	err := row.Scan(&sub.ID, &sub.TenantID, &sub.SubSourceId, &sub.Source, &sub.SubscriptionMode, &sub.Configuration, &sub.CreatedAt)
+	sub, err := scanSubscription(repo.db.QueryRow(ctx, query, subscriptionID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscription: %v", err)
 	}
@@ -55,8 +57,57 @@ func (repo *SubscriptionRepository) Get(ctx context.Context, subscriptionID stri
 	return sub, nil
 }
 
-func (repo *SubscriptionRepository) Delete(ctx context.Context, subscriptionID string) error {
-	query := `DELETE FROM subscription WHERE id = $1`
+// Delete removes the subscription, and its feedback unless another
+// subscription of the tenant has the same source and sub-source. It
+// returns the number of feedback records removed.
+func (repo *SubscriptionRepository) Delete(ctx context.Context, subscriptionID string) (int, error) {
+	deleted := 0
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var (
+			tenantID, subSourceID string
+			source                models.Source
+		)
+		err := tx.QueryRow(ctx, `DELETE FROM subscription WHERE id = $1 RETURNING tenant_id, source, sub_source_id`, subscriptionID).Scan(&tenantID, &source, &subSourceID)
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("no subscription found with ID %s", subscriptionID)
+		}
+		if err != nil {
+			return err
+		}
+
+		var shared bool
+		sharedQuery := `SELECT EXISTS (SELECT 1 FROM subscription WHERE tenant_id = $1 AND source = $2 AND sub_source_id = $3)`
+		if err := tx.QueryRow(ctx, sharedQuery, tenantID, source, subSourceID).Scan(&shared); err != nil {
+			return err
+		}
+		if shared {
+			return nil
+		}
+
+		vaultQuery := `
+            DELETE FROM pii_vault WHERE tenant_id = $1 AND source = $2
+            AND feedback_id IN (SELECT id FROM feedback WHERE tenant_id = $1 AND source = $2 AND sub_source_id = $3)
+        `
+		if _, err := tx.Exec(ctx, vaultQuery, tenantID, source, subSourceID); err != nil {
+			return fmt.Errorf("failed to delete PII vault entries: %v", err)
+		}
+		cmdTag, err := tx.Exec(ctx, `DELETE FROM feedback WHERE tenant_id = $1 AND source = $2 AND sub_source_id = $3`, tenantID, source, subSourceID)
+		if err != nil {
+			return fmt.Errorf("failed to delete feedback: %v", err)
+		}
+		deleted = int(cmdTag.RowsAffected())
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete subscription: %v", err)
+	}
+
+	return deleted, nil
+}
+
+// SoftDelete deactivates the subscription for good, its feedback is kept.
+func (repo *SubscriptionRepository) SoftDelete(ctx context.Context, subscriptionID string) error {
+	query := `UPDATE subscription SET deleted_at = NOW(), active = false WHERE id = $1 AND deleted_at IS NULL`
 
 	cmdTag, err := repo.db.Exec(ctx, query, subscriptionID)
 	if err != nil {
@@ -65,12 +116,91 @@ func (repo *SubscriptionRepository) Delete(ctx context.Context, subscriptionID s
 	if cmdTag.RowsAffected() == 0 {
 		return fmt.Errorf("no subscription found with ID %s", subscriptionID)
 	}
+	return nil
+}
+
+// SetActive pauses or resumes the subscription, unless it is deleted.
+// Resuming it pulls it at once, even if it was disabled for failing, and
+// counts its failures from zero again.
+func (repo *SubscriptionRepository) SetActive(ctx context.Context, subscriptionID string, active bool) error {
+	query := `
+        UPDATE subscription SET active = $2,
+            next_pull_at = CASE WHEN $2 THEN NULL ELSE next_pull_at END,
+            disabled_reason = CASE WHEN $2 THEN '' ELSE disabled_reason END,
+            consecutive_failures = CASE WHEN $2 THEN 0 ELSE consecutive_failures END
+        WHERE id = $1 AND deleted_at IS NULL
+    `
 
+	cmdTag, err := repo.db.Exec(ctx, query, subscriptionID, active)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no subscription found with ID %s", subscriptionID)
+	}
 	return nil
 }
 
+// List returns the subscriptions matching the filter, the last created
+// first.
+func (repo *SubscriptionRepository) List(ctx context.Context, filter *models.SubscriptionFilter) ([]*models.Subscription, error) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{filter.TenantID}
+
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if filter.Source != "" {
+		add("source", filter.Source)
+	}
+	if filter.SubscriptionMode != "" {
+		add("subscription_mode", filter.SubscriptionMode)
+	}
+	if filter.Active != nil {
+		add("active", *filter.Active)
+	}
+	if filter.Health != "" {
+		add("health", filter.Health)
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	query := `SELECT ` + subscriptionColumns + ` FROM subscription WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+	defer rows.Close()
+
+	var subs []*models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %v", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return subs, nil
+}
+
 func (repo *SubscriptionRepository) ListByTenantAndApp(ctx context.Context, tenantID, appID string) ([]*models.Subscription, error) {
-	query := `SELECT id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at FROM subscription WHERE tenant_id = $1 AND sub_source_id = $2`
+	query := `SELECT ` + subscriptionColumns + ` FROM subscription WHERE tenant_id = $1 AND sub_source_id = $2 AND deleted_at IS NULL`
 
 	rows, err := repo.db.Query(ctx, query, tenantID, appID)
 	if err != nil {
@@ -80,8 +210,8 @@ func (repo *SubscriptionRepository) ListByTenantAndApp(ctx context.Context, tena
 
 	var subs []*models.Subscription
 	for rows.Next() {
-		sub := &models.Subscription{}
-		if err := rows.Scan(&sub.ID, &sub.TenantID, &sub.SubSourceId, &sub.Source, &sub.SubscriptionMode, &sub.Configuration, &sub.CreatedAt); err != nil {
+		sub, err := scanSubscription(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscription: %v", err)
 		}
 		subs = append(subs, sub)
@@ -95,7 +225,8 @@ func (repo *SubscriptionRepository) ListByTenantAndApp(ctx context.Context, tena
 }
 
 func (repo *SubscriptionRepository) GetAllActivePullSubscriptions(ctx context.Context) ([]*models.Subscription, error) {
-	query := `SELECT id, tenant_id, sub_source_id, source, subscription_mode, configuration, created_at FROM subscription WHERE subscription_mode = 'pull' AND active = true`
+	query := `SELECT ` + subscriptionColumns + ` FROM subscription WHERE subscription_mode = 'pull' AND active = true AND needs_reauth = false AND deleted_at IS NULL
+        AND (next_pull_at IS NULL OR next_pull_at <= NOW())`
 
 	rows, err := repo.db.Query(ctx, query)
 	if err != nil {
@@ -105,8 +236,8 @@ func (repo *SubscriptionRepository) GetAllActivePullSubscriptions(ctx context.Co
 
 	var subs []*models.Subscription
 	for rows.Next() {
-		sub := &models.Subscription{}
-		if err := rows.Scan(&sub.ID, &sub.TenantID, &sub.SubSourceId, &sub.Source, &sub.SubscriptionMode, &sub.Configuration, &sub.CreatedAt); err != nil {
+		sub, err := scanSubscription(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscription: %v", err)
 		}
 		subs = append(subs, sub)
@@ -125,3 +256,122 @@ func (repo *SubscriptionRepository) UpdateLastPulled(ctx context.Context, subscr
 	_, err := repo.db.Exec(ctx, query, now, subscriptionID)
 	return err
 }
+
+// ListAll returns every subscription with its secrets, the oldest first.
+func (repo *SubscriptionRepository) ListAll(ctx context.Context) ([]*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscription ORDER BY created_at, id`
+
+	rows, err := repo.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+	defer rows.Close()
+
+	var subs []*models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %v", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return subs, nil
+}
+
+// UpdateSecrets saves the configuration and webhook secret of the
+// subscription, e.g. encrypted again with another key.
+func (repo *SubscriptionRepository) UpdateSecrets(ctx context.Context, sub *models.Subscription) error {
+	query := `UPDATE subscription SET configuration = $2, webhook_secret = $3 WHERE id = $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, sub.ID, sub.Configuration, sub.WebhookSecret)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription secrets: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no subscription found with ID %s", sub.ID)
+	}
+	return nil
+}
+
+// SetNeedsReauth flags the subscription as needing to be connected again,
+// it has an auth error, or clears the flag and pulls it at once.
+func (repo *SubscriptionRepository) SetNeedsReauth(ctx context.Context, subscriptionID string, needsReauth bool) error {
+	query := `
+        UPDATE subscription SET needs_reauth = $2,
+            health = CASE WHEN $2 THEN 'auth_error' ELSE health END,
+            next_pull_at = CASE WHEN $2 THEN next_pull_at ELSE NULL END
+        WHERE id = $1
+    `
+
+	cmdTag, err := repo.db.Exec(ctx, query, subscriptionID, needsReauth)
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no subscription found with ID %s", subscriptionID)
+	}
+	return nil
+}
+
+// RecordSuccess records a successful pull of the subscription, it is
+// healthy again.
+func (repo *SubscriptionRepository) RecordSuccess(ctx context.Context, subscriptionID string) error {
+	query := `
+        UPDATE subscription SET health = 'healthy', consecutive_failures = 0, last_error = '', last_success_at = NOW(), next_pull_at = NULL
+        WHERE id = $1
+    `
+	if _, err := repo.db.Exec(ctx, query, subscriptionID); err != nil {
+		return fmt.Errorf("failed to update subscription health: %v", err)
+	}
+	return nil
+}
+
+// RecordFailure records a failed pull of the subscription with its error
+// and returns its consecutive failures.
+func (repo *SubscriptionRepository) RecordFailure(ctx context.Context, subscriptionID, lastError string) (int, error) {
+	query := `
+        UPDATE subscription SET consecutive_failures = consecutive_failures + 1, last_error = $2
+        WHERE id = $1
+        RETURNING consecutive_failures
+    `
+	var failures int
+	if err := repo.db.QueryRow(ctx, query, subscriptionID, lastError).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to update subscription health: %v", err)
+	}
+	return failures, nil
+}
+
+// UpdateHealth sets the health of the subscription and when it is pulled
+// next. With a reason it is disabled.
+func (repo *SubscriptionRepository) UpdateHealth(ctx context.Context, subscriptionID string, health models.SubscriptionHealth, nextPullAt time.Time, disabledReason string) error {
+	query := `
+        UPDATE subscription SET health = $2, next_pull_at = $3,
+            active = CASE WHEN $4 = '' THEN active ELSE false END,
+            disabled_reason = CASE WHEN $4 = '' THEN disabled_reason ELSE $4 END
+        WHERE id = $1
+    `
+	if _, err := repo.db.Exec(ctx, query, subscriptionID, health, nextPullAt, disabledReason); err != nil {
+		return fmt.Errorf("failed to update subscription health: %v", err)
+	}
+	return nil
+}
+
+func scanSubscription(row pgx.Row) (*models.Subscription, error) {
+	sub := &models.Subscription{}
+	var lastPulled *time.Time
+	err := row.Scan(&sub.ID, &sub.TenantID, &sub.SubSourceId, &sub.Source, &sub.SubscriptionMode, &sub.Configuration, &sub.CreatedAt, &lastPulled,
+		&sub.Active, &sub.WebhookSecret, &sub.NeedsReauth, &sub.DeletedAt,
+		&sub.Health, &sub.ConsecutiveFailures, &sub.LastError, &sub.LastSuccessAt, &sub.NextPullAt, &sub.DisabledReason)
+	if err != nil {
+		return nil, err
+	}
+	if lastPulled != nil {
+		sub.LastPulled = *lastPulled
+	}
+	return sub, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const tagRuleColumns = `id, tenant_id, name, tag, enabled, conditions, created_at, updated_at`
+
+type TagRuleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTagRuleRepository(db *pgxpool.Pool) *TagRuleRepository {
+	return &TagRuleRepository{db: db}
+}
+
+func (repo *TagRuleRepository) Save(ctx context.Context, rule *models.TagRule) error {
+	query := `
+        INSERT INTO tag_rule (` + tagRuleColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	rule.CreatedAt = time.Now().UTC()
+	rule.UpdatedAt = rule.CreatedAt
+
+	_, err := repo.db.Exec(ctx, query, rule.ID, rule.TenantID, rule.Name, rule.Tag, rule.Enabled, rule.Conditions, rule.CreatedAt, rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save tag rule: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *TagRuleRepository) Get(ctx context.Context, ruleID string) (*models.TagRule, error) {
+	query := `SELECT ` + tagRuleColumns + ` FROM tag_rule WHERE id = $1`
+
+	rule, err := scanTagRule(repo.db.QueryRow(ctx, query, ruleID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag rule: %v", err)
+	}
+
+	return rule, nil
+}
+
+func (repo *TagRuleRepository) Update(ctx context.Context, rule *models.TagRule) error {
+	query := `
+        UPDATE tag_rule
+        SET name = $2, tag = $3, enabled = $4, conditions = $5, updated_at = $6
+        WHERE id = $1
+        RETURNING tenant_id, created_at
+    `
+	rule.UpdatedAt = time.Now().UTC()
+
+	err := repo.db.QueryRow(ctx, query, rule.ID, rule.Name, rule.Tag, rule.Enabled, rule.Conditions, rule.UpdatedAt).Scan(&rule.TenantID, &rule.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("no tag rule found with ID %s", rule.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update tag rule: %v", err)
+	}
+
+	return nil
+}
+
+// Delete removes the rule, and with it the tags it added.
+func (repo *TagRuleRepository) Delete(ctx context.Context, ruleID string) error {
+	query := `DELETE FROM tag_rule WHERE id = $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag rule: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no tag rule found with ID %s", ruleID)
+	}
+
+	return nil
+}
+
+func (repo *TagRuleRepository) ListByTenant(ctx context.Context, tenantID string) ([]*models.TagRule, error) {
+	query := `SELECT ` + tagRuleColumns + ` FROM tag_rule WHERE tenant_id = $1 ORDER BY created_at, id`
+
+	rows, err := repo.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []*models.TagRule{}
+	for rows.Next() {
+		rule, err := scanTagRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag rule: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return rules, nil
+}
+
+func scanTagRule(row pgx.Row) (*models.TagRule, error) {
+	rule := &models.TagRule{}
+	err := row.Scan(&rule.ID, &rule.TenantID, &rule.Name, &rule.Tag, &rule.Enabled, &rule.Conditions, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
@@ -18,11 +18,11 @@ func NewTenantRepository(db *pgxpool.Pool) *TenantRepository {
 
 func (repo *TenantRepository) Save(ctx context.Context, tenant *models.Tenant) error {
 	query := `
-        INSERT INTO tenant (id, name, api_key)
-        VALUES ($1, $2, $3)
+        INSERT INTO tenant (id, name, api_key, settings)
+        VALUES ($1, $2, $3, $4)
     `
 
-	_, err := repo.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.ApiKey)
+	_, err := repo.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.ApiKey, tenant.Settings)
 	if err != nil {
 		return fmt.Errorf("failed to save tenant: %v", err)
 	}
@@ -31,12 +31,12 @@ func (repo *TenantRepository) Save(ctx context.Context, tenant *models.Tenant) e
 }
 
 func (repo *TenantRepository) Get(ctx context.Context, tenantID string) (*models.Tenant, error) {
-	query := `SELECT id, name, api_key FROM tenant WHERE id = $1`
+	query := `SELECT id, name, api_key, settings FROM tenant WHERE id = $1`
 
 	tenant := &models.Tenant{}
 	row := repo.db.QueryRow(ctx, query, tenantID)
 
-	err := row.Scan(&tenant.ID, &tenant.Name, &tenant.ApiKey)
+	err := row.Scan(&tenant.ID, &tenant.Name, &tenant.ApiKey, &tenant.Settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant: %v", err)
 	}
@@ -44,14 +44,39 @@ func (repo *TenantRepository) Get(ctx context.Context, tenantID string) (*models
 	return tenant, nil
 }
 
+// ListIDs returns the IDs of every tenant.
+func (repo *TenantRepository) ListIDs(ctx context.Context) ([]string, error) {
+	query := `SELECT id FROM tenant ORDER BY id`
+
+	rows, err := repo.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return ids, nil
+}
+
 func (repo *TenantRepository) Update(ctx context.Context, tenant *models.Tenant) error {
 	query := `
         UPDATE tenant
-        SET name = $2
+        SET name = $2, settings = $3
         WHERE id = $1
     `
 
-	_, err := repo.db.Exec(ctx, query, tenant.ID, tenant.Name)
+	_, err := repo.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.Settings)
 	if err != nil {
 		return fmt.Errorf("failed to update tenant: %v", err)
 	}
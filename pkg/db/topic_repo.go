@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// feedbackTime is when a feedback was written, windows of the topic job and
+// reports are on it
+const feedbackTime = `COALESCE(feedback.source_created_at, feedback.created_at)`
+
+type TopicRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTopicRepository(db *pgxpool.Pool) *TopicRepository {
+	return &TopicRepository{db: db}
+}
+
+// ListDocuments pages, in (source, id) order, through the tenant's feedback
+// written within [from, to). Only the fields needed to extract topics are
+// loaded.
+func (repo *TopicRepository) ListDocuments(ctx context.Context, tenantID string, from, to time.Time, afterSource models.Source, afterID string, limit int) ([]*models.Feedback, error) {
+	query := `
+        SELECT id, source, language, content FROM feedback
+        WHERE tenant_id = $1 AND ` + feedbackTime + ` >= $2 AND ` + feedbackTime + ` < $3 AND (source, id) > ($4, $5)
+        ORDER BY source, id
+        LIMIT $6
+    `
+
+	rows, err := repo.db.Query(ctx, query, tenantID, from, to, afterSource, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback records: %v", err)
+	}
+	defer rows.Close()
+
+	var feedbacks []*models.Feedback
+	for rows.Next() {
+		feedback := &models.Feedback{TenantID: tenantID}
+		if err := rows.Scan(&feedback.ID, &feedback.Source, &feedback.Language, &feedback.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback record: %v", err)
+		}
+		feedbacks = append(feedbacks, feedback)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return feedbacks, nil
+}
+
+// ReplaceWindow replaces the topic memberships of the tenant's feedback
+// written within [from, to) by the memberships of topics. Topics are matched
+// to the stored ones by label.
+func (repo *TopicRepository) ReplaceWindow(ctx context.Context, tenantID string, from, to time.Time, topics []*models.Topic) error {
+	deleteQuery := `
+        DELETE FROM topic_member m
+        USING feedback
+        WHERE m.tenant_id = $1 AND feedback.tenant_id = m.tenant_id AND feedback.source = m.source AND feedback.id = m.feedback_id
+          AND ` + feedbackTime + ` >= $2 AND ` + feedbackTime + ` < $3
+    `
+	upsertQuery := `
+        INSERT INTO topic (id, tenant_id, label, keywords, created_at, last_seen_at)
+        VALUES ($1, $2, $3, $4, $5, $5)
+        ON CONFLICT (tenant_id, label) DO UPDATE SET keywords = EXCLUDED.keywords, last_seen_at = EXCLUDED.last_seen_at
+        RETURNING id, created_at
+    `
+	memberQuery := `
+        INSERT INTO topic_member (topic_id, tenant_id, source, feedback_id, score)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT DO NOTHING
+    `
+
+	now := time.Now().UTC()
+	err := repo.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteQuery, tenantID, from, to); err != nil {
+			return fmt.Errorf("failed to clear topic members: %v", err)
+		}
+
+		for _, topic := range topics {
+			err := tx.QueryRow(ctx, upsertQuery, uuid.New().String(), tenantID, topic.Label, topic.Keywords, now).Scan(&topic.ID, &topic.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to save topic: %v", err)
+			}
+			topic.TenantID = tenantID
+			topic.LastSeenAt = now
+
+			batch := &pgx.Batch{}
+			for _, member := range topic.Members {
+				batch.Queue(memberQuery, topic.ID, tenantID, member.Source, member.FeedbackID, member.Score)
+			}
+			results := tx.SendBatch(ctx, batch)
+			for range topic.Members {
+				if _, err := results.Exec(); err != nil {
+					results.Close()
+					return fmt.Errorf("failed to save topic member: %v", err)
+				}
+			}
+			if err := results.Close(); err != nil {
+				return fmt.Errorf("failed to save topic members: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace topics: %v", err)
+	}
+
+	return nil
+}
+
+// Trending returns the topics of the filter's window, the ones whose volume
+// grew the most since the previous window of the same length first.
+func (repo *TopicRepository) Trending(ctx context.Context, filter *models.TopicReportFilter) ([]*models.TrendingTopic, error) {
+	previousFrom := filter.From.Add(-filter.To.Sub(filter.From))
+	args := []interface{}{filter.TenantID, previousFrom, filter.From, filter.To}
+
+	conditions := []string{
+		"t.tenant_id = $1",
+		feedbackTime + " >= $2",
+		feedbackTime + " < $4",
+	}
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		conditions = append(conditions, fmt.Sprintf("feedback.source = $%d", len(args)))
+	}
+	if filter.SubSourceID != "" {
+		args = append(args, filter.SubSourceID)
+		conditions = append(conditions, fmt.Sprintf("feedback.sub_source_id = $%d", len(args)))
+	}
+
+	query := `
+        SELECT t.id, t.tenant_id, t.label, t.keywords, t.created_at, t.last_seen_at,
+               COUNT(*) FILTER (WHERE ` + feedbackTime + ` >= $3) AS volume,
+               COUNT(*) FILTER (WHERE ` + feedbackTime + ` < $3) AS previous_volume
+        FROM topic t
+        JOIN topic_member m ON m.topic_id = t.id
+        JOIN feedback ON feedback.tenant_id = m.tenant_id AND feedback.source = m.source AND feedback.id = m.feedback_id
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        GROUP BY t.id
+        HAVING COUNT(*) FILTER (WHERE ` + feedbackTime + ` >= $3) > 0
+        ORDER BY COUNT(*) FILTER (WHERE ` + feedbackTime + ` >= $3) - COUNT(*) FILTER (WHERE ` + feedbackTime + ` < $3) DESC, volume DESC, t.label`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to report trending topics: %v", err)
+	}
+	defer rows.Close()
+
+	topics := []*models.TrendingTopic{}
+	for rows.Next() {
+		topic := &models.TrendingTopic{}
+		err := rows.Scan(&topic.ID, &topic.TenantID, &topic.Label, &topic.Keywords, &topic.CreatedAt, &topic.LastSeenAt,
+			&topic.Volume, &topic.PreviousVolume)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trending topic: %v", err)
+		}
+		topics = append(topics, topic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return topics, nil
+}
+
+// Samples returns the members of a topic written within the filter's window,
+// the most recent first.
+func (repo *TopicRepository) Samples(ctx context.Context, topicID string, filter *models.TopicReportFilter) ([]*models.Feedback, error) {
+	args := []interface{}{filter.TenantID, topicID, filter.From, filter.To, filter.Samples}
+	conditions := []string{
+		"tenant_id = $1",
+		"(source, id) IN (SELECT m.source, m.feedback_id FROM topic_member m WHERE m.topic_id = $2)",
+		feedbackTime + " >= $3",
+		feedbackTime + " < $4",
+	}
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		conditions = append(conditions, fmt.Sprintf("source = $%d", len(args)))
+	}
+	if filter.SubSourceID != "" {
+		args = append(args, filter.SubSourceID)
+		conditions = append(conditions, fmt.Sprintf("sub_source_id = $%d", len(args)))
+	}
+
+	query := `SELECT ` + feedbackSelectColumns + ` FROM feedback WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY ` + feedbackTime + ` DESC
+        LIMIT $5`
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topic samples: %v", err)
+	}
+	defer rows.Close()
+
+	return collectFeedback(rows)
+}
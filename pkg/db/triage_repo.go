@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TriageRepository stores what the support team adds to feedback: triage,
+// notes and manual tags.
+type TriageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTriageRepository(db *pgxpool.Pool) *TriageRepository {
+	return &TriageRepository{db: db}
+}
+
+// SaveTriage creates or replaces the triage of a feedback.
+func (repo *TriageRepository) SaveTriage(ctx context.Context, triage *models.FeedbackTriage) error {
+	query := `
+        INSERT INTO feedback_triage (tenant_id, source, feedback_id, status, assignee, priority, updated_by, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (tenant_id, source, feedback_id) DO UPDATE
+        SET status = EXCLUDED.status, assignee = EXCLUDED.assignee, priority = EXCLUDED.priority,
+            updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+    `
+	triage.UpdatedAt = time.Now().UTC()
+
+	_, err := repo.db.Exec(ctx, query, triage.TenantID, triage.Source, triage.FeedbackID, triage.Status, triage.Assignee, triage.Priority, triage.UpdatedBy, triage.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback triage: %v", err)
+	}
+
+	return nil
+}
+
+// GetTriage returns the triage of a feedback, a new one if it was never
+// triaged.
+func (repo *TriageRepository) GetTriage(ctx context.Context, tenantID string, source models.Source, feedbackID string) (*models.FeedbackTriage, error) {
+	query := `
+        SELECT status, assignee, priority, updated_by, updated_at FROM feedback_triage
+        WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3
+    `
+
+	triage := &models.FeedbackTriage{TenantID: tenantID, Source: source, FeedbackID: feedbackID}
+	err := repo.db.QueryRow(ctx, query, tenantID, source, feedbackID).Scan(&triage.Status, &triage.Assignee, &triage.Priority, &triage.UpdatedBy, &triage.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		triage.Status = models.TriageStatusNew
+		return triage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback triage: %v", err)
+	}
+
+	return triage, nil
+}
+
+func (repo *TriageRepository) AddNote(ctx context.Context, note *models.FeedbackNote) error {
+	query := `
+        INSERT INTO feedback_note (id, tenant_id, source, feedback_id, author, body, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	if note.ID == "" {
+		note.ID = uuid.New().String()
+	}
+	note.CreatedAt = time.Now().UTC()
+
+	_, err := repo.db.Exec(ctx, query, note.ID, note.TenantID, note.Source, note.FeedbackID, note.Author, note.Body, note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback note: %v", err)
+	}
+
+	return nil
+}
+
+// ListNotes returns the notes of a feedback, oldest first.
+func (repo *TriageRepository) ListNotes(ctx context.Context, tenantID string, source models.Source, feedbackID string) ([]*models.FeedbackNote, error) {
+	query := `
+        SELECT id, tenant_id, source, feedback_id, author, body, created_at FROM feedback_note
+        WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3
+        ORDER BY created_at, id
+    `
+
+	rows, err := repo.db.Query(ctx, query, tenantID, source, feedbackID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback notes: %v", err)
+	}
+	defer rows.Close()
+
+	notes := []*models.FeedbackNote{}
+	for rows.Next() {
+		note := &models.FeedbackNote{}
+		if err := rows.Scan(&note.ID, &note.TenantID, &note.Source, &note.FeedbackID, &note.Author, &note.Body, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback note: %v", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return notes, nil
+}
+
+func (repo *TriageRepository) DeleteNote(ctx context.Context, tenantID, noteID string) error {
+	query := `DELETE FROM feedback_note WHERE tenant_id = $1 AND id = $2`
+
+	cmdTag, err := repo.db.Exec(ctx, query, tenantID, noteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feedback note: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no feedback note found with ID %s", noteID)
+	}
+
+	return nil
+}
+
+// AddTag tags a feedback by hand. A tag the feedback already has from the
+// pipeline or a tag rule becomes a manual one, so that it is kept when the
+// feedback is tagged again.
+func (repo *TriageRepository) AddTag(ctx context.Context, tag *models.FeedbackTag) error {
+	query := `
+        INSERT INTO feedback_tag (tenant_id, source, feedback_id, tag, origin)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (tenant_id, source, feedback_id, tag) DO UPDATE
+        SET origin = EXCLUDED.origin, rule_id = NULL
+    `
+
+	_, err := repo.db.Exec(ctx, query, tag.TenantID, tag.Source, tag.FeedbackID, tag.Tag, TagOriginManual)
+	if err != nil {
+		return fmt.Errorf("failed to tag feedback: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveTag removes a tag added by hand. Tags added at ingest are left alone,
+// they would come back with the next re-tag anyway.
+func (repo *TriageRepository) RemoveTag(ctx context.Context, tag *models.FeedbackTag) error {
+	query := `
+        DELETE FROM feedback_tag
+        WHERE tenant_id = $1 AND source = $2 AND feedback_id = $3 AND tag = $4 AND origin = $5
+    `
+
+	cmdTag, err := repo.db.Exec(ctx, query, tag.TenantID, tag.Source, tag.FeedbackID, tag.Tag, TagOriginManual)
+	if err != nil {
+		return fmt.Errorf("failed to remove feedback tag: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("no manual tag %s found on feedback %s", tag.Tag, tag.FeedbackID)
+	}
+
+	return nil
+}
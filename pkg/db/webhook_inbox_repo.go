@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const webhookInboxColumns = `id, tenant_id, subscription_id, source, headers, query, body, status, attempts, next_attempt_at, last_error, received_at, processed_at`
+
+// ErrLeaseLost is returned when completing or failing an entry whose lease
+// expired and which was claimed again, by another worker.
+var ErrLeaseLost = errors.New("the lease of the webhook call expired, it was claimed again")
+
+// WebhookInboxRepository stores webhook calls until they are processed.
+// Workers claim due entries with SKIP LOCKED, any number of them can run
+// side by side.
+type WebhookInboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookInboxRepository(db *pgxpool.Pool) *WebhookInboxRepository {
+	return &WebhookInboxRepository{db: db}
+}
+
+func (repo *WebhookInboxRepository) Add(ctx context.Context, entry *models.WebhookInboxEntry) error {
+	query := `
+        INSERT INTO webhook_inbox (id, tenant_id, subscription_id, source, headers, query, body, status, next_attempt_at, received_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    `
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	entry.Status = models.InboxPending
+	entry.ReceivedAt = time.Now().UTC()
+	entry.NextAttemptAt = entry.ReceivedAt
+
+	_, err := repo.db.Exec(ctx, query, entry.ID, entry.TenantID, entry.SubscriptionID, entry.Source, entry.Headers, entry.Query, entry.Body,
+		entry.Status, entry.NextAttemptAt, entry.ReceivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store webhook call: %v", err)
+	}
+
+	return nil
+}
+
+// Claim marks up to limit due entries as processing until lockedUntil under
+// a new lease token and returns them, their attempts counting this one.
+// Entries whose worker did not finish them by then are due again, unless
+// they were claimed maxAttempts times already: those are marked dead and
+// returned as well, with their status, to be dead-lettered.
+func (repo *WebhookInboxRepository) Claim(ctx context.Context, limit int, lockedUntil time.Time, maxAttempts int) ([]*models.WebhookInboxEntry, error) {
+	query := `
+        WITH due AS (
+            SELECT id AS due_id, status = 'processing' AND attempts >= $3 AS expired
+            FROM webhook_inbox
+            WHERE (status = 'pending' AND next_attempt_at <= NOW()) OR (status = 'processing' AND locked_until < NOW())
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_inbox SET
+            status = CASE WHEN due.expired THEN 'dead' ELSE 'processing' END,
+            attempts = CASE WHEN due.expired THEN attempts ELSE attempts + 1 END,
+            locked_until = CASE WHEN due.expired THEN NULL ELSE $2::TIMESTAMPTZ END,
+            lease_token = CASE WHEN due.expired THEN NULL ELSE $4::UUID END,
+            last_error = CASE WHEN due.expired THEN 'the worker processing it stopped, on every attempt' ELSE last_error END
+        FROM due
+        WHERE id = due.due_id
+        RETURNING ` + webhookInboxColumns + `, COALESCE(lease_token::TEXT, '')`
+
+	rows, err := repo.db.Query(ctx, query, limit, lockedUntil, maxAttempts, uuid.New().String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook calls: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.WebhookInboxEntry
+	for rows.Next() {
+		entry := &models.WebhookInboxEntry{}
+		err := rows.Scan(&entry.ID, &entry.TenantID, &entry.SubscriptionID, &entry.Source, &entry.Headers, &entry.Query, &entry.Body,
+			&entry.Status, &entry.Attempts, &entry.NextAttemptAt, &entry.LastError, &entry.ReceivedAt, &entry.ProcessedAt, &entry.LeaseToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook call: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return entries, nil
+}
+
+// Complete marks a processed entry as done, if the lease it was claimed
+// under still holds.
+func (repo *WebhookInboxRepository) Complete(ctx context.Context, id, leaseToken string) error {
+	query := `
+        UPDATE webhook_inbox SET status = 'done', locked_until = NULL, lease_token = NULL, last_error = '', processed_at = NOW()
+        WHERE id = $1 AND status = 'processing' AND lease_token = $2
+    `
+
+	cmdTag, err := repo.db.Exec(ctx, query, id, leaseToken)
+	if err != nil {
+		return fmt.Errorf("failed to complete webhook call: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Fail records the error of an attempt, if the lease the entry was claimed
+// under still holds: the entry is due again at nextAttemptAt, or dead when
+// dead is set.
+func (repo *WebhookInboxRepository) Fail(ctx context.Context, id, leaseToken string, lastError string, nextAttemptAt time.Time, dead bool) error {
+	query := `
+        UPDATE webhook_inbox
+        SET status = CASE WHEN $4 THEN 'dead' ELSE 'pending' END, locked_until = NULL, lease_token = NULL, last_error = $2, next_attempt_at = $3
+        WHERE id = $1 AND status = 'processing' AND lease_token = $5
+    `
+
+	cmdTag, err := repo.db.Exec(ctx, query, id, lastError, nextAttemptAt, dead, leaseToken)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook call failure: %v", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// PurgeDone deletes the entries processed before the time, their feedback
+// is stored. It returns the number of entries deleted.
+func (repo *WebhookInboxRepository) PurgeDone(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM webhook_inbox WHERE status = 'done' AND processed_at < $1`
+
+	cmdTag, err := repo.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook calls: %v", err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+func (repo *WebhookInboxRepository) Get(ctx context.Context, id string) (*models.WebhookInboxEntry, error) {
+	query := `SELECT ` + webhookInboxColumns + ` FROM webhook_inbox WHERE id = $1`
+
+	entry, err := scanInboxEntry(repo.db.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook call: %v", err)
+	}
+	return entry, nil
+}
+
+// List returns the entries matching the filter, the last received first.
+func (repo *WebhookInboxRepository) List(ctx context.Context, filter *models.WebhookInboxFilter) ([]*models.WebhookInboxEntry, error) {
+	where, args := inboxFilterClause(filter)
+	query := `SELECT ` + webhookInboxColumns + ` FROM webhook_inbox WHERE ` + where + ` ORDER BY received_at DESC`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook calls: %v", err)
+	}
+	defer rows.Close()
+
+	return collectInboxEntries(rows)
+}
+
+// Replay makes the entries matching the filter due now, with their attempts
+// reset. Entries being processed are left alone. It returns the number of
+// entries replayed.
+func (repo *WebhookInboxRepository) Replay(ctx context.Context, filter *models.WebhookInboxFilter, id string) (int, error) {
+	where, args := inboxFilterClause(filter)
+	if id != "" {
+		args = append(args, id)
+		where += fmt.Sprintf(" AND id = $%d", len(args))
+	}
+	query := `
+        UPDATE webhook_inbox SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = ''
+        WHERE ` + where + ` AND status <> 'processing'`
+
+	cmdTag, err := repo.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook calls: %v", err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+func inboxFilterClause(filter *models.WebhookInboxFilter) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{filter.TenantID}
+
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if filter.SubscriptionID != "" {
+		add("subscription_id", filter.SubscriptionID)
+	}
+	if filter.Source != "" {
+		add("source", filter.Source)
+	}
+	if filter.Status != "" {
+		add("status", filter.Status)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func scanInboxEntry(row pgx.Row) (*models.WebhookInboxEntry, error) {
+	entry := &models.WebhookInboxEntry{}
+	err := row.Scan(&entry.ID, &entry.TenantID, &entry.SubscriptionID, &entry.Source, &entry.Headers, &entry.Query, &entry.Body,
+		&entry.Status, &entry.Attempts, &entry.NextAttemptAt, &entry.LastError, &entry.ReceivedAt, &entry.ProcessedAt)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func collectInboxEntries(rows pgx.Rows) ([]*models.WebhookInboxEntry, error) {
+	var entries []*models.WebhookInboxEntry
+	for rows.Next() {
+		entry, err := scanInboxEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook call: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,223 @@
+package deadletter
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type DeadLetterHandler struct {
+	service     *DeadLetterService
+	adminAPIKey string
+}
+
+func NewDeadLetterHandler(service *DeadLetterService, adminAPIKey string) *DeadLetterHandler {
+	return &DeadLetterHandler{service: service, adminAPIKey: adminAPIKey}
+}
+
+// ListEntriesHandler lists the tenant's dead letters, the last added
+// first, optionally filtered by subscription_id, source and stage. The
+// payloads hold personal data, only callers with the admin API key may use
+// it.
+func (h *DeadLetterHandler) ListEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	filter, err := parseDeadLetterFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	entries, err := h.service.ListEntries(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetEntryHandler returns a dead letter with its payload.
+func (h *DeadLetterHandler) GetEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	id, ok := parseEntryID(w, r)
+	if !ok {
+		return
+	}
+	if id == "" {
+		http.Error(w, "Entry ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	entry, err := h.service.GetEntry(ctx, id)
+	if err != nil {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		*models.DeadLetter
+		Payload string `json:"payload"`
+	}{entry, string(entry.Payload)})
+}
+
+// RetryHandler ingests the tenant's entry with the given id again, or the
+// entries matching the filters. Entries that succeed are removed.
+func (h *DeadLetterHandler) RetryHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseDeadLetterFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, ok := parseEntryID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if id == "" {
+		result, err := h.service.RetryAll(ctx, filter)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to retry dead letters: %v", err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	entry, err := h.service.GetEntry(ctx, id)
+	if err != nil || entry.TenantID != filter.TenantID {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+	if err := h.service.Retry(ctx, entry); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Dead letter %s ingested successfully", id)
+}
+
+// DiscardHandler removes the tenant's entry with the given id, or the
+// entries matching the filters, which must include one of subscription_id,
+// source or stage.
+func (h *DeadLetterHandler) DiscardHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseDeadLetterFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, ok := parseEntryID(w, r)
+	if !ok {
+		return
+	}
+	if id == "" && filter.SubscriptionID == "" && filter.Source == "" && filter.Stage == "" {
+		http.Error(w, "Entry ID, subscription ID, source or stage is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	discarded, err := h.service.Discard(ctx, filter, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to discard dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]int{"discarded": discarded})
+}
+
+func parseEntryID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		return "", true
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid Entry ID format", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func parseDeadLetterFilter(r *http.Request) (*models.DeadLetterFilter, error) {
+	query := r.URL.Query()
+	filter := &models.DeadLetterFilter{
+		TenantID:       query.Get("tenant_id"),
+		SubscriptionID: query.Get("subscription_id"),
+		Source:         models.Source(query.Get("source")),
+		Stage:          query.Get("stage"),
+	}
+
+	if filter.TenantID == "" {
+		return nil, fmt.Errorf("Tenant ID is required")
+	}
+	if _, err := uuid.Parse(filter.TenantID); err != nil {
+		return nil, fmt.Errorf("Invalid Tenant ID format")
+	}
+	if filter.SubscriptionID != "" {
+		if _, err := uuid.Parse(filter.SubscriptionID); err != nil {
+			return nil, fmt.Errorf("Invalid Subscription ID format")
+		}
+	}
+	switch filter.Stage {
+	case "", models.StageFetch, models.StageMap, models.StagePipeline, models.StageStore:
+	default:
+		return nil, fmt.Errorf("Invalid stage %q", filter.Stage)
+	}
+
+	for name, value := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if s := query.Get(name); s != "" {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("Invalid %s, expected a non-negative integer", name)
+			}
+			*value = i
+		}
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+
+	return filter, nil
+}
+
+func (h *DeadLetterHandler) authorized(r *http.Request) bool {
+	if h.adminAPIKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(h.adminAPIKey)) == 1
+}
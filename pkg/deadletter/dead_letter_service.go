@@ -0,0 +1,209 @@
+// Package deadletter keeps the payloads that failed to be ingested until
+// they are retried successfully or discarded.
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+)
+
+// MaxBulkRetry is the number of entries a bulk retry goes through at most
+const MaxBulkRetry = 500
+
+// Retrier ingests the payload of a dead letter again. On failure it sets
+// the stage the entry failed at.
+type Retrier func(ctx context.Context, entry *models.DeadLetter) error
+
+// DeadLetterService keeps the failed payloads. They hold the personal data
+// the pipeline redacts from the feedback, so they are encrypted with the
+// keyring, bound to their ID, when it has a key.
+type DeadLetterService struct {
+	repo    *db.DeadLetterRepository
+	keyring *secrets.Keyring
+	retry   Retrier
+
+	mu       sync.Mutex
+	reported map[string]bool // tenants with a size gauge
+}
+
+func NewDeadLetterService(repo *db.DeadLetterRepository, keyring *secrets.Keyring) *DeadLetterService {
+	return &DeadLetterService{repo: repo, keyring: keyring, reported: map[string]bool{}}
+}
+
+// SetRetrier sets how the entries are retried. It is set once the
+// integrations, which depend on this service, are set up.
+func (s *DeadLetterService) SetRetrier(retry Retrier) {
+	s.retry = retry
+}
+
+// Add records a payload that failed at the stage.
+func (s *DeadLetterService) Add(ctx context.Context, entry *models.DeadLetter) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	stored := *entry
+	var err error
+	if stored.Payload, err = s.keyring.EncryptData(entry.Payload, []byte(entry.ID)); err != nil {
+		return fmt.Errorf("failed to encrypt dead letter payload: %v", err)
+	}
+	if err := s.repo.Add(ctx, &stored); err != nil {
+		return err
+	}
+	entry.Attempts, entry.CreatedAt, entry.LastAttemptAt = stored.Attempts, stored.CreatedAt, stored.LastAttemptAt
+	metrics.IncCounter("dead_letter_added_total", "Payloads that failed to be ingested, by stage.",
+		metrics.Labels{"source": string(entry.Source), "stage": entry.Stage}, 1)
+	s.refreshSize(ctx, entry.TenantID)
+	return nil
+}
+
+func (s *DeadLetterService) GetEntry(ctx context.Context, id string) (*models.DeadLetter, error) {
+	entry, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decrypt(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *DeadLetterService) ListEntries(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := s.decrypt(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Purge deletes the entries not retried for longer than retention, it
+// returns the number of entries deleted.
+func (s *DeadLetterService) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// Retry ingests the entry again, it is removed when that succeeds.
+func (s *DeadLetterService) Retry(ctx context.Context, entry *models.DeadLetter) error {
+	if s.retry == nil {
+		return fmt.Errorf("dead letters cannot be retried")
+	}
+
+	if err := s.retry(ctx, entry); err != nil {
+		entry.Error = err.Error()
+		if recordErr := s.repo.RecordAttempt(ctx, entry); recordErr != nil {
+			return recordErr
+		}
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, entry.ID); err != nil {
+		return err
+	}
+	s.refreshSize(ctx, entry.TenantID)
+	return nil
+}
+
+// RetryAll retries the entries matching the filter, up to MaxBulkRetry.
+func (s *DeadLetterService) RetryAll(ctx context.Context, filter *models.DeadLetterFilter) (*models.DeadLetterRetryResult, error) {
+	filter.Limit, filter.Offset = MaxBulkRetry, 0
+	entries, err := s.ListEntries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DeadLetterRetryResult{Errors: map[string]string{}}
+	for _, entry := range entries {
+		result.Retried++
+		if err := s.Retry(ctx, entry); err != nil {
+			result.Failed++
+			result.Errors[entry.ID] = err.Error()
+			continue
+		}
+		result.Succeeded++
+	}
+	return result, nil
+}
+
+// Discard removes the entry with the ID, or the entries matching the
+// filter when id is empty, and returns how many were removed.
+func (s *DeadLetterService) Discard(ctx context.Context, filter *models.DeadLetterFilter, id string) (int, error) {
+	discarded := 1
+	if id != "" {
+		entry, err := s.repo.Get(ctx, id)
+		if err != nil || entry.TenantID != filter.TenantID {
+			return 0, fmt.Errorf("no dead letter found with ID %s", id)
+		}
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return 0, err
+		}
+	} else {
+		var err error
+		if discarded, err = s.repo.DeleteMatching(ctx, filter); err != nil {
+			return 0, err
+		}
+	}
+
+	s.refreshSize(ctx, filter.TenantID)
+	return discarded, nil
+}
+
+// RefreshMetrics sets the size gauge of every tenant, the entries being
+// also added and removed by the other instances of the service.
+func (s *DeadLetterService) RefreshMetrics(ctx context.Context) error {
+	counts, err := s.repo.CountByTenant(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for tenantID := range s.reported {
+		if _, ok := counts[tenantID]; !ok {
+			counts[tenantID] = 0
+		}
+	}
+	for tenantID, count := range counts {
+		s.setSize(tenantID, count)
+	}
+	return nil
+}
+
+func (s *DeadLetterService) refreshSize(ctx context.Context, tenantID string) {
+	counts, err := s.repo.CountByTenant(ctx, tenantID)
+	if err != nil {
+		fmt.Printf("Failed to count the dead letters of tenant %s: %v\n", tenantID, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setSize(tenantID, counts[tenantID])
+}
+
+func (s *DeadLetterService) setSize(tenantID string, count int) {
+	s.reported[tenantID] = true
+	metrics.SetGauge("dead_letter_entries", "Payloads waiting in the dead letter queue.", metrics.Labels{"tenant_id": tenantID}, float64(count))
+}
+
+// decrypt decrypts the payload of the entry, kept as is when it was added
+// in plaintext before a key was configured.
+func (s *DeadLetterService) decrypt(entry *models.DeadLetter) error {
+	payload, err := s.keyring.DecryptData(entry.Payload, []byte(entry.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt dead letter %s: %v", entry.ID, err)
+	}
+	entry.Payload = payload
+	return nil
+}
@@ -0,0 +1,89 @@
+package dedup
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const DefaultThreshold = 0.7
+
+type DedupService struct {
+	repo *db.FeedbackRepository
+}
+
+func NewDedupService(repo *db.FeedbackRepository) *DedupService {
+	return &DedupService{repo: repo}
+}
+
+// Assign signs the feedback and puts it in the cluster of its most similar
+// near-duplicate, creating the cluster if that one had none yet. Candidates
+// are the stored feedback and the pending ones, signed but not stored yet
+// (the rest of the batch being ingested).
+func (s *DedupService) Assign(ctx context.Context, settings *models.DeduplicationSettings, feedback *models.Feedback, pending []*models.Feedback) error {
+	if settings.Disabled {
+		return nil
+	}
+	threshold := settings.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	signature, ok := Sign(feedback.Text())
+	if !ok {
+		return nil
+	}
+	bands := signature.BandHashes()
+
+	stored, err := s.repo.FindBySignatureBands(ctx, feedback.TenantID, bands[:])
+	if err != nil {
+		return err
+	}
+	candidates := append(stored, pending...)
+
+	var (
+		best           *models.Feedback
+		bestSimilarity float64
+	)
+	for _, candidate := range candidates {
+		if candidate.Source == feedback.Source && candidate.ID == feedback.ID {
+			continue
+		}
+		candidateSignature, ok := ParseSignature(candidate.Signature)
+		if !ok {
+			continue
+		}
+		if similarity := Similarity(signature, candidateSignature); similarity >= threshold && similarity > bestSimilarity {
+			best, bestSimilarity = candidate, similarity
+		}
+	}
+
+	feedback.Signature = signature.Bytes()
+	feedback.SignatureBands = bands[:]
+
+	if best == nil {
+		return nil
+	}
+	if best.DuplicateClusterID == "" {
+		best.DuplicateClusterID = uuid.New().String()
+		if !isPending(best, pending) {
+			if err := s.repo.SetDuplicateCluster(ctx, best); err != nil {
+				return err
+			}
+		}
+	}
+	feedback.DuplicateClusterID = best.DuplicateClusterID
+
+	return nil
+}
+
+func isPending(feedback *models.Feedback, pending []*models.Feedback) bool {
+	for _, p := range pending {
+		if p == feedback {
+			return true
+		}
+	}
+	return false
+}
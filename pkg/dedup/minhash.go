@@ -0,0 +1,122 @@
+// Package dedup finds near-duplicate feedback with MinHash signatures and
+// locality-sensitive hashing.
+//
+// The signature of a text estimates the Jaccard similarity of its words and
+// word pairs with another text. Signatures are split into bands; two texts
+// sharing any band are candidates, and are near-duplicates when their
+// estimated similarity reaches the tenant's threshold.
+package dedup
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+const (
+	NumHashes   = 64
+	Bands       = 16
+	rowsPerBand = NumHashes / Bands
+
+	// texts with fewer words say too little to be compared
+	minWords = 4
+)
+
+type Signature [NumHashes]uint64
+
+// Sign returns the MinHash signature of text, and false when the text is too
+// short to be compared.
+func Sign(text string) (Signature, bool) {
+	var sig Signature
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minWords {
+		return sig, false
+	}
+
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		base := h.Sum64()
+		for i := range sig {
+			if v := mix(base + uint64(i)*0x9e3779b97f4a7c15); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	// single words and word pairs: feedback is short, longer shingles would
+	// make one edited word change most of them
+	for i, word := range words {
+		add(word)
+		if i > 0 {
+			add(words[i-1] + " " + word)
+		}
+	}
+
+	return sig, true
+}
+
+// Similarity estimates the Jaccard similarity of the texts of two signatures.
+func Similarity(a, b Signature) float64 {
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / NumHashes
+}
+
+// BandHashes returns the hash of each band of the signature, the keys of the
+// similarity index.
+func (s Signature) BandHashes() [Bands]int64 {
+	var hashes [Bands]int64
+	buf := make([]byte, 8)
+	for band := 0; band < Bands; band++ {
+		h := fnv.New64a()
+		for _, v := range s[band*rowsPerBand : (band+1)*rowsPerBand] {
+			binary.LittleEndian.PutUint64(buf, v)
+			h.Write(buf)
+		}
+		hashes[band] = int64(h.Sum64())
+	}
+	return hashes
+}
+
+// Bytes encodes the signature for storage.
+func (s Signature) Bytes() []byte {
+	buf := make([]byte, 8*NumHashes)
+	for i, v := range s {
+		binary.LittleEndian.PutUint64(buf[i*8:], v)
+	}
+	return buf
+}
+
+// ParseSignature decodes a stored signature.
+func ParseSignature(b []byte) (Signature, bool) {
+	var sig Signature
+	if len(b) != 8*NumHashes {
+		return sig, false
+	}
+	for i := range sig {
+		sig[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return sig, true
+}
+
+// mix is the splitmix64 finalizer, it derives the independent hash functions
+// from a single FNV hash.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
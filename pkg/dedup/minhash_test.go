@@ -0,0 +1,89 @@
+package dedup
+
+import "testing"
+
+func TestSignSimilarity(t *testing.T) {
+	base := "The app crashes every time I open the settings page on my phone"
+
+	tests := []struct {
+		name     string
+		other    string
+		min, max float64
+	}{
+		{"identical", base, 1, 1},
+		{"case and punctuation", "the APP crashes, every time I open the settings page on my phone!", 1, 1},
+		{"one word edited", "The app crashes every time I open the settings screen on my phone", 0.5, 0.95},
+		{"unrelated", "Please add an option to export my invoices as a spreadsheet file", 0, 0.2},
+	}
+	a, ok := Sign(base)
+	if !ok {
+		t.Fatalf("Sign(%q) refused the text", base)
+	}
+	for _, tt := range tests {
+		b, ok := Sign(tt.other)
+		if !ok {
+			t.Fatalf("%s: Sign refused the text", tt.name)
+		}
+		if got := Similarity(a, b); got < tt.min || got > tt.max {
+			t.Errorf("%s: similarity %v, want between %v and %v", tt.name, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestSignTooShort(t *testing.T) {
+	for _, text := range []string{"", "thanks!", "great app, love", "!!! ... ???"} {
+		if _, ok := Sign(text); ok {
+			t.Errorf("Sign(%q) accepted a text too short to compare", text)
+		}
+	}
+	if _, ok := Sign("works fine for me"); !ok {
+		t.Errorf("Sign refused a text of %d words", minWords)
+	}
+}
+
+func TestBandHashes(t *testing.T) {
+	a, _ := Sign("The app crashes every time I open the settings page on my phone")
+	b, _ := Sign("The app crashes every time I open the settings page on my phone")
+	c, _ := Sign("Please add an option to export my invoices as a spreadsheet file")
+
+	if a.BandHashes() != b.BandHashes() {
+		t.Errorf("identical texts have different bands")
+	}
+	shared := 0
+	ha, hc := a.BandHashes(), c.BandHashes()
+	for i := range ha {
+		if ha[i] == hc[i] {
+			shared++
+		}
+	}
+	if shared > 0 {
+		t.Errorf("unrelated texts share %d bands", shared)
+	}
+
+	// a band only changes with its own rows
+	d := a
+	d[0]++
+	hd := d.BandHashes()
+	if hd[0] == ha[0] {
+		t.Errorf("changing a row of the first band kept its hash")
+	}
+	for i := 1; i < Bands; i++ {
+		if hd[i] != ha[i] {
+			t.Errorf("changing a row of the first band changed band %d", i)
+		}
+	}
+}
+
+func TestSignatureBytesRoundTrip(t *testing.T) {
+	sig, _ := Sign("The app crashes every time I open the settings page on my phone")
+
+	parsed, ok := ParseSignature(sig.Bytes())
+	if !ok || parsed != sig {
+		t.Errorf("the signature did not survive a round trip")
+	}
+	for _, b := range [][]byte{nil, make([]byte, 8*NumHashes-1), make([]byte, 8*NumHashes+1)} {
+		if _, ok := ParseSignature(b); ok {
+			t.Errorf("a signature of %d bytes was parsed", len(b))
+		}
+	}
+}
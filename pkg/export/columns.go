@@ -0,0 +1,254 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// ColumnType is the type of the values of a column, it decides how Parquet
+// stores them. CSV writes every value as text.
+type ColumnType int
+
+const (
+	TypeString ColumnType = iota
+	TypeDouble
+	TypeInt64
+	TypeTimestamp
+)
+
+// Column maps a field of the feedback to a column of the export. Path is a
+// feedback attribute, "text" for the text of the content, or a dotted path
+// into the metadata ("metadata.app.version") or the content
+// ("content.body"). Objects and lists are written as JSON.
+type Column struct {
+	Name string
+	Path string
+	Type ColumnType
+}
+
+var attributeTypes = map[string]ColumnType{
+	"id":                   TypeString,
+	"tenant_id":            TypeString,
+	"source":               TypeString,
+	"sub_source_id":        TypeString,
+	"source_type":          TypeString,
+	"language":             TypeString,
+	"language_confidence":  TypeDouble,
+	"author":               TypeString,
+	"url":                  TypeString,
+	"rating":               TypeDouble,
+	"source_created_at":    TypeTimestamp,
+	"ingested_at":          TypeTimestamp,
+	"created_at":           TypeTimestamp,
+	"updated_at":           TypeTimestamp,
+	"sentiment":            TypeString,
+	"sentiment_score":      TypeDouble,
+	"tags":                 TypeString,
+	"status":               TypeString,
+	"assignee":             TypeString,
+	"priority":             TypeString,
+	"note_count":           TypeInt64,
+	"duplicate_cluster_id": TypeString,
+	"text":                 TypeString,
+	"metadata":             TypeString,
+	"content":              TypeString,
+}
+
+// DefaultColumns are the columns of a CSV or Parquet export without a
+// column mapping.
+var DefaultColumns = mustParseColumns("id,tenant_id,source,sub_source_id,source_type,language,author,url,rating," +
+	"sentiment,sentiment_score,tags,status,assignee,priority,source_created_at,created_at,text,metadata")
+
+// ParseColumns parses a comma-separated column mapping, each column being
+// either a path or name=path.
+func ParseColumns(spec string) ([]Column, error) {
+	var columns []Column
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, path, found := strings.Cut(item, "=")
+		if !found {
+			path = name
+		}
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if name == "" {
+			return nil, fmt.Errorf("column %q has no name", item)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+
+		typ, ok := attributeTypes[path]
+		if !ok {
+			key, isMetadata := strings.CutPrefix(path, "metadata.")
+			if !isMetadata {
+				key, _ = strings.CutPrefix(path, "content.")
+			}
+			if key == "" || key == path {
+				return nil, fmt.Errorf("unsupported field %q", path)
+			}
+			typ = TypeString
+		}
+		columns = append(columns, Column{Name: name, Path: path, Type: typ})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	return columns, nil
+}
+
+func mustParseColumns(spec string) []Column {
+	columns, err := ParseColumns(spec)
+	if err != nil {
+		panic(err)
+	}
+	return columns
+}
+
+// Value returns the value of the column for the feedback: nil, a string, a
+// float64, an int64 or a time.Time, as the column type.
+func (c *Column) Value(feedback *models.Feedback) interface{} {
+	switch c.Path {
+	case "id":
+		return feedback.ID
+	case "tenant_id":
+		return feedback.TenantID
+	case "source":
+		return string(feedback.Source)
+	case "sub_source_id":
+		return feedback.SubSourceID
+	case "source_type":
+		return string(feedback.SourceType)
+	case "language":
+		return feedback.Language
+	case "language_confidence":
+		return feedback.LanguageConfidence
+	case "author":
+		return feedback.Author
+	case "url":
+		return feedback.URL
+	case "rating":
+		if feedback.Rating == nil {
+			return nil
+		}
+		return *feedback.Rating
+	case "source_created_at":
+		if feedback.SourceCreatedAt == nil {
+			return nil
+		}
+		return *feedback.SourceCreatedAt
+	case "ingested_at":
+		return feedback.IngestedAt
+	case "created_at":
+		return feedback.CreatedAt
+	case "updated_at":
+		return feedback.UpdatedAt
+	case "sentiment":
+		return feedback.Sentiment
+	case "sentiment_score":
+		if feedback.SentimentScore == nil {
+			return nil
+		}
+		return *feedback.SentimentScore
+	case "tags":
+		return strings.Join(feedback.Tags, ";")
+	case "status":
+		return feedback.Status
+	case "assignee":
+		return feedback.Assignee
+	case "priority":
+		return feedback.Priority
+	case "note_count":
+		return int64(feedback.NoteCount)
+	case "duplicate_cluster_id":
+		return feedback.DuplicateClusterID
+	case "text":
+		return feedback.Text()
+	case "metadata":
+		return toText(feedback.Metadata)
+	case "content":
+		return toText(feedback.Content)
+	}
+
+	if key, ok := strings.CutPrefix(c.Path, "metadata."); ok {
+		return lookup(feedback.Metadata, key)
+	}
+	if key, ok := strings.CutPrefix(c.Path, "content."); ok {
+		content, isMap := feedback.Content.(map[string]interface{})
+		if !isMap {
+			// typed content, e.g. from an integration, has the JSON form
+			// it is stored as
+			raw, err := json.Marshal(feedback.Content)
+			if err != nil || json.Unmarshal(raw, &content) != nil {
+				return nil
+			}
+		}
+		return lookup(content, key)
+	}
+	return nil
+}
+
+// lookup returns the value at the dotted path as text, nil when there is
+// none.
+func lookup(values map[string]interface{}, path string) interface{} {
+	var value interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return toText(value)
+}
+
+// toText writes scalars as text and objects and lists as JSON.
+func toText(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return string(raw)
+}
+
+// formatValue writes a column value as CSV text.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
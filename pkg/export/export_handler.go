@@ -0,0 +1,80 @@
+package export
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+)
+
+type ExportHandler struct {
+	service *ExportService
+}
+
+func NewExportHandler(service *ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// ExportFeedbackHandler streams the feedback matching the search filters as
+// NDJSON (the default), CSV or Parquet. columns maps fields to columns,
+// e.g. columns=id,body=content.body,app_version=metadata.app.version.
+func (h *ExportHandler) ExportFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	filter, err := feedback.ParseFeedbackFilter(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := params.Get("format")
+	if format == "" {
+		format = FormatNDJSON
+	}
+	contentType, ok := Formats[format]
+	if !ok {
+		http.Error(w, "Invalid format, expected ndjson, csv or parquet", http.StatusBadRequest)
+		return
+	}
+
+	var columns []Column
+	if spec := params.Get("columns"); spec != "" {
+		if columns, err = ParseColumns(spec); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid columns: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="feedback-%s.%s"`, filter.TenantID, format))
+
+	ctx := r.Context()
+	out := &flushWriter{w: w}
+	count, err := h.service.Export(ctx, filter, format, columns, out)
+	if err != nil {
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, fmt.Sprintf("Failed to export feedback records: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// the status is sent already, abort the response so that the client
+		// sees a truncated export rather than a complete one
+		fmt.Printf("Failed to export feedback of tenant %s after %d records: %v\n", filter.TenantID, count, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// flushWriter sends every write to the client as it is made, the writers
+// buffer by themselves.
+type flushWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.written = true
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"context"
+	"io"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type ExportService struct {
+	repo *db.FeedbackRepository
+}
+
+func NewExportService(repo *db.FeedbackRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// Export streams the feedback matching the filter to w in the format, it
+// returns the number of records written.
+func (s *ExportService) Export(ctx context.Context, filter *models.FeedbackFilter, format string, columns []Column, w io.Writer) (int, error) {
+	writer, err := NewWriter(format, w, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = s.repo.Stream(ctx, filter, func(feedback *models.Feedback) error {
+		count++
+		return writer.Write(feedback)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writer.Close()
+}
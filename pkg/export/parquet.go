@@ -0,0 +1,286 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// A minimal Parquet writer: flat schema of optional columns, PLAIN encoded,
+// uncompressed, one data page per column chunk. Rows are buffered into row
+// groups, which bounds the memory an export takes.
+// See https://github.com/apache/parquet-format.
+
+const (
+	parquetMagic = "PAR1"
+
+	// a row group is written once it has this many rows or values bytes
+	rowGroupRows  = 10000
+	rowGroupBytes = 32 << 20
+
+	createdBy = "feedback-ingestion-system"
+)
+
+// parquet-format enums
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	pageTypeData      = 0
+)
+
+type columnChunk struct {
+	present []bool // the definition level of each row
+	values  []byte // PLAIN encoded values of the rows that have one
+}
+
+type columnChunkMeta struct {
+	offset int64
+	size   int64
+}
+
+type rowGroupMeta struct {
+	rows    int64
+	size    int64
+	columns []columnChunkMeta
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type parquetWriter struct {
+	out       *countingWriter
+	columns   []Column
+	chunks    []columnChunk
+	rows      int
+	bytes     int
+	totalRows int64
+	rowGroups []rowGroupMeta
+}
+
+func newParquetWriter(w io.Writer, columns []Column) *parquetWriter {
+	return &parquetWriter{out: &countingWriter{w: w}, columns: columns, chunks: make([]columnChunk, len(columns))}
+}
+
+// writeMagic starts the file, it is written with the first row group so
+// that nothing is written before the first row is read.
+func (w *parquetWriter) writeMagic() error {
+	if w.out.n > 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.out, parquetMagic)
+	return err
+}
+
+func (w *parquetWriter) Write(feedback *models.Feedback) error {
+	for i := range w.columns {
+		chunk := &w.chunks[i]
+		before := len(chunk.values)
+		chunk.values = appendPlain(chunk.values, w.columns[i].Type, w.columns[i].Value(feedback))
+		chunk.present = append(chunk.present, len(chunk.values) > before)
+		w.bytes += len(chunk.values) - before
+	}
+	w.rows++
+
+	if w.rows >= rowGroupRows || w.bytes >= rowGroupBytes {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+// appendPlain appends the PLAIN encoding of the value, nothing for nil.
+func appendPlain(buf []byte, typ ColumnType, value interface{}) []byte {
+	if value == nil {
+		return buf
+	}
+	switch typ {
+	case TypeDouble:
+		if v, ok := value.(float64); ok {
+			return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	case TypeInt64:
+		if v, ok := value.(int64); ok {
+			return binary.LittleEndian.AppendUint64(buf, uint64(v))
+		}
+	case TypeTimestamp:
+		if v, ok := value.(time.Time); ok {
+			return binary.LittleEndian.AppendUint64(buf, uint64(v.UnixMicro()))
+		}
+	default:
+		s := formatValue(value)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)))
+		return append(buf, s...)
+	}
+	return buf
+}
+
+func (w *parquetWriter) flushRowGroup() error {
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+	group := rowGroupMeta{rows: int64(w.rows)}
+	for i := range w.chunks {
+		chunk := &w.chunks[i]
+		levels := encodeLevels(chunk.present)
+
+		page := newThriftWriter()
+		page.i32(1, pageTypeData)
+		pageSize := int32(4 + len(levels) + len(chunk.values))
+		page.i32(2, pageSize)
+		page.i32(3, pageSize)
+		page.beginStruct(5)
+		page.i32(1, int32(w.rows))
+		page.i32(2, encodingPlain)
+		page.i32(3, encodingRLE)
+		page.i32(4, encodingRLE)
+		page.endStruct()
+		header := page.end()
+
+		offset := w.out.n
+		var levelsLength [4]byte
+		binary.LittleEndian.PutUint32(levelsLength[:], uint32(len(levels)))
+		for _, part := range [][]byte{header, levelsLength[:], levels, chunk.values} {
+			if _, err := w.out.Write(part); err != nil {
+				return err
+			}
+		}
+
+		size := w.out.n - offset
+		group.columns = append(group.columns, columnChunkMeta{offset: offset, size: size})
+		group.size += size
+
+		chunk.present = chunk.present[:0]
+		chunk.values = chunk.values[:0]
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.totalRows += int64(w.rows)
+	w.rows, w.bytes = 0, 0
+	return nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 as runs of the
+// RLE/bit-packing hybrid.
+func encodeLevels(present []bool) []byte {
+	var buf []byte
+	for i := 0; i < len(present); {
+		j := i
+		for j < len(present) && present[j] == present[i] {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		if present[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.writeMagic(); err != nil {
+		return err
+	}
+	if w.rows > 0 {
+		if err := w.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	meta := newThriftWriter()
+	meta.i32(1, 1)
+
+	meta.beginList(2, thriftStruct, len(w.columns)+1)
+	meta.beginElement()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.endStruct()
+	for _, column := range w.columns {
+		meta.beginElement()
+		meta.i32(1, physicalType(column.Type))
+		meta.i32(3, repetitionOptional)
+		meta.string(4, column.Name)
+		switch column.Type {
+		case TypeString:
+			meta.i32(6, convertedUTF8)
+		case TypeTimestamp:
+			meta.i32(6, convertedTimestampMicros)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, w.totalRows)
+
+	meta.beginList(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		meta.beginElement()
+		meta.beginList(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			column := w.columns[i]
+			meta.beginElement()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, physicalType(column.Type))
+			meta.beginList(2, thriftI32, 2)
+			meta.i32Element(encodingPlain)
+			meta.i32Element(encodingRLE)
+			meta.beginList(3, thriftBinary, 1)
+			meta.rawString(column.Name)
+			meta.i32(4, codecUncompressed)
+			meta.i64(5, group.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.endStruct()
+	}
+
+	meta.string(6, createdBy)
+	footer := meta.end()
+
+	var footerLength [4]byte
+	binary.LittleEndian.PutUint32(footerLength[:], uint32(len(footer)))
+	for _, part := range [][]byte{footer, footerLength[:], []byte(parquetMagic)} {
+		if _, err := w.out.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func physicalType(typ ColumnType) int32 {
+	switch typ {
+	case TypeDouble:
+		return parquetDouble
+	case TypeInt64, TypeTimestamp:
+		return parquetInt64
+	}
+	return parquetByteArray
+}
@@ -0,0 +1,502 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// The files are read back with the reader below, written from the
+// parquet-format and Thrift compact protocol specifications independently of
+// the writer: it decodes every field it finds instead of the ones the writer
+// is known to write.
+
+func TestParquetRoundTrip(t *testing.T) {
+	columns, err := ParseColumns("id,rating,note_count,source_created_at,author,app=metadata.app")
+	if err != nil {
+		t.Fatalf("ParseColumns: %v", err)
+	}
+
+	// three row groups, the last one partial
+	rowCount := 2*rowGroupRows + 3
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	feedbacks := make([]*models.Feedback, rowCount)
+	for i := range feedbacks {
+		feedback := &models.Feedback{
+			ID:        fmt.Sprintf("fb-%d", i),
+			NoteCount: i % 7,
+			Author:    "Zoë",
+			Metadata:  map[string]interface{}{},
+		}
+		if i%3 != 0 {
+			rating := float64(i%5) + 0.5
+			feedback.Rating = &rating
+		}
+		if i%4 != 0 {
+			created := base.Add(time.Duration(i) * 1500 * time.Microsecond)
+			feedback.SourceCreatedAt = &created
+		}
+		if i%2 == 0 {
+			feedback.Metadata["app"] = "ios"
+		}
+		feedbacks[i] = feedback
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatParquet, &buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, feedback := range feedbacks {
+		if err := w.Write(feedback); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	file := readParquet(t, buf.Bytes())
+
+	if len(file.rowGroups) != 3 {
+		t.Errorf("got %d row groups, want 3", len(file.rowGroups))
+	}
+	if file.numRows != int64(rowCount) || len(file.rows) != rowCount {
+		t.Fatalf("got %d rows (%d in the footer), want %d", len(file.rows), file.numRows, rowCount)
+	}
+
+	wantSchema := []schemaElement{
+		{name: "id", physical: parquetByteArray, converted: convertedUTF8},
+		{name: "rating", physical: parquetDouble, converted: -1},
+		{name: "note_count", physical: parquetInt64, converted: -1},
+		{name: "source_created_at", physical: parquetInt64, converted: convertedTimestampMicros},
+		{name: "author", physical: parquetByteArray, converted: convertedUTF8},
+		{name: "app", physical: parquetByteArray, converted: convertedUTF8},
+	}
+	if len(file.schema) != len(wantSchema) {
+		t.Fatalf("got %d columns, want %d", len(file.schema), len(wantSchema))
+	}
+	for i, want := range wantSchema {
+		if got := file.schema[i]; got != want {
+			t.Errorf("column %d: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	for i, feedback := range feedbacks {
+		row := file.rows[i]
+		if row[0] != feedback.ID {
+			t.Fatalf("row %d: id %v, want %s", i, row[0], feedback.ID)
+		}
+		if feedback.Rating == nil {
+			if row[1] != nil {
+				t.Errorf("row %d: rating %v, want null", i, row[1])
+			}
+		} else if row[1] != *feedback.Rating {
+			t.Errorf("row %d: rating %v, want %v", i, row[1], *feedback.Rating)
+		}
+		if row[2] != int64(feedback.NoteCount) {
+			t.Errorf("row %d: note_count %v, want %d", i, row[2], feedback.NoteCount)
+		}
+		if feedback.SourceCreatedAt == nil {
+			if row[3] != nil {
+				t.Errorf("row %d: source_created_at %v, want null", i, row[3])
+			}
+		} else if micros, ok := row[3].(int64); !ok || !time.UnixMicro(micros).Equal(*feedback.SourceCreatedAt) {
+			t.Errorf("row %d: source_created_at %v, want %v", i, row[3], *feedback.SourceCreatedAt)
+		}
+		if row[4] != "Zoë" {
+			t.Errorf("row %d: author %v, want Zoë", i, row[4])
+		}
+		if i%2 == 0 && row[5] != "ios" {
+			t.Errorf("row %d: app %v, want ios", i, row[5])
+		}
+		if i%2 != 0 && row[5] != nil {
+			t.Errorf("row %d: app %v, want null", i, row[5])
+		}
+	}
+}
+
+func TestParquetEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatParquet, &buf, nil)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	file := readParquet(t, buf.Bytes())
+	if file.numRows != 0 || len(file.rowGroups) != 0 || len(file.rows) != 0 {
+		t.Errorf("got %d rows in %d row groups, want none", file.numRows, len(file.rowGroups))
+	}
+	if len(file.schema) != len(DefaultColumns) {
+		t.Errorf("got %d columns, want %d", len(file.schema), len(DefaultColumns))
+	}
+}
+
+type schemaElement struct {
+	name      string
+	physical  int64
+	converted int64 // -1 when not set
+}
+
+type parquetFile struct {
+	numRows   int64
+	schema    []schemaElement
+	rowGroups []int64 // the rows of each row group
+	rows      [][]interface{}
+}
+
+// readParquet reads a flat file of optional columns, failing the test on
+// anything malformed.
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("missing PAR1 magic")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLength
+	if footerStart < 4 {
+		t.Fatalf("invalid footer length %d", footerLength)
+	}
+	decoder := &thriftDecoder{data: data[footerStart : len(data)-8]}
+	meta := decoder.readStruct()
+	if decoder.err != nil || decoder.pos != footerLength {
+		t.Fatalf("invalid footer: %v, %d of %d bytes read", decoder.err, decoder.pos, footerLength)
+	}
+
+	file := &parquetFile{numRows: meta.i64(3)}
+	if version := meta.i64(1); version != 1 {
+		t.Errorf("version %d, want 1", version)
+	}
+
+	elements := meta.list(2)
+	if len(elements) == 0 {
+		t.Fatalf("no schema")
+	}
+	root := elements[0].(decodedStruct)
+	if int(root.i64(5)) != len(elements)-1 {
+		t.Fatalf("the root has %d children, the schema %d columns", root.i64(5), len(elements)-1)
+	}
+	for _, element := range elements[1:] {
+		element := element.(decodedStruct)
+		if repetition := element.i64(3); repetition != repetitionOptional {
+			t.Errorf("column %s: repetition %d, want optional", element.str(4), repetition)
+		}
+		converted := int64(-1)
+		if _, ok := element[6]; ok {
+			converted = element.i64(6)
+		}
+		file.schema = append(file.schema, schemaElement{name: element.str(4), physical: element.i64(1), converted: converted})
+	}
+
+	var groupRows int64
+	for _, group := range meta.list(4) {
+		group := group.(decodedStruct)
+		rows := group.i64(3)
+		chunks := group.list(1)
+		if len(chunks) != len(file.schema) {
+			t.Fatalf("row group with %d column chunks, want %d", len(chunks), len(file.schema))
+		}
+
+		groupValues := make([][]interface{}, len(chunks))
+		var groupSize int64
+		for i, chunk := range chunks {
+			chunk := chunk.(decodedStruct)
+			column := chunk.strct(3)
+			if path := column.list(3); len(path) != 1 || string(path[0].([]byte)) != file.schema[i].name {
+				t.Fatalf("column chunk %d has path %v, want %s", i, path, file.schema[i].name)
+			}
+			if column.i64(1) != file.schema[i].physical || column.i64(4) != codecUncompressed || column.i64(5) != rows {
+				t.Fatalf("column chunk %s: unexpected metadata %v", file.schema[i].name, column)
+			}
+			offset, size := column.i64(9), column.i64(7)
+			if chunk.i64(2) != offset {
+				t.Errorf("column chunk %s: file offset %d, data page offset %d", file.schema[i].name, chunk.i64(2), offset)
+			}
+			if offset < 4 || offset+size > int64(footerStart) {
+				t.Fatalf("column chunk %s out of the file", file.schema[i].name)
+			}
+			groupSize += size
+			groupValues[i] = readColumnChunk(t, data[offset:offset+size], file.schema[i], rows)
+		}
+		if total := group.i64(2); total != groupSize {
+			t.Errorf("row group size %d, the column chunks add up to %d", total, groupSize)
+		}
+
+		for row := int64(0); row < rows; row++ {
+			values := make([]interface{}, len(chunks))
+			for i := range chunks {
+				values[i] = groupValues[i][row]
+			}
+			file.rows = append(file.rows, values)
+		}
+		file.rowGroups = append(file.rowGroups, rows)
+		groupRows += rows
+	}
+	if groupRows != file.numRows {
+		t.Errorf("the row groups have %d rows, the footer says %d", groupRows, file.numRows)
+	}
+
+	return file
+}
+
+// readColumnChunk reads the single data page of a column chunk.
+func readColumnChunk(t *testing.T, chunk []byte, column schemaElement, rows int64) []interface{} {
+	t.Helper()
+
+	decoder := &thriftDecoder{data: chunk}
+	header := decoder.readStruct()
+	if decoder.err != nil {
+		t.Fatalf("column %s: invalid page header: %v", column.name, decoder.err)
+	}
+	if header.i64(1) != pageTypeData {
+		t.Fatalf("column %s: page type %d, want a data page", column.name, header.i64(1))
+	}
+	page := chunk[decoder.pos:]
+	if int(header.i64(2)) != len(page) || int(header.i64(3)) != len(page) {
+		t.Fatalf("column %s: page sizes %d/%d, the chunk has %d bytes", column.name, header.i64(2), header.i64(3), len(page))
+	}
+	dataHeader := header.strct(5)
+	if dataHeader.i64(1) != rows || dataHeader.i64(2) != encodingPlain || dataHeader.i64(3) != encodingRLE {
+		t.Fatalf("column %s: unexpected data page header %v", column.name, dataHeader)
+	}
+
+	if len(page) < 4 {
+		t.Fatalf("column %s: page too short", column.name)
+	}
+	levelsLength := int(binary.LittleEndian.Uint32(page))
+	if 4+levelsLength > len(page) {
+		t.Fatalf("column %s: levels length %d out of the page", column.name, levelsLength)
+	}
+	levels := decodeHybrid(t, page[4:4+levelsLength], int(rows))
+	values := page[4+levelsLength:]
+
+	out := make([]interface{}, rows)
+	for row, level := range levels {
+		if level == 0 {
+			continue
+		}
+		switch column.physical {
+		case parquetDouble, parquetInt64:
+			if len(values) < 8 {
+				t.Fatalf("column %s: truncated values", column.name)
+			}
+			bits := binary.LittleEndian.Uint64(values)
+			if column.physical == parquetDouble {
+				out[row] = math.Float64frombits(bits)
+			} else {
+				out[row] = int64(bits)
+			}
+			values = values[8:]
+		case parquetByteArray:
+			if len(values) < 4 {
+				t.Fatalf("column %s: truncated values", column.name)
+			}
+			n := int(binary.LittleEndian.Uint32(values))
+			if 4+n > len(values) {
+				t.Fatalf("column %s: truncated values", column.name)
+			}
+			out[row] = string(values[4 : 4+n])
+			values = values[4+n:]
+		default:
+			t.Fatalf("column %s: unexpected physical type %d", column.name, column.physical)
+		}
+	}
+	if len(values) != 0 {
+		t.Fatalf("column %s: %d bytes left after the values", column.name, len(values))
+	}
+	return out
+}
+
+// decodeHybrid decodes count levels of bit width 1 from the RLE/bit-packing
+// hybrid encoding.
+func decodeHybrid(t *testing.T, data []byte, count int) []int {
+	t.Helper()
+
+	var levels []int
+	for len(levels) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("invalid run header after %d levels", len(levels))
+		}
+		data = data[n:]
+		if header&1 == 0 {
+			// a run of the value, stored on one byte for bit width 1
+			if len(data) < 1 {
+				t.Fatalf("truncated run")
+			}
+			for i := uint64(0); i < header>>1; i++ {
+				levels = append(levels, int(data[0]))
+			}
+			data = data[1:]
+		} else {
+			// groups of 8 bit-packed values
+			groups := int(header >> 1)
+			if len(data) < groups {
+				t.Fatalf("truncated bit-packed run")
+			}
+			for _, b := range data[:groups] {
+				for bit := 0; bit < 8; bit++ {
+					levels = append(levels, int(b>>bit)&1)
+				}
+			}
+			data = data[groups:]
+		}
+	}
+	if len(levels) > count && len(data) == 0 {
+		// the padding of the last bit-packed group
+		levels = levels[:count]
+	}
+	if len(levels) != count || len(data) != 0 {
+		t.Fatalf("got %d levels and %d bytes left, want %d levels", len(levels), len(data), count)
+	}
+	for _, level := range levels {
+		if level > 1 {
+			t.Fatalf("definition level %d above the maximum of 1", level)
+		}
+	}
+	return levels
+}
+
+// decodedStruct maps the field ids of a decoded struct to their values:
+// int64, float64, bool, []byte, []interface{} or decodedStruct.
+type decodedStruct map[int16]interface{}
+
+func (s decodedStruct) i64(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s decodedStruct) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s decodedStruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+func (s decodedStruct) strct(id int16) decodedStruct {
+	v, _ := s[id].(decodedStruct)
+	return v
+}
+
+// thriftDecoder decodes the Thrift compact protocol.
+type thriftDecoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *thriftDecoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format+" at byte %d", append(args, d.pos)...)
+	}
+}
+
+func (d *thriftDecoder) readByte() byte {
+	if d.pos >= len(d.data) {
+		d.fail("unexpected end")
+		return 0
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *thriftDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("invalid varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *thriftDecoder) zigzag() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *thriftDecoder) readStruct() decodedStruct {
+	s := decodedStruct{}
+	var last int16
+	for d.err == nil {
+		header := d.readByte()
+		if header == 0 {
+			return s
+		}
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(d.zigzag())
+		}
+		switch typ {
+		case 1, 2:
+			// booleans are stored in the field type
+			s[last] = typ == 1
+		default:
+			s[last] = d.readValue(typ)
+		}
+	}
+	return s
+}
+
+func (d *thriftDecoder) readValue(typ byte) interface{} {
+	switch typ {
+	case 3:
+		return int64(int8(d.readByte()))
+	case 4, 5, 6:
+		return d.zigzag()
+	case 7:
+		if d.pos+8 > len(d.data) {
+			d.fail("truncated double")
+			return nil
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.data[d.pos:]))
+		d.pos += 8
+		return v
+	case 8:
+		n := int(d.uvarint())
+		if d.err != nil || d.pos+n > len(d.data) {
+			d.fail("truncated binary")
+			return nil
+		}
+		v := d.data[d.pos : d.pos+n]
+		d.pos += n
+		return v
+	case 9, 10:
+		header := d.readByte()
+		size, elemType := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size && d.err == nil; i++ {
+			if elemType == 1 || elemType == 2 {
+				list = append(list, d.readByte() == 1)
+				continue
+			}
+			list = append(list, d.readValue(elemType))
+		}
+		return list
+	case 12:
+		return d.readStruct()
+	}
+	d.fail("unsupported type %d", typ)
+	return nil
+}
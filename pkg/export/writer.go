@@ -0,0 +1,111 @@
+// Package export writes feedback out as NDJSON, CSV or Parquet, one record
+// at a time so that exports of any size stream in constant memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	FormatNDJSON  = "ndjson"
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// Formats maps the export formats to their content type.
+var Formats = map[string]string{
+	FormatNDJSON:  "application/x-ndjson",
+	FormatCSV:     "text/csv",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// Writer writes feedback records in an export format. Close writes what is
+// still buffered, and the footer of formats that have one, it does not
+// close the underlying writer.
+type Writer interface {
+	Write(feedback *models.Feedback) error
+	Close() error
+}
+
+// NewWriter returns a writer of the format. NDJSON writes whole feedback
+// records unless columns are given, CSV and Parquet write the default
+// columns unless columns are given.
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatCSV:
+		if len(columns) == 0 {
+			columns = DefaultColumns
+		}
+		return newCSVWriter(w, columns)
+	case FormatParquet:
+		if len(columns) == 0 {
+			columns = DefaultColumns
+		}
+		return newParquetWriter(w, columns), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type ndjsonWriter struct {
+	out     *bufio.Writer
+	enc     *json.Encoder
+	columns []Column
+}
+
+func newNDJSONWriter(w io.Writer, columns []Column) *ndjsonWriter {
+	out := bufio.NewWriterSize(w, 64*1024)
+	return &ndjsonWriter{out: out, enc: json.NewEncoder(out), columns: columns}
+}
+
+func (w *ndjsonWriter) Write(feedback *models.Feedback) error {
+	if len(w.columns) == 0 {
+		return w.enc.Encode(feedback)
+	}
+	record := make(map[string]interface{}, len(w.columns))
+	for i := range w.columns {
+		record[w.columns[i].Name] = w.columns[i].Value(feedback)
+	}
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.out.Flush()
+}
+
+type csvWriter struct {
+	out     *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	out := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := out.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvWriter{out: out, columns: columns, record: make([]string, len(columns))}, nil
+}
+
+func (w *csvWriter) Write(feedback *models.Feedback) error {
+	for i := range w.columns {
+		w.record[i] = formatValue(w.columns[i].Value(feedback))
+	}
+	return w.out.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.out.Flush()
+	return w.out.Error()
+}
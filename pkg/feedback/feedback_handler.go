@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/sentiment"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/triage"
 )
 
 type FeedbackHandler struct {
@@ -116,3 +121,196 @@ func (h *FeedbackHandler) ListFeedbackByTenantHandler(w http.ResponseWriter, r *
 
 	json.NewEncoder(w).Encode(feedbacks)
 }
+
+func (h *FeedbackHandler) SearchFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseFeedbackFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	feedbacks, err := h.service.SearchFeedback(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search feedback records: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(feedbacks)
+}
+
+// ListDuplicatesHandler lists the near-duplicates of a feedback, itself
+// included.
+func (h *FeedbackHandler) ListDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tenantID := query.Get("tenant_id")
+	source := models.Source(query.Get("source"))
+	feedbackID := query.Get("id")
+	if tenantID == "" || source == "" || feedbackID == "" {
+		http.Error(w, "Tenant ID, source and feedback ID are required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	feedbacks, err := h.service.ListDuplicates(ctx, tenantID, source, feedbackID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list duplicate feedback records: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(feedbacks)
+}
+
+// SentimentSummaryHandler counts the feedback matching the search filters by
+// sentiment, optionally grouped by group_by (source, sub_source_id,
+// source_type, language, day, week or month).
+func (h *FeedbackHandler) SentimentSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseFeedbackFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	switch groupBy {
+	case "", "source", "sub_source_id", "source_type", "language", "day", "week", "month":
+	default:
+		http.Error(w, "Invalid group_by", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	summaries, err := h.service.SentimentSummary(ctx, filter, groupBy)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to summarize feedback sentiment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// ParseFeedbackFilter reads the search API filters from the query string.
+// Timestamps are RFC3339, tenant_id is required.
+func ParseFeedbackFilter(query url.Values) (*models.FeedbackFilter, error) {
+	filter := &models.FeedbackFilter{
+		TenantID:    query.Get("tenant_id"),
+		Source:      models.Source(query.Get("source")),
+		SubSourceID: query.Get("sub_source_id"),
+		SourceType:  models.SourceType(query.Get("source_type")),
+		Language:    query.Get("language"),
+		Author:      query.Get("author"),
+		Tag:         query.Get("tag"),
+		Sentiment:   query.Get("sentiment"),
+		Status:      query.Get("status"),
+		Assignee:    query.Get("assignee"),
+		Priority:    query.Get("priority"),
+
+		DuplicateClusterID: query.Get("duplicate_cluster_id"),
+		CollapseDuplicates: query.Get("collapse_duplicates") == "true",
+	}
+
+	if filter.TenantID == "" {
+		return nil, fmt.Errorf("Tenant ID is required")
+	}
+	if _, err := uuid.Parse(filter.TenantID); err != nil {
+		return nil, fmt.Errorf("Invalid Tenant ID format")
+	}
+	if filter.SubSourceID != "" {
+		if _, err := uuid.Parse(filter.SubSourceID); err != nil {
+			return nil, fmt.Errorf("Invalid Sub Source ID format")
+		}
+	}
+	if filter.DuplicateClusterID != "" {
+		if _, err := uuid.Parse(filter.DuplicateClusterID); err != nil {
+			return nil, fmt.Errorf("Invalid Duplicate Cluster ID format")
+		}
+	}
+
+	switch filter.Sentiment {
+	case "", sentiment.Positive, sentiment.Neutral, sentiment.Negative:
+	default:
+		return nil, fmt.Errorf("Invalid sentiment, expected positive, neutral or negative")
+	}
+
+	if filter.Status != "" && !triage.ValidStatus(filter.Status) {
+		return nil, fmt.Errorf("Invalid status, expected new, reviewed, actioned or ignored")
+	}
+	if !triage.ValidPriority(filter.Priority) {
+		return nil, fmt.Errorf("Invalid priority, expected low, medium, high or urgent")
+	}
+	if value := query.Get("has_notes"); value != "" {
+		hasNotes, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid has_notes, expected true or false")
+		}
+		filter.HasNotes = &hasNotes
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(query, "from"); err != nil {
+		return nil, err
+	}
+	if filter.To, err = parseTimeParam(query, "to"); err != nil {
+		return nil, err
+	}
+	if filter.MinRating, err = parseFloatParam(query, "min_rating"); err != nil {
+		return nil, err
+	}
+	if filter.MaxRating, err = parseFloatParam(query, "max_rating"); err != nil {
+		return nil, err
+	}
+	if filter.MinSentiment, err = parseFloatParam(query, "min_sentiment"); err != nil {
+		return nil, err
+	}
+	if filter.MaxSentiment, err = parseFloatParam(query, "max_sentiment"); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = parseIntParam(query, "limit"); err != nil {
+		return nil, err
+	}
+	if filter.Offset, err = parseIntParam(query, "offset"); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s, expected RFC3339 timestamp", name)
+	}
+	return t, nil
+}
+
+func parseFloatParam(query url.Values, name string) (*float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s, expected a number", name)
+	}
+	return &f, nil
+}
+
+func parseIntParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("Invalid %s, expected a non-negative integer", name)
+	}
+	return i, nil
+}
@@ -34,3 +34,37 @@ func (s *FeedbackService) DeleteFeedback(ctx context.Context, feedbackID string)
 func (s *FeedbackService) ListFeedbackByTenant(ctx context.Context, tenantID string) ([]*models.Feedback, error) {
 	return s.repo.ListByTenant(ctx, tenantID)
 }
+
+func (s *FeedbackService) SearchFeedback(ctx context.Context, filter *models.FeedbackFilter) ([]*models.Feedback, error) {
+	return s.repo.Search(ctx, filter)
+}
+
+func (s *FeedbackService) SentimentSummary(ctx context.Context, filter *models.FeedbackFilter, groupBy string) ([]*models.SentimentSummary, error) {
+	return s.repo.SentimentSummary(ctx, filter, groupBy)
+}
+
+// ListDuplicates returns the cluster of near-duplicates of a feedback, or
+// the feedback alone if it has none.
+func (s *FeedbackService) ListDuplicates(ctx context.Context, tenantID string, source models.Source, feedbackID string) ([]*models.Feedback, error) {
+	feedback, err := s.repo.GetByKey(ctx, tenantID, source, feedbackID)
+	if err != nil {
+		return nil, err
+	}
+	if feedback.DuplicateClusterID == "" {
+		return []*models.Feedback{feedback}, nil
+	}
+
+	return s.repo.Search(ctx, &models.FeedbackFilter{TenantID: tenantID, DuplicateClusterID: feedback.DuplicateClusterID})
+}
+
+// UpsertFeedbacks saves the feedbacks, updating the ones already stored, so
+// a batch can be saved again.
+func (s *FeedbackService) UpsertFeedbacks(ctx context.Context, feedbacks []*models.Feedback) (int, int, error) {
+	return s.repo.Upsert(ctx, feedbacks)
+}
+
+// GetFeedbacksByKey returns the stored feedback of the tenant and source
+// among the IDs, by ID.
+func (s *FeedbackService) GetFeedbacksByKey(ctx context.Context, tenantID string, source models.Source, feedbackIDs []string) (map[string]*models.Feedback, error) {
+	return s.repo.GetByKeys(ctx, tenantID, source, feedbackIDs)
+}
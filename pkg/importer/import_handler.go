@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type ImportHandler struct {
+	service *ImportService
+}
+
+func NewImportHandler(service *ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+// ImportFeedbackHandler imports a CSV or NDJSON file posted as
+// multipart/form-data: a "mapping" part with the JSON column mapping, then
+// a "file" part. The format is the format parameter or the file extension.
+// dry_run=true only validates the rows. The response is the import report.
+func (h *ImportHandler) ImportFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	tenantID := query.Get("tenant_id")
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	parts, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart/form-data request", http.StatusBadRequest)
+		return
+	}
+
+	var mapping *models.ImportMapping
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			http.Error(w, "The request has no file part", http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "mapping":
+			mapping = &models.ImportMapping{}
+			if err := json.NewDecoder(part).Decode(mapping); err != nil {
+				http.Error(w, "Invalid mapping", http.StatusBadRequest)
+				return
+			}
+		case "file":
+			if mapping == nil {
+				http.Error(w, "The mapping part must come before the file part", http.StatusBadRequest)
+				return
+			}
+			format := query.Get("format")
+			if format == "" {
+				format = formatOf(part.FileName())
+			}
+			if format != FormatCSV && format != FormatNDJSON {
+				http.Error(w, "Invalid format, expected csv or ndjson", http.StatusBadRequest)
+				return
+			}
+
+			ctx := r.Context()
+			result, err := h.service.Import(ctx, tenantID, format, mapping, part, dryRun)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to import feedback: %v", err), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+	}
+}
+
+// formatOf guesses the format of a file from its extension.
+func formatOf(fileName string) string {
+	switch path.Ext(fileName) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	return ""
+}
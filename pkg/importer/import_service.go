@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+)
+
+const (
+	// BatchSize is the number of rows written in one transaction
+	BatchSize = 500
+	// MaxReportedErrors bounds the row errors of an import result
+	MaxReportedErrors = 1000
+)
+
+type ImportService struct {
+	repo       *db.FeedbackRepository
+	tenantRepo *db.TenantRepository
+	pipeline   *pipeline.PipelineService
+}
+
+func NewImportService(repo *db.FeedbackRepository, tenantRepo *db.TenantRepository, pipelineService *pipeline.PipelineService) *ImportService {
+	return &ImportService{repo: repo, tenantRepo: tenantRepo, pipeline: pipelineService}
+}
+
+// Import reads the rows of a CSV or NDJSON file, maps them to feedback and,
+// unless it is a dry run, runs them through the enrichment pipeline and
+// upserts them by native ID in batches. Rows that fail are reported and
+// skipped, a malformed CSV file stops the import where it is malformed.
+func (s *ImportService) Import(ctx context.Context, tenantID, format string, mapping *models.ImportMapping, r io.Reader, dryRun bool) (*models.ImportResult, error) {
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping: %v", err)
+	}
+	if _, err := s.tenantRepo.Get(ctx, tenantID); err != nil {
+		return nil, err
+	}
+
+	reader, err := newRowReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{DryRun: dryRun, Errors: []models.ImportRowError{}}
+	var (
+		batch     []*models.Feedback
+		batchRows []int
+	)
+	for {
+		n, row, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		result.Rows++
+		if err != nil {
+			var rowErr *rowError
+			if errors.As(err, &rowErr) {
+				fail(result, n, "", rowErr.err)
+				continue
+			}
+			fail(result, n, "", err)
+			break
+		}
+
+		feedback, err := toFeedback(tenantID, mapping, row)
+		if err != nil {
+			var fieldErr *fieldError
+			if errors.As(err, &fieldErr) {
+				fail(result, n, fieldErr.field, fieldErr.err)
+			} else {
+				fail(result, n, "", err)
+			}
+			continue
+		}
+		result.Valid++
+		if dryRun {
+			continue
+		}
+
+		batch = append(batch, feedback)
+		batchRows = append(batchRows, n)
+		if len(batch) >= BatchSize {
+			s.write(ctx, result, batch, batchRows)
+			batch, batchRows = nil, nil
+		}
+	}
+	if len(batch) > 0 {
+		s.write(ctx, result, batch, batchRows)
+	}
+
+	return result, nil
+}
+
+// write enriches and upserts a batch. When the batch fails to be saved, its
+// rows are saved one at a time so that only the rows that fail are reported.
+func (s *ImportService) write(ctx context.Context, result *models.ImportResult, batch []*models.Feedback, rows []int) {
+	processed, stageErrors, err := s.pipeline.Process(ctx, batch)
+	if err != nil {
+		failAll(result, rows, fmt.Errorf("failed to run the enrichment pipeline: %v", err))
+		return
+	}
+	for _, stageError := range stageErrors {
+		fmt.Printf("Enrichment pipeline: %v\n", stageError)
+	}
+	if dropped := len(batch) - len(processed); dropped > 0 {
+		result.Skipped += dropped
+	}
+
+	inserted, updated, err := s.repo.Upsert(ctx, processed)
+	if err != nil {
+		s.writeEach(ctx, result, batch, rows, processed)
+		return
+	}
+	result.Inserted += inserted
+	result.Updated += updated
+}
+
+// writeEach upserts the processed feedback of the batch one at a time, the
+// row of a feedback is found by its ID, the pipeline may have dropped some.
+func (s *ImportService) writeEach(ctx context.Context, result *models.ImportResult, batch []*models.Feedback, rows []int, processed []*models.Feedback) {
+	rowsOf := make(map[string][]int, len(batch))
+	for i, feedback := range batch {
+		rowsOf[feedback.ID] = append(rowsOf[feedback.ID], rows[i])
+	}
+
+	for _, feedback := range processed {
+		var row int
+		if feedbackRows := rowsOf[feedback.ID]; len(feedbackRows) > 0 {
+			row, rowsOf[feedback.ID] = feedbackRows[0], feedbackRows[1:]
+		}
+		inserted, updated, err := s.repo.Upsert(ctx, []*models.Feedback{feedback})
+		if err != nil {
+			fail(result, row, "", err)
+			continue
+		}
+		result.Inserted += inserted
+		result.Updated += updated
+	}
+}
+
+func fail(result *models.ImportResult, row int, field string, err error) {
+	result.Failed++
+	if len(result.Errors) < MaxReportedErrors {
+		result.Errors = append(result.Errors, models.ImportRowError{Row: row, Field: field, Error: err.Error()})
+	}
+}
+
+func failAll(result *models.ImportResult, rows []int, err error) {
+	for _, row := range rows {
+		fail(result, row, "", err)
+	}
+}
@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// the feedback fields a mapping can set besides content.<path> and
+// metadata.<path>
+var mappableFields = map[string]bool{
+	"id":                true,
+	"author":            true,
+	"url":               true,
+	"language":          true,
+	"rating":            true,
+	"source_created_at": true,
+	"tags":              true,
+	"content":           true,
+	"metadata":          true,
+}
+
+// ValidateMapping checks the mapping and fills in its defaults.
+func ValidateMapping(mapping *models.ImportMapping) error {
+	if mapping.Source == "" {
+		return fmt.Errorf("source is required")
+	}
+	if mapping.SubSourceID == "" {
+		return fmt.Errorf("sub_source_id is required")
+	}
+	if _, err := uuid.Parse(mapping.SubSourceID); err != nil {
+		return fmt.Errorf("invalid sub_source_id format")
+	}
+	if mapping.SourceType == "" {
+		mapping.SourceType = models.STFeedback
+	}
+	if mapping.TimeFormat == "" {
+		mapping.TimeFormat = time.RFC3339
+	}
+	if mapping.TagSeparator == "" {
+		mapping.TagSeparator = ";"
+	}
+
+	hasContent := false
+	for field, column := range mapping.Fields {
+		if column == "" {
+			return fmt.Errorf("field %s has no column", field)
+		}
+		if field == "content" || strings.HasPrefix(field, "content.") {
+			hasContent = true
+		}
+		if mappableFields[field] {
+			continue
+		}
+		if key, ok := strings.CutPrefix(field, "metadata."); ok && key != "" {
+			continue
+		}
+		if key, ok := strings.CutPrefix(field, "content."); ok && key != "" {
+			continue
+		}
+		return fmt.Errorf("unsupported field %q", field)
+	}
+	if !hasContent {
+		return fmt.Errorf("no column is mapped to the content")
+	}
+	if mapping.Fields["content"] != "" && len(fieldsWithPrefix(mapping, "content.")) > 0 {
+		return fmt.Errorf("content and content.<path> cannot both be mapped")
+	}
+
+	return nil
+}
+
+func fieldsWithPrefix(mapping *models.ImportMapping, prefix string) []string {
+	var fields []string
+	for field := range mapping.Fields {
+		if strings.HasPrefix(field, prefix) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// fieldError is a row error about one field.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.field, e.err)
+}
+
+// toFeedback builds the feedback of a row.
+func toFeedback(tenantID string, mapping *models.ImportMapping, r row) (*models.Feedback, error) {
+	feedback := &models.Feedback{
+		TenantID:    tenantID,
+		Source:      mapping.Source,
+		SourceType:  mapping.SourceType,
+		SubSourceID: mapping.SubSourceID,
+	}
+
+	var content map[string]interface{}
+	for field, column := range mapping.Fields {
+		value, ok := r.get(column)
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch field {
+		case "id":
+			feedback.ID = text(value)
+		case "author":
+			feedback.Author = text(value)
+		case "url":
+			feedback.URL = text(value)
+		case "language":
+			feedback.Language = strings.ToLower(text(value))
+		case "rating":
+			var rating float64
+			if rating, err = number(value); err == nil {
+				feedback.Rating = &rating
+			}
+		case "source_created_at":
+			var createdAt time.Time
+			if createdAt, err = timestamp(value, mapping.TimeFormat); err == nil {
+				feedback.SourceCreatedAt = &createdAt
+			}
+		case "tags":
+			err = addTags(feedback, value, mapping.TagSeparator)
+		case "content":
+			feedback.Content = value
+		case "metadata":
+			metadata, isMap := value.(map[string]interface{})
+			if !isMap {
+				err = fmt.Errorf("expected an object")
+			}
+			for key, v := range metadata {
+				setPath(&feedback.Metadata, key, v)
+			}
+		default:
+			if key, ok := strings.CutPrefix(field, "metadata."); ok {
+				setPath(&feedback.Metadata, key, value)
+			} else if key, ok := strings.CutPrefix(field, "content."); ok {
+				setPath(&content, key, value)
+			}
+		}
+		if err != nil {
+			return nil, &fieldError{field: field, err: err}
+		}
+	}
+	if content != nil {
+		feedback.Content = content
+	}
+
+	if feedback.Content == nil {
+		return nil, &fieldError{field: "content", err: fmt.Errorf("content is empty")}
+	}
+	if feedback.ID == "" {
+		if _, mapped := mapping.Fields["id"]; mapped {
+			return nil, &fieldError{field: "id", err: fmt.Errorf("id is empty")}
+		}
+		// the same row gets the same ID, re-importing it does not
+		// duplicate it
+		feedback.ID = uuid.NewSHA1(uuid.MustParse(tenantID), r.raw()).String()
+	}
+
+	return feedback, nil
+}
+
+// text returns a value as text, objects and lists as JSON.
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
+
+func number(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("expected a number")
+}
+
+func timestamp(value interface{}, layout string) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case string:
+		t, err := time.Parse(layout, strings.TrimSpace(v))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q, expected the layout %s", v, layout)
+		}
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a time")
+}
+
+func addTags(feedback *models.Feedback, value interface{}, separator string) error {
+	switch v := value.(type) {
+	case string:
+		for _, tag := range strings.Split(v, separator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				feedback.AddTag(tag)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			tag, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("expected a list of strings")
+			}
+			if tag = strings.TrimSpace(tag); tag != "" {
+				feedback.AddTag(tag)
+			}
+		}
+	default:
+		return fmt.Errorf("expected a string or a list of strings")
+	}
+	return nil
+}
+
+// setPath sets the value at the dotted path, creating the objects on the
+// way.
+func setPath(target *map[string]interface{}, path string, value interface{}) {
+	if *target == nil {
+		*target = map[string]interface{}{}
+	}
+	m := *target
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,222 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	testTenantID    = "5b0f1c9e-7a3d-4e6b-9f1a-2c8d4e6f8a0b"
+	testSubSourceID = "0e7c2a4b-1d3f-4a5b-8c6d-7e9f0a1b2c3d"
+)
+
+func testMapping(t *testing.T, fields map[string]string) *models.ImportMapping {
+	t.Helper()
+
+	mapping := &models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID, Fields: fields}
+	if err := ValidateMapping(mapping); err != nil {
+		t.Fatalf("ValidateMapping: %v", err)
+	}
+	return mapping
+}
+
+// testRow reads the only row of an NDJSON line.
+func testRow(t *testing.T, line string) row {
+	t.Helper()
+
+	reader, err := newRowReader(FormatNDJSON, strings.NewReader(line))
+	if err != nil {
+		t.Fatalf("newRowReader: %v", err)
+	}
+	_, r, err := reader.next()
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	return r
+}
+
+func TestValidateMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping models.ImportMapping
+		// wantErr is the error, empty when the mapping is valid
+		wantErr string
+	}{
+		{"valid", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"content.text": "body", "metadata.plan": "plan", "rating": "stars"}}, ""},
+		{"no source", models.ImportMapping{SubSourceID: testSubSourceID, Fields: map[string]string{"content": "body"}}, "source is required"},
+		{"no sub source", models.ImportMapping{Source: models.SourceIntercom, Fields: map[string]string{"content": "body"}}, "sub_source_id is required"},
+		{"invalid sub source", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: "abc",
+			Fields: map[string]string{"content": "body"}}, "invalid sub_source_id format"},
+		{"no column", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"content": ""}}, "field content has no column"},
+		{"unsupported field", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"content": "body", "score": "stars"}}, `unsupported field "score"`},
+		{"empty path", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"content": "body", "metadata.": "plan"}}, `unsupported field "metadata."`},
+		{"no content", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"author": "name"}}, "no column is mapped to the content"},
+		{"content twice", models.ImportMapping{Source: models.SourceIntercom, SubSourceID: testSubSourceID,
+			Fields: map[string]string{"content": "body", "content.text": "text"}}, "content and content.<path> cannot both be mapped"},
+	}
+	for _, tt := range tests {
+		err := ValidateMapping(&tt.mapping)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	mapping := testMapping(t, map[string]string{"content": "body"})
+	if mapping.SourceType != models.STFeedback || mapping.TimeFormat != time.RFC3339 || mapping.TagSeparator != ";" {
+		t.Errorf("the defaults were not filled in: %+v", mapping)
+	}
+}
+
+func TestToFeedback(t *testing.T) {
+	mapping := testMapping(t, map[string]string{
+		"id":                 "ticket.id",
+		"author":             "user",
+		"url":                "link",
+		"language":           "lang",
+		"rating":             "stars",
+		"source_created_at":  "created",
+		"tags":               "labels",
+		"content.text":       "body",
+		"content.reply.text": "reply",
+		"metadata":           "extra",
+		"metadata.plan":      "plan",
+	})
+	r := testRow(t, `{"ticket": {"id": 42}, "user": " ann ", "link": "https://example.com/42", "lang": "EN", `+
+		`"stars": "4.5", "created": "2024-05-01T10:00:00+02:00", "labels": "billing; ;refund", "body": "Refund please", `+
+		`"reply": "Done", "extra": {"seats": 3}, "plan": "pro", "ignored": "x"}`)
+
+	feedback, err := toFeedback(testTenantID, mapping, r)
+	if err != nil {
+		t.Fatalf("toFeedback: %v", err)
+	}
+	createdAt := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	switch {
+	case feedback.ID != "42", feedback.Author != "ann", feedback.URL != "https://example.com/42", feedback.Language != "en":
+		t.Errorf("got ID %q, author %q, URL %q, language %q", feedback.ID, feedback.Author, feedback.URL, feedback.Language)
+	case feedback.Rating == nil || *feedback.Rating != 4.5:
+		t.Errorf("got rating %v, want 4.5", feedback.Rating)
+	case feedback.SourceCreatedAt == nil || !feedback.SourceCreatedAt.Equal(createdAt):
+		t.Errorf("got source_created_at %v, want %v", feedback.SourceCreatedAt, createdAt)
+	case feedback.TenantID != testTenantID || feedback.Source != models.SourceIntercom || feedback.SubSourceID != testSubSourceID:
+		t.Errorf("got tenant %q, source %q, sub source %q", feedback.TenantID, feedback.Source, feedback.SubSourceID)
+	}
+	if !reflect.DeepEqual(feedback.Tags, []string{"billing", "refund"}) {
+		t.Errorf("got tags %q", feedback.Tags)
+	}
+	wantContent := map[string]interface{}{"text": "Refund please", "reply": map[string]interface{}{"text": "Done"}}
+	if !reflect.DeepEqual(feedback.Content, wantContent) {
+		t.Errorf("got content %v, want %v", feedback.Content, wantContent)
+	}
+	wantMetadata := map[string]interface{}{"seats": float64(3), "plan": "pro"}
+	if !reflect.DeepEqual(feedback.Metadata, wantMetadata) {
+		t.Errorf("got metadata %v, want %v", feedback.Metadata, wantMetadata)
+	}
+}
+
+func TestToFeedbackDerivesID(t *testing.T) {
+	mapping := testMapping(t, map[string]string{"content": "body"})
+
+	first, err := toFeedback(testTenantID, mapping, testRow(t, `{"body": "Slow sync"}`))
+	if err != nil {
+		t.Fatalf("toFeedback: %v", err)
+	}
+	again, _ := toFeedback(testTenantID, mapping, testRow(t, `{"body": "Slow sync"}`))
+	other, _ := toFeedback(testTenantID, mapping, testRow(t, `{"body": "Fast sync"}`))
+	if first.ID == "" || first.ID != again.ID || first.ID == other.ID {
+		t.Errorf("got IDs %q, %q and %q, want the same ID for the same row only", first.ID, again.ID, other.ID)
+	}
+	if first.Content != "Slow sync" {
+		t.Errorf("got content %v", first.Content)
+	}
+}
+
+func TestToFeedbackFieldErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fields    map[string]string
+		line      string
+		wantField string
+	}{
+		{"invalid rating", map[string]string{"content": "body", "rating": "stars"}, `{"body": "x", "stars": "five"}`, "rating"},
+		{"rating not a number", map[string]string{"content": "body", "rating": "stars"}, `{"body": "x", "stars": true}`, "rating"},
+		{"invalid time", map[string]string{"content": "body", "source_created_at": "created"}, `{"body": "x", "created": "yesterday"}`, "source_created_at"},
+		{"invalid tags", map[string]string{"content": "body", "tags": "labels"}, `{"body": "x", "labels": [1, 2]}`, "tags"},
+		{"metadata not an object", map[string]string{"content": "body", "metadata": "extra"}, `{"body": "x", "extra": "pro"}`, "metadata"},
+		{"empty content", map[string]string{"content": "body"}, `{"body": " "}`, "content"},
+		{"empty id", map[string]string{"content": "body", "id": "id"}, `{"body": "x"}`, "id"},
+	}
+	for _, tt := range tests {
+		_, err := toFeedback(testTenantID, testMapping(t, tt.fields), testRow(t, tt.line))
+		var fieldErr *fieldError
+		if !errors.As(err, &fieldErr) || fieldErr.field != tt.wantField {
+			t.Errorf("%s: got error %v, want an error with %s", tt.name, err, tt.wantField)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		layout  string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-05-01T10:00:00Z", time.RFC3339, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{" 01/05/2024 ", "02/01/2006", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{float64(1714557600), time.RFC3339, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"05/01/2024", time.RFC3339, time.Time{}, true},
+		{true, time.RFC3339, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := timestamp(tt.value, tt.layout)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("timestamp(%v, %q) = %v, %v, want %v", tt.value, tt.layout, got, err, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{" ann ", "ann"},
+		{float64(42), "42"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{map[string]interface{}{"a": float64(1)}, `{"a":1}`},
+		{[]interface{}{"a", "b"}, `["a","b"]`},
+	}
+	for _, tt := range tests {
+		if got := text(tt.value); got != tt.want {
+			t.Errorf("text(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	var target map[string]interface{}
+	setPath(&target, "a.b", 1)
+	setPath(&target, "a.c", 2)
+	setPath(&target, "d", 3)
+	// a value on the way is replaced by an object
+	setPath(&target, "d.e", 4)
+
+	want := map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}, "d": map[string]interface{}{"e": 4}}
+	if !reflect.DeepEqual(target, want) {
+		t.Errorf("got %v, want %v", target, want)
+	}
+}
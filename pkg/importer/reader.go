@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// row is a record of an import file.
+type row interface {
+	// get returns the non-empty value of the column
+	get(column string) (interface{}, bool)
+	// raw is the row as read, the ID of rows without one is derived from it
+	raw() []byte
+}
+
+// rowReader reads an import file one row at a time. next returns the row
+// number with the row, and io.EOF at the end of the file. A row error is
+// returned as a *rowError, reading can go on after it.
+type rowReader interface {
+	next() (int, row, error)
+}
+
+type rowError struct {
+	row int
+	err error
+}
+
+func (e *rowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.row, e.err)
+}
+
+func newRowReader(format string, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return &ndjsonReader{in: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	in      *csv.Reader
+	columns map[string]int
+	width   int
+	rows    int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+
+	header, err := in.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// spreadsheets like to start files with a byte order mark
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		columns[name] = i
+	}
+	return &csvReader{in: in, columns: columns, width: len(header)}, nil
+}
+
+func (r *csvReader) next() (int, row, error) {
+	record, err := r.in.Read()
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	r.rows++
+	if err != nil {
+		// a malformed quote leaves the reader at an unknown place in the
+		// file, the import cannot go on
+		return r.rows, nil, fmt.Errorf("row %d: %v", r.rows, err)
+	}
+	if len(record) != r.width {
+		return r.rows, nil, &rowError{row: r.rows, err: fmt.Errorf("expected %d columns, got %d", r.width, len(record))}
+	}
+	return r.rows, &csvRow{columns: r.columns, record: record}, nil
+}
+
+type csvRow struct {
+	columns map[string]int
+	record  []string
+}
+
+func (r *csvRow) get(column string) (interface{}, bool) {
+	i, ok := r.columns[column]
+	if !ok || strings.TrimSpace(r.record[i]) == "" {
+		return nil, false
+	}
+	return r.record[i], true
+}
+
+func (r *csvRow) raw() []byte {
+	return []byte(strings.Join(r.record, "\x1f"))
+}
+
+type ndjsonReader struct {
+	in   *bufio.Reader
+	line int
+}
+
+func (r *ndjsonReader) next() (int, row, error) {
+	for {
+		line, err := r.in.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return r.line, nil, err
+		}
+		if len(line) == 0 && err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		r.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal(line, &object); err != nil {
+			return r.line, nil, &rowError{row: r.line, err: fmt.Errorf("invalid JSON object: %v", err)}
+		}
+		return r.line, &ndjsonRow{object: object, line: line}, nil
+	}
+}
+
+type ndjsonRow struct {
+	object map[string]interface{}
+	line   []byte
+}
+
+// get looks the column up as a dotted path.
+func (r *ndjsonRow) get(column string) (interface{}, bool) {
+	var value interface{} = r.object
+	for _, key := range strings.Split(column, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+		return nil, false
+	}
+	return value, value != nil
+}
+
+func (r *ndjsonRow) raw() []byte {
+	return r.line
+}
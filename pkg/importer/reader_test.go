@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	reader, err := newRowReader(FormatCSV, strings.NewReader("\ufeffid, body ,stars\n1,Slow sync,\n2,too,many,columns\n3,\"Fast, \"\"really\"\"\",5\n"))
+	if err != nil {
+		t.Fatalf("newRowReader: %v", err)
+	}
+
+	n, r, err := reader.next()
+	if err != nil || n != 1 {
+		t.Fatalf("row 1: got %d, %v", n, err)
+	}
+	if id, _ := r.get("id"); id != "1" {
+		t.Errorf("got id %v, want the column after the byte order mark", id)
+	}
+	if body, _ := r.get("body"); body != "Slow sync" {
+		t.Errorf("got body %v, want the column with its name trimmed", body)
+	}
+	if _, ok := r.get("stars"); ok {
+		t.Errorf("an empty column has a value")
+	}
+	if _, ok := r.get("missing"); ok {
+		t.Errorf("a missing column has a value")
+	}
+
+	n, _, err = reader.next()
+	var rowErr *rowError
+	if !errors.As(err, &rowErr) || n != 2 {
+		t.Errorf("row 2: got %d, %v, want a row error", n, err)
+	}
+
+	n, r, err = reader.next()
+	if err != nil || n != 3 {
+		t.Fatalf("row 3: got %d, %v", n, err)
+	}
+	if body, _ := r.get("body"); body != `Fast, "really"` {
+		t.Errorf("got body %v", body)
+	}
+
+	if _, _, err := reader.next(); err != io.EOF {
+		t.Errorf("got %v at the end, want io.EOF", err)
+	}
+}
+
+func TestCSVReaderMalformedQuote(t *testing.T) {
+	reader, err := newRowReader(FormatCSV, strings.NewReader("id,body\n1,\"Slow\" sync\n2,ok\n"))
+	if err != nil {
+		t.Fatalf("newRowReader: %v", err)
+	}
+	_, _, err = reader.next()
+	var rowErr *rowError
+	if err == nil || err == io.EOF || errors.As(err, &rowErr) {
+		t.Errorf("got %v, want an error that ends the import", err)
+	}
+}
+
+func TestCSVReaderEmpty(t *testing.T) {
+	if _, err := newRowReader(FormatCSV, strings.NewReader("")); err == nil || err.Error() != "the file is empty" {
+		t.Errorf("got error %v for an empty file", err)
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	reader, err := newRowReader(FormatNDJSON, strings.NewReader("{\"a\": {\"b\": \"x\"}, \"c\": \" \", \"d\": null}\n\n[1]\n{\"e\": 1}"))
+	if err != nil {
+		t.Fatalf("newRowReader: %v", err)
+	}
+
+	n, r, err := reader.next()
+	if err != nil || n != 1 {
+		t.Fatalf("line 1: got %d, %v", n, err)
+	}
+	tests := []struct {
+		column string
+		want   interface{}
+		wantOK bool
+	}{
+		{"a.b", "x", true},
+		{"a", map[string]interface{}{"b": "x"}, true},
+		{"a.b.c", nil, false},
+		{"c", nil, false},
+		{"d", nil, false},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		value, ok := r.get(tt.column)
+		if ok != tt.wantOK || (ok && text(value) != text(tt.want)) {
+			t.Errorf("get(%q) = %v, %v, want %v, %v", tt.column, value, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// blank lines are skipped but counted
+	n, _, err = reader.next()
+	var rowErr *rowError
+	if !errors.As(err, &rowErr) || n != 3 {
+		t.Errorf("line 3: got %d, %v, want a row error", n, err)
+	}
+
+	n, r, err = reader.next()
+	if err != nil || n != 4 || string(r.raw()) != `{"e": 1}` {
+		t.Fatalf("line 4 without a newline: got %d, %v", n, err)
+	}
+
+	if _, _, err := reader.next(); err != io.EOF {
+		t.Errorf("got %v at the end, want io.EOF", err)
+	}
+}
+
+func TestNewRowReaderUnsupportedFormat(t *testing.T) {
+	if _, err := newRowReader("xlsx", strings.NewReader("")); err == nil {
+		t.Errorf("an unsupported format was accepted")
+	}
+}
@@ -0,0 +1,164 @@
+package inbox
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type InboxHandler struct {
+	service     *InboxService
+	adminAPIKey string
+}
+
+func NewInboxHandler(service *InboxService, adminAPIKey string) *InboxHandler {
+	return &InboxHandler{service: service, adminAPIKey: adminAPIKey}
+}
+
+// ListEntriesHandler lists the tenant's webhook calls, the last received
+// first, optionally filtered by subscription_id, source and status. Only
+// callers with the admin API key may use it.
+func (h *InboxHandler) ListEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	filter, err := parseInboxFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	entries, err := h.service.ListEntries(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list the webhook inbox: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetEntryHandler returns a webhook call with its body.
+func (h *InboxHandler) GetEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Entry ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid Entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	entry, err := h.service.GetEntry(ctx, id)
+	if err != nil {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		*models.WebhookInboxEntry
+		Body string `json:"body"`
+	}{entry, string(entry.Body)})
+}
+
+// ReplayHandler processes the tenant's entry with the given id again, or
+// all the entries matching the filters, which must include a status (e.g.
+// status=dead).
+func (h *InboxHandler) ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseInboxFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid Entry ID format", http.StatusBadRequest)
+			return
+		}
+	} else if filter.Status == "" {
+		http.Error(w, "Entry ID or status is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	replayed, err := h.service.Replay(ctx, filter, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to replay webhook calls: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]int{"replayed": replayed})
+}
+
+func parseInboxFilter(r *http.Request) (*models.WebhookInboxFilter, error) {
+	query := r.URL.Query()
+	filter := &models.WebhookInboxFilter{
+		TenantID:       query.Get("tenant_id"),
+		SubscriptionID: query.Get("subscription_id"),
+		Source:         models.Source(query.Get("source")),
+		Status:         query.Get("status"),
+	}
+
+	if filter.TenantID == "" {
+		return nil, fmt.Errorf("Tenant ID is required")
+	}
+	if _, err := uuid.Parse(filter.TenantID); err != nil {
+		return nil, fmt.Errorf("Invalid Tenant ID format")
+	}
+	if filter.SubscriptionID != "" {
+		if _, err := uuid.Parse(filter.SubscriptionID); err != nil {
+			return nil, fmt.Errorf("Invalid Subscription ID format")
+		}
+	}
+	switch filter.Status {
+	case "", models.InboxPending, models.InboxProcessing, models.InboxDone, models.InboxDead:
+	default:
+		return nil, fmt.Errorf("Invalid status %q", filter.Status)
+	}
+
+	for name, value := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if s := query.Get(name); s != "" {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("Invalid %s, expected a non-negative integer", name)
+			}
+			*value = i
+		}
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+
+	return filter, nil
+}
+
+func (h *InboxHandler) authorized(r *http.Request) bool {
+	if h.adminAPIKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(h.adminAPIKey)) == 1
+}
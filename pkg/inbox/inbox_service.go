@@ -0,0 +1,158 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+)
+
+const (
+	// MaxAttempts is the number of times an entry is processed before it is
+	// dead
+	MaxAttempts = 8
+	// the first retry waits retryBase, each one after twice the previous, up
+	// to retryMax
+	retryBase = 30 * time.Second
+	retryMax  = time.Hour
+	// an entry not completed or failed within lease, e.g. its worker died,
+	// is claimed again
+	lease = 5 * time.Minute
+)
+
+// InboxService keeps webhook calls until they are processed, retrying them
+// with exponential backoff. The bodies hold the personal data the pipeline
+// redacts from the feedback, so they are encrypted with the keyring, bound
+// to their ID, when it has a key.
+type InboxService struct {
+	repo    *db.WebhookInboxRepository
+	keyring *secrets.Keyring
+}
+
+func NewInboxService(repo *db.WebhookInboxRepository, keyring *secrets.Keyring) *InboxService {
+	return &InboxService{repo: repo, keyring: keyring}
+}
+
+func (s *InboxService) Enqueue(ctx context.Context, entry *models.WebhookInboxEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	stored := *entry
+	var err error
+	if stored.Body, err = s.keyring.EncryptData(entry.Body, []byte(entry.ID)); err != nil {
+		return fmt.Errorf("failed to encrypt webhook call: %v", err)
+	}
+	if err := s.repo.Add(ctx, &stored); err != nil {
+		return err
+	}
+	entry.Status, entry.ReceivedAt, entry.NextAttemptAt = stored.Status, stored.ReceivedAt, stored.NextAttemptAt
+	return nil
+}
+
+// Claim returns up to limit entries due for processing, each must then be
+// completed or failed. It also returns the entries whose worker stopped on
+// each of their MaxAttempts attempts, e.g. the call crashes it, which are
+// dead now.
+func (s *InboxService) Claim(ctx context.Context, limit int) ([]*models.WebhookInboxEntry, []*models.WebhookInboxEntry, error) {
+	entries, err := s.repo.Claim(ctx, limit, time.Now().Add(lease), MaxAttempts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var claimed, dead []*models.WebhookInboxEntry
+	for _, entry := range entries {
+		if err := s.decrypt(entry); err != nil {
+			return nil, nil, err
+		}
+		if entry.Status == models.InboxDead {
+			dead = append(dead, entry)
+		} else {
+			claimed = append(claimed, entry)
+		}
+	}
+	return claimed, dead, nil
+}
+
+// Complete marks the entry as done, db.ErrLeaseLost is returned when its
+// lease expired and it was claimed again.
+func (s *InboxService) Complete(ctx context.Context, entry *models.WebhookInboxEntry) error {
+	return s.repo.Complete(ctx, entry.ID, entry.LeaseToken)
+}
+
+// Fail schedules the next attempt of the entry, or marks it dead after
+// MaxAttempts or when the failure is permanent. It returns whether the
+// entry is dead, or db.ErrLeaseLost like Complete.
+func (s *InboxService) Fail(ctx context.Context, entry *models.WebhookInboxEntry, cause error, permanent bool) (bool, error) {
+	dead := permanent || entry.Attempts >= MaxAttempts
+	nextAttemptAt := time.Now().Add(backoff(entry.Attempts))
+	if err := s.repo.Fail(ctx, entry.ID, entry.LeaseToken, cause.Error(), nextAttemptAt, dead); err != nil {
+		return false, err
+	}
+	return dead, nil
+}
+
+// Purge deletes the entries done for longer than retention, it returns the
+// number of entries deleted.
+func (s *InboxService) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	return s.repo.PurgeDone(ctx, time.Now().Add(-retention))
+}
+
+func (s *InboxService) GetEntry(ctx context.Context, id string) (*models.WebhookInboxEntry, error) {
+	entry, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decrypt(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *InboxService) ListEntries(ctx context.Context, filter *models.WebhookInboxFilter) ([]*models.WebhookInboxEntry, error) {
+	entries, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := s.decrypt(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Replay processes the entry again, or every entry of the filter when id is
+// empty, with a fresh set of attempts.
+func (s *InboxService) Replay(ctx context.Context, filter *models.WebhookInboxFilter, id string) (int, error) {
+	if id == "" && filter.Status == "" {
+		return 0, fmt.Errorf("a status is required to replay more than one entry")
+	}
+	return s.repo.Replay(ctx, filter, id)
+}
+
+// decrypt decrypts the body of the entry, kept as is when it was stored in
+// plaintext before a key was configured.
+func (s *InboxService) decrypt(entry *models.WebhookInboxEntry) error {
+	body, err := s.keyring.DecryptData(entry.Body, []byte(entry.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt webhook call %s: %v", entry.ID, err)
+	}
+	entry.Body = body
+	return nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	wait := retryBase
+	for i := 1; i < attempts && wait < retryMax; i++ {
+		wait *= 2
+	}
+	if wait > retryMax {
+		wait = retryMax
+	}
+	return wait
+}
@@ -0,0 +1,174 @@
+package integrations
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// commonConfigProperties are the configuration properties of every
+// source's subscriptions.
+var commonConfigProperties = map[string]*models.ConfigSchema{
+	rateLimitKey: {
+		Type:        "object",
+		Description: "Rate limit of the subscription's calls to the source, instead of the source's",
+		Properties: map[string]*models.ConfigSchema{
+			"requests_per_second": {Type: "number", Minimum: floatPtr(0)},
+			"burst":               {Type: "integer", Minimum: floatPtr(1)},
+		},
+		Required:             []string{"requests_per_second", "burst"},
+		AdditionalProperties: false,
+	},
+	models.OAuthConfigKey: {
+		Type:        "object",
+		Description: "OAuth token, set by the connect flow",
+		Properties: map[string]*models.ConfigSchema{
+			"access_token":  {Type: "string", WriteOnly: true},
+			"refresh_token": {Type: "string", WriteOnly: true},
+			"token_type":    {Type: "string"},
+			"scope":         {Type: "string"},
+			"expires_at":    {Type: "string"},
+		},
+		ReadOnly: true,
+	},
+}
+
+// configSchemaOf returns the configuration schema of the strategy with the
+// common properties. Strategies without one accept only those.
+func configSchemaOf(strategy SourceStrategy) *models.ConfigSchema {
+	schema := &models.ConfigSchema{Type: "object", AdditionalProperties: false}
+	if describer, ok := strategy.(Describer); ok {
+		own := *describer.ConfigSchema()
+		schema = &own
+	}
+
+	properties := make(map[string]*models.ConfigSchema, len(schema.Properties)+len(commonConfigProperties))
+	for name, property := range commonConfigProperties {
+		properties[name] = property
+	}
+	for name, property := range schema.Properties {
+		properties[name] = property
+	}
+	schema.Properties = properties
+	return schema
+}
+
+// clientConfiguration returns the configuration without the properties set
+// by the service (ReadOnly), as a client sends it.
+func clientConfiguration(schema *models.ConfigSchema, configuration map[string]interface{}) map[string]interface{} {
+	var client map[string]interface{}
+	for name, value := range configuration {
+		if property, ok := schema.Properties[name]; ok && property.ReadOnly {
+			continue
+		}
+		if client == nil {
+			client = make(map[string]interface{}, len(configuration))
+		}
+		client[name] = value
+	}
+	return client
+}
+
+// validateConfig checks the value against the schema, path is the dotted
+// path of the value in the configuration. Values of ReadOnly properties are
+// rejected, they are not the clients' to set.
+func validateConfig(schema *models.ConfigSchema, value interface{}, path string) error {
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return configError(path, "must be one of %v", schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return configError(path, "must be an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return configError(joinPath(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				switch additional := schema.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						return configError(joinPath(path, name), "is not a known field")
+					}
+				case *models.ConfigSchema:
+					property = additional
+				}
+			}
+			if property == nil {
+				continue
+			}
+			if property.ReadOnly {
+				return configError(joinPath(path, name), "is set by the service")
+			}
+			if err := validateConfig(property, object[name], joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return configError(path, "must be a string")
+		}
+		if utf8.RuneCountInString(text) < schema.MinLength {
+			return configError(path, "must be at least %d characters long", schema.MinLength)
+		}
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			return configError(path, "must be a number")
+		}
+		if schema.Type == "integer" && number != math.Trunc(number) {
+			return configError(path, "must be an integer")
+		}
+		if schema.Minimum != nil && number < *schema.Minimum {
+			return configError(path, "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			return configError(path, "must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return configError(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
+
+func configError(path, format string, args ...interface{}) error {
+	if path == "" {
+		path = "configuration"
+	}
+	return fmt.Errorf("%s %s", path, fmt.Sprintf(format, args...))
+}
+
+func joinPath(path, name string) string {
+	return strings.TrimPrefix(path+"."+name, ".")
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
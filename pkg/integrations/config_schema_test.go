@@ -0,0 +1,185 @@
+package integrations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func TestValidateConfig(t *testing.T) {
+	schema := &models.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*models.ConfigSchema{
+			"name":    {Type: "string", MinLength: 2},
+			"mode":    {Type: "string", Enum: []interface{}{"fast", "slow"}},
+			"count":   {Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(10)},
+			"ratio":   {Type: "number"},
+			"enabled": {Type: "boolean"},
+			"labels":  {Type: "object", AdditionalProperties: &models.ConfigSchema{Type: "string"}},
+			"extra":   {Type: "object", AdditionalProperties: true},
+			"token":   {Type: "object", ReadOnly: true},
+			"nested": {
+				Type:                 "object",
+				Properties:           map[string]*models.ConfigSchema{"id": {Type: "string"}},
+				Required:             []string{"id"},
+				AdditionalProperties: false,
+			},
+		},
+		Required:             []string{"name"},
+		AdditionalProperties: false,
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		// wantErr is the start of the error, empty when the configuration is
+		// valid
+		wantErr string
+	}{
+		{"valid", map[string]interface{}{
+			"name": "ab", "mode": "fast", "count": float64(10), "ratio": 0.5, "enabled": true,
+			"labels": map[string]interface{}{"team": "core"}, "extra": map[string]interface{}{"any": float64(1)},
+			"nested": map[string]interface{}{"id": "x"},
+		}, ""},
+		{"missing required", map[string]interface{}{}, "name is required"},
+		{"unknown field", map[string]interface{}{"name": "ab", "other": "x"}, "other is not a known field"},
+		{"wrong type", map[string]interface{}{"name": float64(1)}, "name must be a string"},
+		{"too short", map[string]interface{}{"name": "é"}, "name must be at least 2 characters long"},
+		{"not in enum", map[string]interface{}{"name": "ab", "mode": "medium"}, "mode must be one of"},
+		{"not an integer", map[string]interface{}{"name": "ab", "count": 1.5}, "count must be an integer"},
+		{"below minimum", map[string]interface{}{"name": "ab", "count": float64(0)}, "count must be at least 1"},
+		{"above maximum", map[string]interface{}{"name": "ab", "count": float64(11)}, "count must be at most 10"},
+		{"not a number", map[string]interface{}{"name": "ab", "ratio": "half"}, "ratio must be a number"},
+		{"not a boolean", map[string]interface{}{"name": "ab", "enabled": "yes"}, "enabled must be a boolean"},
+		{"additional property schema", map[string]interface{}{"name": "ab", "labels": map[string]interface{}{"team": true}}, "labels.team must be a string"},
+		{"not an object", map[string]interface{}{"name": "ab", "nested": "x"}, "nested must be an object"},
+		{"nested required", map[string]interface{}{"name": "ab", "nested": map[string]interface{}{}}, "nested.id is required"},
+		{"read-only", map[string]interface{}{"name": "ab", "token": map[string]interface{}{}}, "token is set by the service"},
+		{"nested unknown field", map[string]interface{}{"name": "ab", "nested": map[string]interface{}{"id": "x", "y": "z"}}, "nested.y is not a known field"},
+	}
+	for _, tt := range tests {
+		err := validateConfig(schema, tt.config, "")
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if err := validateConfig(schema, "x", ""); err == nil || err.Error() != "configuration must be an object" {
+		t.Errorf("got error %v for a configuration that is not an object", err)
+	}
+}
+
+func TestValidateConfigReportsFieldsInOrder(t *testing.T) {
+	schema := &models.ConfigSchema{Type: "object", AdditionalProperties: false}
+	config := map[string]interface{}{"c": "", "a": "", "b": ""}
+
+	for i := 0; i < 10; i++ {
+		if err := validateConfig(schema, config, ""); err == nil || err.Error() != "a is not a known field" {
+			t.Fatalf("got error %v, want the first field in order", err)
+		}
+	}
+}
+
+func TestConfigSchemaOfAddsCommonProperties(t *testing.T) {
+	for source, strategy := range NewStrategies(nil) {
+		schema := configSchemaOf(strategy)
+		for name := range commonConfigProperties {
+			if _, ok := schema.Properties[name]; !ok {
+				t.Errorf("%s: the schema lacks %s", source, name)
+			}
+		}
+	}
+
+	// the strategy's own schema is left as is
+	strategy := NewDiscourseStrategy(nil)
+	configSchemaOf(strategy)
+	if _, ok := strategy.ConfigSchema().Properties[rateLimitKey]; ok {
+		t.Errorf("the common properties were added to the strategy's schema")
+	}
+}
+
+func TestValidateSubscription(t *testing.T) {
+	m := &IntegrationManager{strategies: NewStrategies(nil)}
+
+	tests := []struct {
+		name    string
+		sub     *models.Subscription
+		wantErr bool
+	}{
+		{"discourse", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{"api_key": "key"}}, false},
+		{"without configuration", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull}, false},
+		{"rate limit", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{rateLimitKey: map[string]interface{}{"requests_per_second": 0.5, "burst": float64(5)}}}, false},
+		{"invalid rate limit", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{rateLimitKey: map[string]interface{}{"requests_per_second": 0.5}}}, true},
+		{"zero rate limit", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{rateLimitKey: map[string]interface{}{"requests_per_second": float64(0), "burst": float64(1)}}}, true},
+		{"unknown field", &models.Subscription{Source: models.SourceDiscourse, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{"api_token": "key"}}, true},
+		{"missing package name", &models.Subscription{Source: models.SourcePlaystore, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{}}, true},
+		{"empty package name", &models.Subscription{Source: models.SourcePlaystore, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{"package_name": ""}}, true},
+		{"generic webhook", &models.Subscription{Source: models.SourceWebhook, SubscriptionMode: models.SubscriptionModePush,
+			Configuration: map[string]interface{}{"text": "$.comment", "metadata": map[string]interface{}{"plan": "$.plan"}}}, false},
+		{"generic webhook without text", &models.Subscription{Source: models.SourceWebhook, SubscriptionMode: models.SubscriptionModePush,
+			Configuration: map[string]interface{}{"author": "$.user"}}, true},
+		{"generic webhook signature without header", &models.Subscription{Source: models.SourceWebhook, SubscriptionMode: models.SubscriptionModePush,
+			Configuration: map[string]interface{}{"text": "$.comment", "signature": map[string]interface{}{"secret": "s"}}}, true},
+		{"OAuth token", &models.Subscription{Source: models.SourcePlaystore, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{"package_name": "app", models.OAuthConfigKey: map[string]interface{}{"access_token": "token"}}}, true},
+		{"unsupported mode", &models.Subscription{Source: models.SourceWebhook, SubscriptionMode: models.SubscriptionModePull,
+			Configuration: map[string]interface{}{"text": "$.comment"}}, true},
+		{"unsupported source", &models.Subscription{Source: "zendesk", SubscriptionMode: models.SubscriptionModePull}, true},
+	}
+	for _, tt := range tests {
+		err := m.ValidateSubscription(tt.sub)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateStoredSubscription(t *testing.T) {
+	m := &IntegrationManager{strategies: NewStrategies(nil)}
+	sub := &models.Subscription{Source: models.SourcePlaystore, SubscriptionMode: models.SubscriptionModePull,
+		Configuration: map[string]interface{}{"package_name": "app", models.OAuthConfigKey: map[string]interface{}{"access_token": "token"}}}
+
+	if err := m.validateStoredSubscription(sub); err != nil {
+		t.Errorf("the OAuth token set by the connect flow was rejected: %v", err)
+	}
+	if _, ok := sub.Configuration[models.OAuthConfigKey]; !ok {
+		t.Errorf("the check changed the subscription")
+	}
+
+	sub.Configuration["api_token"] = "key"
+	if err := m.validateStoredSubscription(sub); err == nil {
+		t.Errorf("an unknown field was accepted")
+	}
+}
+
+func TestClientConfiguration(t *testing.T) {
+	schema := configSchemaOf(NewIntercomStrategy(nil))
+
+	tests := []struct {
+		name          string
+		configuration map[string]interface{}
+		want          map[string]interface{}
+	}{
+		{"nil", nil, nil},
+		{"only set by the service", map[string]interface{}{models.OAuthConfigKey: map[string]interface{}{}}, nil},
+		{"both", map[string]interface{}{models.OAuthConfigKey: map[string]interface{}{}, rateLimitKey: "x", "other": "y"},
+			map[string]interface{}{rateLimitKey: "x", "other": "y"}},
+	}
+	for _, tt := range tests {
+		if got := clientConfiguration(schema, tt.configuration); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	// DefaultSampleSize is the number of feedback a dry-run pull returns
+	DefaultSampleSize = 5
+	// MaxSampleSize caps the sample of a dry-run pull
+	MaxSampleSize = 50
+)
+
+// ConnectionTester is implemented by strategies able to check that their
+// source is reachable and accepts the subscription's credentials, with a
+// cheap call.
+type ConnectionTester interface {
+	TestConnection(ctx context.Context, sub *models.Subscription) error
+}
+
+// ErrNotTestable is returned by TestConnection when the subscription makes
+// no call to its source, e.g. it only receives webhook calls.
+var ErrNotTestable = errors.New("the subscription does not call its source")
+
+type sampleContextKey struct{}
+
+// WithSample asks the pulls made with the context for a sample of at most
+// size feedback, strategies fetch no more than they need for it.
+func WithSample(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, sampleContextKey{}, size)
+}
+
+// sampleOf returns the sample size of a dry-run pull.
+func sampleOf(ctx context.Context) (int, bool) {
+	size, ok := ctx.Value(sampleContextKey{}).(int)
+	return size, ok
+}
+
+// TestConnection checks the subscription's configuration, refreshes its
+// OAuth token if needed and has the strategy call the source. Sources
+// without a ConnectionTester only get their configuration checked.
+func (m *IntegrationManager) TestConnection(ctx context.Context, sub *models.Subscription) *models.ConnectionTest {
+	result := &models.ConnectionTest{}
+	if err := m.validateStoredSubscription(sub); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	tester, ok := m.strategies[sub.Source].(ConnectionTester)
+	if !ok {
+		result.OK, result.Skipped = true, true
+		return result
+	}
+
+	start := time.Now()
+	err := m.authorize(ctx, sub)
+	if err == nil {
+		err = tester.TestConnection(ctx, sub)
+	}
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if errors.Is(err, ErrNotTestable) {
+		result.OK, result.Skipped = true, true
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// DryRunPull pulls a sample of the subscription's feedback and runs it
+// through a preview of the pipeline. Nothing is saved: not the feedback,
+// the payloads, the failures nor the time the subscription was pulled.
+func (m *IntegrationManager) DryRunPull(ctx context.Context, sub *models.Subscription, size int) (*models.PullSample, error) {
+	puller, ok := m.strategies[sub.Source].(Puller)
+	if !ok || sub.SubscriptionMode != models.SubscriptionModePull {
+		return nil, fmt.Errorf("only pull subscriptions can be pulled")
+	}
+	if err := m.authorize(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	sample := &models.PullSample{Feedbacks: []*models.Feedback{}, Errors: []string{}}
+	feedbacks, err := puller.Pull(WithSample(ctx, size), sub)
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		for _, failure := range partial.Failures {
+			sample.Errors = append(sample.Errors, failure.Error())
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to pull data from source: %v", err)
+	}
+	if len(feedbacks) > size {
+		feedbacks = feedbacks[:size]
+	}
+
+	feedbacks, stageErrors, err := m.pipelineService.Preview(ctx, feedbacks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the enrichment pipeline: %v", err)
+	}
+	for _, stageError := range stageErrors {
+		sample.Errors = append(sample.Errors, stageError.Error())
+	}
+	sample.Feedbacks = append(sample.Feedbacks, feedbacks...)
+
+	return sample, nil
+}
+
+// TestConnectionHandler handles POST /subscription/test?tenant_id=...&id=...,
+// it reports whether the subscription's source can be called with its
+// configuration.
+func (m *IntegrationManager) TestConnectionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sub, ok := m.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(m.TestConnection(r.Context(), sub))
+}
+
+// DryRunHandler handles POST /subscription/dry-run?tenant_id=...&id=...&limit=...,
+// it returns a sample of the feedback a pull of the subscription would
+// ingest, without saving anything.
+func (m *IntegrationManager) DryRunHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sub, ok := m.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	size := DefaultSampleSize
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > MaxSampleSize {
+			http.Error(w, fmt.Sprintf("Invalid limit, expected 1 to %d", MaxSampleSize), http.StatusBadRequest)
+			return
+		}
+		size = value
+	}
+	if sub.SubscriptionMode != models.SubscriptionModePull {
+		http.Error(w, "Only pull subscriptions can be pulled", http.StatusBadRequest)
+		return
+	}
+
+	sample, err := m.DryRunPull(r.Context(), sub, size)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to pull: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(sample)
+}
+
+// subscriptionOf returns the subscription of the request's id, it must be
+// the tenant's and not deleted.
+func (m *IntegrationManager) subscriptionOf(w http.ResponseWriter, r *http.Request) (*models.Subscription, bool) {
+	query := r.URL.Query()
+	tenantID, subscriptionID := query.Get("tenant_id"), query.Get("id")
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return nil, false
+	}
+	if subscriptionID == "" {
+		http.Error(w, "Subscription ID is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		http.Error(w, "Invalid Subscription ID format", http.StatusBadRequest)
+		return nil, false
+	}
+
+	sub, err := m.subService.GetSubscription(r.Context(), subscriptionID)
+	if err != nil || sub.TenantID != tenantID || sub.DeletedAt != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return nil, false
+	}
+	return sub, true
+}
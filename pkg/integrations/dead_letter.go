@@ -0,0 +1,182 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// PayloadFailure is a payload that failed to be ingested at a stage, it is
+// dead-lettered. For the fetch stage the data is what the strategy needs to
+// fetch the payload again, see Refetcher.
+type PayloadFailure struct {
+	Stage string
+	Kind  string // pull or push
+	Data  []byte
+	Err   error
+}
+
+func (f *PayloadFailure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Stage, f.Err)
+}
+
+func (f *PayloadFailure) Unwrap() error {
+	return f.Err
+}
+
+// PartialError is returned by Pull along with the feedback of the payloads
+// that were fetched and mapped, for the ones that were not.
+type PartialError struct {
+	Failures []*PayloadFailure
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d payloads failed, first: %v", len(e.Failures), e.Failures[0])
+}
+
+// Refetcher is implemented by strategies whose payloads that failed to be
+// fetched can be fetched again when their dead letter is retried.
+type Refetcher interface {
+	Refetch(ctx context.Context, sub *models.Subscription, reference []byte) ([]*models.Feedback, error)
+}
+
+// ingest runs the feedbacks mapped from one payload through the pipeline
+// and saves them, replacing the feedback already stored. A failure is
+// returned as a *PayloadFailure of the pipeline or store stage.
+func (m *IntegrationManager) ingest(ctx context.Context, feedbacks []*models.Feedback) error {
+	feedbacks, stageErrors, err := m.pipelineService.Process(ctx, feedbacks)
+	if err != nil {
+		return &PayloadFailure{Stage: models.StagePipeline, Err: fmt.Errorf("failed to run the enrichment pipeline: %v", err)}
+	}
+	for _, stageError := range stageErrors {
+		fmt.Printf("Enrichment pipeline: %v\n", stageError)
+	}
+
+	if len(feedbacks) == 0 {
+		return nil
+	}
+	if _, _, err := m.feedbackService.UpsertFeedbacks(ctx, feedbacks); err != nil {
+		return &PayloadFailure{Stage: models.StageStore, Err: err}
+	}
+	return nil
+}
+
+// ingestByPayload ingests the feedbacks of each payload on their own, the
+// payloads failing are dead-lettered.
+func (m *IntegrationManager) ingestByPayload(ctx context.Context, sub *models.Subscription, feedbacks []*models.Feedback) {
+	var (
+		order  []*models.RawPayload
+		groups = map[*models.RawPayload][]*models.Feedback{}
+	)
+	for _, feedback := range feedbacks {
+		if _, ok := groups[feedback.RawPayload]; !ok {
+			order = append(order, feedback.RawPayload)
+		}
+		groups[feedback.RawPayload] = append(groups[feedback.RawPayload], feedback)
+	}
+
+	for _, payload := range order {
+		err := m.ingest(ctx, groups[payload])
+		if err == nil {
+			continue
+		}
+		if payload == nil {
+			// nothing to retry from
+			fmt.Printf("Failed to store feedback for subscription %s: %v\n", sub.ID, err)
+			continue
+		}
+		m.deadLetter(ctx, sub.TenantID, sub.ID, sub.Source, failureOf(err, payload.Kind, payload.Data), 1)
+	}
+}
+
+// failureOf returns the failure err is, or wraps it as a store failure of
+// the payload.
+func failureOf(err error, kind string, data []byte) *PayloadFailure {
+	var failure *PayloadFailure
+	if !errors.As(err, &failure) {
+		failure = &PayloadFailure{Stage: models.StageStore, Err: err}
+	}
+	if failure.Kind == "" {
+		failure.Kind = kind
+	}
+	if failure.Data == nil {
+		failure.Data = data
+	}
+	return failure
+}
+
+func (m *IntegrationManager) deadLetter(ctx context.Context, tenantID, subscriptionID string, source models.Source, failure *PayloadFailure, attempts int) {
+	entry := &models.DeadLetter{
+		TenantID:       tenantID,
+		SubscriptionID: subscriptionID,
+		Source:         source,
+		Kind:           failure.Kind,
+		Payload:        failure.Data,
+		Stage:          failure.Stage,
+		Error:          failure.Err.Error(),
+		Attempts:       attempts,
+	}
+	if entry.Payload == nil {
+		entry.Payload = []byte{}
+	}
+	if err := m.deadLetterService.Add(ctx, entry); err != nil {
+		fmt.Printf("Failed to dead-letter a %s payload of subscription %s (%v): %v\n", source, subscriptionID, failure, err)
+	}
+}
+
+// RetryDeadLetter fetches or maps the payload of the dead letter again and
+// ingests its feedback. On failure the entry's stage is set to the one that
+// failed.
+func (m *IntegrationManager) RetryDeadLetter(ctx context.Context, entry *models.DeadLetter) error {
+	err := m.retryDeadLetter(ctx, entry)
+	if err != nil {
+		entry.Stage = failureOf(err, entry.Kind, nil).Stage
+	}
+	return err
+}
+
+func (m *IntegrationManager) retryDeadLetter(ctx context.Context, entry *models.DeadLetter) error {
+	strategy, ok := m.strategies[entry.Source]
+	if !ok {
+		return &PayloadFailure{Stage: entry.Stage, Err: fmt.Errorf("no strategy found for source: %s", entry.Source)}
+	}
+	sub, err := m.subService.GetSubscription(ctx, entry.SubscriptionID)
+	if err != nil {
+		return &PayloadFailure{Stage: entry.Stage, Err: err}
+	}
+
+	var feedbacks []*models.Feedback
+	if entry.Stage == models.StageFetch {
+		refetcher, ok := strategy.(Refetcher)
+		if !ok {
+			return &PayloadFailure{Stage: models.StageFetch, Err: fmt.Errorf("%s payloads cannot be fetched again", entry.Source)}
+		}
+		if err := m.authorize(ctx, sub); err != nil {
+			return &PayloadFailure{Stage: models.StageFetch, Err: err}
+		}
+		if feedbacks, err = refetcher.Refetch(ctx, sub, entry.Payload); err != nil {
+			return failureOf(err, entry.Kind, nil)
+		}
+	} else {
+		mapper, ok := strategy.(PayloadMapper)
+		if !ok {
+			return &PayloadFailure{Stage: models.StageMap, Err: fmt.Errorf("%s payloads cannot be mapped again", entry.Source)}
+		}
+		payload := &models.RawPayload{Kind: entry.Kind, Data: entry.Payload}
+		if feedbacks, err = mapper.MapPayload(ctx, sub, payload); err != nil {
+			return &PayloadFailure{Stage: models.StageMap, Err: err}
+		}
+		for _, feedback := range feedbacks {
+			if feedback.RawPayload == nil {
+				feedback.RawPayload = payload
+			}
+		}
+	}
+
+	if err := m.archivePayloads(ctx, sub, feedbacks); err != nil {
+		return &PayloadFailure{Stage: models.StageStore, Err: err}
+	}
+	return m.ingest(ctx, feedbacks)
+}
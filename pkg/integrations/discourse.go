@@ -4,131 +4,197 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"sync"
 	"time"
 
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
 )
 
-type DiscourseIntegration struct{}
+const discourseBaseURL = "https://meta.discourse.org"
 
-func NewDiscourseStrategy() *DiscourseIntegration {
-	return &DiscourseIntegration{}
-}
+// discourseConcurrency is the number of posts of a pull fetched at a time
+const discourseConcurrency = 4
 
-func (s *DiscourseIntegration) Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error) {
-	lastPulled := sub.LastPulled.Format("2006-01-02")
-	now := time.Now().Format("2006-01-02")
+type DiscourseIntegration struct {
+	client  *SourceClient
+	baseURL string
+}
 
-	url := fmt.Sprintf("https://meta.discourse.org/search.json?page=1&q=after%%3A%s+before%%3A%s", lastPulled, now)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
+func NewDiscourseStrategy(client *SourceClient) *DiscourseIntegration {
+	return &DiscourseIntegration{client: client, baseURL: discourseBaseURL}
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data: %v", err)
-	}
-	defer resp.Body.Close()
+// discourseSearchPost is a post of the search results.
+type discourseSearchPost struct {
+	ID         int    `json:"id"`
+	TopicID    int    `json:"topic_id"`
+	CreatedAt  string `json:"created_at"`
+	Blurb      string `json:"blurb"`
+	Username   string `json:"username"`
+	TopicTitle string `json:"topic_title_headline"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch data, status code: %d", resp.StatusCode)
-	}
+func (s *DiscourseIntegration) Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error) {
+	// the calls are anonymous, they share the one quota of the source
+	ctx = WithRateLimit(ctx, sub, "")
 
-	body, err := io.ReadAll(resp.Body)
+	posts, err := s.search(ctx, sub.LastPulled, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, err
 	}
 
-	var searchResults struct {
-		Posts []struct {
-			ID         int    `json:"id"`
-			TopicID    int    `json:"topic_id"`
-			CreatedAt  string `json:"created_at"`
-			Blurb      string `json:"blurb"`
-			Username   string `json:"username"`
-			TopicTitle string `json:"topic_title_headline"`
-		} `json:"posts"`
-	}
-
-	if err := json.Unmarshal(body, &searchResults); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal discourse search results: %v", err)
-	}
-
-	searchResults.Posts = searchResults.Posts[:3]
-
 	var (
 		feedbacks []*models.Feedback
+		failures  []*PayloadFailure
 		mutex     sync.Mutex
 		wg        sync.WaitGroup
+		slots     = make(chan struct{}, discourseConcurrency)
 	)
 
-	for _, post := range searchResults.Posts {
+	for _, post := range posts {
 		wg.Add(1)
-		go func(post struct {
-			ID         int    `json:"id"`
-			TopicID    int    `json:"topic_id"`
-			CreatedAt  string `json:"created_at"`
-			Blurb      string `json:"blurb"`
-			Username   string `json:"username"`
-			TopicTitle string `json:"topic_title_headline"`
-		}) {
+		go func(post discourseSearchPost) {
 			defer wg.Done()
 
-			postCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-			defer cancel()
+			slots <- struct{}{}
+			defer func() { <-slots }()
 
-			feedback, err := s.processPullPost(postCtx, post.ID, post.TopicID, sub)
+			// the calls may wait for the rate limit, each attempt is bounded
+			// by the client's timeout
+			feedback, err := s.processPullPost(ctx, post.ID, post.TopicID, sub)
+
+			mutex.Lock()
+			defer mutex.Unlock()
 			if err != nil {
 				fmt.Printf("Failed to process post ID %d: %v\n", post.ID, err)
+				failures = append(failures, err)
 				return
 			}
-
-			mutex.Lock()
 			feedbacks = append(feedbacks, feedback...)
-			mutex.Unlock()
 		}(post)
 	}
 
 	wg.Wait()
+	if len(failures) > 0 {
+		return feedbacks, &PartialError{Failures: failures}
+	}
 	fmt.Println("Successfully pulled and processed data from Discourse")
 	return feedbacks, nil
 }
 
-func (s *DiscourseIntegration) processPullPost(ctx context.Context, postID, topicID int, sub *models.Subscription) ([]*models.Feedback, error) {
-	url := fmt.Sprintf("https://meta.discourse.org/t/%d/posts.json?post_ids[]=%d", topicID, postID)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+// search returns the posts created since the cursor, following the pages
+// of the search results until there are no more. A dry-run pull stops
+// once it has its sample.
+func (s *DiscourseIntegration) search(ctx context.Context, since, until time.Time) ([]discourseSearchPost, error) {
+	size, sampled := sampleOf(ctx)
+
+	var (
+		posts []discourseSearchPost
+		seen  = map[int]bool{}
+	)
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/search.json?page=%d&q=after%%3A%s+before%%3A%s", s.baseURL, page, since.Format("2006-01-02"), until.Format("2006-01-02"))
+		body, err := s.client.Get(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch data: %w", err)
+		}
+
+		var searchResults struct {
+			Posts               []discourseSearchPost `json:"posts"`
+			GroupedSearchResult struct {
+				MoreFullPageResults bool `json:"more_full_page_results"`
+			} `json:"grouped_search_result"`
+		}
+		if err := json.Unmarshal(body, &searchResults); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal discourse search results: %v", err)
+		}
+
+		for _, post := range searchResults.Posts {
+			// a post created while paging shifts the later pages
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				posts = append(posts, post)
+			}
+		}
+
+		if sampled && len(posts) >= size {
+			return posts[:size], nil
+		}
+		if len(searchResults.Posts) == 0 || !searchResults.GroupedSearchResult.MoreFullPageResults {
+			return posts, nil
+		}
 	}
+}
+
+// TestConnection fetches the forum's about page, the calls are anonymous.
+func (s *DiscourseIntegration) TestConnection(ctx context.Context, sub *models.Subscription) error {
+	if _, err := s.client.Get(WithRateLimit(ctx, sub, ""), s.baseURL+"/about.json"); err != nil {
+		return fmt.Errorf("failed to reach %s: %v", s.baseURL, err)
+	}
+	return nil
+}
+
+// postReference is the data of a post that failed to be fetched
+type postReference struct {
+	PostID  int `json:"post_id"`
+	TopicID int `json:"topic_id"`
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// Refetch fetches a post that failed to be fetched again.
+func (s *DiscourseIntegration) Refetch(ctx context.Context, sub *models.Subscription, reference []byte) ([]*models.Feedback, error) {
+	var post postReference
+	if err := json.Unmarshal(reference, &post); err != nil {
+		return nil, fmt.Errorf("invalid post reference: %v", err)
+	}
+	feedbacks, err := s.processPullPost(WithRateLimit(ctx, sub, ""), post.PostID, post.TopicID, sub)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch post: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return feedbacks, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch post, status code: %d", resp.StatusCode)
+// processPullPost fetches the post and maps it. A failure is returned as
+// a *PayloadFailure, of the fetch stage with a reference to the post or of
+// the map stage with the response.
+func (s *DiscourseIntegration) processPullPost(ctx context.Context, postID, topicID int, sub *models.Subscription) ([]*models.Feedback, *PayloadFailure) {
+	fetchFailure := func(err error) *PayloadFailure {
+		reference, _ := json.Marshal(postReference{PostID: postID, TopicID: topicID})
+		return &PayloadFailure{Stage: models.StageFetch, Kind: models.PayloadPull, Data: reference, Err: err}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	url := fmt.Sprintf("%s/t/%d/posts.json?post_ids[]=%d", s.baseURL, topicID, postID)
+	body, err := s.client.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, fetchFailure(fmt.Errorf("failed to fetch post: %v", err))
 	}
 
+	feedbacks, err := s.mapPost(sub, body)
+	if err != nil {
+		return nil, &PayloadFailure{Stage: models.StageMap, Kind: models.PayloadPull, Data: body, Err: err}
+	}
+	return feedbacks, nil
+}
+
+// MapPayload maps an archived posts.json response again.
+func (s *DiscourseIntegration) MapPayload(ctx context.Context, sub *models.Subscription, payload *models.RawPayload) ([]*models.Feedback, error) {
+	if payload.Kind != models.PayloadPull {
+		return nil, fmt.Errorf("discourse only pulls data, got a %s payload", payload.Kind)
+	}
+	return s.mapPost(sub, payload.Data)
+}
+
+// mapPost maps the first post of a posts.json response to feedback.
+func (s *DiscourseIntegration) mapPost(sub *models.Subscription, body []byte) ([]*models.Feedback, error) {
 	var postResponse struct {
 		PostStream struct {
 			Posts []struct {
-				ID        int    `json:"id"`
-				CreatedAt string `json:"created_at"`
-				Cooked    string `json:"cooked"`
-				Username  string `json:"username"`
-				TopicID   int    `json:"topic_id"`
-				TopicSlug string `json:"topic_slug"`
+				ID         int    `json:"id"`
+				CreatedAt  string `json:"created_at"`
+				Cooked     string `json:"cooked"`
+				Username   string `json:"username"`
+				PostNumber int    `json:"post_number"`
+				TopicID    int    `json:"topic_id"`
+				TopicSlug  string `json:"topic_slug"`
 			} `json:"posts"`
 		} `json:"post_stream"`
 	}
@@ -143,14 +209,21 @@ func (s *DiscourseIntegration) processPullPost(ctx context.Context, postID, topi
 
 	post := postResponse.PostStream.Posts[0]
 
+	var sourceCreatedAt *time.Time
+	if createdAt, err := time.Parse(time.RFC3339, post.CreatedAt); err == nil {
+		sourceCreatedAt = &createdAt
+	}
+
 	feedback := &models.Feedback{
-		ID:          fmt.Sprintf("%d", post.ID),
-		TenantID:    sub.TenantID,
-		SubSourceID: sub.SubSourceId,
-		Source:      s.GetSourceName(),
-		SourceType:  s.GetSourceType(),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:              fmt.Sprintf("%d", post.ID),
+		TenantID:        sub.TenantID,
+		SubSourceID:     sub.SubSourceId,
+		Source:          s.GetSourceName(),
+		SourceType:      s.GetSourceType(),
+		Author:          post.Username,
+		URL:             fmt.Sprintf("%s/t/%s/%d/%d", s.baseURL, post.TopicSlug, post.TopicID, post.PostNumber),
+		SourceCreatedAt: sourceCreatedAt,
+		IngestedAt:      time.Now(),
 		Content: map[string]interface{}{
 			"body": post.Cooked,
 		},
@@ -160,14 +233,31 @@ func (s *DiscourseIntegration) processPullPost(ctx context.Context, postID, topi
 			"topic_slug": post.TopicSlug,
 			"created_at": post.CreatedAt,
 		},
+		RawPayload: &models.RawPayload{Kind: models.PayloadPull, Data: body},
 	}
 
 	return []*models.Feedback{feedback}, nil
 }
 
-func (s *DiscourseIntegration) Push(ctx context.Context, r *http.Request, body []byte) ([]*models.Feedback, error) {
-	// implement webhook logic here
-	return nil, fmt.Errorf("discourse push method not implemented yet")
+// SecretFields are the credentials of the Discourse API.
+func (s *DiscourseIntegration) SecretFields() []string {
+	return []string{"api_key"}
+}
+
+// Capabilities of Discourse: the first pull searches the posts since the
+// forum started.
+func (s *DiscourseIntegration) Capabilities() models.Capabilities {
+	return models.Capabilities{Backfill: true}
+}
+
+func (s *DiscourseIntegration) ConfigSchema() *models.ConfigSchema {
+	return &models.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*models.ConfigSchema{
+			"api_key": {Type: "string", Description: "Key of the Discourse API", WriteOnly: true},
+		},
+		AdditionalProperties: false,
+	}
 }
 
 func (a *DiscourseIntegration) GetSourceName() models.Source {
@@ -0,0 +1,117 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// testDiscourse serves the search result pages, each a list of post IDs,
+// and the posts. It returns the strategy calling it and the number of
+// search pages fetched.
+func testDiscourse(t *testing.T, pages [][]int) (*DiscourseIntegration, *int32) {
+	t.Helper()
+
+	var searches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/search.json":
+			atomic.AddInt32(&searches, 1)
+			if !strings.HasPrefix(r.URL.Query().Get("q"), "after:2024-05-01 before:") {
+				t.Errorf("got query %q", r.URL.Query().Get("q"))
+			}
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			var posts []map[string]interface{}
+			if page >= 1 && page <= len(pages) {
+				for _, id := range pages[page-1] {
+					posts = append(posts, map[string]interface{}{"id": id, "topic_id": id * 10})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"posts":                 posts,
+				"grouped_search_result": map[string]interface{}{"more_full_page_results": page < len(pages)},
+			})
+		case strings.HasSuffix(r.URL.Path, "/posts.json"):
+			id := r.URL.Query().Get("post_ids[]")
+			fmt.Fprintf(w, `{"post_stream": {"posts": [{"id": %s, "cooked": "post %s", "created_at": "2024-05-02T10:00:00Z"}]}}`, id, id)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewDiscourseStrategy(NewSourceClient(testClientConfig(0, 10), nil))
+	s.baseURL = server.URL
+	return s, &searches
+}
+
+func testDiscourseSubscription() *models.Subscription {
+	return &models.Subscription{
+		Source:     models.SourceDiscourse,
+		LastPulled: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDiscoursePullFollowsPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    [][]int
+		want     []string
+		searches int32
+	}{
+		{"one page", [][]int{{1, 2}}, []string{"1", "2"}, 1},
+		{"pages", [][]int{{1, 2}, {3, 4}, {5}}, []string{"1", "2", "3", "4", "5"}, 3},
+		{"shifted page", [][]int{{1, 2}, {2, 3}}, []string{"1", "2", "3"}, 2},
+		{"no posts", nil, nil, 1},
+	}
+	for _, tt := range tests {
+		s, searches := testDiscourse(t, tt.pages)
+
+		feedbacks, err := s.Pull(context.Background(), testDiscourseSubscription())
+		if err != nil {
+			t.Fatalf("%s: Pull: %v", tt.name, err)
+		}
+		var ids []string
+		for _, feedback := range feedbacks {
+			ids = append(ids, feedback.ID)
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got posts %v, want %v", tt.name, ids, tt.want)
+		}
+		if *searches != tt.searches {
+			t.Errorf("%s: fetched %d search pages, want %d", tt.name, *searches, tt.searches)
+		}
+	}
+}
+
+func TestDiscoursePullSample(t *testing.T) {
+	s, searches := testDiscourse(t, [][]int{{1, 2}, {3, 4}, {5, 6}})
+
+	feedbacks, err := s.Pull(WithSample(context.Background(), 3), testDiscourseSubscription())
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(feedbacks) != 3 || *searches != 2 {
+		t.Errorf("got %d posts from %d search pages, want 3 from 2", len(feedbacks), *searches)
+	}
+}
+
+func TestDiscoursePullFailedPage(t *testing.T) {
+	s, _ := testDiscourse(t, [][]int{{1}, {2}})
+	s.baseURL += "/missing"
+
+	if _, err := s.Pull(context.Background(), testDiscourseSubscription()); err == nil {
+		t.Errorf("a failed search did not fail the pull")
+	}
+}
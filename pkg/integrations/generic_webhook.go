@@ -0,0 +1,532 @@
+package integrations
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// GenericWebhookConfig is the configuration of a generic webhook
+// subscription: how its payloads map to feedback. Every mapping is a
+// JSONPath expression into an item of the payload when it starts with $, a
+// constant otherwise.
+type GenericWebhookConfig struct {
+	// Items selects the feedback items of the payload, e.g. $.responses[*].
+	// By default the payload is one item, or an array of items.
+	Items string `json:"items,omitempty"`
+	// ID is the native ID of the feedback, derived from the item when empty
+	ID string `json:"id,omitempty"`
+	// Text is the feedback text, the values it selects are joined by lines
+	Text            string `json:"text"`
+	Author          string `json:"author,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Language        string `json:"language,omitempty"`
+	Rating          string `json:"rating,omitempty"`
+	Timestamp       string `json:"timestamp,omitempty"`
+	TimestampFormat string `json:"timestamp_format,omitempty"` // rfc3339 (default), unix, unix_ms or a Go layout
+	// SourceType is the content type, feedback by default
+	SourceType string `json:"source_type,omitempty"`
+	// Content adds fields to the content besides the text, stored as its body
+	Content  map[string]string `json:"content,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	Signature *WebhookSignature `json:"signature,omitempty"`
+	Response  *WebhookResponse  `json:"response,omitempty"`
+}
+
+// WebhookSignature is how the tool signs its webhook calls: the header must
+// hold the HMAC of the body, keyed by the secret, after the optional prefix
+// (e.g. "sha256="). Without it calls are signed as DefaultSignatureHeader
+// describes.
+type WebhookSignature struct {
+	Header string `json:"header"`
+	// Secret is the subscription's webhook secret unless set, for tools
+	// that generate their own
+	Secret    string `json:"secret,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"` // sha256 (default), sha1 or sha512
+	Encoding  string `json:"encoding,omitempty"`  // hex (default) or base64
+	Prefix    string `json:"prefix,omitempty"`
+}
+
+// WebhookResponse is the response to successful webhook calls, some tools
+// expect a specific one.
+type WebhookResponse struct {
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// genericMapping is a compiled mapping, a path or a constant.
+type genericMapping struct {
+	path     jsonPath
+	constant string
+}
+
+func compileMapping(expr string) (*genericMapping, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(expr, "$") {
+		return &genericMapping{constant: expr}, nil
+	}
+	path, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &genericMapping{path: path}, nil
+}
+
+func (m *genericMapping) values(item interface{}) []interface{} {
+	if m == nil {
+		return nil
+	}
+	if m.path == nil {
+		return []interface{}{m.constant}
+	}
+	return m.path.eval(item)
+}
+
+func (m *genericMapping) first(item interface{}) (interface{}, bool) {
+	for _, value := range m.values(item) {
+		if value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (m *genericMapping) text(item interface{}) string {
+	value, ok := m.first(item)
+	if !ok {
+		return ""
+	}
+	return scalarText(value)
+}
+
+// compiledWebhook is a parsed and compiled GenericWebhookConfig.
+type compiledWebhook struct {
+	config   *GenericWebhookConfig
+	items    jsonPath
+	fields   map[string]*genericMapping
+	content  map[string]*genericMapping
+	metadata map[string]*genericMapping
+	hash     func() hash.Hash
+}
+
+// ParseGenericWebhookConfig reads and checks the configuration of a generic
+// webhook subscription.
+func ParseGenericWebhookConfig(configuration map[string]interface{}) (*GenericWebhookConfig, error) {
+	raw, err := json.Marshal(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	var config GenericWebhookConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	if _, err := compileWebhook(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func compileWebhook(config *GenericWebhookConfig) (*compiledWebhook, error) {
+	if config.Text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+
+	c := &compiledWebhook{
+		config:   config,
+		fields:   map[string]*genericMapping{},
+		content:  map[string]*genericMapping{},
+		metadata: map[string]*genericMapping{},
+	}
+
+	var err error
+	if config.Items != "" {
+		if c.items, err = compileJSONPath(config.Items); err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+	}
+
+	fields := map[string]string{
+		"id":          config.ID,
+		"text":        config.Text,
+		"author":      config.Author,
+		"url":         config.URL,
+		"language":    config.Language,
+		"rating":      config.Rating,
+		"timestamp":   config.Timestamp,
+		"source_type": config.SourceType,
+	}
+	for name, expr := range fields {
+		if c.fields[name], err = compileMapping(expr); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	for key, expr := range config.Content {
+		if c.content[key], err = compileMapping(expr); err != nil {
+			return nil, fmt.Errorf("content.%s: %v", key, err)
+		}
+	}
+	for key, expr := range config.Metadata {
+		if c.metadata[key], err = compileMapping(expr); err != nil {
+			return nil, fmt.Errorf("metadata.%s: %v", key, err)
+		}
+	}
+
+	if sourceType := c.fields["source_type"]; sourceType != nil && sourceType.path == nil {
+		if !models.SourceType(sourceType.constant).Valid() {
+			return nil, fmt.Errorf("source_type: unknown source type %q", sourceType.constant)
+		}
+	}
+
+	switch strings.ToLower(config.TimestampFormat) {
+	case "", "rfc3339", "unix", "unix_ms":
+	default:
+		if !strings.ContainsAny(config.TimestampFormat, "0123456789") {
+			return nil, fmt.Errorf("timestamp_format: expected rfc3339, unix, unix_ms or a Go layout")
+		}
+	}
+
+	if sig := config.Signature; sig != nil {
+		if sig.Header == "" {
+			return nil, fmt.Errorf("signature: header is required")
+		}
+		switch strings.ToLower(sig.Algorithm) {
+		case "", "sha256":
+			c.hash = sha256.New
+		case "sha1":
+			c.hash = sha1.New
+		case "sha512":
+			c.hash = sha512.New
+		default:
+			return nil, fmt.Errorf("signature: unsupported algorithm %q", sig.Algorithm)
+		}
+		switch strings.ToLower(sig.Encoding) {
+		case "", "hex", "base64":
+		default:
+			return nil, fmt.Errorf("signature: unsupported encoding %q", sig.Encoding)
+		}
+	}
+
+	if resp := config.Response; resp != nil && resp.Status != 0 && (resp.Status < 200 || resp.Status > 299) {
+		return nil, fmt.Errorf("response: status must be a 2xx status")
+	}
+
+	return c, nil
+}
+
+// GenericWebhookIntegration ingests the payloads of any tool able to call a
+// webhook, mapped to feedback by the subscription's configuration.
+type GenericWebhookIntegration struct{}
+
+func NewGenericWebhookStrategy() *GenericWebhookIntegration {
+	return &GenericWebhookIntegration{}
+}
+
+func (s *GenericWebhookIntegration) Push(ctx context.Context, sub *models.Subscription, r *http.Request, body []byte) ([]*models.Feedback, error) {
+	return s.mapBody(sub, body)
+}
+
+// MapPayload maps an archived webhook call again, with the subscription's
+// current configuration.
+func (s *GenericWebhookIntegration) MapPayload(ctx context.Context, sub *models.Subscription, payload *models.RawPayload) ([]*models.Feedback, error) {
+	if payload.Kind != models.PayloadPush {
+		return nil, fmt.Errorf("generic webhooks only receive pushed data, got a %s payload", payload.Kind)
+	}
+	return s.mapBody(sub, payload.Data)
+}
+
+func (s *GenericWebhookIntegration) mapBody(sub *models.Subscription, body []byte) ([]*models.Feedback, error) {
+	config, err := ParseGenericWebhookConfig(sub.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	c, err := compileWebhook(config)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook data: %v", err)
+	}
+
+	var items []interface{}
+	switch {
+	case c.items != nil:
+		items = c.items.eval(payload)
+	default:
+		if list, ok := payload.([]interface{}); ok {
+			items = list
+		} else {
+			items = []interface{}{payload}
+		}
+	}
+
+	feedbacks := make([]*models.Feedback, 0, len(items))
+	for i, item := range items {
+		feedback, err := c.toFeedback(sub, item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %v", i, err)
+		}
+		feedbacks = append(feedbacks, feedback)
+	}
+
+	return feedbacks, nil
+}
+
+// RespondWebhook writes the configured response, 200 with no body by default.
+func (s *GenericWebhookIntegration) RespondWebhook(w http.ResponseWriter, sub *models.Subscription) {
+	config, err := ParseGenericWebhookConfig(sub.Configuration)
+	if err != nil || config.Response == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	resp := config.Response
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, resp.Body)
+}
+
+func (s *GenericWebhookIntegration) ValidateConfig(configuration map[string]interface{}) error {
+	_, err := ParseGenericWebhookConfig(configuration)
+	return err
+}
+
+// Capabilities of generic webhooks: a payload sent again with the same
+// ID updates its feedback.
+func (s *GenericWebhookIntegration) Capabilities() models.Capabilities {
+	return models.Capabilities{Edits: true}
+}
+
+// ConfigSchema describes GenericWebhookConfig.
+func (s *GenericWebhookIntegration) ConfigSchema() *models.ConfigSchema {
+	mapping := func(description string) *models.ConfigSchema {
+		return &models.ConfigSchema{Type: "string", Description: description}
+	}
+	mappings := &models.ConfigSchema{Type: "object", AdditionalProperties: mapping("")}
+
+	return &models.ConfigSchema{
+		Type:        "object",
+		Description: "Mappings of the payloads to feedback, JSONPath expressions into an item when they start with $, constants otherwise",
+		Properties: map[string]*models.ConfigSchema{
+			"items":            mapping("Selects the feedback items of the payload, e.g. $.responses[*]"),
+			"id":               mapping("Native ID of the feedback, derived from the item when empty"),
+			"text":             {Type: "string", Description: "Feedback text, the values it selects are joined by lines", MinLength: 1},
+			"author":           mapping(""),
+			"url":              mapping(""),
+			"language":         mapping(""),
+			"rating":           mapping(""),
+			"timestamp":        mapping(""),
+			"timestamp_format": mapping("rfc3339 (default), unix, unix_ms or a Go layout"),
+			"source_type":      mapping("Content type, feedback by default"),
+			"content":          mappings,
+			"metadata":         mappings,
+			"signature": {
+				Type:        "object",
+				Description: "How the tool signs its calls, instead of the default signature",
+				Properties: map[string]*models.ConfigSchema{
+					"header":    {Type: "string", MinLength: 1},
+					"secret":    {Type: "string", Description: "The subscription's webhook secret unless set", WriteOnly: true},
+					"algorithm": {Type: "string", Description: "sha256 (default), sha1 or sha512"},
+					"encoding":  {Type: "string", Description: "hex (default) or base64"},
+					"prefix":    {Type: "string"},
+				},
+				Required:             []string{"header"},
+				AdditionalProperties: false,
+			},
+			"response": {
+				Type:        "object",
+				Description: "Response to the successful calls",
+				Properties: map[string]*models.ConfigSchema{
+					"status":       {Type: "integer", Description: "A 2xx status, 200 by default"},
+					"content_type": {Type: "string"},
+					"body":         {Type: "string"},
+				},
+				AdditionalProperties: false,
+			},
+		},
+		Required:             []string{"text"},
+		AdditionalProperties: false,
+	}
+}
+
+func (a *GenericWebhookIntegration) GetSourceName() models.Source {
+	return models.SourceWebhook
+}
+
+func (a *GenericWebhookIntegration) GetSourceType() models.SourceType {
+	return models.STFeedback
+}
+
+// SecretFields is the tool's own signing secret.
+func (s *GenericWebhookIntegration) SecretFields() []string {
+	return []string{"signature.secret"}
+}
+
+// VerifyWebhook checks the signature of the call as configured, or as
+// DefaultSignatureHeader describes.
+func (s *GenericWebhookIntegration) VerifyWebhook(sub *models.Subscription, r *http.Request, body []byte) error {
+	config, err := ParseGenericWebhookConfig(sub.Configuration)
+	if err != nil {
+		return err
+	}
+	c, err := compileWebhook(config)
+	if err != nil {
+		return err
+	}
+
+	sig := config.Signature
+	if sig == nil {
+		return verifyDefaultSignature(sub, r, body)
+	}
+	secret := sig.Secret
+	if secret == "" {
+		secret = sub.WebhookSecret
+	}
+	return verifyHMAC(r, body, sig.Header, sig.Prefix, secret, c.hash, strings.ToLower(sig.Encoding) == "base64")
+}
+
+func (c *compiledWebhook) toFeedback(sub *models.Subscription, item interface{}) (*models.Feedback, error) {
+	var lines []string
+	for _, value := range c.fields["text"].values(item) {
+		if text := strings.TrimSpace(scalarText(value)); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no text")
+	}
+
+	content := map[string]interface{}{"body": strings.Join(lines, "\n")}
+	for key, mapping := range c.content {
+		if value, ok := mapping.first(item); ok {
+			content[key] = value
+		}
+	}
+
+	feedback := &models.Feedback{
+		ID:          c.fields["id"].text(item),
+		TenantID:    sub.TenantID,
+		SubSourceID: sub.SubSourceId,
+		Source:      sub.Source,
+		SourceType:  models.STFeedback,
+		Author:      c.fields["author"].text(item),
+		URL:         c.fields["url"].text(item),
+		Language:    strings.ToLower(c.fields["language"].text(item)),
+		IngestedAt:  time.Now(),
+		Metadata:    map[string]interface{}{},
+		Content:     content,
+	}
+	if feedback.ID == "" {
+		// the same item gets the same ID, a redelivered call is not
+		// stored twice
+		raw, _ := json.Marshal(item)
+		feedback.ID = uuid.NewSHA1(uuid.MustParse(sub.ID), raw).String()
+	}
+	if sourceType := c.fields["source_type"].text(item); sourceType != "" {
+		feedback.SourceType = models.SourceType(sourceType)
+		if !feedback.SourceType.Valid() {
+			return nil, fmt.Errorf("unknown source type %q", sourceType)
+		}
+	}
+
+	if value, ok := c.fields["rating"].first(item); ok {
+		rating, err := parseNumber(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating %v", value)
+		}
+		feedback.Rating = &rating
+	}
+	if value, ok := c.fields["timestamp"].first(item); ok {
+		createdAt, err := parseTimestamp(value, c.config.TimestampFormat)
+		if err != nil {
+			return nil, err
+		}
+		feedback.SourceCreatedAt = &createdAt
+	}
+
+	for key, mapping := range c.metadata {
+		if value, ok := mapping.first(item); ok {
+			feedback.Metadata[key] = value
+		}
+	}
+
+	return feedback, nil
+}
+
+// parseNumber returns a number of the payload, or a number in a string, as
+// a float64.
+func parseNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("expected a number")
+}
+
+func parseTimestamp(value interface{}, format string) (time.Time, error) {
+	text := scalarText(value)
+	switch strings.ToLower(format) {
+	case "unix", "unix_ms":
+		n, err := parseNumber(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+		}
+		if strings.ToLower(format) == "unix_ms" {
+			return time.UnixMilli(int64(n)).UTC(), nil
+		}
+		return time.Unix(int64(n), 0).UTC(), nil
+	case "", "rfc3339":
+		format = time.RFC3339
+	}
+
+	t, err := time.Parse(format, text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+	}
+	return t.UTC(), nil
+}
+
+// scalarText returns a JSON value as text, objects and lists as JSON.
+// Numbers are written as they were in the payload.
+func scalarText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
@@ -0,0 +1,164 @@
+package integrations
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func testWebhookSubscription(configuration map[string]interface{}) *models.Subscription {
+	return &models.Subscription{
+		ID:            "3f2a1c4e-5b6d-4e7f-8a9b-0c1d2e3f4a5b",
+		TenantID:      "tenant",
+		Source:        models.SourceWebhook,
+		Configuration: configuration,
+	}
+}
+
+func TestGenericWebhookMapsItems(t *testing.T) {
+	sub := testWebhookSubscription(map[string]interface{}{
+		"items":     "$.responses[*]",
+		"id":        "$.id",
+		"text":      "$.answers[*].text",
+		"author":    "$.user.email",
+		"rating":    "$.score",
+		"timestamp": "$.submitted_at",
+		"content":   map[string]interface{}{"survey": "$.survey"},
+		"metadata":  map[string]interface{}{"plan": "$.user.plan", "channel": "survey"},
+	})
+	body := []byte(`{"responses": [
+		{"id": 12345678901234567890, "answers": [{"text": "Love it"}, {"text": " "}, {"text": "More exports"}],
+		 "user": {"email": "ann@example.com", "plan": "pro"}, "score": 4.5, "submitted_at": "2024-05-01T10:00:00Z",
+		 "survey": "nps"},
+		{"id": "r-2", "answers": [{"text": "Too slow"}], "score": "2"}
+	]}`)
+
+	feedbacks, err := NewGenericWebhookStrategy().mapBody(sub, body)
+	if err != nil {
+		t.Fatalf("mapBody: %v", err)
+	}
+	if len(feedbacks) != 2 {
+		t.Fatalf("got %d feedback, want 2", len(feedbacks))
+	}
+
+	first := feedbacks[0]
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	switch {
+	case first.ID != "12345678901234567890":
+		t.Errorf("got ID %q, want the ID with all its digits", first.ID)
+	case first.Author != "ann@example.com":
+		t.Errorf("got author %q", first.Author)
+	case first.Rating == nil || *first.Rating != 4.5:
+		t.Errorf("got rating %v, want 4.5", first.Rating)
+	case first.SourceCreatedAt == nil || !first.SourceCreatedAt.Equal(createdAt):
+		t.Errorf("got source_created_at %v, want %v", first.SourceCreatedAt, createdAt)
+	}
+	content := first.Content.(map[string]interface{})
+	if content["body"] != "Love it\nMore exports" || content["survey"] != "nps" {
+		t.Errorf("got content %v", content)
+	}
+	if first.Metadata["plan"] != "pro" || first.Metadata["channel"] != "survey" {
+		t.Errorf("got metadata %v", first.Metadata)
+	}
+
+	second := feedbacks[1]
+	if second.ID != "r-2" || second.Rating == nil || *second.Rating != 2 || second.SourceCreatedAt != nil {
+		t.Errorf("got ID %q, rating %v, source_created_at %v", second.ID, second.Rating, second.SourceCreatedAt)
+	}
+}
+
+func TestGenericWebhookKeepsNumbers(t *testing.T) {
+	sub := testWebhookSubscription(map[string]interface{}{
+		"text":     "$.comment",
+		"metadata": map[string]interface{}{"account": "$.account_id"},
+	})
+
+	feedbacks, err := NewGenericWebhookStrategy().mapBody(sub, []byte(`{"comment": "Slow", "account_id": 9007199254740993}`))
+	if err != nil {
+		t.Fatalf("mapBody: %v", err)
+	}
+	metadata, err := json.Marshal(feedbacks[0].Metadata)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(metadata) != `{"account":9007199254740993}` {
+		t.Errorf("got metadata %s, want the number as sent", metadata)
+	}
+}
+
+func TestGenericWebhookDerivesID(t *testing.T) {
+	sub := testWebhookSubscription(map[string]interface{}{"text": "$.comment"})
+	strategy := NewGenericWebhookStrategy()
+
+	first, err := strategy.mapBody(sub, []byte(`[{"comment": "Slow"}, {"comment": "Fast"}]`))
+	if err != nil {
+		t.Fatalf("mapBody: %v", err)
+	}
+	again, _ := strategy.mapBody(sub, []byte(`{"comment": "Slow"}`))
+	if first[0].ID == "" || first[0].ID != again[0].ID || first[0].ID == first[1].ID {
+		t.Errorf("got IDs %q, %q and %q, want the same ID for the same item only", first[0].ID, again[0].ID, first[1].ID)
+	}
+}
+
+func TestGenericWebhookMappingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", `{"comment": `},
+		{"data after the payload", `{"comment": "Slow"} {}`},
+		{"no text", `{"comment": " "}`},
+		{"invalid rating", `{"comment": "Slow", "score": "five"}`},
+		{"invalid timestamp", `{"comment": "Slow", "at": "yesterday"}`},
+	}
+	sub := testWebhookSubscription(map[string]interface{}{"text": "$.comment", "rating": "$.score", "timestamp": "$.at"})
+	for _, tt := range tests {
+		if _, err := NewGenericWebhookStrategy().mapBody(sub, []byte(tt.body)); err == nil {
+			t.Errorf("%s: mapped without error", tt.name)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  interface{}
+		format string
+	}{
+		{"2024-05-01T12:00:00+02:00", ""},
+		{json.Number("1714557600"), "unix"},
+		{"1714557600", "unix"},
+		{json.Number("1714557600000"), "unix_ms"},
+		{"01/05/2024 10:00", "02/01/2006 15:04"},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.value, tt.format)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTimestamp(%v, %q) = %v, %v, want %v", tt.value, tt.format, got, err, want)
+		}
+	}
+	if _, err := parseTimestamp(true, "unix"); err == nil {
+		t.Errorf("a boolean was read as a timestamp")
+	}
+}
+
+func TestScalarText(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{json.Number("12345678901234567890"), "12345678901234567890"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{map[string]interface{}{"a": json.Number("1")}, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if got := scalarText(tt.value); got != tt.want {
+			t.Errorf("scalarText(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -2,21 +2,26 @@ package integrations
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/archive"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/deadletter"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/inbox"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
 )
 
+// SourceStrategy is the integration of a source. It supports pull
+// subscriptions if it is a Puller, push ones if it is a Pusher.
 type SourceStrategy interface {
-	// Pull - pulls the data from the source and saves it to the feedback database
-	Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error)
-
-	// Push - recives the data from source's webhook and saves it to the feedback database
-	Push(ctx context.Context, r *http.Request, body []byte) ([]*models.Feedback, error)
-
 	// GetSourceName ...
 	GetSourceName() models.Source
 
@@ -24,69 +29,347 @@ type SourceStrategy interface {
 	GetSourceType() models.SourceType
 }
 
+// Puller is implemented by strategies able to pull their source.
+type Puller interface {
+	// Pull - pulls the data from the source and saves it to the feedback database
+	Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error)
+}
+
+// Pusher is implemented by strategies receiving their source's webhook calls.
+type Pusher interface {
+	// Push - recives the data from source's webhook and saves it to the feedback database.
+	// sub is the active push subscription the webhook was called for, the call is verified already
+	Push(ctx context.Context, sub *models.Subscription, r *http.Request, body []byte) ([]*models.Feedback, error)
+}
+
+// Describer is implemented by strategies to describe their subscriptions,
+// see Integrations.
+type Describer interface {
+	// Capabilities are what the source supports besides push and pull,
+	// which are whether the strategy is a Pusher or a Puller
+	Capabilities() models.Capabilities
+
+	// ConfigSchema is the schema of the subscriptions' configuration,
+	// without the properties common to every source
+	ConfigSchema() *models.ConfigSchema
+}
+
+// ConfigValidator is implemented by strategies whose subscriptions need a
+// configuration, it is checked when the subscription is created.
+type ConfigValidator interface {
+	ValidateConfig(configuration map[string]interface{}) error
+}
+
+// SecretConfig is implemented by strategies whose subscriptions hold
+// credentials in their configuration, the fields are stored encrypted and
+// redacted from the responses.
+type SecretConfig interface {
+	// SecretFields are dotted paths into the configuration
+	SecretFields() []string
+}
+
+// WebhookResponder is implemented by strategies that choose the response to
+// their webhook calls, it is sent once the call is stored.
+type WebhookResponder interface {
+	RespondWebhook(w http.ResponseWriter, sub *models.Subscription)
+}
+
+// NewStrategies returns the strategies of the supported sources, calling
+// them with the client.
+func NewStrategies(client *SourceClient) map[models.Source]SourceStrategy {
+	return map[models.Source]SourceStrategy{
+		models.SourceIntercom:  NewIntercomStrategy(client),
+		models.SourceDiscourse: NewDiscourseStrategy(client),
+		models.SourcePlaystore: NewPlaystoreStrategy(client),
+		models.SourceWebhook:   NewGenericWebhookStrategy(),
+	}
+}
+
+// PayloadMapper is implemented by strategies able to map an archived raw
+// payload to feedback again, see Reprocess.
+type PayloadMapper interface {
+	MapPayload(ctx context.Context, sub *models.Subscription, payload *models.RawPayload) ([]*models.Feedback, error)
+}
+
 type IntegrationManager struct {
-	strategies      map[models.Source]SourceStrategy
-	feedbackService *feedback.FeedbackService
+	strategies        map[models.Source]SourceStrategy
+	feedbackService   *feedback.FeedbackService
+	pipelineService   *pipeline.PipelineService
+	subService        *subscription.SubscriptionService
+	inboxService      *inbox.InboxService
+	archiveService    *archive.ArchiveService
+	deadLetterService *deadletter.DeadLetterService
+	refreshToken      TokenRefresher
+	// maxWebhookBody caps the body of the webhook calls, in bytes
+	maxWebhookBody int64
+}
+
+// TokenRefresher refreshes the OAuth token of the subscription if it
+// expires soon, updating the subscription.
+type TokenRefresher func(ctx context.Context, sub *models.Subscription) error
+
+func NewIntegrationManager(strategies map[models.Source]SourceStrategy, feedbackService *feedback.FeedbackService, pipelineService *pipeline.PipelineService, subService *subscription.SubscriptionService, inboxService *inbox.InboxService, archiveService *archive.ArchiveService, deadLetterService *deadletter.DeadLetterService, maxWebhookBody int64) *IntegrationManager {
+	return &IntegrationManager{strategies: strategies, feedbackService: feedbackService, pipelineService: pipelineService, subService: subService, inboxService: inboxService,
+		archiveService: archiveService, deadLetterService: deadLetterService, maxWebhookBody: maxWebhookBody}
+}
+
+// SetTokenRefresher sets the refresh of the subscriptions' tokens before
+// they are pulled. It is set once the OAuth service, which depends on the
+// subscriptions, is set up.
+func (m *IntegrationManager) SetTokenRefresher(refresh TokenRefresher) {
+	m.refreshToken = refresh
+}
+
+// ValidateSubscription checks that the subscription's source supports its
+// mode and that its configuration matches the source's schema, then that
+// the strategy accepts it, and its rate limit if it has one.
+func (m *IntegrationManager) ValidateSubscription(sub *models.Subscription) error {
+	strategy, ok := m.strategies[sub.Source]
+	if !ok {
+		return fmt.Errorf("unsupported source: %s", sub.Source)
+	}
+	if !supportsMode(strategy, sub.SubscriptionMode) {
+		return fmt.Errorf("%s does not support %s subscriptions", sub.Source, sub.SubscriptionMode)
+	}
+
+	configuration := sub.Configuration
+	if configuration == nil {
+		configuration = map[string]interface{}{}
+	}
+	if err := validateConfig(configSchemaOf(strategy), configuration, ""); err != nil {
+		return fmt.Errorf("invalid %s configuration: %v", sub.Source, err)
+	}
+
+	if _, _, err := subscriptionRateLimit(sub.Configuration); err != nil {
+		return err
+	}
+	if validator, ok := strategy.(ConfigValidator); ok {
+		if err := validator.ValidateConfig(sub.Configuration); err != nil {
+			return fmt.Errorf("invalid %s configuration: %v", sub.Source, err)
+		}
+	}
+	return nil
+}
+
+// validateStoredSubscription checks a stored subscription like
+// ValidateSubscription, leaving out the fields the service set on it, e.g.
+// its OAuth token.
+func (m *IntegrationManager) validateStoredSubscription(sub *models.Subscription) error {
+	checked := *sub
+	if strategy, ok := m.strategies[sub.Source]; ok {
+		checked.Configuration = clientConfiguration(configSchemaOf(strategy), sub.Configuration)
+	}
+	return m.ValidateSubscription(&checked)
+}
+
+// supportsMode reports whether the strategy supports subscriptions of the
+// mode.
+func supportsMode(strategy SourceStrategy, mode models.SubscriptionMode) bool {
+	switch mode {
+	case models.SubscriptionModePull:
+		_, ok := strategy.(Puller)
+		return ok
+	case models.SubscriptionModePush:
+		_, ok := strategy.(Pusher)
+		return ok
+	}
+	return false
+}
+
+// SecretFields returns the secret configuration fields of the source's
+// subscriptions.
+func (m *IntegrationManager) SecretFields(source models.Source) []string {
+	return SecretFieldsOf(m.strategies)(source)
+}
+
+// SecretFieldsOf returns the secret configuration fields of the sources of
+// the strategies.
+func SecretFieldsOf(strategies map[models.Source]SourceStrategy) func(source models.Source) []string {
+	return func(source models.Source) []string {
+		if secret, ok := strategies[source].(SecretConfig); ok {
+			return secret.SecretFields()
+		}
+		return nil
+	}
+}
+
+// Integrations describes the supported sources, sorted by source.
+func (m *IntegrationManager) Integrations() []*models.Integration {
+	integrations := make([]*models.Integration, 0, len(m.strategies))
+	for source, strategy := range m.strategies {
+		integration := &models.Integration{
+			Source:       source,
+			SourceType:   strategy.GetSourceType(),
+			ConfigSchema: configSchemaOf(strategy),
+			SecretFields: m.SecretFields(source),
+		}
+		if describer, ok := strategy.(Describer); ok {
+			integration.Capabilities = describer.Capabilities()
+		}
+		integration.Capabilities.Pull = supportsMode(strategy, models.SubscriptionModePull)
+		integration.Capabilities.Push = supportsMode(strategy, models.SubscriptionModePush)
+		if integration.SecretFields == nil {
+			integration.SecretFields = []string{}
+		}
+		integrations = append(integrations, integration)
+	}
+	sort.Slice(integrations, func(i, j int) bool { return integrations[i].Source < integrations[j].Source })
+	return integrations
 }
 
-func NewIntegrationManager(strategies map[models.Source]SourceStrategy, feedbackService *feedback.FeedbackService) *IntegrationManager {
-	return &IntegrationManager{strategies: strategies, feedbackService: feedbackService}
+// IntegrationsHandler serves the supported sources: what they support, the
+// schema of their subscriptions' configuration and its secrets.
+func (m *IntegrationManager) IntegrationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.Integrations())
 }
 
 func (m *IntegrationManager) Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error) {
-	strategy, ok := m.strategies[models.Source(sub.Source)]
+	strategy, ok := m.strategies[models.Source(sub.Source)].(Puller)
 	if !ok {
-		return nil, fmt.Errorf("no strategy found for source: %s", sub.Source)
+		return nil, fmt.Errorf("no strategy pulling source: %s", sub.Source)
+	}
+	if err := m.authorize(ctx, sub); err != nil {
+		return nil, err
 	}
 	feedbacks, err := strategy.Pull(ctx, sub)
-	if err != nil {
-		return nil, fmt.Errorf("failed to pull data from source: %v", err)
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		for _, failure := range partial.Failures {
+			m.deadLetter(ctx, sub.TenantID, sub.ID, sub.Source, failure, 1)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to pull data from source: %w", err)
 	}
 
-	err = m.feedbacksToDB(feedbacks)
-	if err != nil {
-		// log error
+	if err := m.archivePayloads(ctx, sub, feedbacks); err != nil {
+		fmt.Printf("Failed to archive the payloads of subscription %s: %v\n", sub.ID, err)
 	}
 
+	m.ingestByPayload(ctx, sub, feedbacks)
+
 	return feedbacks, nil
 }
 
-func (m *IntegrationManager) HandleWebhook(w http.ResponseWriter, r *http.Request, source models.Source) {
+// authorize makes sure the subscription can call its source: it does not
+// need re-auth and its OAuth token, if any, is fresh.
+func (m *IntegrationManager) authorize(ctx context.Context, sub *models.Subscription) error {
+	if sub.NeedsReauth {
+		return fmt.Errorf("subscription %s needs to be connected again", sub.ID)
+	}
+	if m.refreshToken == nil {
+		return nil
+	}
+	return m.refreshToken(ctx, sub)
+}
+
+// oauthAccessToken returns the access token the subscription is connected
+// with, see the oauth package.
+func oauthAccessToken(sub *models.Subscription) string {
+	oauth, _ := sub.Configuration[models.OAuthConfigKey].(map[string]interface{})
+	token, _ := oauth["access_token"].(string)
+	return token
+}
+
+// HandleWebhook handles the calls to /webhook/{source}/{subscription_id}:
+// the subscription must be an active push subscription of the source, and
+// the call signed with its webhook secret. The call is stored in the inbox
+// and acknowledged, the feedback is ingested in the background (see
+// ProcessInbox) and attributed to the subscription's tenant and sub-source.
+func (m *IntegrationManager) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	source := models.Source(r.PathValue("source"))
+	subscriptionID := r.PathValue("subscription_id")
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	strategy, ok := m.strategies[source]
+	if _, pushed := strategy.(Pusher); !ok || !pushed {
+		http.Error(w, fmt.Sprintf("no strategy receiving webhooks of source: %s", source), http.StatusNotFound)
 		return
 	}
 
-	strategy, ok := m.strategies[source]
-	if !ok {
-		http.Error(w, fmt.Sprintf("no strategy found for source: %s", strategy), http.StatusInternalServerError)
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		http.Error(w, "Invalid Subscription ID format", http.StatusBadRequest)
+		return
+	}
+	sub, err := m.subService.GetSubscription(ctx, subscriptionID)
+	if err != nil || sub.Source != source || sub.SubscriptionMode != models.SubscriptionModePush || !sub.Active {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
 		return
 	}
 
-	feedbacks, err := strategy.Push(ctx, r, body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, m.maxWebhookBody))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process webhook: %v", err), http.StatusInternalServerError)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Request body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
-	err = m.feedbacksToDB(feedbacks)
+	if verifier, ok := strategy.(WebhookVerifier); ok {
+		err = verifier.VerifyWebhook(sub, r, body)
+	} else {
+		err = verifyDefaultSignature(sub, r, body)
+	}
 	if err != nil {
-		// log the error
+		// the detail stays in the logs, callers need not learn how the
+		// subscription is configured
+		status := http.StatusUnauthorized
+		if !errors.Is(err, ErrWebhookUnauthorized) {
+			status = http.StatusInternalServerError
+		}
+		fmt.Printf("Failed to verify a webhook call of subscription %s: %v\n", sub.ID, err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	entry := &models.WebhookInboxEntry{
+		TenantID:       sub.TenantID,
+		SubscriptionID: sub.ID,
+		Source:         source,
+		Headers:        inboxHeaders(r.Header),
+		Query:          r.URL.RawQuery,
+		Body:           body,
+	}
+	if err := m.inboxService.Enqueue(ctx, entry); err != nil {
+		// the source retries the calls that are not acknowledged
+		fmt.Printf("Failed to store a webhook call of subscription %s: %v\n", sub.ID, err)
+		http.Error(w, "Failed to store webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if responder, ok := strategy.(WebhookResponder); ok {
+		responder.RespondWebhook(w, sub)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "Discourse webhook received successfully")
+	fmt.Fprintf(w, "%s webhook received successfully", source)
 }
 
-func (m *IntegrationManager) feedbacksToDB(feedbacks []*models.Feedback) error {
+// archivePayloads archives the raw payloads the strategy mapped the
+// feedbacks from and references them from the feedbacks. A payload shared
+// by several feedbacks is stored once.
+func (m *IntegrationManager) archivePayloads(ctx context.Context, sub *models.Subscription, feedbacks []*models.Feedback) error {
 	for _, feedback := range feedbacks {
-		err := m.feedbackService.CreateFeedback(context.Background(), feedback)
-		if err != nil {
-			// log error;
+		payload := feedback.RawPayload
+		if payload == nil {
+			continue
+		}
+		if payload.ID == "" {
+			payload.TenantID, payload.SubscriptionID, payload.Source = sub.TenantID, sub.ID, sub.Source
+			if err := m.archiveService.Store(ctx, payload); err != nil {
+				return err
+			}
 		}
+		feedback.RawPayloadID = payload.ID
 	}
 	return nil
 }
@@ -10,24 +10,73 @@ import (
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
 )
 
-type IntercomIntegration struct{}
+const intercomBaseURL = "https://api.intercom.io"
 
-func NewIntercomStrategy() *IntercomIntegration {
-	return &IntercomIntegration{}
+type IntercomIntegration struct {
+	client *SourceClient
 }
 
-func (s *IntercomIntegration) Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error) {
-	// pull logic for intercom based on the tenant subscription
-	return nil, fmt.Errorf("intercom pull method not implemented yet")
+func NewIntercomStrategy(client *SourceClient) *IntercomIntegration {
+	return &IntercomIntegration{client: client}
 }
 
-func (s *IntercomIntegration) Push(ctx context.Context, r *http.Request, body []byte) ([]*models.Feedback, error) {
-	tenantID := r.URL.Query().Get("tenant_id")
-	subSourceID := r.URL.Query().Get("app_id")
+// TestConnection fetches the Intercom app of the subscription's access
+// token, the one of its configuration or its OAuth token. Without one only
+// the webhook calls are received, there is nothing to test.
+func (s *IntercomIntegration) TestConnection(ctx context.Context, sub *models.Subscription) error {
+	token, _ := sub.Configuration["access_token"].(string)
+	if token == "" {
+		token = oauthAccessToken(sub)
+	}
+	if token == "" {
+		return ErrNotTestable
+	}
+
+	req, err := http.NewRequestWithContext(WithRateLimit(ctx, sub, token), http.MethodGet, intercomBaseURL+"/me", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
 
-	// TODO
-	// check if tenant has a subscriptions
-	// by tenantID, source, subSourceId
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Intercom: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the access token was rejected by Intercom")
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("failed to reach Intercom: %v", &StatusError{StatusCode: resp.StatusCode})
+	}
+	return nil
+}
+
+// SecretFields are the credentials of the Intercom app.
+func (s *IntercomIntegration) SecretFields() []string {
+	return []string{"access_token"}
+}
+
+// Capabilities of Intercom: a conversation is sent again when it changes.
+func (s *IntercomIntegration) Capabilities() models.Capabilities {
+	return models.Capabilities{Edits: true}
+}
+
+func (s *IntercomIntegration) ConfigSchema() *models.ConfigSchema {
+	return &models.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*models.ConfigSchema{
+			"access_token": {Type: "string", Description: "Access token of the Intercom app", WriteOnly: true},
+		},
+		AdditionalProperties: false,
+	}
+}
+
+func (s *IntercomIntegration) Push(ctx context.Context, sub *models.Subscription, r *http.Request, body []byte) ([]*models.Feedback, error) {
+	// the subscription was checked and the call verified by the manager
+	tenantID, subSourceID := sub.TenantID, sub.SubSourceId
 
 	var webhookEvent map[string]interface{}
 	if err := json.Unmarshal(body, &webhookEvent); err != nil {
@@ -42,13 +91,40 @@ func (s *IntercomIntegration) Push(ctx context.Context, r *http.Request, body []
 	return []*models.Feedback{feedback}, nil
 }
 
+// MapPayload maps an archived webhook call again.
+func (s *IntercomIntegration) MapPayload(ctx context.Context, sub *models.Subscription, payload *models.RawPayload) ([]*models.Feedback, error) {
+	if payload.Kind != models.PayloadPush {
+		return nil, fmt.Errorf("intercom only receives pushed data, got a %s payload", payload.Kind)
+	}
+	feedback, err := s.processPushRawData(ctx, sub.TenantID, sub.SubSourceId, payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Feedback{feedback}, nil
+}
+
 func (a *IntercomIntegration) processPushRawData(ctx context.Context, tenantID string, SubSourceID string, data []byte) (*models.Feedback, error) {
+	type intercomAuthor struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
 	var intercomData struct {
 		ID             string `json:"id"`
 		ConversationID string `json:"conversation_id"`
-		Messages       []struct {
-			ID   string `json:"id"`
-			Body string `json:"body"`
+		CreatedAt      int64  `json:"created_at"`
+		Source         struct {
+			Author intercomAuthor `json:"author"`
+			URL    string         `json:"url"`
+		} `json:"source"`
+		ConversationRating *struct {
+			Rating *float64 `json:"rating"`
+		} `json:"conversation_rating"`
+		Messages []struct {
+			ID        string         `json:"id"`
+			Body      string         `json:"body"`
+			Author    intercomAuthor `json:"author"`
+			CreatedAt int64          `json:"created_at"`
 		} `json:"conversation_parts"`
 	}
 
@@ -60,8 +136,12 @@ func (a *IntercomIntegration) processPushRawData(ctx context.Context, tenantID s
 	for i, msg := range intercomData.Messages {
 		messages[i] = models.Message{
 			ID:      msg.ID,
+			Author:  msg.Author.ID,
 			Content: msg.Body,
 		}
+		if msg.CreatedAt > 0 {
+			messages[i].Timestamp = time.Unix(msg.CreatedAt, 0).UTC()
+		}
 	}
 
 	content := models.ConversationContent{
@@ -69,17 +149,31 @@ func (a *IntercomIntegration) processPushRawData(ctx context.Context, tenantID s
 		Messages:       messages,
 	}
 
-	return &models.Feedback{
+	feedback := &models.Feedback{
 		ID:          intercomData.ID,
 		TenantID:    tenantID,
 		SubSourceID: SubSourceID,
 		Source:      a.GetSourceName(),
 		SourceType:  a.GetSourceType(),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Author:      intercomData.Source.Author.ID,
+		URL:         intercomData.Source.URL,
+		IngestedAt:  time.Now(),
 		Metadata:    map[string]interface{}{},
 		Content:     content,
-	}, nil
+	}
+
+	if intercomData.CreatedAt > 0 {
+		createdAt := time.Unix(intercomData.CreatedAt, 0).UTC()
+		feedback.SourceCreatedAt = &createdAt
+	}
+	if intercomData.ConversationRating != nil {
+		feedback.Rating = intercomData.ConversationRating.Rating
+	}
+	if intercomData.Source.Author.Name != "" {
+		feedback.Metadata["author_name"] = intercomData.Source.Author.Name
+	}
+
+	return feedback, nil
 }
 
 func (a *IntercomIntegration) GetSourceName() models.Source {
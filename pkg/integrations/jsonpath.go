@@ -0,0 +1,145 @@
+package integrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath expression, the subset webhook mappings
+// need: the root $, child keys (.key or ['key']), array indexes ([0],
+// negative from the end) and wildcards ([*] or .*).
+type jsonPath []pathSegment
+
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func compileJSONPath(expr string) (jsonPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("invalid path %q: expected it to start with $", expr)
+	}
+
+	var path jsonPath
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", expr)
+			}
+			if key == "*" {
+				path = append(path, pathSegment{wildcard: true})
+			} else {
+				path = append(path, pathSegment{key: key})
+			}
+			rest = rest[end:]
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated [", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				path = append(path, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				path = append(path, pathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: unsupported selector [%s]", expr, inner)
+				}
+				path = append(path, pathSegment{index: index, isIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	return path, nil
+}
+
+// decodeJSON decodes a document for paths to select from. Numbers are kept
+// as json.Number, an ID of 20 digits would lose its last ones as a float64.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after the JSON value")
+	}
+	return document, nil
+}
+
+// eval returns the values the path selects in the document.
+func (p jsonPath) eval(document interface{}) []interface{} {
+	values := []interface{}{document}
+	for _, segment := range p {
+		var next []interface{}
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if segment.wildcard {
+					// in key order, the results are stable
+					keys := make([]string, 0, len(v))
+					for key := range v {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						next = append(next, v[key])
+					}
+				} else if item, ok := v[segment.key]; ok && !segment.isIndex {
+					next = append(next, item)
+				}
+			case []interface{}:
+				switch {
+				case segment.wildcard:
+					next = append(next, v...)
+				case segment.isIndex:
+					index := segment.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// first returns the first non-null value the path selects.
+func (p jsonPath) first(document interface{}) (interface{}, bool) {
+	for _, value := range p.eval(document) {
+		if value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
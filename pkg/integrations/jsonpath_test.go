@@ -0,0 +1,60 @@
+package integrations
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	document, err := decodeJSON([]byte(`{"id": 12345678901234567890, "items": [{"text": "a"}, {"text": "b"}, {"note": "c"}],
+		"user": {"name": "ann", "first name": "Ann"}}`))
+	if err != nil {
+		t.Fatalf("decodeJSON: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want []interface{}
+	}{
+		{"$.id", []interface{}{json.Number("12345678901234567890")}},
+		{"$.items[*].text", []interface{}{"a", "b"}},
+		{"$.items.*.text", []interface{}{"a", "b"}},
+		{"$.items[0].text", []interface{}{"a"}},
+		{"$.items[-1].note", []interface{}{"c"}},
+		{"$.items[3].text", nil},
+		{"$.user['first name']", []interface{}{"Ann"}},
+		{`$["user"].name`, []interface{}{"ann"}},
+		{"$.user.*", []interface{}{"Ann", "ann"}},
+		{"$.user[0]", nil},
+		{"$.missing.text", nil},
+	}
+	for _, tt := range tests {
+		path, err := compileJSONPath(tt.expr)
+		if err != nil {
+			t.Fatalf("compileJSONPath(%q): %v", tt.expr, err)
+		}
+		if got := path.eval(document); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{"items", "$.", "$.items[0", "$.items[first]", "$items"} {
+		if _, err := compileJSONPath(expr); err == nil {
+			t.Errorf("%q was compiled", expr)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	for _, data := range []string{"", "{", `{"a": 1} {"b": 2}`, `[1] x`} {
+		if _, err := decodeJSON([]byte(data)); err == nil {
+			t.Errorf("%q was decoded", data)
+		}
+	}
+	if document, err := decodeJSON([]byte(" [1, 2.5]\n")); err != nil || !reflect.DeepEqual(document, []interface{}{json.Number("1"), json.Number("2.5")}) {
+		t.Errorf("got %v, %v", document, err)
+	}
+}
@@ -1,3 +1,273 @@
 package integrations
 
-// implement playstore integratoin
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const playstoreBaseURL = "https://androidpublisher.googleapis.com/androidpublisher/v3/applications"
+
+// playstorePageSize is the number of reviews fetched per call, the most the
+// API returns
+const playstorePageSize = 100
+
+// PlaystoreIntegration pulls the reviews of an app from the Google Play
+// Developer API, with the OAuth token the subscription is connected with.
+// The API only returns the reviews written or edited in the last week.
+type PlaystoreIntegration struct {
+	client *SourceClient
+}
+
+func NewPlaystoreStrategy(client *SourceClient) *PlaystoreIntegration {
+	return &PlaystoreIntegration{client: client}
+}
+
+// playstoreTime is a protobuf timestamp, the seconds are a string.
+type playstoreTime struct {
+	Seconds json.Number `json:"seconds"`
+	Nanos   int64       `json:"nanos"`
+}
+
+func (t *playstoreTime) time() *time.Time {
+	if t == nil {
+		return nil
+	}
+	seconds, err := t.Seconds.Int64()
+	if err != nil {
+		return nil
+	}
+	value := time.Unix(seconds, t.Nanos).UTC()
+	return &value
+}
+
+type playstoreReview struct {
+	ReviewID   string `json:"reviewId"`
+	AuthorName string `json:"authorName"`
+	Comments   []struct {
+		UserComment *struct {
+			Text             string         `json:"text"`
+			LastModified     *playstoreTime `json:"lastModified"`
+			StarRating       int            `json:"starRating"`
+			ReviewerLanguage string         `json:"reviewerLanguage"`
+			Device           string         `json:"device"`
+			AndroidOSVersion int            `json:"androidOsVersion"`
+			AppVersionName   string         `json:"appVersionName"`
+			ThumbsUpCount    int            `json:"thumbsUpCount"`
+			ThumbsDownCount  int            `json:"thumbsDownCount"`
+		} `json:"userComment"`
+		DeveloperComment *struct {
+			Text         string         `json:"text"`
+			LastModified *playstoreTime `json:"lastModified"`
+		} `json:"developerComment"`
+	} `json:"comments"`
+}
+
+func (s *PlaystoreIntegration) Pull(ctx context.Context, sub *models.Subscription) ([]*models.Feedback, error) {
+	token, packageName, err := playstoreCredentials(sub)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithRateLimit(ctx, sub, token)
+	sample, sampled := sampleOf(ctx)
+
+	var (
+		feedbacks []*models.Feedback
+		failures  []*PayloadFailure
+		pageToken string
+	)
+	for {
+		query := url.Values{"maxResults": {strconv.Itoa(playstorePageSize)}}
+		if pageToken != "" {
+			query.Set("token", pageToken)
+		}
+		body, err := s.get(ctx, token, fmt.Sprintf("%s/%s/reviews?%s", playstoreBaseURL, url.PathEscape(packageName), query.Encode()))
+		if err != nil {
+			return feedbacks, fmt.Errorf("failed to fetch reviews: %w", err)
+		}
+
+		var page struct {
+			Reviews         []json.RawMessage `json:"reviews"`
+			TokenPagination *struct {
+				NextPageToken string `json:"nextPageToken"`
+			} `json:"tokenPagination"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return feedbacks, fmt.Errorf("failed to unmarshal reviews: %v", err)
+		}
+
+		for _, raw := range page.Reviews {
+			feedback, modified, err := s.mapReview(sub, packageName, raw)
+			if err != nil {
+				failures = append(failures, &PayloadFailure{Stage: models.StageMap, Kind: models.PayloadPull, Data: raw, Err: err})
+				continue
+			}
+			// seen by the previous pull, unless edited since
+			if modified != nil && !modified.After(sub.LastPulled) {
+				continue
+			}
+			feedbacks = append(feedbacks, feedback)
+		}
+
+		if sampled && len(feedbacks) >= sample {
+			break
+		}
+		if page.TokenPagination == nil || page.TokenPagination.NextPageToken == "" {
+			break
+		}
+		pageToken = page.TokenPagination.NextPageToken
+	}
+
+	if len(failures) > 0 {
+		return feedbacks, &PartialError{Failures: failures}
+	}
+	return feedbacks, nil
+}
+
+// TestConnection fetches one review of the app.
+func (s *PlaystoreIntegration) TestConnection(ctx context.Context, sub *models.Subscription) error {
+	token, packageName, err := playstoreCredentials(sub)
+	if err != nil {
+		return err
+	}
+	_, err = s.get(WithRateLimit(ctx, sub, token), token, fmt.Sprintf("%s/%s/reviews?maxResults=1", playstoreBaseURL, url.PathEscape(packageName)))
+	return err
+}
+
+// MapPayload maps an archived review again.
+func (s *PlaystoreIntegration) MapPayload(ctx context.Context, sub *models.Subscription, payload *models.RawPayload) ([]*models.Feedback, error) {
+	if payload.Kind != models.PayloadPull {
+		return nil, fmt.Errorf("the play store only pulls data, got a %s payload", payload.Kind)
+	}
+	packageName, _ := sub.Configuration["package_name"].(string)
+	feedback, _, err := s.mapReview(sub, packageName, payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Feedback{feedback}, nil
+}
+
+func (s *PlaystoreIntegration) get(ctx context.Context, token, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// mapReview maps a review to feedback, it also returns when the review was
+// last modified.
+func (s *PlaystoreIntegration) mapReview(sub *models.Subscription, packageName string, raw []byte) (*models.Feedback, *time.Time, error) {
+	var review playstoreReview
+	if err := json.Unmarshal(raw, &review); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal review: %v", err)
+	}
+	if review.ReviewID == "" {
+		return nil, nil, fmt.Errorf("review without an ID")
+	}
+
+	feedback := &models.Feedback{
+		ID:          review.ReviewID,
+		TenantID:    sub.TenantID,
+		SubSourceID: sub.SubSourceId,
+		Source:      s.GetSourceName(),
+		SourceType:  s.GetSourceType(),
+		Author:      review.AuthorName,
+		URL:         fmt.Sprintf("https://play.google.com/store/apps/details?id=%s&reviewId=%s", url.QueryEscape(packageName), url.QueryEscape(review.ReviewID)),
+		IngestedAt:  time.Now(),
+		Metadata:    map[string]interface{}{"package_name": packageName},
+		RawPayload:  &models.RawPayload{Kind: models.PayloadPull, Data: raw},
+	}
+
+	var modified *time.Time
+	content := map[string]interface{}{}
+	for _, comment := range review.Comments {
+		if user := comment.UserComment; user != nil {
+			content["body"] = strings.TrimSpace(user.Text)
+			modified = user.LastModified.time()
+			feedback.SourceCreatedAt = modified
+			if user.StarRating > 0 {
+				rating := float64(user.StarRating)
+				feedback.Rating = &rating
+			}
+			// e.g. en_GB, the language is the first part
+			language := user.ReviewerLanguage
+			if i := strings.IndexAny(language, "_-"); i >= 0 {
+				language = language[:i]
+			}
+			feedback.Language = strings.ToLower(language)
+			feedback.Metadata["device"] = user.Device
+			feedback.Metadata["android_os_version"] = user.AndroidOSVersion
+			feedback.Metadata["app_version_name"] = user.AppVersionName
+			feedback.Metadata["thumbs_up_count"] = user.ThumbsUpCount
+			feedback.Metadata["thumbs_down_count"] = user.ThumbsDownCount
+		}
+		if developer := comment.DeveloperComment; developer != nil {
+			feedback.Metadata["developer_reply"] = developer.Text
+		}
+	}
+	if _, ok := content["body"]; !ok {
+		return nil, nil, fmt.Errorf("review %s has no user comment", review.ReviewID)
+	}
+	feedback.Content = content
+
+	return feedback, modified, nil
+}
+
+// playstoreCredentials returns the OAuth access token and the package name
+// of the app of the subscription.
+func playstoreCredentials(sub *models.Subscription) (string, string, error) {
+	packageName, _ := sub.Configuration["package_name"].(string)
+	if packageName == "" {
+		return "", "", fmt.Errorf("package_name is required")
+	}
+	token := oauthAccessToken(sub)
+	if token == "" {
+		return "", "", fmt.Errorf("subscription %s is not connected, see /oauth/%s/start", sub.ID, sub.Source)
+	}
+	return token, packageName, nil
+}
+
+// Capabilities of the Play Store: an edited review is pulled again.
+func (s *PlaystoreIntegration) Capabilities() models.Capabilities {
+	return models.Capabilities{Edits: true}
+}
+
+func (s *PlaystoreIntegration) ConfigSchema() *models.ConfigSchema {
+	return &models.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*models.ConfigSchema{
+			"package_name": {Type: "string", Description: "Package name of the app, e.g. com.example.app", MinLength: 1},
+		},
+		Required:             []string{"package_name"},
+		AdditionalProperties: false,
+	}
+}
+
+func (a *PlaystoreIntegration) GetSourceName() models.Source {
+	return models.SourcePlaystore
+}
+
+func (a *PlaystoreIntegration) GetSourceType() models.SourceType {
+	return models.STReviews
+}
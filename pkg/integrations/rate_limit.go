@@ -0,0 +1,74 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+)
+
+// defaultRateLimits keep the calls within the quotas of the sources: 60
+// requests a minute for Discourse, 10,000 a minute for an Intercom app and
+// 200,000 a day for the Play Developer API.
+var defaultRateLimits = map[models.Source]models.RateLimit{
+	models.SourceDiscourse: {RequestsPerSecond: 1, Burst: 10},
+	models.SourceIntercom:  {RequestsPerSecond: 150, Burst: 300},
+	models.SourcePlaystore: {RequestsPerSecond: 2, Burst: 20},
+}
+
+// rateLimitKey is the configuration key of a subscription's own limit,
+// e.g. "rate_limit": {"requests_per_second": 0.5, "burst": 5}
+const rateLimitKey = "rate_limit"
+
+type rateLimitContextKey struct{}
+
+// rateLimited is the bucket the calls of a context are limited by.
+type rateLimited struct {
+	source models.Source
+	key    string
+	limit  *models.RateLimit // the subscription's own, if any
+}
+
+// WithRateLimit limits the calls made with the context by the source client
+// to those of the subscription's source and credential, all the calls to
+// the source with the credential share one bucket. The subscription's own
+// limit, if configured, replaces the source's.
+func WithRateLimit(ctx context.Context, sub *models.Subscription, credential string) context.Context {
+	limited := &rateLimited{source: sub.Source, key: ratelimit.Key(sub.Source, credential)}
+	if limit, ok, err := subscriptionRateLimit(sub.Configuration); ok && err == nil {
+		limited.limit = &limit
+	}
+	return context.WithValue(ctx, rateLimitContextKey{}, limited)
+}
+
+func rateLimitOf(ctx context.Context) *rateLimited {
+	limited, _ := ctx.Value(rateLimitContextKey{}).(*rateLimited)
+	return limited
+}
+
+// subscriptionRateLimit reads the subscription's own limit from its
+// configuration.
+func subscriptionRateLimit(configuration map[string]interface{}) (models.RateLimit, bool, error) {
+	value, ok := configuration[rateLimitKey]
+	if !ok {
+		return models.RateLimit{}, false, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return models.RateLimit{}, false, fmt.Errorf("invalid %s: %v", rateLimitKey, err)
+	}
+	limit := models.RateLimit{Burst: 1}
+	if err := json.Unmarshal(data, &limit); err != nil {
+		return models.RateLimit{}, false, fmt.Errorf("invalid %s, expected {\"requests_per_second\": ..., \"burst\": ...}", rateLimitKey)
+	}
+	if limit.RequestsPerSecond <= 0 {
+		return models.RateLimit{}, false, fmt.Errorf("invalid %s, requests_per_second must be positive", rateLimitKey)
+	}
+	if limit.Burst < 1 {
+		return models.RateLimit{}, false, fmt.Errorf("invalid %s, burst must be positive", rateLimitKey)
+	}
+	return limit, true, nil
+}
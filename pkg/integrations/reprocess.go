@@ -0,0 +1,239 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// MaxReportedChanges caps the changes and errors listed in a reprocessing
+// result, the counts cover them all
+const MaxReportedChanges = 1000
+
+// Reprocess maps the archived payloads of the request again with the
+// current strategy and saves the feedback, replacing the stored one. A dry
+// run saves nothing: the feedback goes through a preview of the pipeline
+// and the result lists the fields that would change.
+func (m *IntegrationManager) Reprocess(ctx context.Context, req *models.ReprocessRequest) (*models.ReprocessResult, error) {
+	strategy, ok := m.strategies[req.Source]
+	if !ok {
+		return nil, fmt.Errorf("no strategy found for source: %s", req.Source)
+	}
+	mapper, ok := strategy.(PayloadMapper)
+	if !ok {
+		return nil, fmt.Errorf("%s payloads cannot be reprocessed", req.Source)
+	}
+
+	result := &models.ReprocessResult{DryRun: req.DryRun, Changes: []models.FeedbackDiff{}, Errors: []models.PayloadError{}}
+	subs := map[string]*models.Subscription{}
+
+	err := m.archiveService.Each(ctx, req, func(payload *models.RawPayload) error {
+		result.Payloads++
+		if err := m.reprocessPayload(ctx, mapper, subs, payload, result); err != nil {
+			result.Failed++
+			if len(result.Errors) < MaxReportedChanges {
+				result.Errors = append(result.Errors, models.PayloadError{PayloadID: payload.ID, Error: err.Error()})
+			}
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *IntegrationManager) reprocessPayload(ctx context.Context, mapper PayloadMapper, subs map[string]*models.Subscription, payload *models.RawPayload, result *models.ReprocessResult) error {
+	sub, ok := subs[payload.SubscriptionID]
+	if !ok {
+		var err error
+		if sub, err = m.subService.GetSubscription(ctx, payload.SubscriptionID); err != nil {
+			return err
+		}
+		subs[payload.SubscriptionID] = sub
+	}
+
+	feedbacks, err := mapper.MapPayload(ctx, sub, payload)
+	if err != nil {
+		return err
+	}
+	for _, feedback := range feedbacks {
+		feedback.RawPayload = nil
+		feedback.RawPayloadID = payload.ID
+	}
+
+	process := m.pipelineService.Process
+	if result.DryRun {
+		process = m.pipelineService.Preview
+	}
+	feedbacks, stageErrors, err := process(ctx, feedbacks)
+	if err != nil {
+		return fmt.Errorf("failed to run the enrichment pipeline: %v", err)
+	}
+	for _, stageError := range stageErrors {
+		fmt.Printf("Enrichment pipeline: %v\n", stageError)
+	}
+	if len(feedbacks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(feedbacks))
+	for i, feedback := range feedbacks {
+		ids[i] = feedback.ID
+	}
+	stored, err := m.feedbackService.GetFeedbacksByKey(ctx, payload.TenantID, payload.Source, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, feedback := range feedbacks {
+		diff := models.FeedbackDiff{FeedbackID: feedback.ID, Source: feedback.Source, PayloadID: payload.ID}
+		if old, ok := stored[feedback.ID]; ok {
+			diff.Fields = diffFeedback(old, feedback)
+			if len(diff.Fields) == 0 {
+				result.Unchanged++
+				continue
+			}
+			result.Changed++
+		} else {
+			diff.New = true
+			result.New++
+		}
+		if len(result.Changes) < MaxReportedChanges {
+			result.Changes = append(result.Changes, diff)
+		}
+	}
+	result.Feedbacks += len(feedbacks)
+
+	if result.DryRun {
+		return nil
+	}
+	if _, _, err := m.feedbackService.UpsertFeedbacks(ctx, feedbacks); err != nil {
+		return err
+	}
+	return nil
+}
+
+// diffFeedback returns the fields a strategy or the pipeline set that differ
+// between the stored feedback and its new version.
+func diffFeedback(old, current *models.Feedback) map[string]models.FieldChange {
+	fields := []struct {
+		name         string
+		old, current interface{}
+	}{
+		{"sub_source_id", old.SubSourceID, current.SubSourceID},
+		{"source_type", old.SourceType, current.SourceType},
+		{"language", old.Language, current.Language},
+		{"author", old.Author, current.Author},
+		{"url", old.URL, current.URL},
+		{"rating", old.Rating, current.Rating},
+		{"source_created_at", old.SourceCreatedAt, current.SourceCreatedAt},
+		{"metadata", old.Metadata, current.Metadata},
+		{"content", old.Content, current.Content},
+		{"sentiment", old.Sentiment, current.Sentiment},
+	}
+
+	changes := map[string]models.FieldChange{}
+	for _, field := range fields {
+		oldValue, newValue := normalize(field.old), normalize(field.current)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[field.name] = models.FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
+
+// normalize brings a value to its JSON form, the way it is stored, so a
+// struct compares equal to the map read back from the database.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case map[string]interface{}:
+		if len(v) == 0 {
+			// no metadata is stored as null or {}
+			return nil
+		}
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// ReprocessHandler maps the tenant's archived payloads of a source received
+// between from and to (RFC 3339, optional) again. With dry_run=true nothing
+// is saved and the response lists the changes.
+func (m *IntegrationManager) ReprocessHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	req := &models.ReprocessRequest{
+		TenantID: query.Get("tenant_id"),
+		Source:   models.Source(query.Get("source")),
+	}
+	if req.TenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(req.TenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" {
+		http.Error(w, "Source is required", http.StatusBadRequest)
+		return
+	}
+
+	for name, value := range map[string]*time.Time{"from": &req.From, "to": &req.To} {
+		if s := query.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s, expected an RFC 3339 time", name), http.StatusBadRequest)
+				return
+			}
+			*value = t
+		}
+	}
+	if s := query.Get("dry_run"); s != "" {
+		dryRun, err := strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, "Invalid dry_run, expected a boolean", http.StatusBadRequest)
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	result, err := m.Reprocess(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reprocess payloads: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
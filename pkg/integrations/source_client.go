@@ -0,0 +1,397 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+)
+
+// ErrCircuitOpen is returned without calling a host whose circuit is open,
+// after too many consecutive failures.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// ErrResponseTooLarge is returned for a response over the size cap.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// StatusError is returned by Get for a response that is not a 2xx.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.StatusCode)
+}
+
+// Unauthorized reports whether the source refused the credentials of the
+// call.
+func (e *StatusError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// SourceClientConfig configures the calls to the sources.
+type SourceClientConfig struct {
+	// MaxRetries is the number of times a failed call is retried: network
+	// errors, 429 and 5xx responses
+	MaxRetries int
+	// the first retry waits up to BaseDelay, each one after up to twice the
+	// previous, up to MaxDelay (full jitter)
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxRetryAfter is the longest Retry-After waited for, the call fails
+	// when a source asks for more
+	MaxRetryAfter time.Duration
+	// Timeout bounds each attempt
+	Timeout time.Duration
+	// MaxResponseBytes caps the size of response bodies
+	MaxResponseBytes int64
+	UserAgent        string
+	// after FailureThreshold consecutive failures the circuit of a host
+	// opens, calls fail at once for OpenDuration, then one trial call is let
+	// through: the circuit closes if it succeeds
+	FailureThreshold int
+	OpenDuration     time.Duration
+	// RateLimits are the limits of the sources' calls made with a context of
+	// WithRateLimit, a source without one is not limited
+	RateLimits map[models.Source]models.RateLimit
+}
+
+// DefaultSourceClientConfig returns the configuration used unless told
+// otherwise.
+func DefaultSourceClientConfig() SourceClientConfig {
+	return SourceClientConfig{
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         30 * time.Second,
+		MaxRetryAfter:    2 * time.Minute,
+		Timeout:          30 * time.Second,
+		MaxResponseBytes: 10 << 20,
+		UserAgent:        "feedback-ingestion-system",
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+		RateLimits:       defaultRateLimits,
+	}
+}
+
+// SourceClientConfigFrom returns the default configuration with the
+// settings of the service's configuration.
+func SourceClientConfigFrom(cfg *config.Config) SourceClientConfig {
+	clientConfig := DefaultSourceClientConfig()
+	clientConfig.MaxRetries = cfg.SourceMaxRetries
+	clientConfig.Timeout = time.Duration(cfg.SourceTimeoutSeconds) * time.Second
+	clientConfig.MaxResponseBytes = int64(cfg.SourceMaxResponseBytes)
+
+	limits, err := ratelimit.ParseLimits(cfg.SourceRateLimits)
+	if err != nil {
+		fmt.Printf("Ignoring SOURCE_RATE_LIMITS: %v\n", err)
+	}
+	if len(limits) > 0 {
+		clientConfig.RateLimits = map[models.Source]models.RateLimit{}
+		for source, limit := range defaultRateLimits {
+			clientConfig.RateLimits[source] = limit
+		}
+		for source, limit := range limits {
+			clientConfig.RateLimits[source] = limit
+		}
+	}
+	return clientConfig
+}
+
+// SourceClient is the HTTP client the strategies call the sources with. It
+// retries failed calls with backoff, honours Retry-After, breaks the circuit
+// of failing hosts, keeps the calls within the rate limits and counts every
+// call in the source_http_* metrics.
+type SourceClient struct {
+	config  SourceClientConfig
+	client  *http.Client
+	limiter *ratelimit.RateLimiter
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewSourceClient returns a client limiting the calls with the limiter, or
+// not at all if it is nil.
+func NewSourceClient(config SourceClientConfig, limiter *ratelimit.RateLimiter) *SourceClient {
+	return &SourceClient{
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		limiter:  limiter,
+		breakers: map[string]*breaker{},
+	}
+}
+
+// Get fetches the URL and returns the response body, a response that is not
+// a 2xx fails with a *StatusError.
+func (c *SourceClient) Get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Do sends the request, retrying it on failure if it can be sent again
+// (a GET or HEAD, or a body with GetBody). Each attempt waits for the rate
+// limit of the request's context, see WithRateLimit. The body of the
+// response returned is read already, within the size cap.
+func (c *SourceClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" && c.config.UserAgent != "" {
+		req.Header.Set("User-Agent", c.config.UserAgent)
+	}
+	host := req.URL.Host
+	circuit := c.breaker(host)
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Body == nil || req.GetBody != nil
+
+	limited, limit := c.rateLimit(req.Context())
+
+	for attempt := 0; ; attempt++ {
+		if limited != nil {
+			if err := c.limiter.Wait(req.Context(), limited.source, limited.key, limit); err != nil {
+				return nil, err
+			}
+		}
+		if !circuit.allow() {
+			c.count(host, "circuit_open", 0)
+			return nil, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		start := time.Now()
+		resp, err := c.attempt(req)
+		elapsed := time.Since(start)
+		if req.Context().Err() != nil {
+			// given up by the caller, says nothing of the host
+			circuit.release()
+			return nil, req.Context().Err()
+		}
+
+		var status string
+		switch {
+		case errors.Is(err, ErrResponseTooLarge):
+			// the source answered, it is not failing
+			circuit.record(true)
+			c.count(host, "too_large", elapsed)
+			return nil, err
+		case err != nil:
+			status = "error"
+		default:
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		c.count(host, status, elapsed)
+
+		throttled := err == nil && resp.StatusCode == http.StatusTooManyRequests
+		failed := err != nil || throttled || resp.StatusCode >= 500
+		if throttled {
+			// the host answered, only the quota of the caller is spent
+			circuit.release()
+		} else {
+			circuit.record(!failed)
+		}
+		if !failed {
+			return resp, nil
+		}
+
+		wait := c.backoff(attempt)
+		retryAfter, hasRetryAfter := time.Duration(0), false
+		if resp != nil {
+			retryAfter, hasRetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if hasRetryAfter {
+				wait = retryAfter
+			}
+			if limited != nil && throttled {
+				// the quota is spent, hold back the other calls with the
+				// credential too. The next attempt waits for the bucket
+				// like them, not on top of it.
+				c.limiter.Backoff(req.Context(), limited.key, limit, wait)
+				wait = 0
+			}
+		}
+		if !retryable || attempt >= c.config.MaxRetries || (hasRetryAfter && retryAfter > c.config.MaxRetryAfter) {
+			return resp, err
+		}
+
+		metrics.IncCounter("source_http_retries_total", "Calls to the sources retried.", metrics.Labels{"host": host}, 1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once and reads the response body.
+func (c *SourceClient) attempt(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if int64(len(body)) > c.config.MaxResponseBytes {
+		return nil, fmt.Errorf("%s: %w, over %d bytes", req.URL.Host, ErrResponseTooLarge, c.config.MaxResponseBytes)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// backoff is the wait before the retry following the attempt, with full
+// jitter.
+func (c *SourceClient) backoff(attempt int) time.Duration {
+	ceiling := c.config.BaseDelay
+	for i := 0; i < attempt && ceiling < c.config.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if ceiling > c.config.MaxDelay {
+		ceiling = c.config.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// rateLimit returns the bucket and limit of the context, nil if its calls
+// are not limited.
+func (c *SourceClient) rateLimit(ctx context.Context) (*rateLimited, models.RateLimit) {
+	limited := rateLimitOf(ctx)
+	if limited == nil || c.limiter == nil {
+		return nil, models.RateLimit{}
+	}
+	if limited.limit != nil {
+		return limited, *limited.limit
+	}
+	limit, ok := c.config.RateLimits[limited.source]
+	if !ok {
+		return nil, models.RateLimit{}
+	}
+	return limited, limit
+}
+
+func (c *SourceClient) count(host, status string, elapsed time.Duration) {
+	labels := metrics.Labels{"host": host, "status": status}
+	metrics.IncCounter("source_http_requests_total", "Calls to the sources, by response status.", labels, 1)
+	metrics.IncCounter("source_http_request_duration_seconds_total", "Time spent calling the sources.", labels, elapsed.Seconds())
+}
+
+func (c *SourceClient) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{host: host, threshold: c.config.FailureThreshold, openDuration: c.config.OpenDuration}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// breaker is the circuit breaker of a host.
+type breaker struct {
+	host         string
+	threshold    int
+	openDuration time.Duration
+
+	mu        sync.Mutex
+	failures  int // consecutive
+	openUntil time.Time
+	trial     bool // a call is let through the open circuit
+}
+
+// allow reports whether a call can be made: the circuit is closed, or it
+// has been open for long enough and no other trial call is in flight.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// release ends a trial call without a verdict.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := !b.openUntil.IsZero()
+	b.trial = false
+	if success {
+		b.failures = 0
+		b.openUntil = time.Time{}
+	} else {
+		b.failures++
+		if wasOpen || b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.openDuration)
+		}
+	}
+
+	open := 0.0
+	if !b.openUntil.IsZero() {
+		open = 1
+	}
+	metrics.SetGauge("source_http_circuit_open", "Whether the circuit of a source host is open.", metrics.Labels{"host": b.host}, open)
+}
@@ -0,0 +1,288 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+)
+
+// testClientConfig retries at once and opens the circuit after threshold
+// failures.
+func testClientConfig(maxRetries, threshold int) SourceClientConfig {
+	config := DefaultSourceClientConfig()
+	config.MaxRetries = maxRetries
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 2 * time.Millisecond
+	config.MaxRetryAfter = 2 * time.Second
+	config.FailureThreshold = threshold
+	config.OpenDuration = 50 * time.Millisecond
+	return config
+}
+
+// testServer answers each call with the status returned by respond for the
+// number of the call, counting from 1.
+func testServer(t *testing.T, respond func(call int32, w http.ResponseWriter) int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := respond(atomic.AddInt32(&calls, 1), w)
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestSourceClientRetriesServerErrors(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		if call < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	client := NewSourceClient(testClientConfig(3, 10), nil)
+
+	body, err := client.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(body) != "ok" || *calls != 3 {
+		t.Errorf("got body %q after %d calls, want ok after 3", body, *calls)
+	}
+}
+
+func TestSourceClientGivesUpAfterMaxRetries(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		return http.StatusInternalServerError
+	})
+	client := NewSourceClient(testClientConfig(2, 10), nil)
+
+	_, err := client.Get(context.Background(), server.URL)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got error %v, want a 500 StatusError", err)
+	}
+	if *calls != 3 {
+		t.Errorf("got %d calls, want 3", *calls)
+	}
+}
+
+func TestSourceClientDoesNotRetryClientErrors(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		return http.StatusNotFound
+	})
+	client := NewSourceClient(testClientConfig(3, 10), nil)
+
+	if _, err := client.Get(context.Background(), server.URL); err == nil {
+		t.Fatalf("a 404 did not fail")
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, want 1", *calls)
+	}
+}
+
+func TestSourceClientDoesNotResendUnreplayableBody(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		return http.StatusBadGateway
+	})
+	client := NewSourceClient(testClientConfig(3, 10), nil)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	// a reader http.NewRequest cannot replay
+	req.Body = io.NopCloser(strings.NewReader(`{"a": 1}`))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadGateway || *calls != 1 {
+		t.Errorf("got status %d after %d calls, want 502 after 1", resp.StatusCode, *calls)
+	}
+}
+
+func TestSourceClientHonoursRetryAfter(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		if call == 1 {
+			w.Header().Set("Retry-After", "1")
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	client := NewSourceClient(testClientConfig(3, 10), nil)
+
+	start := time.Now()
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 2*time.Second {
+		t.Errorf("waited %s, want the Retry-After of 1s", elapsed)
+	}
+	if *calls != 2 {
+		t.Errorf("got %d calls, want 2", *calls)
+	}
+}
+
+func TestSourceClientFailsOnLongRetryAfter(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		w.Header().Set("Retry-After", "3600")
+		return http.StatusTooManyRequests
+	})
+	client := NewSourceClient(testClientConfig(3, 10), nil)
+
+	_, err := client.Get(context.Background(), server.URL)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got error %v, want a 429 StatusError", err)
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, want 1", *calls)
+	}
+}
+
+func TestSourceClientOpensCircuit(t *testing.T) {
+	var healthy atomic.Bool
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		if healthy.Load() {
+			return http.StatusOK
+		}
+		return http.StatusInternalServerError
+	})
+	client := NewSourceClient(testClientConfig(0, 2), nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(ctx, server.URL); err == nil {
+			t.Fatalf("call %d did not fail", i+1)
+		}
+	}
+	if _, err := client.Get(ctx, server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got error %v, want ErrCircuitOpen", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("the open circuit let a call through: %d calls", *calls)
+	}
+
+	// the trial call after OpenDuration closes it
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(ctx, server.URL); err != nil {
+			t.Fatalf("call %d after the circuit closed: %v", i+1, err)
+		}
+	}
+}
+
+func TestSourceClientTooManyRequestsKeepsCircuitClosed(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		return http.StatusTooManyRequests
+	})
+	client := NewSourceClient(testClientConfig(0, 1), nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(context.Background(), server.URL)
+		if errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: a 429 opened the circuit", i+1)
+		}
+	}
+	if *calls != 3 {
+		t.Errorf("got %d calls, want 3", *calls)
+	}
+}
+
+func TestSourceClientResponseTooLarge(t *testing.T) {
+	server, calls := testServer(t, func(call int32, w http.ResponseWriter) int {
+		return http.StatusOK
+	})
+	config := testClientConfig(3, 10)
+	config.MaxResponseBytes = 1
+	client := NewSourceClient(config, nil)
+
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("got error %v, want ErrResponseTooLarge", err)
+	}
+	if *calls != 1 {
+		t.Errorf("got %d calls, want 1", *calls)
+	}
+}
+
+func TestSourceClientRateLimitOfContext(t *testing.T) {
+	client := NewSourceClient(DefaultSourceClientConfig(), ratelimit.NewRateLimiter(nil))
+	sub := &models.Subscription{Source: models.SourceDiscourse, Configuration: map[string]interface{}{}}
+
+	if limited, _ := client.rateLimit(context.Background()); limited != nil {
+		t.Errorf("a context without a limit is limited")
+	}
+
+	limited, limit := client.rateLimit(WithRateLimit(context.Background(), sub, "key"))
+	if limited == nil || limit != defaultRateLimits[models.SourceDiscourse] {
+		t.Errorf("got limit %+v, want the Discourse default", limit)
+	}
+	if limited.key != ratelimit.Key(models.SourceDiscourse, "key") {
+		t.Errorf("got bucket %q", limited.key)
+	}
+
+	sub.Configuration[rateLimitKey] = map[string]interface{}{"requests_per_second": 0.5, "burst": 5}
+	if _, limit := client.rateLimit(WithRateLimit(context.Background(), sub, "key")); limit != (models.RateLimit{RequestsPerSecond: 0.5, Burst: 5}) {
+		t.Errorf("got limit %+v, want the subscription's own", limit)
+	}
+
+	sub.Source = models.SourceWebhook
+	delete(sub.Configuration, rateLimitKey)
+	if limited, _ := client.rateLimit(WithRateLimit(context.Background(), sub, "")); limited != nil {
+		t.Errorf("a source without a limit is limited")
+	}
+
+	if limited, _ := NewSourceClient(DefaultSourceClientConfig(), nil).rateLimit(WithRateLimit(context.Background(), sub, "")); limited != nil {
+		t.Errorf("a client without a limiter limits calls")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if wait, ok := parseRetryAfter("120"); !ok || wait != 2*time.Minute {
+		t.Errorf("seconds: got %s, %v", wait, ok)
+	}
+	if wait, ok := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("date: got %s, %v", wait, ok)
+	}
+	if wait, ok := parseRetryAfter("Mon, 01 Jan 2001 00:00:00 GMT"); !ok || wait != 0 {
+		t.Errorf("past date: got %s, %v", wait, ok)
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Errorf("%q was read as a Retry-After", value)
+		}
+	}
+}
+
+func TestStatusErrorUnauthorized(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusUnauthorized:        true,
+		http.StatusForbidden:           true,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+	} {
+		err := fmt.Errorf("failed to pull data from source: %w", fmt.Errorf("failed to fetch data: %w", &StatusError{StatusCode: status}))
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("%d: the StatusError was lost", status)
+		}
+		if statusErr.Unauthorized() != want {
+			t.Errorf("%d: got unauthorized %v, want %v", status, !want, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// inboxBatchSize is the number of webhook calls a worker claims at a time
+const inboxBatchSize = 10
+
+// inboxHeaders are the headers of a webhook call kept in the inbox, the
+// strategies may read them when the call is processed. Credentials are
+// left out, the call is verified already.
+func inboxHeaders(header http.Header) map[string][]string {
+	headers := make(map[string][]string, len(header))
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Cookie", "X-Admin-Key":
+			continue
+		}
+		headers[name] = values
+	}
+	return headers
+}
+
+// ProcessInbox ingests the webhook calls due in the inbox until there are
+// none left. Any number of workers can run it at the same time, each call
+// is claimed by one of them. A call failing is retried later with backoff,
+// and dead after inbox.MaxAttempts, or at once if its body cannot be
+// mapped. Dead calls are dead-lettered. A worker whose lease on a call
+// expired leaves it to the one that claimed it again.
+func (m *IntegrationManager) ProcessInbox(ctx context.Context) error {
+	for {
+		entries, dead, err := m.inboxService.Claim(ctx, inboxBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 && len(dead) == 0 {
+			return nil
+		}
+
+		for _, entry := range dead {
+			fmt.Printf("Webhook call %s is dead, its worker stopped on each of its %d attempts\n", entry.ID, entry.Attempts)
+			failure := failureOf(errors.New(entry.LastError), models.PayloadPush, entry.Body)
+			m.deadLetter(ctx, entry.TenantID, entry.SubscriptionID, entry.Source, failure, entry.Attempts)
+			metrics.IncCounter("webhook_inbox_processed_total", "Webhook calls processed from the inbox, by outcome.",
+				metrics.Labels{"source": string(entry.Source), "outcome": models.InboxDead}, 1)
+		}
+
+		for _, entry := range entries {
+			labels := metrics.Labels{"source": string(entry.Source)}
+
+			if err := m.processInboxEntry(ctx, entry); err != nil {
+				// a body the strategy cannot map fails the same way every time
+				failure := failureOf(err, models.PayloadPush, entry.Body)
+				permanent := failure.Stage == models.StageMap
+
+				dead, failErr := m.inboxService.Fail(ctx, entry, err, permanent)
+				if failErr != nil {
+					fmt.Printf("Failed to record the failure of webhook call %s: %v\n", entry.ID, failErr)
+					continue
+				}
+				if dead {
+					labels["outcome"] = models.InboxDead
+					fmt.Printf("Webhook call %s is dead after %d attempts: %v\n", entry.ID, entry.Attempts, err)
+					m.deadLetter(ctx, entry.TenantID, entry.SubscriptionID, entry.Source, failure, entry.Attempts)
+				} else {
+					labels["outcome"] = "retry"
+					fmt.Printf("Webhook call %s failed (attempt %d): %v\n", entry.ID, entry.Attempts, err)
+				}
+			} else {
+				if err := m.inboxService.Complete(ctx, entry); err != nil {
+					fmt.Printf("Failed to complete webhook call %s: %v\n", entry.ID, err)
+					continue
+				}
+				labels["outcome"] = models.InboxDone
+			}
+			metrics.IncCounter("webhook_inbox_processed_total", "Webhook calls processed from the inbox, by outcome.", labels, 1)
+		}
+	}
+}
+
+// processInboxEntry replays the stored call to the source's strategy and
+// saves its feedback. Saving updates the feedback stored by a previous
+// attempt, so a call can be processed more than once.
+func (m *IntegrationManager) processInboxEntry(ctx context.Context, entry *models.WebhookInboxEntry) error {
+	strategy, ok := m.strategies[entry.Source].(Pusher)
+	if !ok {
+		return fmt.Errorf("no strategy receiving webhooks of source: %s", entry.Source)
+	}
+
+	sub, err := m.subService.GetSubscription(ctx, entry.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	target := &url.URL{Path: fmt.Sprintf("/webhook/%s/%s", entry.Source, entry.SubscriptionID), RawQuery: entry.Query}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(entry.Body))
+	if err != nil {
+		return fmt.Errorf("failed to rebuild the webhook request: %v", err)
+	}
+	r.Header = http.Header(entry.Headers)
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+
+	// archived before it is mapped, a call the strategy fails on can be
+	// reprocessed once it is fixed
+	payload := &models.RawPayload{
+		TenantID:       sub.TenantID,
+		SubscriptionID: sub.ID,
+		Source:         sub.Source,
+		Kind:           models.PayloadPush,
+		Data:           entry.Body,
+		ReceivedAt:     entry.ReceivedAt,
+	}
+	if err := m.archiveService.Store(ctx, payload); err != nil {
+		return err
+	}
+
+	feedbacks, err := strategy.Push(ctx, sub, r, entry.Body)
+	if err != nil {
+		return &PayloadFailure{Stage: models.StageMap, Err: fmt.Errorf("failed to process webhook: %v", err)}
+	}
+	for _, feedback := range feedbacks {
+		if feedback.RawPayload == nil {
+			feedback.RawPayload = payload
+		}
+	}
+	if err := m.archivePayloads(ctx, sub, feedbacks); err != nil {
+		return err
+	}
+
+	return m.ingest(ctx, feedbacks)
+}
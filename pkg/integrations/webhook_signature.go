@@ -0,0 +1,82 @@
+package integrations
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// ErrWebhookUnauthorized is returned when the signature of a webhook call
+// does not verify.
+var ErrWebhookUnauthorized = errors.New("invalid webhook signature")
+
+// DefaultSignatureHeader carries the signature of webhook calls for sources
+// without a signing scheme of their own: "sha256=" followed by the hex
+// HMAC-SHA256 of the body, keyed by the subscription's webhook secret.
+const DefaultSignatureHeader = "X-Webhook-Signature"
+
+// WebhookVerifier is implemented by strategies whose source signs its
+// webhook calls its own way.
+type WebhookVerifier interface {
+	VerifyWebhook(sub *models.Subscription, r *http.Request, body []byte) error
+}
+
+// verifyDefaultSignature checks a call signed as DefaultSignatureHeader
+// describes.
+func verifyDefaultSignature(sub *models.Subscription, r *http.Request, body []byte) error {
+	return verifyHMAC(r, body, DefaultSignatureHeader, "sha256=", sub.WebhookSecret, sha256.New, false)
+}
+
+// verifyHMAC checks that the header holds, after the prefix, the HMAC of the
+// body keyed by the secret, hex or base64 encoded.
+func verifyHMAC(r *http.Request, body []byte, header, prefix, secret string, newHash func() hash.Hash, base64Encoded bool) error {
+	if secret == "" {
+		return ErrWebhookUnauthorized
+	}
+
+	got, ok := strings.CutPrefix(strings.TrimSpace(r.Header.Get(header)), prefix)
+	if !ok || got == "" {
+		return ErrWebhookUnauthorized
+	}
+
+	var (
+		signature []byte
+		err       error
+	)
+	if base64Encoded {
+		signature, err = base64.StdEncoding.DecodeString(got)
+	} else {
+		signature, err = hex.DecodeString(got)
+	}
+	if err != nil {
+		return ErrWebhookUnauthorized
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrWebhookUnauthorized
+	}
+	return nil
+}
+
+// Intercom signs with the app's client secret, the subscription's webhook
+// secret: X-Hub-Signature is "sha1=" and the hex HMAC-SHA1 of the body.
+func (s *IntercomIntegration) VerifyWebhook(sub *models.Subscription, r *http.Request, body []byte) error {
+	return verifyHMAC(r, body, "X-Hub-Signature", "sha1=", sub.WebhookSecret, sha1.New, false)
+}
+
+// Discourse signs with the secret of the webhook, the subscription's webhook
+// secret: X-Discourse-Event-Signature is "sha256=" and the hex HMAC-SHA256
+// of the body.
+func (s *DiscourseIntegration) VerifyWebhook(sub *models.Subscription, r *http.Request, body []byte) error {
+	return verifyHMAC(r, body, "X-Discourse-Event-Signature", "sha256=", sub.WebhookSecret, sha256.New, false)
+}
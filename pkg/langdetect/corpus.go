@@ -0,0 +1,91 @@
+package langdetect
+
+// Training samples for the latin-script languages. The texts are short on
+// purpose: they only need to produce stable character n-gram frequencies,
+// and are written in the register of product feedback so the model sees the
+// vocabulary it is going to be used on.
+var corpus = map[string]string{
+	"en": `The app keeps crashing every time I try to open the settings page. I have already reinstalled it twice and the problem is still there.
+It would be great if you could add a dark mode and an option to export my data. The new update is much slower than the previous version.
+I was charged twice for my subscription this month and nobody from support has answered my email yet. Please refund the extra payment.
+Thank you for the quick response, the issue with the login screen is fixed now and everything works as expected.
+The search feature does not return any results when the query contains special characters. This is really frustrating for our team.
+We love the product, but the pricing for small teams is too high compared with other tools we have used before.
+How can I change the language of the notifications? I could not find this option anywhere in the documentation.
+Customer service was friendly and helpful, they solved my problem within a few minutes. I would recommend this service to my friends.
+The latest release broke the integration with our calendar, and the events are no longer synchronized between the devices.
+Please make it possible to attach files to a conversation, it is the one feature that we are still missing.
+Where is the button to cancel my account? The website should explain this more clearly because it takes far too long to find.`,
+
+	"es": `La aplicación se cierra cada vez que intento abrir la página de configuración. Ya la he reinstalado dos veces y el problema sigue.
+Sería genial que añadieran un modo oscuro y una opción para exportar mis datos. La nueva actualización es mucho más lenta que la versión anterior.
+Me cobraron dos veces la suscripción este mes y nadie del servicio de atención ha respondido a mi correo. Por favor, devuélvanme el pago extra.
+Gracias por la respuesta tan rápida, el problema con la pantalla de inicio de sesión ya está solucionado y todo funciona como esperaba.
+La función de búsqueda no devuelve ningún resultado cuando la consulta contiene caracteres especiales. Esto es muy frustrante para nuestro equipo.
+Nos encanta el producto, pero el precio para equipos pequeños es demasiado alto comparado con otras herramientas que hemos usado.
+¿Cómo puedo cambiar el idioma de las notificaciones? No encontré esta opción en ninguna parte de la documentación.
+El servicio al cliente fue amable y útil, resolvieron mi problema en pocos minutos. Recomendaría este servicio a mis amigos.
+La última versión rompió la integración con nuestro calendario y los eventos ya no se sincronizan entre los dispositivos.
+Por favor, permitan adjuntar archivos a una conversación, es la única función que todavía nos falta.
+¿Dónde está el botón para cancelar mi cuenta? La página debería explicarlo con más claridad porque se tarda demasiado en encontrarlo.`,
+
+	"fr": `L'application plante chaque fois que j'essaie d'ouvrir la page des paramètres. Je l'ai déjà réinstallée deux fois et le problème est toujours là.
+Ce serait génial si vous pouviez ajouter un mode sombre et une option pour exporter mes données. La nouvelle mise à jour est beaucoup plus lente que la version précédente.
+J'ai été facturé deux fois pour mon abonnement ce mois-ci et personne du support n'a encore répondu à mon courriel. Merci de rembourser le paiement en trop.
+Merci pour la réponse rapide, le problème avec l'écran de connexion est maintenant corrigé et tout fonctionne comme prévu.
+La fonction de recherche ne renvoie aucun résultat quand la requête contient des caractères spéciaux. C'est vraiment frustrant pour notre équipe.
+Nous adorons le produit, mais le prix pour les petites équipes est trop élevé par rapport aux autres outils que nous avons utilisés.
+Comment puis-je changer la langue des notifications ? Je n'ai trouvé cette option nulle part dans la documentation.
+Le service client était aimable et efficace, ils ont résolu mon problème en quelques minutes. Je recommanderais ce service à mes amis.
+La dernière version a cassé l'intégration avec notre calendrier et les événements ne sont plus synchronisés entre les appareils.
+S'il vous plaît, permettez de joindre des fichiers à une conversation, c'est la seule fonctionnalité qui nous manque encore.
+Où est le bouton pour supprimer mon compte ? Le site devrait l'expliquer plus clairement car il faut beaucoup trop de temps pour le trouver.`,
+
+	"de": `Die App stürzt jedes Mal ab, wenn ich versuche, die Seite mit den Einstellungen zu öffnen. Ich habe sie schon zweimal neu installiert und das Problem besteht weiterhin.
+Es wäre toll, wenn ihr einen dunklen Modus und eine Möglichkeit zum Exportieren meiner Daten hinzufügen könntet. Das neue Update ist viel langsamer als die vorherige Version.
+Mir wurde das Abonnement diesen Monat zweimal berechnet und niemand vom Support hat bisher auf meine E-Mail geantwortet. Bitte erstattet die zusätzliche Zahlung.
+Danke für die schnelle Antwort, das Problem mit dem Anmeldebildschirm ist jetzt behoben und alles funktioniert wie erwartet.
+Die Suchfunktion liefert keine Ergebnisse, wenn die Anfrage Sonderzeichen enthält. Das ist wirklich frustrierend für unser Team.
+Wir lieben das Produkt, aber der Preis für kleine Teams ist im Vergleich zu anderen Werkzeugen, die wir benutzt haben, zu hoch.
+Wie kann ich die Sprache der Benachrichtigungen ändern? Ich konnte diese Option nirgendwo in der Dokumentation finden.
+Der Kundendienst war freundlich und hilfsbereit, sie haben mein Problem innerhalb weniger Minuten gelöst. Ich würde diesen Dienst meinen Freunden empfehlen.
+Die neueste Version hat die Verbindung mit unserem Kalender kaputt gemacht und die Termine werden nicht mehr zwischen den Geräten synchronisiert.
+Bitte macht es möglich, Dateien an eine Unterhaltung anzuhängen, das ist die einzige Funktion, die uns noch fehlt.
+Wo ist der Knopf, um mein Konto zu kündigen? Die Webseite sollte das deutlicher erklären, weil man viel zu lange danach suchen muss.`,
+
+	"it": `L'applicazione si chiude ogni volta che provo ad aprire la pagina delle impostazioni. L'ho già reinstallata due volte e il problema è ancora lì.
+Sarebbe fantastico se poteste aggiungere una modalità scura e un'opzione per esportare i miei dati. Il nuovo aggiornamento è molto più lento della versione precedente.
+Mi hanno addebitato due volte l'abbonamento questo mese e nessuno dell'assistenza ha ancora risposto alla mia email. Per favore rimborsate il pagamento in eccesso.
+Grazie per la risposta veloce, il problema con la schermata di accesso adesso è risolto e tutto funziona come previsto.
+La funzione di ricerca non restituisce nessun risultato quando la richiesta contiene caratteri speciali. Questo è davvero frustrante per la nostra squadra.
+Adoriamo il prodotto, ma il prezzo per i piccoli gruppi è troppo alto rispetto agli altri strumenti che abbiamo usato.
+Come posso cambiare la lingua delle notifiche? Non ho trovato questa opzione da nessuna parte nella documentazione.
+Il servizio clienti è stato gentile e disponibile, hanno risolto il mio problema in pochi minuti. Consiglierei questo servizio ai miei amici.
+L'ultima versione ha rotto l'integrazione con il nostro calendario e gli eventi non sono più sincronizzati tra i dispositivi.
+Per favore rendete possibile allegare file a una conversazione, è l'unica funzione che ci manca ancora.
+Dov'è il pulsante per cancellare il mio account? Il sito dovrebbe spiegarlo più chiaramente perché ci vuole troppo tempo per trovarlo.`,
+
+	"pt": `O aplicativo fecha toda vez que tento abrir a página de configurações. Já reinstalei duas vezes e o problema continua.
+Seria ótimo se vocês pudessem adicionar um modo escuro e uma opção para exportar os meus dados. A nova atualização é muito mais lenta do que a versão anterior.
+Fui cobrado duas vezes pela assinatura este mês e ninguém do suporte respondeu ao meu e-mail ainda. Por favor, devolvam o pagamento a mais.
+Obrigado pela resposta rápida, o problema com a tela de login já foi resolvido e tudo funciona como esperado.
+A função de pesquisa não retorna nenhum resultado quando a consulta contém caracteres especiais. Isso é muito frustrante para a nossa equipe.
+Adoramos o produto, mas o preço para equipes pequenas é alto demais em comparação com outras ferramentas que já usamos.
+Como posso mudar o idioma das notificações? Não encontrei essa opção em nenhum lugar da documentação.
+O atendimento ao cliente foi simpático e prestativo, resolveram o meu problema em poucos minutos. Eu recomendaria este serviço aos meus amigos.
+A última versão quebrou a integração com o nosso calendário e os eventos não são mais sincronizados entre os dispositivos.
+Por favor, permitam anexar arquivos a uma conversa, é a única funcionalidade que ainda nos falta.
+Onde fica o botão para cancelar a minha conta? O site deveria explicar isso com mais clareza porque demora demais para encontrar.`,
+
+	"nl": `De app crasht elke keer als ik de pagina met instellingen probeer te openen. Ik heb hem al twee keer opnieuw geïnstalleerd en het probleem is er nog steeds.
+Het zou geweldig zijn als jullie een donkere modus en een optie om mijn gegevens te exporteren zouden toevoegen. De nieuwe update is veel trager dan de vorige versie.
+Mijn abonnement is deze maand twee keer afgeschreven en niemand van de klantenservice heeft mijn e-mail nog beantwoord. Betaal de extra betaling alstublieft terug.
+Bedankt voor het snelle antwoord, het probleem met het inlogscherm is nu opgelost en alles werkt zoals verwacht.
+De zoekfunctie geeft geen resultaten als de zoekopdracht speciale tekens bevat. Dat is echt frustrerend voor ons team.
+We zijn dol op het product, maar de prijs voor kleine teams is te hoog vergeleken met andere hulpmiddelen die we eerder hebben gebruikt.
+Hoe kan ik de taal van de meldingen veranderen? Ik kon deze optie nergens in de documentatie vinden.
+De klantenservice was vriendelijk en behulpzaam, ze hebben mijn probleem binnen een paar minuten opgelost. Ik zou deze dienst aan mijn vrienden aanraden.
+De nieuwste versie heeft de koppeling met onze agenda kapotgemaakt en de afspraken worden niet meer tussen de apparaten gesynchroniseerd.
+Maak het alsjeblieft mogelijk om bestanden aan een gesprek toe te voegen, dat is de enige functie die we nog missen.
+Waar is de knop om mijn account op te zeggen? De website zou dit duidelijker moeten uitleggen omdat het veel te lang duurt om hem te vinden.`,
+}
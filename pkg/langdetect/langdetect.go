@@ -0,0 +1,247 @@
+// Package langdetect is an offline language identifier.
+//
+// Texts in a script used by a single language (Cyrillic, Hangul, kana, ...)
+// are identified by their script. Latin-script texts are scored with a naive
+// Bayes model over character 1-3 grams trained on the samples in corpus.go.
+package langdetect
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	maxNgram = 3
+
+	// overlapping n-grams are far from independent, summing the evidence of
+	// every n-gram of a long text makes any guess look certain. Scores are
+	// averaged and scaled to this many n-grams before being normalised.
+	maxEvidence = 24
+
+	// texts with fewer letters are too short to say anything about
+	minLetters = 3
+)
+
+var (
+	htmlTagRe    = regexp.MustCompile(`<[^>]*>`)
+	htmlEntityRe = regexp.MustCompile(`&[a-zA-Z#0-9]+;`)
+	urlRe        = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	emailRe      = regexp.MustCompile(`\S+@\S+\.\S+`)
+)
+
+// Result is the detected language (ISO 639-1) and the confidence, between 0
+// and 1, of the detection. An empty language means the text could not be
+// identified.
+type Result struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+type profile struct {
+	logProb map[string]float64
+	unseen  [maxNgram + 1]float64 // log probability of an n-gram never seen in training, by order
+}
+
+type Detector struct {
+	profiles map[string]*profile
+}
+
+var (
+	defaultDetector     *Detector
+	defaultDetectorOnce sync.Once
+)
+
+// Default returns a shared detector trained on the built-in corpus.
+func Default() *Detector {
+	defaultDetectorOnce.Do(func() {
+		defaultDetector = New(corpus)
+	})
+	return defaultDetector
+}
+
+// New trains a detector from sample texts keyed by language code.
+func New(samples map[string]string) *Detector {
+	counts := make(map[string]map[string]int, len(samples))
+	vocabulary := [maxNgram + 1]map[string]struct{}{}
+	for n := 1; n <= maxNgram; n++ {
+		vocabulary[n] = map[string]struct{}{}
+	}
+
+	for lang, text := range samples {
+		counts[lang] = map[string]int{}
+		for _, gram := range ngrams(normalize(text)) {
+			counts[lang][gram]++
+			vocabulary[len([]rune(gram))][gram] = struct{}{}
+		}
+	}
+
+	d := &Detector{profiles: make(map[string]*profile, len(samples))}
+	for lang, grams := range counts {
+		totals := [maxNgram + 1]int{}
+		for gram, count := range grams {
+			totals[len([]rune(gram))] += count
+		}
+
+		p := &profile{logProb: make(map[string]float64, len(grams))}
+		for n := 1; n <= maxNgram; n++ {
+			// add-one smoothing, with room for one unseen n-gram per order
+			p.unseen[n] = math.Log(1 / float64(totals[n]+len(vocabulary[n])+1))
+		}
+		for gram, count := range grams {
+			n := len([]rune(gram))
+			p.logProb[gram] = math.Log(float64(count+1) / float64(totals[n]+len(vocabulary[n])+1))
+		}
+		d.profiles[lang] = p
+	}
+
+	return d
+}
+
+// Languages lists the languages the detector can identify.
+func (d *Detector) Languages() []string {
+	seen := map[string]bool{}
+	languages := make([]string, 0, len(d.profiles)+len(scriptLanguages))
+	for lang := range d.profiles {
+		seen[lang] = true
+		languages = append(languages, lang)
+	}
+	for _, lang := range scriptLanguages {
+		if !seen[lang] {
+			seen[lang] = true
+			languages = append(languages, lang)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Detect identifies the language of text among all supported languages.
+func (d *Detector) Detect(text string) Result {
+	return d.DetectAmong(text, nil)
+}
+
+// DetectAmong identifies the language of text among the given candidates, an
+// empty list meaning every supported language.
+func (d *Detector) DetectAmong(text string, candidates []string) Result {
+	text = normalize(text)
+
+	allowed := func(lang string) bool {
+		if len(candidates) == 0 {
+			return true
+		}
+		for _, candidate := range candidates {
+			if candidate == lang {
+				return true
+			}
+		}
+		return false
+	}
+
+	scripts, letters := countScripts(text)
+	if letters < minLetters {
+		return Result{}
+	}
+
+	// a script used by a single language settles it on its own
+	for script, count := range scripts {
+		lang, ok := scriptLanguages[script]
+		if !ok || !allowed(lang) {
+			continue
+		}
+		if ratio := float64(count) / float64(letters); ratio > 0.5 {
+			return Result{Language: lang, Confidence: round(ratio)}
+		}
+	}
+
+	if float64(scripts[latinScript])/float64(letters) <= 0.5 {
+		return Result{}
+	}
+
+	grams := ngrams(text)
+	if len(grams) == 0 {
+		return Result{}
+	}
+
+	scores := map[string]float64{}
+	for lang, p := range d.profiles {
+		if !allowed(lang) {
+			continue
+		}
+		score := 0.0
+		for _, gram := range grams {
+			if logProb, ok := p.logProb[gram]; ok {
+				score += logProb
+			} else {
+				score += p.unseen[len([]rune(gram))]
+			}
+		}
+		scores[lang] = score / float64(len(grams)) * math.Min(float64(len(grams)), maxEvidence)
+	}
+	if len(scores) == 0 {
+		return Result{}
+	}
+
+	// softmax over the scaled log-likelihoods
+	best, bestScore := "", math.Inf(-1)
+	for lang, score := range scores {
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+
+	return Result{Language: best, Confidence: round(1 / sum)}
+}
+
+// normalize strips markup, links and anything that is not a letter, and
+// lower-cases what is left. Words are separated by a single space.
+func normalize(text string) string {
+	text = htmlTagRe.ReplaceAllString(text, " ")
+	text = htmlEntityRe.ReplaceAllString(text, " ")
+	text = urlRe.ReplaceAllString(text, " ")
+	text = emailRe.ReplaceAllString(text, " ")
+
+	var b strings.Builder
+	space := true
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		} else if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ngrams returns the character 1 to maxNgram grams of every word, words
+// being padded with a space on each side so that prefixes and suffixes get
+// their own grams.
+func ngrams(text string) []string {
+	var grams []string
+	for _, word := range strings.Fields(text) {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= maxNgram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram == " " {
+					continue
+				}
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
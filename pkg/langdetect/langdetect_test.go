@@ -0,0 +1,107 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The checkout page keeps freezing after the latest update, please fix it", "en"},
+		{"La aplicación se cierra cada vez que intento pagar con mi tarjeta", "es"},
+		{"L'application plante à chaque fois que je veux ouvrir mes paramètres", "fr"},
+		{"Die App stürzt jedes Mal ab, wenn ich die Einstellungen öffnen will", "de"},
+		{"L'applicazione si blocca ogni volta che provo ad aprire le impostazioni", "it"},
+		{"O aplicativo fecha sozinho toda vez que tento abrir as configurações", "pt"},
+		{"De app crasht elke keer als ik de instellingen probeer te openen", "nl"},
+		{"Приложение постоянно вылетает при оплате", "ru"},
+		{"アプリが起動しません。設定画面を開くと落ちます", "ja"},
+		{"앱이 계속 종료됩니다", "ko"},
+		{"应用程序一直崩溃", "zh"},
+		{"Η εφαρμογή κλείνει συνέχεια", "el"},
+	}
+	d := Default()
+	for _, tt := range tests {
+		got := d.Detect(tt.text)
+		if got.Language != tt.want {
+			t.Errorf("Detect(%q) = %+v, want %s", tt.text, got, tt.want)
+		}
+		if got.Confidence <= 0 || got.Confidence > 1 {
+			t.Errorf("Detect(%q): confidence %v out of (0, 1]", tt.text, got.Confidence)
+		}
+	}
+}
+
+func TestDetectUnidentified(t *testing.T) {
+	d := Default()
+	for _, text := range []string{"", "ok", "12345 !!!", "https://example.com/a/b/c", "jane.doe@example.com", "<p></p>"} {
+		if got := d.Detect(text); got.Language != "" {
+			t.Errorf("Detect(%q) = %+v, want no language", text, got)
+		}
+	}
+}
+
+func TestDetectAmong(t *testing.T) {
+	d := Default()
+	text := "The checkout page keeps freezing after the latest update"
+
+	if got := d.DetectAmong(text, []string{"fr", "de"}); got.Language == "en" {
+		t.Errorf("DetectAmong returned %s outside of the candidates", got.Language)
+	}
+	if got := d.DetectAmong("Приложение постоянно вылетает", []string{"en", "fr"}); got.Language == "ru" {
+		t.Errorf("a script language outside of the candidates was returned")
+	}
+	if got := d.DetectAmong(text, []string{"en"}); got.Language != "en" || got.Confidence != 1 {
+		t.Errorf("a single candidate: got %+v, want en with confidence 1", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"<b>Hello</b>&nbsp;World!", "hello world"},
+		{"see https://example.com/x or www.example.org now", "see or now"},
+		{"mail jane@example.com 42 times", "mail times"},
+		{"  Çà   ÉTÉ  ", "çà été"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.text); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNgrams(t *testing.T) {
+	got := ngrams("ab")
+	want := []string{"a", "b", " a", "ab", "b ", " ab", "ab "}
+	if len(got) != len(want) {
+		t.Fatalf("ngrams(ab) = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ngrams(ab) = %q, want %q", got, want)
+			break
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	languages := Default().Languages()
+	seen := map[string]bool{}
+	for i, lang := range languages {
+		if seen[lang] {
+			t.Errorf("%s is listed twice", lang)
+		}
+		seen[lang] = true
+		if i > 0 && languages[i-1] > lang {
+			t.Errorf("the languages are not sorted: %v", languages)
+		}
+	}
+	for _, lang := range []string{"en", "fr", "ja", "ru", "zh"} {
+		if !seen[lang] {
+			t.Errorf("%s is not listed", lang)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package langdetect
+
+import "unicode"
+
+const latinScript = "Latin"
+
+// scripts written by (practically) a single language. Han counts as Chinese
+// only when no kana is present, see countScripts.
+var scriptLanguages = map[string]string{
+	"Hangul":     "ko",
+	"Hiragana":   "ja",
+	"Katakana":   "ja",
+	"Han":        "zh",
+	"Cyrillic":   "ru",
+	"Greek":      "el",
+	"Hebrew":     "he",
+	"Arabic":     "ar",
+	"Devanagari": "hi",
+	"Thai":       "th",
+}
+
+var scriptTables = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{latinScript, unicode.Latin},
+	{"Hangul", unicode.Hangul},
+	{"Hiragana", unicode.Hiragana},
+	{"Katakana", unicode.Katakana},
+	{"Han", unicode.Han},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Hebrew", unicode.Hebrew},
+	{"Arabic", unicode.Arabic},
+	{"Devanagari", unicode.Devanagari},
+	{"Thai", unicode.Thai},
+}
+
+// countScripts counts the letters of text by script. Kana and Han are merged
+// into Japanese when kana is present, as Japanese text is mostly Han with a
+// sprinkle of kana.
+func countScripts(text string) (map[string]int, int) {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptTables {
+			if unicode.Is(script.table, r) {
+				counts[script.name]++
+				break
+			}
+		}
+	}
+
+	if kana := counts["Hiragana"] + counts["Katakana"]; kana > 0 {
+		counts["Hiragana"] = kana + counts["Han"]
+		delete(counts, "Katakana")
+		delete(counts, "Han")
+	}
+
+	return counts, letters
+}
@@ -0,0 +1,83 @@
+package language
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/langdetect"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const redetectBatchSize = 500
+
+type LanguageService struct {
+	feedbackRepo *db.FeedbackRepository
+	tenantRepo   *db.TenantRepository
+}
+
+func NewLanguageService(feedbackRepo *db.FeedbackRepository, tenantRepo *db.TenantRepository) *LanguageService {
+	return &LanguageService{feedbackRepo: feedbackRepo, tenantRepo: tenantRepo}
+}
+
+// Detect sets the language of a single feedback following the tenant's
+// settings, it returns whether a language was found.
+func Detect(settings *models.LanguageDetectionSettings, feedback *models.Feedback) bool {
+	if settings.Disabled {
+		return false
+	}
+
+	result := langdetect.Default().DetectAmong(feedback.Text(), settings.Languages)
+	if result.Language == "" || result.Confidence < settings.MinConfidence {
+		return false
+	}
+
+	feedback.Language = result.Language
+	feedback.LanguageConfidence = result.Confidence
+	return true
+}
+
+// Redetect runs the detection over the tenant's stored feedback without a
+// language, or over every feedback whose language was detected when all is
+// set. It returns the number of updated records.
+func (s *LanguageService) Redetect(ctx context.Context, tenantID string, all bool) (int, error) {
+	tenant, err := s.tenantRepo.Get(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	settings := tenant.Settings.LanguageDetection
+	if settings.Disabled {
+		return 0, fmt.Errorf("language detection is disabled for tenant %s", tenantID)
+	}
+
+	var (
+		updated     int
+		afterSource models.Source
+		afterID     string
+	)
+	for {
+		feedbacks, err := s.feedbackRepo.ListForLanguageDetection(ctx, tenantID, all, afterSource, afterID, redetectBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(feedbacks) == 0 {
+			return updated, nil
+		}
+
+		for _, feedback := range feedbacks {
+			previous := feedback.Language
+			feedback.Language = ""
+			feedback.LanguageConfidence = 0
+			if !Detect(&settings, feedback) && previous == "" {
+				continue
+			}
+			if err := s.feedbackRepo.UpdateLanguage(ctx, feedback); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+
+		last := feedbacks[len(feedbacks)-1]
+		afterSource, afterID = last.Source, last.ID
+	}
+}
@@ -0,0 +1,35 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func TestDetect(t *testing.T) {
+	const french = "L'application plante à chaque fois que je veux ouvrir mes paramètres"
+
+	tests := []struct {
+		name     string
+		settings models.LanguageDetectionSettings
+		text     string
+		want     string
+		found    bool
+	}{
+		{"detected", models.LanguageDetectionSettings{}, french, "fr", true},
+		{"disabled", models.LanguageDetectionSettings{Disabled: true}, french, "", false},
+		{"below min confidence", models.LanguageDetectionSettings{MinConfidence: 1.01}, french, "", false},
+		{"restricted languages", models.LanguageDetectionSettings{Languages: []string{"fr", "en"}}, french, "fr", true},
+		{"too short", models.LanguageDetectionSettings{}, "ok", "", false},
+	}
+	for _, tt := range tests {
+		feedback := &models.Feedback{Content: map[string]interface{}{"text": tt.text}}
+		found := Detect(&tt.settings, feedback)
+		if found != tt.found || feedback.Language != tt.want {
+			t.Errorf("%s: got %q, %v, want %q, %v", tt.name, feedback.Language, found, tt.want, tt.found)
+		}
+		if found && (feedback.LanguageConfidence <= 0 || feedback.LanguageConfidence > 1) {
+			t.Errorf("%s: confidence %v out of (0, 1]", tt.name, feedback.LanguageConfidence)
+		}
+	}
+}
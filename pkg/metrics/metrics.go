@@ -0,0 +1,152 @@
+// Package metrics is a small in-process registry of counters and gauges,
+// exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Labels map[string]string
+
+type metricType string
+
+const (
+	counterType metricType = "counter"
+	gaugeType   metricType = "gauge"
+)
+
+type family struct {
+	help   string
+	typ    metricType
+	series map[string]*series
+}
+
+type series struct {
+	labels Labels
+	value  float64
+}
+
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// Default is the registry served at /metrics.
+var Default = NewRegistry()
+
+// IncCounter adds delta to the counter with the given name and labels.
+func IncCounter(name, help string, labels Labels, delta float64) {
+	Default.IncCounter(name, help, labels, delta)
+}
+
+// SetGauge sets the gauge with the given name and labels.
+func SetGauge(name, help string, labels Labels, value float64) {
+	Default.SetGauge(name, help, labels, value)
+}
+
+func (r *Registry) IncCounter(name, help string, labels Labels, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.series(name, help, counterType, labels).value += delta
+}
+
+func (r *Registry) SetGauge(name, help string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.series(name, help, gaugeType, labels).value = value
+}
+
+// Value returns the current value of a series, 0 if it does not exist.
+func (r *Registry) Value(name string, labels Labels) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		return 0
+	}
+	if s, ok := f.series[labelKey(labels)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (r *Registry) series(name, help string, typ metricType, labels Labels) *series {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{help: help, typ: typ, series: map[string]*series{}}
+		r.families[name] = f
+	}
+
+	key := labelKey(labels)
+	s, ok := f.series[key]
+	if !ok {
+		copied := make(Labels, len(labels))
+		for k, v := range labels {
+			copied[k] = v
+		}
+		s = &series{labels: copied}
+		f.series[key] = s
+	}
+	return s
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s%s %g\n", name, key, f.series[key].value)
+		}
+	}
+}
+
+// Handler serves the default registry.
+func Handler() http.Handler {
+	return Default
+}
+
+// labelKey renders the labels as they appear in the exposition format, it
+// doubles as the series key.
+func labelKey(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, labels[name])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
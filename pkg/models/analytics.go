@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AnalyticsQuery counts the feedback matching Filter per Interval bucket
+// (hour, day or week), grouped by the GroupBy dimensions (source,
+// source_type, sub_source_id, tag, language, rating, sentiment). Buckets are
+// on when the feedback was written, in UTC, and so are the From and To of
+// the filter here.
+type AnalyticsQuery struct {
+	Filter   FeedbackFilter
+	Interval string
+	GroupBy  []string
+}
+
+// AnalyticsRow is the count of a bucket and group. A feedback with several
+// tags is counted once per tag when grouped by tag.
+type AnalyticsRow struct {
+	Bucket     time.Time         `json:"bucket"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Count      int               `json:"count"`
+}
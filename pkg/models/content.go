@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Text returns the human-written text of the feedback content, one
+// paragraph per message or field. Content read back from the database is
+// generic JSON, content built by the integrations may be one of the content
+// structs, both are handled.
+func (f *Feedback) Text() string {
+	var parts []string
+	collectText(normalizeContent(f.Content), &parts)
+	return strings.Join(parts, "\n")
+}
+
+// MapText replaces every text of the feedback content, the ones Text
+// returns, with fn applied to it.
+func (f *Feedback) MapText(fn func(string) string) {
+	switch c := f.Content.(type) {
+	case ConversationContent:
+		f.Content = mapConversation(c, fn)
+	case *ConversationContent:
+		mapped := mapConversation(*c, fn)
+		f.Content = &mapped
+	default:
+		f.Content = mapText(normalizeContent(c), fn, true)
+	}
+}
+
+func mapConversation(c ConversationContent, fn func(string) string) ConversationContent {
+	messages := make([]Message, len(c.Messages))
+	for i, msg := range c.Messages {
+		msg.Content = fn(msg.Content)
+		messages[i] = msg
+	}
+	c.Messages = messages
+	return c
+}
+
+// mapText mirrors collectText: only text keys are descended into, and
+// strings are text when they sit under a text key or in a list that does.
+func mapText(value interface{}, fn func(string) string, isText bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		if isText {
+			return fn(v)
+		}
+		return v
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			mapped[i] = mapText(item, fn, isText)
+		}
+		return mapped
+	case map[string]interface{}:
+		mapped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if textKeys[key] {
+				mapped[key] = mapText(item, fn, true)
+			} else {
+				mapped[key] = item
+			}
+		}
+		return mapped
+	default:
+		return v
+	}
+}
+
+// normalizeContent turns typed content into its generic JSON form.
+func normalizeContent(content interface{}) interface{} {
+	switch c := content.(type) {
+	case nil, string, map[string]interface{}, []interface{}:
+		return c
+	case ConversationContent:
+		return conversationToGeneric(c)
+	case *ConversationContent:
+		return conversationToGeneric(*c)
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil
+	}
+	return generic
+}
+
+func conversationToGeneric(c ConversationContent) interface{} {
+	messages := make([]interface{}, len(c.Messages))
+	for i, msg := range c.Messages {
+		messages[i] = map[string]interface{}{"content": msg.Content}
+	}
+	return map[string]interface{}{"messages": messages}
+}
+
+// textKeys are the content fields holding text, identifiers and other
+// bookkeeping fields are skipped.
+var textKeys = map[string]bool{
+	"body":     true,
+	"content":  true,
+	"text":     true,
+	"title":    true,
+	"subject":  true,
+	"comment":  true,
+	"messages": true,
+}
+
+func collectText(value interface{}, parts *[]string) {
+	switch v := value.(type) {
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			*parts = append(*parts, s)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectText(item, parts)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if textKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+		// title before body and so on, keeps the output stable
+		sort.Slice(keys, func(i, j int) bool {
+			if textKeyOrder(keys[i]) != textKeyOrder(keys[j]) {
+				return textKeyOrder(keys[i]) < textKeyOrder(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			collectText(v[key], parts)
+		}
+	}
+}
+
+func textKeyOrder(key string) int {
+	switch key {
+	case "title", "subject":
+		return 0
+	case "body", "text", "content", "comment":
+		return 1
+	default:
+		return 2
+	}
+}
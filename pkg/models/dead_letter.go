@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Stages a payload can fail at
+const (
+	StageFetch    = "fetch"    // the payload could not be fetched, its data is a reference to it
+	StageMap      = "map"      // the strategy could not map it to feedback
+	StagePipeline = "pipeline" // the enrichment pipeline failed
+	StageStore    = "store"    // the feedback could not be saved
+)
+
+// DeadLetter is a payload that failed to be ingested, kept until it is
+// retried successfully or discarded.
+type DeadLetter struct {
+	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
+	SubscriptionID string    `json:"subscription_id"`
+	Source         Source    `json:"source"`
+	Kind           string    `json:"kind"` // pull or push, see RawPayload
+	Payload        []byte    `json:"-"`
+	Stage          string    `json:"stage"`
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastAttemptAt  time.Time `json:"last_attempt_at"`
+}
+
+// DeadLetterFilter holds the filters of the dead letter listing, zero
+// values are ignored.
+type DeadLetterFilter struct {
+	TenantID       string
+	SubscriptionID string
+	Source         Source
+	Stage          string
+	Limit          int
+	Offset         int
+}
+
+// DeadLetterRetryResult is the outcome of a bulk retry.
+type DeadLetterRetryResult struct {
+	Retried   int               `json:"retried"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Errors    map[string]string `json:"errors,omitempty"` // by dead letter ID
+}
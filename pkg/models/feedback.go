@@ -3,15 +3,97 @@ package models
 import "time"
 
 type Feedback struct {
-	ID          string                 `json:"id"`
-	TenantID    string                 `json:"tenant_id"`
-	Source      Source                 `json:"source"`
-	SubSourceID string                 `json:"sub_source_id"` // defining either tag / app or relevant identifier
-	SourceType  SourceType             `json:"source_type"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
-	Metadata    map[string]interface{} `json:"metadata"`
-	Content     interface{}            `json:"content"` // Holds type-specific content
+	ID                 string                 `json:"id"`
+	TenantID           string                 `json:"tenant_id"`
+	Source             Source                 `json:"source"`
+	SubSourceID        string                 `json:"sub_source_id"` // defining either tag / app or relevant identifier
+	SourceType         SourceType             `json:"source_type"`
+	Language           string                 `json:"language"`                    // declared or detected language (ISO 639-1)
+	LanguageConfidence float64                `json:"language_confidence"`         // confidence of the detection, 0 when the language was declared by the source
+	Author             string                 `json:"author"`                      // author reference as known by the source (username, user id, ...)
+	URL                string                 `json:"url"`                         // permalink to the feedback on the source
+	Rating             *float64               `json:"rating,omitempty"`            // only for sources that have ratings, e.g. reviews
+	SourceCreatedAt    *time.Time             `json:"source_created_at,omitempty"` // when the feedback was written on the source
+	IngestedAt         time.Time              `json:"ingested_at"`                 // when the feedback was received by us
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+	Metadata           map[string]interface{} `json:"metadata"`
+	Content            interface{}            `json:"content"`                   // Holds type-specific content
+	SentimentScore     *float64               `json:"sentiment_score,omitempty"` // between -1 and 1, see pkg/sentiment
+	Sentiment          string                 `json:"sentiment,omitempty"`       // positive, neutral or negative
+	Tags               []string               `json:"tags,omitempty"`
+	Status             string                 `json:"status"`             // triage status, see FeedbackTriage
+	Assignee           string                 `json:"assignee,omitempty"` // triage assignee
+	Priority           string                 `json:"priority,omitempty"` // triage priority
+	NoteCount          int                    `json:"note_count"`
+	RuleTags           []RuleTag              `json:"-"`                              // the tags added by the tenant's tag rules, also in Tags
+	DuplicateClusterID string                 `json:"duplicate_cluster_id,omitempty"` // shared by near-duplicates, see pkg/dedup
+	DuplicateCount     int                    `json:"duplicate_count,omitempty"`      // feedbacks in the cluster, this one included
+	Signature          []byte                 `json:"-"`                              // MinHash signature
+	SignatureBands     []int64                `json:"-"`                              // band hashes of the signature, the similarity index keys
+	RawPayloadID       string                 `json:"raw_payload_id,omitempty"`       // the archived payload the feedback was mapped from
+	RawPayload         *RawPayload            `json:"-"`                              // set by the strategies, archived on ingestion
+}
+
+// AddTag adds the tag unless the feedback already has it.
+func (f *Feedback) AddTag(tag string) {
+	for _, existing := range f.Tags {
+		if existing == tag {
+			return
+		}
+	}
+	f.Tags = append(f.Tags, tag)
+}
+
+// AddRuleTag adds a tag on behalf of a tag rule.
+func (f *Feedback) AddRuleTag(tag, ruleID string) {
+	for _, existing := range f.RuleTags {
+		if existing.Tag == tag {
+			return
+		}
+	}
+	f.RuleTags = append(f.RuleTags, RuleTag{Tag: tag, RuleID: ruleID})
+	f.AddTag(tag)
+}
+
+// FeedbackFilter holds the filters supported by the feedback search API.
+// Zero values are ignored.
+type FeedbackFilter struct {
+	TenantID    string
+	Source      Source
+	SubSourceID string
+	SourceType  SourceType
+	Language    string
+	Author      string
+	Tag         string
+	// DuplicateClusterID restricts the results to one cluster of near-duplicates
+	DuplicateClusterID string
+	// CollapseDuplicates keeps only the first feedback of each cluster
+	CollapseDuplicates bool
+	From               time.Time // inclusive, on source_created_at, created_at without it
+	To                 time.Time // exclusive, on source_created_at, created_at without it
+	MinRating          *float64
+	MaxRating          *float64
+	Sentiment          string // positive, neutral or negative
+	MinSentiment       *float64
+	MaxSentiment       *float64
+	Status             string // triage status, new includes the feedback never triaged
+	Assignee           string
+	Priority           string
+	HasNotes           *bool
+	Limit              int
+	Offset             int
+}
+
+// SentimentSummary counts the feedback of a group by sentiment. Feedback
+// without a sentiment is only in Count.
+type SentimentSummary struct {
+	Group        string   `json:"group,omitempty"`
+	Count        int      `json:"count"`
+	Positive     int      `json:"positive"`
+	Neutral      int      `json:"neutral"`
+	Negative     int      `json:"negative"`
+	AverageScore *float64 `json:"average_score"`
 }
 
 type Message struct {
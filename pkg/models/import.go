@@ -0,0 +1,44 @@
+package models
+
+// ImportMapping maps the columns of a CSV or NDJSON import file to feedback
+// fields. Every imported record gets the mapping's source, source type and
+// sub-source.
+type ImportMapping struct {
+	Source      Source     `json:"source"`
+	SourceType  SourceType `json:"source_type"`
+	SubSourceID string     `json:"sub_source_id"`
+	// Fields maps each feedback field to the column holding it, a dotted
+	// path for NDJSON. The fields are id (the native ID, the import key),
+	// author, url, language, rating, source_created_at, tags, content and
+	// metadata, or content.<path> and metadata.<path> for a single value.
+	// Without an id column the ID is derived from the row.
+	Fields map[string]string `json:"fields"`
+	// TimeFormat is the Go layout of source_created_at, RFC3339 by default.
+	// Numbers are Unix timestamps in seconds.
+	TimeFormat string `json:"time_format,omitempty"`
+	// TagSeparator splits the tags column, ";" by default
+	TagSeparator string `json:"tag_separator,omitempty"`
+}
+
+// ImportRowError is an error with a row of an import file, rows are
+// numbered from 1 not counting the CSV header.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportResult reports an import. A dry run only reads and validates the
+// rows.
+type ImportResult struct {
+	DryRun   bool `json:"dry_run"`
+	Rows     int  `json:"rows"`
+	Valid    int  `json:"valid"`
+	Inserted int  `json:"inserted"`
+	Updated  int  `json:"updated"`
+	// Skipped counts the records dropped by the enrichment pipeline
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+	// Errors lists the first failed rows, there may be more, see Failed
+	Errors []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// Capabilities are what an integration supports.
+type Capabilities struct {
+	// Push subscriptions receive the source's webhook calls
+	Push bool `json:"push"`
+	// Pull subscriptions pull the source on a schedule
+	Pull bool `json:"pull"`
+	// Backfill is whether a new subscription ingests the existing feedback
+	Backfill bool `json:"backfill"`
+	// Edits is whether feedback edited at the source is updated
+	Edits bool `json:"edits"`
+	// Deletes is whether feedback deleted at the source is deleted
+	Deletes bool `json:"deletes"`
+}
+
+// ConfigSchema is the JSON Schema of a subscription's configuration, the
+// subset of it the configurations are checked against: type, properties,
+// required, additionalProperties, enum, minimum, maximum and minLength.
+type ConfigSchema struct {
+	Type        string                   `json:"type,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Properties  map[string]*ConfigSchema `json:"properties,omitempty"`
+	Required    []string                 `json:"required,omitempty"`
+	// AdditionalProperties is false when only the listed properties are
+	// allowed, or the *ConfigSchema of the other properties
+	AdditionalProperties interface{}   `json:"additionalProperties,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	Minimum              *float64      `json:"minimum,omitempty"`
+	Maximum              *float64      `json:"maximum,omitempty"`
+	MinLength            int           `json:"minLength,omitempty"`
+	// ReadOnly properties are set by the service, e.g. the OAuth token
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// WriteOnly properties are secrets, redacted from the responses
+	WriteOnly bool `json:"writeOnly,omitempty"`
+}
+
+// Integration describes a supported source, to create its subscriptions.
+type Integration struct {
+	Source       Source        `json:"source"`
+	SourceType   SourceType    `json:"source_type"`
+	Capabilities Capabilities  `json:"capabilities"`
+	ConfigSchema *ConfigSchema `json:"config_schema"`
+	// SecretFields are the configuration fields holding credentials, as
+	// dotted paths, e.g. signature.secret
+	SecretFields []string `json:"secret_fields"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// OAuthConfigKey is the configuration field holding the OAuth tokens of a
+// subscription, see OAuthToken.
+const OAuthConfigKey = "oauth"
+
+// OAuthSecretFields are the fields of the OAuth tokens kept encrypted.
+var OAuthSecretFields = []string{OAuthConfigKey + ".access_token", OAuthConfigKey + ".refresh_token"}
+
+// OAuthToken is the token a subscription calls its source with, obtained by
+// the OAuth connect flow and refreshed before it expires.
+type OAuthToken struct {
+	AccessToken  string     `json:"access_token"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	TokenType    string     `json:"token_type,omitempty"`
+	Scope        string     `json:"scope,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // never when nil
+}
+
+// OAuthState is a started connect flow, the callback must bring its state
+// back before it expires.
+type OAuthState struct {
+	State          string    `json:"state"`
+	SubscriptionID string    `json:"subscription_id"`
+	Source         Source    `json:"source"`
+	CodeVerifier   string    `json:"-"` // PKCE
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PIIVaultEntry is an original value removed by the redaction, Value is only
+// set once decrypted.
+type PIIVaultEntry struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Source      Source    `json:"source"`
+	FeedbackID  string    `json:"feedback_id"`
+	Kind        string    `json:"kind"`
+	Replacement string    `json:"replacement"`
+	Ciphertext  []byte    `json:"-"`
+	Value       string    `json:"value,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
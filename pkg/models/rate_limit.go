@@ -0,0 +1,8 @@
+package models
+
+// RateLimit is a token bucket: calls are made at RequestsPerSecond on
+// average, with bursts of up to Burst calls.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
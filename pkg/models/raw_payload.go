@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// How a raw payload was received
+const (
+	PayloadPull = "pull" // fetched from the source
+	PayloadPush = "push" // sent to a webhook
+)
+
+// RawPayload is a payload as received from a source, archived so the
+// feedback mapped from it can be mapped again.
+type RawPayload struct {
+	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
+	SubscriptionID string    `json:"subscription_id"`
+	Source         Source    `json:"source"`
+	Kind           string    `json:"kind"` // pull or push
+	Data           []byte    `json:"-"`
+	Size           int       `json:"size"` // of the data, uncompressed
+	ReceivedAt     time.Time `json:"received_at"`
+}
+
+// ReprocessRequest selects the archived payloads to map again, From and To
+// bound the time they were received at.
+type ReprocessRequest struct {
+	TenantID string
+	Source   Source
+	From     time.Time // inclusive
+	To       time.Time // exclusive, no bound when zero
+	DryRun   bool
+}
+
+// ReprocessResult is the outcome of a reprocessing. A dry run reports the
+// changes the feedback would get without saving them.
+type ReprocessResult struct {
+	DryRun    bool           `json:"dry_run"`
+	Payloads  int            `json:"payloads"`
+	Feedbacks int            `json:"feedbacks"`
+	New       int            `json:"new"` // mapped from an archived payload but not stored
+	Changed   int            `json:"changed"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"` // payloads that could not be reprocessed
+	Changes   []FeedbackDiff `json:"changes"`
+	Errors    []PayloadError `json:"errors"`
+}
+
+// FeedbackDiff lists the fields of a feedback changed by a reprocessing,
+// with their stored and new values.
+type FeedbackDiff struct {
+	FeedbackID string                 `json:"feedback_id"`
+	Source     Source                 `json:"source"`
+	PayloadID  string                 `json:"payload_id"`
+	New        bool                   `json:"new,omitempty"`
+	Fields     map[string]FieldChange `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type PayloadError struct {
+	PayloadID string `json:"payload_id"`
+	Error     string `json:"error"`
+}
@@ -6,6 +6,8 @@ const (
 	SourceIntercom  Source = "intercom"
 	SourcePlaystore Source = "playstore"
 	SourceDiscourse Source = "discourse"
+	// SourceWebhook is the generic webhook, configured per subscription
+	SourceWebhook Source = "webhook"
 )
 
 type SourceType string
@@ -16,3 +18,12 @@ const (
 	STConversation SourceType = "conversation"
 	STReviews      SourceType = "reviews"
 )
+
+// Valid reports whether the source type is one of the known ones.
+func (t SourceType) Valid() bool {
+	switch t {
+	case STFeedback, STSurvey, STConversation, STReviews:
+		return true
+	}
+	return false
+}
@@ -12,11 +12,81 @@ type Subscription struct {
 	CreatedAt        time.Time              `json:"created_at"`
 	LastPulled       time.Time              `json:"last_pulled"` // Only applicable for pull
 	Active           bool                   `json:"active"`
+	// NeedsReauth is set when its OAuth token could not be refreshed, it is
+	// not pulled until it is connected again
+	NeedsReauth bool `json:"needs_reauth"`
+	// WebhookSecret verifies the webhook calls of push subscriptions, it is
+	// generated unless given when the subscription is created
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+	// DeletedAt is set when the subscription is deleted but its feedback
+	// kept, it is then inactive for good
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// WebhookURL is where the source calls a push subscription, only set
+	// when it is created
+	WebhookURL string `json:"webhook_url,omitempty"`
+
+	// Health is the state of its pulls, see SubscriptionHealth
+	Health              SubscriptionHealth `json:"health"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	LastError           string             `json:"last_error,omitempty"`
+	LastSuccessAt       *time.Time         `json:"last_success_at,omitempty"`
+	// NextPullAt is set when it failed, it is not pulled before
+	NextPullAt *time.Time `json:"next_pull_at,omitempty"`
+	// DisabledReason is set when it was deactivated for failing too many
+	// times in a row, resuming it clears it
+	DisabledReason string `json:"disabled_reason,omitempty"`
 }
 
+// SubscriptionHealth is the state of a subscription's pulls.
+type SubscriptionHealth string
+
+const (
+	HealthHealthy SubscriptionHealth = "healthy"
+	// HealthDegraded is a subscription whose last pulls failed
+	HealthDegraded SubscriptionHealth = "degraded"
+	// HealthFailing is a subscription failing for a while, it is about to
+	// be disabled
+	HealthFailing SubscriptionHealth = "failing"
+	// HealthAuthError is a subscription rejected by its source, it must be
+	// connected again
+	HealthAuthError SubscriptionHealth = "auth_error"
+)
+
 type SubscriptionMode string
 
 const (
 	SubscriptionModePush SubscriptionMode = "push"
 	SubscriptionModePull SubscriptionMode = "pull"
 )
+
+// SubscriptionFilter selects the subscriptions of a tenant.
+type SubscriptionFilter struct {
+	TenantID         string
+	Source           Source
+	SubscriptionMode SubscriptionMode
+	Active           *bool
+	Health           SubscriptionHealth
+	IncludeDeleted   bool
+	Limit            int
+	Offset           int
+}
+
+// ConnectionTest is the outcome of testing a subscription's connection to
+// its source.
+type ConnectionTest struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// Skipped is set when the source is not called, e.g. for a generic
+	// webhook: only the configuration was checked
+	Skipped   bool  `json:"skipped,omitempty"`
+	LatencyMS int64 `json:"latency_ms"`
+}
+
+// PullSample is the outcome of a dry-run pull: a sample of the feedback a
+// pull would ingest, run through the enrichment pipeline but not saved.
+type PullSample struct {
+	Feedbacks []*Feedback `json:"feedbacks"`
+	// Errors lists the payloads that failed to be fetched or mapped and the
+	// errors of the pipeline's stages
+	Errors []string `json:"errors"`
+}
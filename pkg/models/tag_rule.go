@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// TagRule tags the tenant's feedback matching all of its conditions with
+// Tag, at ingest and when the tenant's feedback is re-tagged.
+type TagRule struct {
+	ID         string            `json:"id"`
+	TenantID   string            `json:"tenant_id"`
+	Name       string            `json:"name"`
+	Tag        string            `json:"tag"`
+	Enabled    bool              `json:"enabled"`
+	Conditions TagRuleConditions `json:"conditions"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// TagRuleConditions are all required to match, empty ones are ignored. The
+// text matches when it contains one of the keywords (whole words, any case)
+// or matches one of the patterns.
+type TagRuleConditions struct {
+	Keywords   []string            `json:"keywords,omitempty"`
+	Patterns   []string            `json:"patterns,omitempty"`
+	Sources    []Source            `json:"sources,omitempty"`
+	Metadata   []MetadataCondition `json:"metadata,omitempty"`
+	Sentiments []string            `json:"sentiments,omitempty"` // positive, neutral or negative
+	// MinSentiment and MaxSentiment bound the sentiment score, feedback without one never matches
+	MinSentiment *float64 `json:"min_sentiment,omitempty"`
+	MaxSentiment *float64 `json:"max_sentiment,omitempty"`
+}
+
+// MetadataCondition compares a metadata field, a dotted path for nested
+// fields, to Value.
+type MetadataCondition struct {
+	Field string `json:"field"`
+	// Op is one of eq, ne, lt, lte, gt, gte, contains or exists
+	Op    string `json:"op"`
+	Value string `json:"value,omitempty"`
+	// Type is how values are compared: version ("2.10" > "2.9"), number or
+	// text. By default versions when both values look like one, text otherwise.
+	Type string `json:"type,omitempty"`
+}
+
+// RuleTag is a tag added by a tag rule.
+type RuleTag struct {
+	Tag    string
+	RuleID string
+}
@@ -1,7 +1,82 @@
 package models
 
 type Tenant struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	ApiKey string `json:"api_key"`
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	ApiKey   string         `json:"api_key"`
+	Settings TenantSettings `json:"settings"`
+}
+
+// TenantSettings holds the per-tenant ingestion settings, stored as JSON on
+// the tenant record. The zero value is the default behaviour.
+type TenantSettings struct {
+	LanguageDetection LanguageDetectionSettings `json:"language_detection"`
+	Pipeline          PipelineSettings          `json:"pipeline"`
+	Redaction         RedactionSettings         `json:"redaction"`
+	Deduplication     DeduplicationSettings     `json:"deduplication"`
+	Sentiment         SentimentSettings         `json:"sentiment"`
+}
+
+type LanguageDetectionSettings struct {
+	Disabled bool `json:"disabled"`
+	// Languages restricts the detection to these languages (ISO 639-1), empty means every supported language
+	Languages []string `json:"languages,omitempty"`
+	// MinConfidence below which the detected language is not stored
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+}
+
+// PipelineSettings configures the enrichment stages run on the tenant's
+// feedback before it is stored. Sources replaces Stages for the given source.
+type PipelineSettings struct {
+	Stages  []StageConfig            `json:"stages,omitempty"`
+	Sources map[Source][]StageConfig `json:"sources,omitempty"`
+}
+
+// StagesFor returns the stages configured for the source.
+func (p *PipelineSettings) StagesFor(source Source) []StageConfig {
+	if stages, ok := p.Sources[source]; ok {
+		return stages
+	}
+	return p.Stages
+}
+
+type StageConfig struct {
+	// Type of the built-in processor, e.g. html_to_text
+	Type    string                 `json:"type"`
+	Options map[string]interface{} `json:"options,omitempty"`
+	// OnError is either "skip" (default), passing the record on unchanged, or "drop"
+	OnError string `json:"on_error,omitempty"`
+}
+
+// RedactionSettings configures the removal of personal data from the
+// feedback before it is stored.
+type RedactionSettings struct {
+	Enabled bool `json:"enabled"`
+	// Mode is one of mask (default), hash or tokenize
+	Mode string `json:"mode,omitempty"`
+	// Detectors restricts the built-in detectors (email, phone, credit_card, iban, ip), empty means all of them
+	Detectors []string        `json:"detectors,omitempty"`
+	Rules     []RedactionRule `json:"rules,omitempty"`
+	// Vault keeps the original values, encrypted, in the PII vault
+	Vault bool `json:"vault,omitempty"`
+}
+
+// RedactionRule is a tenant-defined detector, Name is used as its kind.
+type RedactionRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+type DeduplicationSettings struct {
+	Disabled bool `json:"disabled"`
+	// Threshold is the estimated text similarity (0-1) from which two feedbacks are near-duplicates, 0.7 by default
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+type SentimentSettings struct {
+	Disabled bool `json:"disabled"`
+	// RatingWeight is the share (0-1) of the rating in the score of rated feedback, 0.5 by default
+	RatingWeight *float64 `json:"rating_weight,omitempty"`
+	// RatingScale is the highest rating of the tenant's sources, ratings going from 1 to it, 5 by default
+	RatingScale float64 `json:"rating_scale,omitempty"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Topic groups feedback sharing a keyphrase, its label. Topics are kept
+// across runs of the topic job, a label found again being the same topic.
+type Topic struct {
+	ID         string        `json:"id"`
+	TenantID   string        `json:"tenant_id"`
+	Label      string        `json:"label"`
+	Keywords   []string      `json:"keywords"`
+	CreatedAt  time.Time     `json:"created_at"`
+	LastSeenAt time.Time     `json:"last_seen_at"`
+	Members    []TopicMember `json:"-"`
+}
+
+type TopicMember struct {
+	Source     Source  `json:"source"`
+	FeedbackID string  `json:"feedback_id"`
+	Score      float64 `json:"score"` // weight of the topic label in the feedback
+}
+
+// TrendingTopic is the volume of a topic over a window compared with the
+// window of the same length right before it.
+type TrendingTopic struct {
+	Topic
+	Volume         int         `json:"volume"`
+	PreviousVolume int         `json:"previous_volume"`
+	Change         int         `json:"change"`
+	ChangePercent  *float64    `json:"change_percent"` // nil when the topic was absent from the previous window
+	Samples        []*Feedback `json:"samples"`
+}
+
+// TopicReportFilter selects the window, and optionally the source and
+// sub-source, of a trending topics report.
+type TopicReportFilter struct {
+	TenantID    string
+	Source      Source
+	SubSourceID string
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+	Limit       int
+	Samples     int
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Triage statuses, feedback without a triage record is new
+const (
+	TriageStatusNew      = "new"
+	TriageStatusReviewed = "reviewed"
+	TriageStatusActioned = "actioned"
+	TriageStatusIgnored  = "ignored"
+)
+
+// Triage priorities, empty when not prioritised yet
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// FeedbackTriage is the support team's handling of a feedback, kept apart
+// from the ingested record so that re-ingesting it never overwrites it.
+type FeedbackTriage struct {
+	TenantID   string    `json:"tenant_id"`
+	Source     Source    `json:"source"`
+	FeedbackID string    `json:"feedback_id"`
+	Status     string    `json:"status"`
+	Assignee   string    `json:"assignee"`
+	Priority   string    `json:"priority"`
+	UpdatedBy  string    `json:"updated_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// FeedbackNote is an internal note on a feedback.
+type FeedbackNote struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	Source     Source    `json:"source"`
+	FeedbackID string    `json:"feedback_id"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FeedbackTag is a tag added by hand to a feedback.
+type FeedbackTag struct {
+	TenantID   string `json:"tenant_id"`
+	Source     Source `json:"source"`
+	FeedbackID string `json:"feedback_id"`
+	Tag        string `json:"tag"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Webhook inbox statuses
+const (
+	InboxPending    = "pending"
+	InboxProcessing = "processing"
+	InboxDone       = "done"
+	InboxDead       = "dead" // failed every attempt
+)
+
+// WebhookInboxEntry is a webhook call as received, processed in the
+// background.
+type WebhookInboxEntry struct {
+	ID             string              `json:"id"`
+	TenantID       string              `json:"tenant_id"`
+	SubscriptionID string              `json:"subscription_id"`
+	Source         Source              `json:"source"`
+	Headers        map[string][]string `json:"headers,omitempty"`
+	Query          string              `json:"query,omitempty"`
+	Body           []byte              `json:"-"`
+	Status         string              `json:"status"`
+	Attempts       int                 `json:"attempts"`
+	NextAttemptAt  time.Time           `json:"next_attempt_at"`
+	LastError      string              `json:"last_error,omitempty"`
+	ReceivedAt     time.Time           `json:"received_at"`
+	ProcessedAt    *time.Time          `json:"processed_at,omitempty"`
+	// LeaseToken is the token of the claim the entry is processed under
+	LeaseToken string `json:"-"`
+}
+
+// WebhookInboxFilter holds the filters of the inbox listing, zero values
+// are ignored.
+type WebhookInboxFilter struct {
+	TenantID       string
+	SubscriptionID string
+	Source         Source
+	Status         string
+	Limit          int
+	Offset         int
+}
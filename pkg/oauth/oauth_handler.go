@@ -0,0 +1,93 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+)
+
+type OAuthHandler struct {
+	service    *OAuthService
+	subService *subscription.SubscriptionService
+}
+
+func NewOAuthHandler(service *OAuthService, subService *subscription.SubscriptionService) *OAuthHandler {
+	return &OAuthHandler{service: service, subService: subService}
+}
+
+// StartHandler handles POST /oauth/{source}/start?tenant_id=...&subscription_id=...,
+// it returns the authorization_url the user connects the subscription at.
+func (h *OAuthHandler) StartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+	subscriptionID := r.URL.Query().Get("subscription_id")
+	if subscriptionID == "" {
+		http.Error(w, "Subscription ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		http.Error(w, "Invalid Subscription ID format", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	sub, err := h.subService.GetSubscription(ctx, subscriptionID)
+	if err != nil || sub.TenantID != tenantID || sub.Source != models.Source(r.PathValue("source")) || sub.DeletedAt != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	authorizationURL, err := h.service.Start(ctx, sub)
+	if errors.Is(err, ErrNotConfigured) {
+		http.Error(w, fmt.Sprintf("OAuth is not configured for %s", sub.Source), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to start OAuth: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"authorization_url": authorizationURL})
+}
+
+// CallbackHandler handles GET /oauth/{source}/callback, where the provider
+// redirects the user after they consented.
+func (h *OAuthHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		http.Error(w, fmt.Sprintf("Authorization failed: %s", providerError), http.StatusBadRequest)
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		http.Error(w, "State and code are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	sub, err := h.service.Callback(ctx, models.Source(r.PathValue("source")), state, code)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to connect subscription: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Subscription %s connected to %s", sub.ID, sub.Source)
+}
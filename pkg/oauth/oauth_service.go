@@ -0,0 +1,312 @@
+// Package oauth connects subscriptions to their source with the OAuth
+// authorization code flow (with PKCE), and keeps their access token fresh.
+// The tokens are kept in the subscription's configuration, encrypted.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+)
+
+const (
+	// stateTTL is the time the user has to consent
+	stateTTL = 15 * time.Minute
+	// refreshMargin is how long before it expires a token is refreshed
+	refreshMargin = 5 * time.Minute
+)
+
+// ErrNotConfigured is returned for a source without an OAuth client.
+var ErrNotConfigured = errors.New("OAuth is not configured for the source")
+
+// TokenError is the error response of a token endpoint.
+type TokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+// Rejected reports whether the grant is not valid anymore (e.g. revoked),
+// the subscription must be connected again. Other errors may be temporary.
+func (e *TokenError) Rejected() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+func (e *TokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("token endpoint returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("token endpoint returned %s: %s", e.Code, e.Description)
+}
+
+type OAuthService struct {
+	repo       *db.OAuthStateRepository
+	subService *subscription.SubscriptionService
+	providers  map[models.Source]*Provider
+	publicURL  string
+	client     *http.Client
+}
+
+func NewOAuthService(repo *db.OAuthStateRepository, subService *subscription.SubscriptionService, providers map[models.Source]*Provider, publicURL string) *OAuthService {
+	return &OAuthService{
+		repo:       repo,
+		subService: subService,
+		providers:  providers,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Start starts the connect flow of the subscription and returns the URL the
+// user consents at, the provider then redirects them to the callback.
+func (s *OAuthService) Start(ctx context.Context, sub *models.Subscription) (string, error) {
+	provider, ok := s.providers[sub.Source]
+	if !ok {
+		return "", ErrNotConfigured
+	}
+
+	state := &models.OAuthState{SubscriptionID: sub.ID, Source: sub.Source, CreatedAt: time.Now().UTC()}
+	for _, value := range []*string{&state.State, &state.CodeVerifier} {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("failed to generate state: %v", err)
+		}
+		*value = base64.RawURLEncoding.EncodeToString(random)
+	}
+	state.ExpiresAt = state.CreatedAt.Add(stateTTL)
+	if err := s.repo.Save(ctx, state); err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {s.redirectURI(sub.Source)},
+		"state":                 {state.State},
+		"code_challenge":        {codeChallenge(state.CodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if len(provider.Scopes) > 0 {
+		query.Set("scope", strings.Join(provider.Scopes, " "))
+	}
+	for key, value := range provider.AuthParams {
+		query.Set(key, value)
+	}
+
+	return provider.AuthURL + "?" + query.Encode(), nil
+}
+
+// Callback completes the connect flow: the code is exchanged for a token,
+// saved on the subscription of the state, which no longer needs re-auth.
+func (s *OAuthService) Callback(ctx context.Context, source models.Source, state, code string) (*models.Subscription, error) {
+	provider, ok := s.providers[source]
+	if !ok {
+		return nil, ErrNotConfigured
+	}
+
+	started, err := s.repo.Consume(ctx, state)
+	if err != nil || started.Source != source {
+		return nil, fmt.Errorf("unknown or expired state")
+	}
+
+	token, err := s.requestToken(ctx, provider, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURI(source)},
+		"code_verifier": {started.CodeVerifier},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange the code: %v", err)
+	}
+
+	sub, err := s.subService.GetSubscription(ctx, started.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, sub, token); err != nil {
+		return nil, err
+	}
+	if err := s.subService.SetNeedsReauth(ctx, sub.ID, false); err != nil {
+		return nil, err
+	}
+	sub.NeedsReauth = false
+
+	return sub, nil
+}
+
+// EnsureFresh refreshes the access token of the subscription if it expires
+// soon, the subscription is updated with the new one. Subscriptions of
+// sources without OAuth, or not connected, are left alone. When the
+// provider rejects the refresh the subscription is flagged as needing
+// re-auth.
+func (s *OAuthService) EnsureFresh(ctx context.Context, sub *models.Subscription) error {
+	provider, ok := s.providers[sub.Source]
+	if !ok {
+		return nil
+	}
+	token, ok := TokenOf(sub)
+	if !ok || !expiresSoon(token) {
+		return nil
+	}
+
+	// another replica may have refreshed it already
+	if stored, err := s.subService.GetSubscription(ctx, sub.ID); err == nil {
+		if storedToken, ok := TokenOf(stored); ok && !expiresSoon(storedToken) {
+			sub.Configuration = stored.Configuration
+			return nil
+		}
+	}
+
+	if token.RefreshToken == "" {
+		return s.needsReauth(ctx, sub, fmt.Errorf("the access token expired and there is no refresh token"))
+	}
+
+	refreshed, err := s.requestToken(ctx, provider, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Rejected() {
+		return s.needsReauth(ctx, sub, err)
+	}
+	if err != nil {
+		metrics.IncCounter("oauth_refresh_total", "OAuth token refreshes, by outcome.", metrics.Labels{"source": string(sub.Source), "outcome": "error"}, 1)
+		return fmt.Errorf("failed to refresh the access token: %v", err)
+	}
+	if refreshed.RefreshToken == "" {
+		// not rotated
+		refreshed.RefreshToken = token.RefreshToken
+	}
+
+	metrics.IncCounter("oauth_refresh_total", "OAuth token refreshes, by outcome.", metrics.Labels{"source": string(sub.Source), "outcome": "refreshed"}, 1)
+	return s.save(ctx, sub, refreshed)
+}
+
+func (s *OAuthService) needsReauth(ctx context.Context, sub *models.Subscription, cause error) error {
+	metrics.IncCounter("oauth_refresh_total", "OAuth token refreshes, by outcome.", metrics.Labels{"source": string(sub.Source), "outcome": "needs_reauth"}, 1)
+	if err := s.subService.SetNeedsReauth(ctx, sub.ID, true); err != nil {
+		return fmt.Errorf("failed to flag subscription %s as needing re-auth (%v): %v", sub.ID, cause, err)
+	}
+	sub.NeedsReauth = true
+	return fmt.Errorf("subscription %s needs to be connected again: %v", sub.ID, cause)
+}
+
+// save sets the token on the subscription and saves it.
+func (s *OAuthService) save(ctx context.Context, sub *models.Subscription, token *models.OAuthToken) error {
+	var value map[string]interface{}
+	data, _ := json.Marshal(token)
+	json.Unmarshal(data, &value)
+
+	if sub.Configuration == nil {
+		sub.Configuration = map[string]interface{}{}
+	}
+	sub.Configuration[models.OAuthConfigKey] = value
+	return s.subService.SaveConfiguration(ctx, sub)
+}
+
+// requestToken calls the token endpoint of the provider.
+func (s *OAuthService) requestToken(ctx context.Context, provider *Provider, form url.Values) (*models.OAuthToken, error) {
+	form.Set("client_id", provider.ClientID)
+	form.Set("client_secret", provider.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		tokenErr := &TokenError{StatusCode: resp.StatusCode}
+		var errorResponse struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &errorResponse) == nil {
+			tokenErr.Code, tokenErr.Description = errorResponse.Error, errorResponse.ErrorDescription
+		}
+		return nil, tokenErr
+	}
+
+	var tokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		Scope        string `json:"scope"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token response: %v", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("no access token in the response")
+	}
+
+	token := &models.OAuthToken{
+		AccessToken:  tokenResponse.AccessToken,
+		RefreshToken: tokenResponse.RefreshToken,
+		TokenType:    tokenResponse.TokenType,
+		Scope:        tokenResponse.Scope,
+	}
+	if tokenResponse.ExpiresIn > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+		token.ExpiresAt = &expiresAt
+	}
+	return token, nil
+}
+
+// codeChallenge is the PKCE S256 challenge of the code verifier.
+func codeChallenge(verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(challenge[:])
+}
+
+func (s *OAuthService) redirectURI(source models.Source) string {
+	return fmt.Sprintf("%s/oauth/%s/callback", s.publicURL, source)
+}
+
+// TokenOf returns the OAuth token of the subscription, if it is connected.
+func TokenOf(sub *models.Subscription) (*models.OAuthToken, bool) {
+	value, ok := sub.Configuration[models.OAuthConfigKey]
+	if !ok {
+		return nil, false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	token := &models.OAuthToken{}
+	if err := json.Unmarshal(data, token); err != nil || token.AccessToken == "" {
+		return nil, false
+	}
+	return token, true
+}
+
+func expiresSoon(token *models.OAuthToken) bool {
+	return token.ExpiresAt != nil && time.Until(*token.ExpiresAt) < refreshMargin
+}
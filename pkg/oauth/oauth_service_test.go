@@ -0,0 +1,206 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// testTokenServer answers the token requests with the status and body,
+// after checking them with check.
+func testTokenServer(t *testing.T, status int, body string, check func(form url.Values)) *Provider {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("got a %s request of %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		if check != nil {
+			check(r.PostForm)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return &Provider{TokenURL: server.URL, ClientID: "client", ClientSecret: "client-secret"}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	tests := []struct {
+		verifier, want string
+	}{
+		// base64url of the SHA-256 of the verifier, without padding
+		{"dBjftJeZ4CVP-mJ92ZUhiVJVEc8xOKk1Dzk4hKNvSn0", "LwwnLw2Qp4gQ3wQPJinD97jMHiBegDHMnowtmjnpZnw"},
+		{"dBjftJeZ4CVP-mJ92ZUjYrjeF_Rf8JxxyfHeTpMdVxg", "_Y8qnteM8sFr-lzaSsCAt8kL6Labl54QxRFNs0kss50"},
+	}
+	for _, tt := range tests {
+		if got := codeChallenge(tt.verifier); got != tt.want {
+			t.Errorf("codeChallenge(%q) = %q, want %q", tt.verifier, got, tt.want)
+		}
+	}
+}
+
+func TestRequestTokenRefresh(t *testing.T) {
+	provider := testTokenServer(t, http.StatusOK,
+		`{"access_token": "new-access", "refresh_token": "new-refresh", "token_type": "Bearer", "scope": "read", "expires_in": 3600}`,
+		func(form url.Values) {
+			want := url.Values{
+				"grant_type":    {"refresh_token"},
+				"refresh_token": {"old-refresh"},
+				"client_id":     {"client"},
+				"client_secret": {"client-secret"},
+			}
+			if form.Encode() != want.Encode() {
+				t.Errorf("got form %v, want %v", form, want)
+			}
+		})
+	s := NewOAuthService(nil, nil, nil, "https://feedback.example.com/")
+
+	token, err := s.requestToken(context.Background(), provider, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"old-refresh"}})
+	if err != nil {
+		t.Fatalf("requestToken: %v", err)
+	}
+	if token.AccessToken != "new-access" || token.RefreshToken != "new-refresh" || token.TokenType != "Bearer" || token.Scope != "read" {
+		t.Errorf("got token %+v", token)
+	}
+	if token.ExpiresAt == nil || time.Until(*token.ExpiresAt) < 59*time.Minute || time.Until(*token.ExpiresAt) > time.Hour {
+		t.Errorf("got expiry %v, want in an hour", token.ExpiresAt)
+	}
+}
+
+func TestRequestTokenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		// wantRejected is whether the error is a TokenError rejecting the
+		// grant, the subscription needs re-auth then
+		wantRejected bool
+		wantErr      string
+	}{
+		{"revoked", http.StatusBadRequest, `{"error": "invalid_grant", "error_description": "revoked"}`, true, "token endpoint returned invalid_grant: revoked"},
+		{"unauthorized", http.StatusUnauthorized, ``, true, "token endpoint returned status 401"},
+		{"forbidden", http.StatusForbidden, `not json`, true, "token endpoint returned status 403"},
+		{"unavailable", http.StatusServiceUnavailable, ``, false, "token endpoint returned status 503"},
+		{"too many requests", http.StatusTooManyRequests, ``, false, "token endpoint returned status 429"},
+		{"no access token", http.StatusOK, `{"token_type": "Bearer"}`, false, "no access token in the response"},
+		{"invalid response", http.StatusOK, `{`, false, "failed to unmarshal token response: unexpected end of JSON input"},
+	}
+	s := NewOAuthService(nil, nil, nil, "")
+	for _, tt := range tests {
+		provider := testTokenServer(t, tt.status, tt.body, nil)
+		_, err := s.requestToken(context.Background(), provider, url.Values{"grant_type": {"refresh_token"}})
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			continue
+		}
+		var tokenErr *TokenError
+		if rejected := errors.As(err, &tokenErr) && tokenErr.Rejected(); rejected != tt.wantRejected {
+			t.Errorf("%s: got rejected %v, want %v", tt.name, rejected, tt.wantRejected)
+		}
+	}
+}
+
+func TestTokenOf(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration map[string]interface{}
+		want          string
+	}{
+		{"connected", map[string]interface{}{models.OAuthConfigKey: map[string]interface{}{
+			"access_token": "access", "refresh_token": "refresh", "expires_at": "2030-01-01T00:00:00Z"}}, "access"},
+		{"not connected", map[string]interface{}{"api_key": "key"}, ""},
+		{"no configuration", nil, ""},
+		{"no access token", map[string]interface{}{models.OAuthConfigKey: map[string]interface{}{"refresh_token": "refresh"}}, ""},
+		{"not an object", map[string]interface{}{models.OAuthConfigKey: "access"}, ""},
+	}
+	for _, tt := range tests {
+		token, ok := TokenOf(&models.Subscription{Configuration: tt.configuration})
+		if ok != (tt.want != "") || (ok && token.AccessToken != tt.want) {
+			t.Errorf("%s: got %+v, %v, want access token %q", tt.name, token, ok, tt.want)
+		}
+	}
+}
+
+func TestExpiresSoon(t *testing.T) {
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d)
+		return &t
+	}
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      bool
+	}{
+		{"never", nil, false},
+		{"expired", at(-time.Minute), true},
+		{"within the margin", at(refreshMargin - time.Minute), true},
+		{"after the margin", at(refreshMargin + time.Minute), false},
+	}
+	for _, tt := range tests {
+		if got := expiresSoon(&models.OAuthToken{AccessToken: "access", ExpiresAt: tt.expiresAt}); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// EnsureFresh leaves the subscriptions it has nothing to refresh of alone,
+// without reading them again.
+func TestEnsureFreshLeavesFreshTokensAlone(t *testing.T) {
+	s := NewOAuthService(nil, nil, map[models.Source]*Provider{models.SourceIntercom: {ClientID: "client"}}, "")
+	in := func(d time.Duration) string {
+		return time.Now().Add(d).UTC().Format(time.RFC3339)
+	}
+
+	tests := []struct {
+		name string
+		sub  *models.Subscription
+	}{
+		{"source without OAuth", &models.Subscription{Source: models.SourceDiscourse, Configuration: map[string]interface{}{
+			models.OAuthConfigKey: map[string]interface{}{"access_token": "access", "expires_at": in(-time.Hour)}}}},
+		{"not connected", &models.Subscription{Source: models.SourceIntercom, Configuration: map[string]interface{}{}}},
+		{"never expires", &models.Subscription{Source: models.SourceIntercom, Configuration: map[string]interface{}{
+			models.OAuthConfigKey: map[string]interface{}{"access_token": "access"}}}},
+		{"expires later", &models.Subscription{Source: models.SourceIntercom, Configuration: map[string]interface{}{
+			models.OAuthConfigKey: map[string]interface{}{"access_token": "access", "expires_at": in(time.Hour)}}}},
+	}
+	for _, tt := range tests {
+		if err := s.EnsureFresh(context.Background(), tt.sub); err != nil || tt.sub.NeedsReauth {
+			t.Errorf("%s: got error %v, needs re-auth %v", tt.name, err, tt.sub.NeedsReauth)
+		}
+	}
+}
+
+func TestNewProviders(t *testing.T) {
+	if providers := NewProviders(&config.Config{}); len(providers) != 0 {
+		t.Errorf("got providers %v without clients", providers)
+	}
+
+	providers := NewProviders(&config.Config{GoogleClientID: "google", GoogleClientSecret: "secret"})
+	google, ok := providers[models.SourcePlaystore]
+	if len(providers) != 1 || !ok {
+		t.Fatalf("got providers %v, want the Play Store's", providers)
+	}
+	if google.ClientID != "google" || google.ClientSecret != "secret" || google.AuthParams["access_type"] != "offline" {
+		t.Errorf("got provider %+v", google)
+	}
+}
+
+func TestRedirectURI(t *testing.T) {
+	s := NewOAuthService(nil, nil, nil, "https://feedback.example.com/")
+	if got := s.redirectURI(models.SourceIntercom); got != "https://feedback.example.com/oauth/intercom/callback" {
+		t.Errorf("got %q", got)
+	}
+}
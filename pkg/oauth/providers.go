@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// Provider is the OAuth authorization server of a source and the client
+// registered with it.
+type Provider struct {
+	AuthURL  string
+	TokenURL string
+	Scopes   []string
+	// AuthParams are added to the authorization URL
+	AuthParams   map[string]string
+	ClientID     string
+	ClientSecret string
+}
+
+// NewProviders returns the providers of the sources whose client is
+// configured.
+func NewProviders(cfg *config.Config) map[models.Source]*Provider {
+	providers := map[models.Source]*Provider{}
+	if cfg.IntercomClientID != "" {
+		providers[models.SourceIntercom] = &Provider{
+			AuthURL:      "https://app.intercom.com/oauth",
+			TokenURL:     "https://api.intercom.io/auth/eagle/token",
+			ClientID:     cfg.IntercomClientID,
+			ClientSecret: cfg.IntercomClientSecret,
+		}
+	}
+	if cfg.GoogleClientID != "" {
+		providers[models.SourcePlaystore] = &Provider{
+			AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+			Scopes:   []string{"https://www.googleapis.com/auth/androidpublisher"},
+			// a refresh token is only returned for offline access, and again
+			// on a later consent when prompted
+			AuthParams:   map[string]string{"access_type": "offline", "prompt": "consent"},
+			ClientID:     cfg.GoogleClientID,
+			ClientSecret: cfg.GoogleClientSecret,
+		}
+	}
+	return providers
+}
@@ -0,0 +1,35 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/dedup"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// Deduplicate fingerprints the feedback and clusters it with its stored
+// near-duplicates. It runs last, on the text as it is going to be stored.
+type Deduplicate struct {
+	service  *dedup.DedupService
+	settings models.DeduplicationSettings
+	// the feedback already processed by this run, not stored yet
+	pending []*models.Feedback
+}
+
+func NewDeduplicate(service *dedup.DedupService, settings models.DeduplicationSettings) *Deduplicate {
+	return &Deduplicate{service: service, settings: settings}
+}
+
+func (p *Deduplicate) Name() string {
+	return "deduplicate"
+}
+
+func (p *Deduplicate) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	if err := p.service.Assign(ctx, &p.settings, feedback, p.pending); err != nil {
+		return nil, err
+	}
+	if feedback.Signature != nil {
+		p.pending = append(p.pending, feedback)
+	}
+	return []*models.Feedback{feedback}, nil
+}
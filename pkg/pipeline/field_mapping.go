@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func init() {
+	Register("field_mapping", func(options map[string]interface{}) (Processor, error) {
+		p := &FieldMapping{}
+		if err := decodeOptions(options, p); err != nil {
+			return nil, err
+		}
+		for _, mapping := range p.Mappings {
+			if err := validateFieldPath(mapping.From); err != nil {
+				return nil, err
+			}
+			if err := validateFieldPath(mapping.To); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	})
+}
+
+// FieldMapping copies values between the common attributes, the metadata
+// ("metadata.<key>") and the content ("content.<key>", for object content).
+type FieldMapping struct {
+	Mappings []FieldMappingRule `json:"mappings"`
+}
+
+type FieldMappingRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Remove deletes the source field once copied, making the mapping a move
+	Remove bool `json:"remove,omitempty"`
+	// Overwrite replaces a target that already has a value
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+var commonFields = map[string]bool{
+	"author":            true,
+	"url":               true,
+	"language":          true,
+	"rating":            true,
+	"source_created_at": true,
+}
+
+func (p *FieldMapping) Name() string {
+	return "field_mapping"
+}
+
+func (p *FieldMapping) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	// validate every rule first, a failing stage leaves the feedback untouched
+	values := make([]interface{}, len(p.Mappings))
+	for i, mapping := range p.Mappings {
+		value, ok := getField(feedback, mapping.From)
+		if !ok {
+			continue
+		}
+		converted, err := convertField(mapping.To, value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot map %s to %s: %v", mapping.From, mapping.To, err)
+		}
+		values[i] = converted
+	}
+
+	for i, mapping := range p.Mappings {
+		if values[i] == nil {
+			continue
+		}
+		if _, exists := getField(feedback, mapping.To); exists && !mapping.Overwrite {
+			continue
+		}
+		setField(feedback, mapping.To, values[i])
+		if mapping.Remove {
+			deleteField(feedback, mapping.From)
+		}
+	}
+
+	return []*models.Feedback{feedback}, nil
+}
+
+func validateFieldPath(path string) error {
+	if commonFields[path] {
+		return nil
+	}
+	if key, ok := strings.CutPrefix(path, "metadata."); ok && key != "" {
+		return nil
+	}
+	if key, ok := strings.CutPrefix(path, "content."); ok && key != "" {
+		return nil
+	}
+	return fmt.Errorf("unsupported field %q", path)
+}
+
+// getField returns the value at path, and whether it is set.
+func getField(feedback *models.Feedback, path string) (interface{}, bool) {
+	switch path {
+	case "author":
+		return feedback.Author, feedback.Author != ""
+	case "url":
+		return feedback.URL, feedback.URL != ""
+	case "language":
+		return feedback.Language, feedback.Language != ""
+	case "rating":
+		if feedback.Rating == nil {
+			return nil, false
+		}
+		return *feedback.Rating, true
+	case "source_created_at":
+		if feedback.SourceCreatedAt == nil {
+			return nil, false
+		}
+		return *feedback.SourceCreatedAt, true
+	}
+
+	if key, ok := strings.CutPrefix(path, "metadata."); ok {
+		value, exists := feedback.Metadata[key]
+		return value, exists && value != nil
+	}
+	if key, ok := strings.CutPrefix(path, "content."); ok {
+		content, isMap := feedback.Content.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		value, exists := content[key]
+		return value, exists && value != nil
+	}
+	return nil, false
+}
+
+// convertField converts value to the type of the field at path.
+func convertField(path string, value interface{}) (interface{}, error) {
+	switch path {
+	case "author", "url", "language":
+		return fmt.Sprint(value), nil
+	case "rating":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case json.Number:
+			return v.Float64()
+		case int:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+		return nil, fmt.Errorf("not a number: %v", value)
+	case "source_created_at":
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return time.Parse(time.RFC3339, v)
+		case float64:
+			return time.Unix(int64(v), 0).UTC(), nil
+		case json.Number:
+			seconds, err := v.Float64()
+			return time.Unix(int64(seconds), 0).UTC(), err
+		}
+		return nil, fmt.Errorf("not a timestamp: %v", value)
+	}
+	return value, nil
+}
+
+func setField(feedback *models.Feedback, path string, value interface{}) {
+	switch path {
+	case "author":
+		feedback.Author = value.(string)
+	case "url":
+		feedback.URL = value.(string)
+	case "language":
+		feedback.Language = value.(string)
+		feedback.LanguageConfidence = 0
+	case "rating":
+		rating := value.(float64)
+		feedback.Rating = &rating
+	case "source_created_at":
+		createdAt := value.(time.Time)
+		feedback.SourceCreatedAt = &createdAt
+	}
+
+	if key, ok := strings.CutPrefix(path, "metadata."); ok {
+		if feedback.Metadata == nil {
+			feedback.Metadata = map[string]interface{}{}
+		}
+		feedback.Metadata[key] = value
+	}
+	if key, ok := strings.CutPrefix(path, "content."); ok {
+		if content, isMap := feedback.Content.(map[string]interface{}); isMap {
+			content[key] = value
+		}
+	}
+}
+
+func deleteField(feedback *models.Feedback, path string) {
+	switch path {
+	case "author", "url", "language":
+		setField(feedback, path, "")
+	case "rating":
+		feedback.Rating = nil
+	case "source_created_at":
+		feedback.SourceCreatedAt = nil
+	}
+
+	if key, ok := strings.CutPrefix(path, "metadata."); ok {
+		delete(feedback.Metadata, key)
+	}
+	if key, ok := strings.CutPrefix(path, "content."); ok {
+		if content, isMap := feedback.Content.(map[string]interface{}); isMap {
+			delete(content, key)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func init() {
+	Register("filter", func(options map[string]interface{}) (Processor, error) {
+		p := &Filter{}
+		if err := decodeOptions(options, p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+}
+
+// Filter drops the feedback that is too short to be useful or was written by
+// one of the excluded authors, bots usually.
+type Filter struct {
+	MinLength      int      `json:"min_length"`
+	ExcludeAuthors []string `json:"exclude_authors"`
+}
+
+func (p *Filter) Name() string {
+	return "filter"
+}
+
+func (p *Filter) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	for _, author := range p.ExcludeAuthors {
+		if strings.EqualFold(author, feedback.Author) {
+			return nil, nil
+		}
+	}
+	if p.MinLength > 0 && utf8.RuneCountInString(strings.TrimSpace(feedback.Text())) < p.MinLength {
+		return nil, nil
+	}
+	return []*models.Feedback{feedback}, nil
+}
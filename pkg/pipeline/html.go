@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+var (
+	htmlHiddenRe = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBreakRe  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBlockRe  = regexp.MustCompile(`(?i)</?(p|div|li|ul|ol|tr|table|blockquote|pre|h[1-6]|aside|section|article)\b[^>]*>`)
+	htmlTagRe    = regexp.MustCompile(`<[^>]*>`)
+	htmlLooksRe  = regexp.MustCompile(`<[a-zA-Z/][^>]*>|&[a-zA-Z#0-9]+;`)
+)
+
+func init() {
+	Register("html_to_text", func(options map[string]interface{}) (Processor, error) {
+		return &HTMLToText{}, nil
+	})
+}
+
+// HTMLToText turns the HTML of the content texts into plain text, block
+// elements becoming line breaks.
+type HTMLToText struct{}
+
+func (p *HTMLToText) Name() string {
+	return "html_to_text"
+}
+
+func (p *HTMLToText) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	feedback.MapText(HTMLToPlainText)
+	return []*models.Feedback{feedback}, nil
+}
+
+// HTMLToPlainText strips the markup of s, text that does not look like HTML
+// is returned as is.
+func HTMLToPlainText(s string) string {
+	if !htmlLooksRe.MatchString(s) {
+		return s
+	}
+
+	s = htmlHiddenRe.ReplaceAllString(s, "")
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = htmlBlockRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
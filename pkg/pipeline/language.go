@@ -0,0 +1,30 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/language"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// LanguageDetect detects the language of the feedback the source did not
+// declare one for. It always runs, at the end of the pipeline, following the
+// tenant's language detection settings.
+type LanguageDetect struct {
+	settings models.LanguageDetectionSettings
+}
+
+func NewLanguageDetect(settings models.LanguageDetectionSettings) *LanguageDetect {
+	return &LanguageDetect{settings: settings}
+}
+
+func (p *LanguageDetect) Name() string {
+	return "language_detect"
+}
+
+func (p *LanguageDetect) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	if feedback.Language == "" {
+		language.Detect(&p.settings, feedback)
+	}
+	return []*models.Feedback{feedback}, nil
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	EmojiKeep  = "keep"
+	EmojiStrip = "strip"
+
+	zeroWidthJoiner = '\u200d'
+)
+
+func init() {
+	Register("normalize", func(options map[string]interface{}) (Processor, error) {
+		p := &Normalize{Emoji: EmojiKeep}
+		if err := decodeOptions(options, p); err != nil {
+			return nil, err
+		}
+		if p.Emoji != EmojiKeep && p.Emoji != EmojiStrip {
+			return nil, fmt.Errorf("emoji must be %q or %q", EmojiKeep, EmojiStrip)
+		}
+		return p, nil
+	})
+}
+
+// Normalize puts the content texts in Unicode NFC form, drops invisible
+// characters, collapses runs of whitespace and blank lines and optionally
+// strips emoji.
+type Normalize struct {
+	Emoji string `json:"emoji"`
+}
+
+func (p *Normalize) Name() string {
+	return "normalize"
+}
+
+func (p *Normalize) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	feedback.MapText(p.normalize)
+	return []*models.Feedback{feedback}, nil
+}
+
+func (p *Normalize) normalize(s string) string {
+	s = norm.NFC.String(s)
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\r':
+			continue
+		case r == '\n':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		case p.Emoji == EmojiStrip && (isEmoji(r) || r == zeroWidthJoiner):
+			continue
+		case unicode.Is(unicode.Cf, r) && r != zeroWidthJoiner:
+			// zero-width spaces, direction marks, ... the joiner is part of emoji sequences
+			continue
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	kept := lines[:0]
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(kept) > 0 {
+				kept = append(kept, line)
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// isEmoji reports whether r belongs to one of the emoji blocks, or is one of
+// the modifiers used to build emoji sequences.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, flags, ...
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag sequences
+		return true
+	case r == 0x20E3: // keycap
+		return true
+	}
+	return false
+}
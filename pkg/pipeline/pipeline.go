@@ -0,0 +1,170 @@
+// Package pipeline runs the enrichment stages between the integrations and
+// the feedback storage.
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	OnErrorSkip = "skip"
+	OnErrorDrop = "drop"
+)
+
+// Processor is one stage of the pipeline. Process returns the records to
+// hand to the next stage: none drops the feedback, one passes it on
+// (modified or not) and several fan it out. A processor returning an error
+// must leave the feedback untouched.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error)
+}
+
+// Factory builds a processor from the options of its stage configuration.
+type Factory func(options map[string]interface{}) (Processor, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a processor type available to the stage configuration.
+func Register(typ string, factory Factory) {
+	factories[typ] = factory
+}
+
+// Types lists the registered processor types.
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+type stage struct {
+	processor Processor
+	onError   string
+}
+
+type Pipeline struct {
+	stages []stage
+	labels metrics.Labels
+}
+
+// StageError is a failure of a stage on one feedback.
+type StageError struct {
+	Stage    string
+	Feedback *models.Feedback
+	Dropped  bool
+	Err      error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s failed on feedback %s: %v", e.Stage, e.Feedback.ID, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Build creates the pipeline of the given stages, labels are attached to
+// every stage metric.
+func Build(configs []models.StageConfig, labels metrics.Labels) (*Pipeline, error) {
+	p := &Pipeline{labels: labels}
+	for _, config := range configs {
+		factory, ok := factories[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline stage type: %s", config.Type)
+		}
+		processor, err := factory(config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid options for pipeline stage %s: %v", config.Type, err)
+		}
+
+		onError := config.OnError
+		if onError == "" {
+			onError = OnErrorSkip
+		}
+		if onError != OnErrorSkip && onError != OnErrorDrop {
+			return nil, fmt.Errorf("invalid on_error for pipeline stage %s: %s", config.Type, config.OnError)
+		}
+
+		p.stages = append(p.stages, stage{processor: processor, onError: onError})
+	}
+	return p, nil
+}
+
+// Append adds processors at the end of the pipeline.
+func (p *Pipeline) Append(onError string, processors ...Processor) {
+	for _, processor := range processors {
+		p.stages = append(p.stages, stage{processor: processor, onError: onError})
+	}
+}
+
+// Run passes the feedbacks through every stage in order and returns what
+// comes out of the last one, along with the failures of each stage.
+func (p *Pipeline) Run(ctx context.Context, feedbacks []*models.Feedback) ([]*models.Feedback, []*StageError) {
+	var stageErrors []*StageError
+
+	for _, s := range p.stages {
+		name := s.processor.Name()
+		labels := p.stageLabels(name)
+		var next []*models.Feedback
+		start := time.Now()
+
+		for _, feedback := range feedbacks {
+			out, err := s.processor.Process(ctx, feedback)
+			if err != nil {
+				stageError := &StageError{Stage: name, Feedback: feedback, Dropped: s.onError == OnErrorDrop, Err: err}
+				stageErrors = append(stageErrors, stageError)
+				metrics.IncCounter("pipeline_stage_errors_total", "Records a pipeline stage failed on.", labels, 1)
+				if stageError.Dropped {
+					metrics.IncCounter("pipeline_stage_dropped_total", "Records dropped by a pipeline stage.", labels, 1)
+				} else {
+					next = append(next, feedback)
+				}
+				continue
+			}
+
+			if len(out) == 0 {
+				metrics.IncCounter("pipeline_stage_dropped_total", "Records dropped by a pipeline stage.", labels, 1)
+			}
+			next = append(next, out...)
+		}
+
+		metrics.IncCounter("pipeline_stage_records_in_total", "Records received by a pipeline stage.", labels, float64(len(feedbacks)))
+		metrics.IncCounter("pipeline_stage_records_out_total", "Records passed on by a pipeline stage.", labels, float64(len(next)))
+		metrics.IncCounter("pipeline_stage_duration_seconds_total", "Time spent in a pipeline stage.", labels, time.Since(start).Seconds())
+
+		feedbacks = next
+	}
+
+	return feedbacks, stageErrors
+}
+
+func (p *Pipeline) stageLabels(stage string) metrics.Labels {
+	labels := metrics.Labels{"stage": stage}
+	for k, v := range p.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// decodeOptions fills the processor's options struct from the generic
+// stage options.
+func decodeOptions(options map[string]interface{}, target interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
@@ -0,0 +1,142 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/dedup"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
+)
+
+type PipelineService struct {
+	tenantRepo *db.TenantRepository
+	vault      *redaction.VaultService
+	dedup      *dedup.DedupService
+	tagRules   *tagrule.TagRuleService
+	hashKey    []byte
+}
+
+func NewPipelineService(tenantRepo *db.TenantRepository, vault *redaction.VaultService, dedupService *dedup.DedupService, tagRuleService *tagrule.TagRuleService, hashKey string) *PipelineService {
+	return &PipelineService{tenantRepo: tenantRepo, vault: vault, dedup: dedupService, tagRules: tagRuleService, hashKey: []byte(hashKey)}
+}
+
+// ValidateSettings checks that every configured stage can be built.
+func ValidateSettings(settings *models.PipelineSettings) error {
+	if _, err := Build(settings.Stages, nil); err != nil {
+		return err
+	}
+	for source, stages := range settings.Sources {
+		if _, err := Build(stages, nil); err != nil {
+			return fmt.Errorf("source %s: %v", source, err)
+		}
+	}
+	return nil
+}
+
+// ForSource builds the tenant's pipeline for the source: the redaction if
+// enabled, the configured stages, then the built-in ones every feedback goes
+// through.
+func (s *PipelineService) ForSource(ctx context.Context, tenantID string, source models.Source) (*Pipeline, error) {
+	return s.forSource(ctx, tenantID, source, false)
+}
+
+// forSource builds the pipeline, without the stages writing to the database
+// (the PII vault and the deduplication) for a preview.
+func (s *PipelineService) forSource(ctx context.Context, tenantID string, source models.Source, preview bool) (*Pipeline, error) {
+	tenant, err := s.tenantRepo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pipeline settings: %v", err)
+	}
+
+	labels := metrics.Labels{"tenant_id": tenantID, "source": string(source)}
+	settings := tenant.Settings
+
+	p := &Pipeline{labels: labels}
+	if settings.Redaction.Enabled {
+		redactor, err := redaction.NewRedactor(tenantID, &settings.Redaction, s.hashKey)
+		if err != nil {
+			return nil, err
+		}
+		var vault *redaction.VaultService
+		if settings.Redaction.Vault && !preview {
+			vault = s.vault
+		}
+		p.Append(OnErrorDrop, NewRedact(redactor, vault))
+	}
+
+	configured, err := Build(settings.Pipeline.StagesFor(source), labels)
+	if err != nil {
+		return nil, err
+	}
+	p.stages = append(p.stages, configured.stages...)
+
+	engine, err := s.tagRules.Engine(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Append(OnErrorSkip,
+		NewLanguageDetect(settings.LanguageDetection),
+		NewSentiment(settings.Sentiment),
+		NewTagRules(engine),
+	)
+	if !preview {
+		p.Append(OnErrorSkip, NewDeduplicate(s.dedup, settings.Deduplication))
+	}
+
+	return p, nil
+}
+
+// Process runs every feedback through the pipeline of its tenant and source.
+// It fails when the pipeline of a tenant cannot be built, e.g. its settings
+// failed to be read: the feedback must not be stored unredacted, it is
+// dead-lettered or retried instead.
+func (s *PipelineService) Process(ctx context.Context, feedbacks []*models.Feedback) ([]*models.Feedback, []*StageError, error) {
+	return s.process(ctx, feedbacks, false)
+}
+
+// Preview runs the feedbacks through their pipeline like Process but
+// without storing anything, the redacted values are not kept in the vault
+// and no duplicate is looked for.
+func (s *PipelineService) Preview(ctx context.Context, feedbacks []*models.Feedback) ([]*models.Feedback, []*StageError, error) {
+	return s.process(ctx, feedbacks, true)
+}
+
+func (s *PipelineService) process(ctx context.Context, feedbacks []*models.Feedback, preview bool) ([]*models.Feedback, []*StageError, error) {
+	type key struct {
+		tenantID string
+		source   models.Source
+	}
+
+	var (
+		order  []key
+		groups = map[key][]*models.Feedback{}
+	)
+	for _, feedback := range feedbacks {
+		k := key{feedback.TenantID, feedback.Source}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], feedback)
+	}
+
+	var (
+		processed   []*models.Feedback
+		stageErrors []*StageError
+	)
+	for _, k := range order {
+		p, err := s.forSource(ctx, k.tenantID, k.source, preview)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build the pipeline of tenant %s for %s: %v", k.tenantID, k.source, err)
+		}
+		out, errs := p.Run(ctx, groups[k])
+		processed = append(processed, out...)
+		stageErrors = append(stageErrors, errs...)
+	}
+
+	return processed, stageErrors, nil
+}
@@ -0,0 +1,72 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
+)
+
+// Redact removes personal data from the content texts, the metadata and the
+// author. It runs first, when the tenant enables it, so that no other stage
+// sees the original values; a failure drops the feedback rather than
+// storing it unredacted.
+type Redact struct {
+	redactor *redaction.Redactor
+	vault    *redaction.VaultService // nil unless the tenant keeps the original values
+}
+
+func NewRedact(redactor *redaction.Redactor, vault *redaction.VaultService) *Redact {
+	return &Redact{redactor: redactor, vault: vault}
+}
+
+func (p *Redact) Name() string {
+	return "redact"
+}
+
+func (p *Redact) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	var findings []redaction.Finding
+	redact := func(s string) string {
+		redacted, found := p.redactor.Redact(s)
+		findings = append(findings, found...)
+		return redacted
+	}
+
+	// work on a copy, the feedback must stay untouched if the vault fails
+	redacted := *feedback
+	redacted.MapText(redact)
+	redacted.Author = redact(feedback.Author)
+	if feedback.Metadata != nil {
+		redacted.Metadata = redactValue(feedback.Metadata, redact).(map[string]interface{})
+	}
+
+	if p.vault != nil && len(findings) > 0 {
+		if err := p.vault.Store(ctx, &redacted, findings); err != nil {
+			return nil, err
+		}
+	}
+
+	*feedback = redacted
+	return []*models.Feedback{feedback}, nil
+}
+
+func redactValue(value interface{}, redact func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redact(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item, redact)
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactValue(item, redact)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
@@ -0,0 +1,27 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/sentiment"
+)
+
+// Sentiment scores the feedback. It always runs, after the language
+// detection since the lexicon depends on the language.
+type Sentiment struct {
+	settings models.SentimentSettings
+}
+
+func NewSentiment(settings models.SentimentSettings) *Sentiment {
+	return &Sentiment{settings: settings}
+}
+
+func (p *Sentiment) Name() string {
+	return "sentiment"
+}
+
+func (p *Sentiment) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	sentiment.Score(&p.settings, feedback)
+	return []*models.Feedback{feedback}, nil
+}
@@ -0,0 +1,27 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
+)
+
+// TagRules applies the tenant's tag rules. It always runs, after the
+// sentiment scoring since rules may match on sentiment.
+type TagRules struct {
+	engine *tagrule.Engine
+}
+
+func NewTagRules(engine *tagrule.Engine) *TagRules {
+	return &TagRules{engine: engine}
+}
+
+func (p *TagRules) Name() string {
+	return "tag_rules"
+}
+
+func (p *TagRules) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	p.engine.Apply(feedback)
+	return []*models.Feedback{feedback}, nil
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func init() {
+	Register("tagging", func(options map[string]interface{}) (Processor, error) {
+		var opts struct {
+			Rules []struct {
+				Tag      string   `json:"tag"`
+				Keywords []string `json:"keywords"`
+				Patterns []string `json:"patterns"`
+			} `json:"rules"`
+		}
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+
+		p := &Tagging{}
+		for _, rule := range opts.Rules {
+			if rule.Tag == "" {
+				return nil, fmt.Errorf("tagging rule without a tag")
+			}
+			patterns := make([]string, 0, len(rule.Keywords)+len(rule.Patterns))
+			for _, keyword := range rule.Keywords {
+				patterns = append(patterns, `(?i)\b`+regexp.QuoteMeta(strings.TrimSpace(keyword))+`\b`)
+			}
+			patterns = append(patterns, rule.Patterns...)
+
+			compiled := tagRule{tag: rule.Tag}
+			for _, pattern := range patterns {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("invalid pattern for tag %s: %v", rule.Tag, err)
+				}
+				compiled.patterns = append(compiled.patterns, re)
+			}
+			p.rules = append(p.rules, compiled)
+		}
+		return p, nil
+	})
+}
+
+// Tagging adds a tag to the feedback whose text contains one of the rule's
+// keywords (whole words, any case) or matches one of its patterns.
+type Tagging struct {
+	rules []tagRule
+}
+
+type tagRule struct {
+	tag      string
+	patterns []*regexp.Regexp
+}
+
+func (p *Tagging) Name() string {
+	return "tagging"
+}
+
+func (p *Tagging) Process(ctx context.Context, feedback *models.Feedback) ([]*models.Feedback, error) {
+	text := feedback.Text()
+	for _, rule := range p.rules {
+		for _, re := range rule.patterns {
+			if re.MatchString(text) {
+				feedback.AddTag(rule.tag)
+				break
+			}
+		}
+	}
+	return []*models.Feedback{feedback}, nil
+}
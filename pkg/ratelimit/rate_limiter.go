@@ -0,0 +1,116 @@
+// Package ratelimit limits the calls to the sources with token buckets kept
+// in the database, so the limits hold across concurrent pulls and replicas.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type RateLimiter struct {
+	repo *db.RateLimitRepository
+}
+
+func NewRateLimiter(repo *db.RateLimitRepository) *RateLimiter {
+	return &RateLimiter{repo: repo}
+}
+
+// Key returns the bucket of the calls made to the source with the
+// credential, which is hashed so it is not stored.
+func Key(source models.Source, credential string) string {
+	if credential == "" {
+		return string(source)
+	}
+	sum := sha256.Sum256([]byte(credential))
+	return string(source) + ":" + hex.EncodeToString(sum[:8])
+}
+
+// Wait blocks until a call can be made within the limit. The bucket is
+// fair: callers are served in the order they asked for a token. If the
+// bucket cannot be read the call is let through.
+func (l *RateLimiter) Wait(ctx context.Context, source models.Source, key string, limit models.RateLimit) error {
+	if limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+	tokens, err := l.repo.Take(ctx, key, normalize(limit))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("Rate limiter: %v, letting the call to %s through\n", err, source)
+		return nil
+	}
+	if tokens >= 0 {
+		return nil
+	}
+
+	wait := time.Duration(-tokens / limit.RequestsPerSecond * float64(time.Second))
+	metrics.IncCounter("source_rate_limit_wait_seconds_total", "Time spent waiting for the rate limits of the sources.", metrics.Labels{"source": string(source)}, wait.Seconds())
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Backoff holds back every call of the bucket for the given time, e.g. when
+// the source answered 429.
+func (l *RateLimiter) Backoff(ctx context.Context, key string, limit models.RateLimit, wait time.Duration) {
+	if limit.RequestsPerSecond <= 0 || wait <= 0 {
+		return
+	}
+	if err := l.repo.Drain(ctx, key, normalize(limit), wait); err != nil {
+		fmt.Printf("Rate limiter: %v\n", err)
+	}
+}
+
+// normalize makes room for at least one call in the bucket.
+func normalize(limit models.RateLimit) models.RateLimit {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return limit
+}
+
+// ParseLimits reads limits written source=requests_per_second:burst and
+// separated by commas, e.g. "discourse=1:10,intercom=10:50". The burst
+// defaults to 1.
+func ParseLimits(value string) (map[models.Source]models.RateLimit, error) {
+	limits := map[models.Source]models.RateLimit{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, spec, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(source) == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected source=requests_per_second:burst", entry)
+		}
+		rate, burst, _ := strings.Cut(spec, ":")
+
+		limit := models.RateLimit{Burst: 1}
+		var err error
+		if limit.RequestsPerSecond, err = strconv.ParseFloat(strings.TrimSpace(rate), 64); err != nil || limit.RequestsPerSecond <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q, expected a positive number of requests per second", entry)
+		}
+		if burst != "" {
+			if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("invalid rate limit %q, expected a positive burst", entry)
+			}
+		}
+		limits[models.Source(strings.TrimSpace(source))] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" discourse=0.5:5, intercom=100 ,")
+	if err != nil {
+		t.Fatalf("ParseLimits: %v", err)
+	}
+	want := map[models.Source]models.RateLimit{
+		models.SourceDiscourse: {RequestsPerSecond: 0.5, Burst: 5},
+		models.SourceIntercom:  {RequestsPerSecond: 100, Burst: 1},
+	}
+	if len(limits) != len(want) {
+		t.Fatalf("got %v, want %v", limits, want)
+	}
+	for source, limit := range want {
+		if limits[source] != limit {
+			t.Errorf("%s: got %+v, want %+v", source, limits[source], limit)
+		}
+	}
+
+	if limits, err := ParseLimits(""); err != nil || len(limits) != 0 {
+		t.Errorf("empty: got %v, %v", limits, err)
+	}
+	for _, value := range []string{"discourse", "=1", "discourse=0", "discourse=fast", "discourse=1:0", "discourse=1:many"} {
+		if _, err := ParseLimits(value); err == nil {
+			t.Errorf("%q was accepted", value)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	if key := Key(models.SourceDiscourse, ""); key != "discourse" {
+		t.Errorf("without credential: got %q", key)
+	}
+	key := Key(models.SourceDiscourse, "secret-token")
+	if !strings.HasPrefix(key, "discourse:") || strings.Contains(key, "secret-token") {
+		t.Errorf("got %q, want the source and a hash of the credential", key)
+	}
+	if key != Key(models.SourceDiscourse, "secret-token") || key == Key(models.SourceDiscourse, "other-token") {
+		t.Errorf("the key is not the credential's own")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if limit := normalize(models.RateLimit{RequestsPerSecond: 1}); limit.Burst != 1 {
+		t.Errorf("got burst %d, want 1", limit.Burst)
+	}
+	if limit := normalize(models.RateLimit{RequestsPerSecond: 1, Burst: 5}); limit.Burst != 5 {
+		t.Errorf("got burst %d, want 5", limit.Burst)
+	}
+}
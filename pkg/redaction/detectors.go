@@ -0,0 +1,148 @@
+package redaction
+
+import (
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	KindEmail      = "email"
+	KindPhone      = "phone"
+	KindCreditCard = "credit_card"
+	KindIBAN       = "iban"
+	KindIP         = "ip"
+)
+
+// Detector finds one kind of personal data in a text.
+type Detector struct {
+	Kind    string
+	pattern *regexp.Regexp
+	// valid filters out the pattern matches that are not the real thing
+	valid func(match string) bool
+}
+
+// builtinDetectors in priority order: when matches overlap the first
+// detector wins, e.g. a card number is not also reported as a phone number.
+var builtinDetectors = []*Detector{
+	{
+		Kind:    KindEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		Kind:    KindIBAN,
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		valid:   validIBAN,
+	},
+	{
+		Kind:    KindCreditCard,
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid:   validCardNumber,
+	},
+	{
+		Kind:    KindIP,
+		pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|(?i)\b(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}\b`),
+		valid:   validIP,
+	},
+	{
+		Kind:    KindPhone,
+		pattern: regexp.MustCompile(`(?:\+|\b00|\(|\b)\d[\d ().-]{6,}\d\b`),
+		valid:   validPhone,
+	},
+}
+
+// BuiltinKinds lists the kinds of the built-in detectors.
+func BuiltinKinds() []string {
+	kinds := make([]string, len(builtinDetectors))
+	for i, detector := range builtinDetectors {
+		kinds[i] = detector.Kind
+	}
+	return kinds
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validCardNumber checks the length and the Luhn checksum.
+func validCardNumber(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func validIP(match string) bool {
+	ip := net.ParseIP(match)
+	if ip == nil {
+		return false
+	}
+	// "::" and friends parse, but are not worth redacting
+	return strings.Count(match, ":") < 2 || len(strings.Trim(match, ":")) > 2
+}
+
+var dateLikeRe = regexp.MustCompile(`^\d{4}[-./]\d{1,2}[-./]\d{1,2}$|^\d{1,2}[-./]\d{1,2}[-./]\d{2,4}$`)
+
+// validPhone accepts 8 to 15 digits (E.164), numbers written without an
+// international prefix need at least 9. Dates are not phone numbers.
+func validPhone(match string) bool {
+	if dateLikeRe.MatchString(strings.TrimSpace(match)) {
+		return false
+	}
+	number := digits(match)
+	international := strings.HasPrefix(match, "+") || strings.HasPrefix(match, "00")
+	if international {
+		return len(number) >= 8 && len(number) <= 15
+	}
+	return len(number) >= 9 && len(number) <= 15
+}
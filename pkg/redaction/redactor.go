@@ -0,0 +1,178 @@
+// Package redaction finds personal data in feedback texts and masks, hashes
+// or tokenizes it before the feedback is stored.
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	ModeMask     = "mask"
+	ModeHash     = "hash"
+	ModeTokenize = "tokenize"
+)
+
+// Finding is one redacted value.
+type Finding struct {
+	Kind        string
+	Value       string
+	Replacement string
+}
+
+type Redactor struct {
+	tenantID  string
+	mode      string
+	hashKey   []byte
+	detectors []*Detector
+}
+
+// NewRedactor builds the redactor of a tenant's settings. hashKey keys the
+// hashes and tokens, the tenant ID is mixed in so that the same value gets a
+// different hash for every tenant. The hash and tokenize modes require a key,
+// without one the hashes could be reversed by hashing the likely values.
+func NewRedactor(tenantID string, settings *models.RedactionSettings, hashKey []byte) (*Redactor, error) {
+	mode := settings.Mode
+	if mode == "" {
+		mode = ModeMask
+	}
+	if mode != ModeMask && mode != ModeHash && mode != ModeTokenize {
+		return nil, fmt.Errorf("unknown redaction mode: %s", settings.Mode)
+	}
+	if mode != ModeMask && len(hashKey) == 0 {
+		return nil, fmt.Errorf("the %s redaction mode requires PII_HASH_KEY to be set", mode)
+	}
+
+	r := &Redactor{tenantID: tenantID, mode: mode, hashKey: hashKey}
+
+	if len(settings.Detectors) == 0 {
+		r.detectors = append(r.detectors, builtinDetectors...)
+	} else {
+		for _, kind := range settings.Detectors {
+			detector := builtinDetector(kind)
+			if detector == nil {
+				return nil, fmt.Errorf("unknown redaction detector: %s", kind)
+			}
+			r.detectors = append(r.detectors, detector)
+		}
+	}
+
+	for _, rule := range settings.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule without a name")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for redaction rule %s: %v", rule.Name, err)
+		}
+		r.detectors = append(r.detectors, &Detector{Kind: rule.Name, pattern: pattern})
+	}
+
+	return r, nil
+}
+
+func builtinDetector(kind string) *Detector {
+	for _, detector := range builtinDetectors {
+		if detector.Kind == kind {
+			return detector
+		}
+	}
+	return nil
+}
+
+// Mode returns the redaction mode in use.
+func (r *Redactor) Mode() string {
+	return r.mode
+}
+
+type match struct {
+	start, end int
+	kind       string
+	priority   int
+}
+
+// Redact replaces the personal data found in text.
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	var matches []match
+	for priority, detector := range r.detectors {
+		for _, loc := range detector.pattern.FindAllStringIndex(text, -1) {
+			value := text[loc[0]:loc[1]]
+			if detector.valid != nil && !detector.valid(value) {
+				continue
+			}
+			matches = append(matches, match{start: loc[0], end: loc[1], kind: detector.Kind, priority: priority})
+		}
+	}
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	// on overlaps keep the match of the detector that comes first
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].priority != matches[j].priority {
+			return matches[i].priority < matches[j].priority
+		}
+		return matches[i].start < matches[j].start
+	})
+	var kept []match
+	for _, m := range matches {
+		overlaps := false
+		for _, k := range kept {
+			if m.start < k.end && k.start < m.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+
+	var (
+		b        strings.Builder
+		findings []Finding
+		last     int
+	)
+	for _, m := range kept {
+		value := text[m.start:m.end]
+		replacement := r.replacement(m.kind, value)
+		b.WriteString(text[last:m.start])
+		b.WriteString(replacement)
+		last = m.end
+		findings = append(findings, Finding{Kind: m.kind, Value: value, Replacement: replacement})
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), findings
+}
+
+func (r *Redactor) replacement(kind, value string) string {
+	switch r.mode {
+	case ModeHash:
+		return fmt.Sprintf("[%s:%s]", kind, r.digest(kind, value)[:16])
+	case ModeTokenize:
+		return fmt.Sprintf("[tok_%s_%s]", kind, r.digest("token", kind, value)[:20])
+	default:
+		return fmt.Sprintf("[%s]", strings.ToUpper(kind))
+	}
+}
+
+// digest is deterministic per tenant, so that the same value always gets the
+// same hash or token and stays countable after redaction.
+func (r *Redactor) digest(parts ...string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(r.tenantID))
+	for _, part := range parts {
+		mac.Write([]byte{0})
+		mac.Write([]byte(part))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,141 @@
+package redaction
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+var testHashKey = []byte("test-hash-key")
+
+func TestRedactMask(t *testing.T) {
+	r, err := NewRedactor("tenant", &models.RedactionSettings{Enabled: true}, nil)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"mail me at jane.doe@example.com please", "mail me at [EMAIL] please"},
+		{"card 4111 1111 1111 1111 expired", "card [CREDIT_CARD] expired"},
+		{"call +44 20 7946 0958 today", "call [PHONE] today"},
+		{"IBAN GB82 WEST 1234 5698 7654 32", "IBAN [IBAN]"},
+		{"from 192.168.10.12", "from [IP]"},
+		// fails the Luhn checksum
+		{"order 4111 1111 1111 1112", "order 4111 1111 1111 1112"},
+		// a date is not a phone number
+		{"since 2024-01-15", "since 2024-01-15"},
+		{"nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		got, _ := r.Redact(tt.text)
+		if got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRedactFindings(t *testing.T) {
+	r, err := NewRedactor("tenant", &models.RedactionSettings{Enabled: true}, nil)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	_, findings := r.Redact("a@example.com and b@example.com")
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(findings))
+	}
+	if findings[0].Kind != KindEmail || findings[0].Value != "a@example.com" || findings[0].Replacement != "[EMAIL]" {
+		t.Errorf("unexpected first finding: %+v", findings[0])
+	}
+	if findings[1].Value != "b@example.com" {
+		t.Errorf("unexpected second finding: %+v", findings[1])
+	}
+}
+
+func TestRedactHashIsKeyedPerTenant(t *testing.T) {
+	settings := &models.RedactionSettings{Enabled: true, Mode: ModeHash}
+	first, err := NewRedactor("tenant-1", settings, testHashKey)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	second, _ := NewRedactor("tenant-2", settings, testHashKey)
+	otherKey, _ := NewRedactor("tenant-1", settings, []byte("another-key"))
+
+	text := "jane.doe@example.com"
+	hashed, _ := first.Redact(text)
+	if !strings.HasPrefix(hashed, "[email:") || strings.Contains(hashed, "jane") {
+		t.Fatalf("unexpected hash: %q", hashed)
+	}
+	if again, _ := first.Redact(text); again != hashed {
+		t.Errorf("the hash is not deterministic: %q then %q", hashed, again)
+	}
+	if other, _ := second.Redact(text); other == hashed {
+		t.Errorf("two tenants got the same hash %q", hashed)
+	}
+	if other, _ := otherKey.Redact(text); other == hashed {
+		t.Errorf("two keys gave the same hash %q", hashed)
+	}
+}
+
+func TestRedactTokenize(t *testing.T) {
+	r, err := NewRedactor("tenant", &models.RedactionSettings{Enabled: true, Mode: ModeTokenize}, testHashKey)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	got, _ := r.Redact("jane.doe@example.com")
+	if !strings.HasPrefix(got, "[tok_email_") || len(got) != len("[tok_email_]")+20 {
+		t.Errorf("unexpected token: %q", got)
+	}
+}
+
+func TestNewRedactorRequiresHashKey(t *testing.T) {
+	for _, mode := range []string{ModeHash, ModeTokenize} {
+		if _, err := NewRedactor("tenant", &models.RedactionSettings{Enabled: true, Mode: mode}, nil); err == nil {
+			t.Errorf("the %s mode was accepted without a hash key", mode)
+		}
+	}
+	if _, err := NewRedactor("tenant", &models.RedactionSettings{Enabled: true, Mode: ModeMask}, nil); err != nil {
+		t.Errorf("the mask mode needs no hash key: %v", err)
+	}
+}
+
+func TestNewRedactorSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.RedactionSettings
+		wantErr  bool
+	}{
+		{"unknown mode", models.RedactionSettings{Mode: "scramble"}, true},
+		{"unknown detector", models.RedactionSettings{Detectors: []string{"ssn"}}, true},
+		{"rule without a name", models.RedactionSettings{Rules: []models.RedactionRule{{Pattern: `\d+`}}}, true},
+		{"invalid rule pattern", models.RedactionSettings{Rules: []models.RedactionRule{{Name: "order", Pattern: `(`}}}, true},
+		{"detectors and rules", models.RedactionSettings{Detectors: []string{KindEmail}, Rules: []models.RedactionRule{{Name: "order", Pattern: `ORD-\d+`}}}, false},
+	}
+	for _, tt := range tests {
+		_, err := NewRedactor("tenant", &tt.settings, testHashKey)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRedactRestrictedDetectorsAndRules(t *testing.T) {
+	settings := &models.RedactionSettings{
+		Enabled:   true,
+		Detectors: []string{KindEmail},
+		Rules:     []models.RedactionRule{{Name: "order", Pattern: `ORD-\d+`}},
+	}
+	r, err := NewRedactor("tenant", settings, nil)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	got, _ := r.Redact("a@example.com from 192.168.10.12 about ORD-1234")
+	if want := "[EMAIL] from 192.168.10.12 about [ORDER]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package redaction
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type VaultHandler struct {
+	service     *VaultService
+	adminAPIKey string
+}
+
+func NewVaultHandler(service *VaultService, adminAPIKey string) *VaultHandler {
+	return &VaultHandler{service: service, adminAPIKey: adminAPIKey}
+}
+
+// GetVaultEntriesHandler reveals the original values redacted from a
+// feedback (tenant_id, source, feedback_id) or behind a hash or token
+// (tenant_id, replacement). Only callers with the admin API key may use it.
+func (h *VaultHandler) GetVaultEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	tenantID := query.Get("tenant_id")
+	source := models.Source(query.Get("source"))
+	feedbackID := query.Get("feedback_id")
+	replacement := query.Get("replacement")
+
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+	if feedbackID == "" && replacement == "" {
+		http.Error(w, "Feedback ID or replacement is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	entries, err := h.service.Lookup(ctx, tenantID, source, feedbackID, replacement)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read the PII vault: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *VaultHandler) authorized(r *http.Request) bool {
+	if h.adminAPIKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(h.adminAPIKey)) == 1
+}
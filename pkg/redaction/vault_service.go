@@ -0,0 +1,113 @@
+package redaction
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// VaultService keeps the original values of redacted data, encrypted with
+// AES-256-GCM. The tenant, feedback and kind are bound to the ciphertext as
+// additional data, so an entry cannot be moved to another record.
+type VaultService struct {
+	repo *db.PIIVaultRepository
+	aead cipher.AEAD
+}
+
+// NewVaultService creates the vault from the base64 encoded 32 bytes key, an
+// empty key leaves the vault disabled.
+func NewVaultService(repo *db.PIIVaultRepository, key string) (*VaultService, error) {
+	s := &VaultService{repo: repo}
+	if key == "" {
+		return s, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII vault key: %v", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid PII vault key: expected 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII vault key: %v", err)
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("invalid PII vault key: %v", err)
+	}
+
+	return s, nil
+}
+
+// Enabled reports whether a key is configured.
+func (s *VaultService) Enabled() bool {
+	return s.aead != nil
+}
+
+// Store encrypts and saves the findings of a feedback.
+func (s *VaultService) Store(ctx context.Context, feedback *models.Feedback, findings []Finding) error {
+	if !s.Enabled() {
+		return fmt.Errorf("the PII vault is not configured")
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	entries := make([]*models.PIIVaultEntry, len(findings))
+	for i, finding := range findings {
+		entry := &models.PIIVaultEntry{
+			TenantID:    feedback.TenantID,
+			Source:      feedback.Source,
+			FeedbackID:  feedback.ID,
+			Kind:        finding.Kind,
+			Replacement: finding.Replacement,
+		}
+
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %v", err)
+		}
+		entry.Ciphertext = s.aead.Seal(nonce, nonce, []byte(finding.Value), additionalData(entry))
+		entries[i] = entry
+	}
+
+	return s.repo.SaveAll(ctx, entries)
+}
+
+// Lookup returns the decrypted entries of a feedback, or of a replacement
+// when feedbackID is empty.
+func (s *VaultService) Lookup(ctx context.Context, tenantID string, source models.Source, feedbackID, replacement string) ([]*models.PIIVaultEntry, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("the PII vault is not configured")
+	}
+
+	entries, err := s.repo.List(ctx, tenantID, source, feedbackID, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		nonceSize := s.aead.NonceSize()
+		if len(entry.Ciphertext) < nonceSize {
+			return nil, fmt.Errorf("corrupted PII vault entry %s", entry.ID)
+		}
+		plaintext, err := s.aead.Open(nil, entry.Ciphertext[:nonceSize], entry.Ciphertext[nonceSize:], additionalData(entry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt PII vault entry %s: %v", entry.ID, err)
+		}
+		entry.Value = string(plaintext)
+	}
+
+	return entries, nil
+}
+
+func additionalData(entry *models.PIIVaultEntry) []byte {
+	return []byte(entry.TenantID + "\x00" + string(entry.Source) + "\x00" + entry.FeedbackID + "\x00" + entry.Kind)
+}
@@ -6,26 +6,50 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/analytics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/archive"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/cron"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/deadletter"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/dedup"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/export"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/feedback"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/importer"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/inbox"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/integrations"
-	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/oauth"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/server"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/tagrule"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/tenant"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/topics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/triage"
 )
 
 func SetupRoutes(srv *server.Server) {
 	// Initialize IntegrationManager with strategies
-	strategiesMap := map[models.Source]integrations.SourceStrategy{
-		models.SourceIntercom:  integrations.NewIntercomStrategy(),
-		models.SourceDiscourse: integrations.NewDiscourseStrategy(),
+	rateLimiter := ratelimit.NewRateLimiter(db.NewRateLimitRepository(srv.DBPool))
+	sourceClient := integrations.NewSourceClient(integrations.SourceClientConfigFrom(srv.Config), rateLimiter)
+	strategiesMap := integrations.NewStrategies(sourceClient)
+
+	// PII vault
+	vaultService, err := redaction.NewVaultService(db.NewPIIVaultRepository(srv.DBPool), srv.Config.PIIVaultKey)
+	if err != nil {
+		log.Fatalf("Failed to set up the PII vault: %v", err)
 	}
+	vaultHandler := redaction.NewVaultHandler(vaultService, srv.Config.AdminAPIKey)
 
 	// Tenant handlers
 	tenantRepo := db.NewTenantRepository(srv.DBPool)
-	tenantService := tenant.NewTenantService(tenantRepo)
+	tenantService := tenant.NewTenantService(tenantRepo, vaultService, srv.Config.PIIHashKey)
+	if err := tenantService.CheckRedactionKeys(context.Background()); err != nil {
+		log.Fatalf("Failed to check the tenants' redaction settings: %v", err)
+	}
 	tenantHandler := tenant.NewTenantHandler(tenantService)
 
 	// Feedback handlers
@@ -33,34 +57,107 @@ func SetupRoutes(srv *server.Server) {
 	feedbackService := feedback.NewFeedbackService(feedbackRepo)
 	feedbackHandler := feedback.NewFeedbackHandler(feedbackService)
 
+	// Export handlers
+	exportHandler := export.NewExportHandler(export.NewExportService(feedbackRepo))
+
+	// Triage, notes and manual tags handlers
+	triageService := triage.NewTriageService(db.NewTriageRepository(srv.DBPool))
+	triageHandler := triage.NewTriageHandler(triageService)
+
 	// Subsription handlers
 	subRepo := db.NewSubscriptionRepository(srv.DBPool)
-	subService := subscription.NewSubscriptionService(subRepo)
-	subHandler := subscription.NewSubscriptionHandler(subService)
+	keyring, err := secrets.NewKeyring(srv.Config.SecretKeys)
+	if err != nil {
+		log.Fatalf("Failed to set up the secret keys: %v", err)
+	}
+	if !keyring.Enabled() {
+		// the subscriptions' credentials, the webhook calls and the archived
+		// and dead-lettered payloads would be stored in plaintext
+		log.Fatalf("SECRET_KEYS is not set, it is required to encrypt the credentials and payloads")
+	}
+	subService := subscription.NewSubscriptionService(subRepo, keyring)
+	subHandler := subscription.NewSubscriptionHandler(subService, srv.Config.PublicURL)
+
+	// Tag rule handlers
+	tagRuleService := tagrule.NewTagRuleService(db.NewTagRuleRepository(srv.DBPool), feedbackRepo)
+	tagRuleHandler := tagrule.NewTagRuleHandler(tagRuleService)
+
+	dedupService := dedup.NewDedupService(feedbackRepo)
+	pipelineService := pipeline.NewPipelineService(tenantRepo, vaultService, dedupService, tagRuleService, srv.Config.PIIHashKey)
+
+	// Webhook inbox
+	inboxService := inbox.NewInboxService(db.NewWebhookInboxRepository(srv.DBPool), keyring)
+	inboxHandler := inbox.NewInboxHandler(inboxService, srv.Config.AdminAPIKey)
+
+	// Raw payload archive
+	archiveService := archive.NewArchiveService(db.NewRawPayloadRepository(srv.DBPool), keyring)
+
+	// Dead letter queue
+	deadLetterService := deadletter.NewDeadLetterService(db.NewDeadLetterRepository(srv.DBPool), keyring)
+	deadLetterHandler := deadletter.NewDeadLetterHandler(deadLetterService, srv.Config.AdminAPIKey)
+
+	integrationManager := integrations.NewIntegrationManager(strategiesMap, feedbackService, pipelineService, subService, inboxService, archiveService, deadLetterService, int64(srv.Config.WebhookMaxBodyBytes))
+	deadLetterService.SetRetrier(integrationManager.RetryDeadLetter)
+	subService.SetConfigValidator(integrationManager.ValidateSubscription)
+	subService.SetSecretFields(integrationManager.SecretFields)
+
+	// OAuth connect flow
+	oauthService := oauth.NewOAuthService(db.NewOAuthStateRepository(srv.DBPool), subService, oauth.NewProviders(srv.Config), srv.Config.PublicURL)
+	oauthHandler := oauth.NewOAuthHandler(oauthService, subService)
+	integrationManager.SetTokenRefresher(oauthService.EnsureFresh)
+
+	// Import handlers
+	importHandler := importer.NewImportHandler(importer.NewImportService(feedbackRepo, tenantRepo, pipelineService))
+
+	// Topics
+	topicService := topics.NewTopicService(db.NewTopicRepository(srv.DBPool), tenantRepo)
+	topicHandler := topics.NewTopicHandler(topicService)
 
-	integrationManager := integrations.NewIntegrationManager(strategiesMap, feedbackService)
+	// Analytics
+	analyticsService := analytics.NewAnalyticsService(db.NewAnalyticsRepository(srv.DBPool), tenantRepo)
+	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService)
 
 	// Init cron manager
-	cronManager := cron.NewCronManager(subService, integrationManager)
+	cronManager := cron.NewCronManager(subService, integrationManager, topicService, analyticsService, deadLetterService, inboxService, archiveService)
 	// TODO: need to change timer to 8 hr
-	err := cronManager.StartGlobalPullJob(context.Background(), 8*time.Hour)
+	err = cronManager.StartGlobalPullJob(context.Background(), 8*time.Hour, srv.Config.SubscriptionDisableAfter)
 	if err != nil {
 		log.Fatalf("Failed to start global pull job: %v", err)
 	}
+	if err := cronManager.StartTopicJob(context.Background(), 24*time.Hour, topics.DefaultWindow); err != nil {
+		log.Fatalf("Failed to start topic job: %v", err)
+	}
+	if err := cronManager.StartRollupJob(context.Background(), time.Hour); err != nil {
+		log.Fatalf("Failed to start rollup job: %v", err)
+	}
+	if err := cronManager.StartInboxJob(context.Background(), 5*time.Second, srv.Config.WebhookWorkers); err != nil {
+		log.Fatalf("Failed to start webhook inbox job: %v", err)
+	}
+	if err := cronManager.StartInboxPurgeJob(context.Background(), time.Hour, time.Duration(srv.Config.WebhookInboxRetentionDays)*24*time.Hour); err != nil {
+		log.Fatalf("Failed to start webhook inbox purge job: %v", err)
+	}
+	if err := cronManager.StartArchivePurgeJob(context.Background(), time.Hour, time.Duration(srv.Config.ArchiveRetentionDays)*24*time.Hour); err != nil {
+		log.Fatalf("Failed to start archive purge job: %v", err)
+	}
+	if err := cronManager.StartDeadLetterPurgeJob(context.Background(), time.Hour, time.Duration(srv.Config.DeadLetterRetentionDays)*24*time.Hour); err != nil {
+		log.Fatalf("Failed to start dead letter purge job: %v", err)
+	}
+	if err := cronManager.StartDeadLetterMetricsJob(context.Background(), time.Minute); err != nil {
+		log.Fatalf("Failed to start dead letter metrics job: %v", err)
+	}
 
-	// webhooks - need to setup web hook routes for all the sources which can support push based ingestion
-	srv.Router.HandleFunc("/webhook/discourse", func(w http.ResponseWriter, r *http.Request) {
-		integrationManager.HandleWebhook(w, r, models.SourceDiscourse)
-	})
-	srv.Router.HandleFunc("/webhook/intercom", func(w http.ResponseWriter, r *http.Request) {
-		integrationManager.HandleWebhook(w, r, models.SourceIntercom)
-	})
+	// webhooks - the URL of each push subscription, returned when it is created.
+	// Calls are stored in the inbox, the inbox job ingests them
+	srv.Router.HandleFunc("/webhook/{source}/{subscription_id}", integrationManager.HandleWebhook)
 
 	// Health check
 	srv.Router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	// Metrics
+	srv.Router.Handle("/metrics", metrics.Handler())
+
 	// Tenant CRUD routes
 	srv.Router.HandleFunc("/tenant", tenantHandler.CreateTenantHandler)
 	srv.Router.HandleFunc("/tenant/get", tenantHandler.GetTenantHandler)
@@ -73,7 +170,63 @@ func SetupRoutes(srv *server.Server) {
 	srv.Router.HandleFunc("/feedback/update", feedbackHandler.UpdateFeedbackHandler)
 	srv.Router.HandleFunc("/feedback/delete", feedbackHandler.DeleteFeedbackHandler)
 	srv.Router.HandleFunc("/feedback/list", feedbackHandler.ListFeedbackByTenantHandler)
+	srv.Router.HandleFunc("/feedback/search", feedbackHandler.SearchFeedbackHandler)
+	srv.Router.HandleFunc("/feedback/duplicates", feedbackHandler.ListDuplicatesHandler)
+	srv.Router.HandleFunc("/feedback/sentiment/summary", feedbackHandler.SentimentSummaryHandler)
+	srv.Router.HandleFunc("/feedback/export", exportHandler.ExportFeedbackHandler)
+	srv.Router.HandleFunc("/feedback/import", importHandler.ImportFeedbackHandler)
+	srv.Router.HandleFunc("/feedback/reprocess", integrationManager.ReprocessHandler)
+
+	// Feedback triage routes
+	srv.Router.HandleFunc("/feedback/triage", triageHandler.SetTriageHandler)
+	srv.Router.HandleFunc("/feedback/triage/get", triageHandler.GetTriageHandler)
+	srv.Router.HandleFunc("/feedback/note", triageHandler.AddNoteHandler)
+	srv.Router.HandleFunc("/feedback/note/list", triageHandler.ListNotesHandler)
+	srv.Router.HandleFunc("/feedback/note/delete", triageHandler.DeleteNoteHandler)
+	srv.Router.HandleFunc("/feedback/tag", triageHandler.AddTagHandler)
+	srv.Router.HandleFunc("/feedback/tag/delete", triageHandler.RemoveTagHandler)
+
+	// Tag rule CRUD routes
+	srv.Router.HandleFunc("/tag-rule", tagRuleHandler.CreateTagRuleHandler)
+	srv.Router.HandleFunc("/tag-rule/get", tagRuleHandler.GetTagRuleHandler)
+	srv.Router.HandleFunc("/tag-rule/update", tagRuleHandler.UpdateTagRuleHandler)
+	srv.Router.HandleFunc("/tag-rule/delete", tagRuleHandler.DeleteTagRuleHandler)
+	srv.Router.HandleFunc("/tag-rule/list", tagRuleHandler.ListTagRulesHandler)
+	srv.Router.HandleFunc("/tag-rule/retag", tagRuleHandler.RetagHandler)
+
+	// Analytics routes
+	srv.Router.HandleFunc("/analytics/feedback", analyticsHandler.CountFeedbackHandler)
+
+	// Topic routes
+	srv.Router.HandleFunc("/topics/run", topicHandler.RunTopicsHandler)
+	srv.Router.HandleFunc("/topics/trending", topicHandler.TrendingTopicsHandler)
+
+	// Privileged routes
+	srv.Router.HandleFunc("/admin/pii-vault", vaultHandler.GetVaultEntriesHandler)
+	srv.Router.HandleFunc("/admin/webhook-inbox", inboxHandler.ListEntriesHandler)
+	srv.Router.HandleFunc("/admin/webhook-inbox/get", inboxHandler.GetEntryHandler)
+	srv.Router.HandleFunc("/admin/webhook-inbox/replay", inboxHandler.ReplayHandler)
+	srv.Router.HandleFunc("/admin/dead-letter", deadLetterHandler.ListEntriesHandler)
+	srv.Router.HandleFunc("/admin/dead-letter/get", deadLetterHandler.GetEntryHandler)
+	srv.Router.HandleFunc("/admin/dead-letter/retry", deadLetterHandler.RetryHandler)
+	srv.Router.HandleFunc("/admin/dead-letter/discard", deadLetterHandler.DiscardHandler)
+
+	// Integrations - the supported sources and their subscriptions' configuration
+	srv.Router.HandleFunc("/integrations", integrationManager.IntegrationsHandler)
 
 	// Subscription CRUD routes
 	srv.Router.HandleFunc("/subscription", subHandler.CreateSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/list", subHandler.ListSubscriptionsHandler)
+	srv.Router.HandleFunc("/subscription/get", subHandler.GetSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/update", subHandler.UpdateSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/pause", subHandler.PauseSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/resume", subHandler.ResumeSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/delete", subHandler.DeleteSubscriptionHandler)
+	srv.Router.HandleFunc("/subscription/test", integrationManager.TestConnectionHandler)
+	srv.Router.HandleFunc("/subscription/dry-run", integrationManager.DryRunHandler)
+
+	// OAuth routes - start returns the URL the user consents at, the
+	// provider then redirects them to the callback
+	srv.Router.HandleFunc("/oauth/{source}/start", oauthHandler.StartHandler)
+	srv.Router.HandleFunc("/oauth/{source}/callback", oauthHandler.CallbackHandler)
 }
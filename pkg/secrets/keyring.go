@@ -0,0 +1,197 @@
+// Package secrets encrypts the credentials of the subscriptions with
+// envelope encryption: each value is encrypted with AES-256-GCM under a
+// random data key, itself encrypted under a key-encryption key (KEK) from
+// the configuration. The KEK is named in the value, so KEKs can be rotated:
+// new values are encrypted with the first one, the others still decrypt.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// prefix starts every encrypted value, followed by the KEK ID, the
+// encrypted data key and the encrypted value separated by colons
+const prefix = "enc:v1:"
+
+type Keyring struct {
+	activeID string
+	keks     map[string]cipher.AEAD
+}
+
+// NewKeyring creates the keyring from KEKs written id=base64 key of 32
+// bytes and separated by commas, the first one encrypts. No keys leave the
+// keyring disabled, values are then kept in plaintext.
+func NewKeyring(keys string) (*Keyring, error) {
+	k := &Keyring{keks: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, key, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid secret key %q, expected id=base64 key", id)
+		}
+		if _, exists := k.keks[id]; exists {
+			return nil, fmt.Errorf("duplicate secret key %q", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key %q: %v", id, err)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret key %q: %v", id, err)
+		}
+		k.keks[id] = aead
+		if k.activeID == "" {
+			k.activeID = id
+		}
+	}
+	return k, nil
+}
+
+// Enabled reports whether a key is configured.
+func (k *Keyring) Enabled() bool {
+	return k.activeID != ""
+}
+
+// IsEncrypted reports whether the value was encrypted by a keyring.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Current reports whether the value is encrypted with the active KEK,
+// values that are not need to be encrypted again.
+func (k *Keyring) Current(value string) bool {
+	id, _, _, err := split(value)
+	return err == nil && id == k.activeID
+}
+
+// Encrypt encrypts the plaintext with the active KEK. The additional data is
+// bound to the value, it must be given again to decrypt it.
+func (k *Keyring) Encrypt(plaintext, additionalData []byte) (string, error) {
+	if !k.Enabled() {
+		return "", fmt.Errorf("no secret key is configured")
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %v", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.keks[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + k.activeID + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt.
+func (k *Keyring) Decrypt(value string, additionalData []byte) ([]byte, error) {
+	id, wrappedKey, ciphertext, err := split(value)
+	if err != nil {
+		return nil, err
+	}
+	kek, ok := k.keks[id]
+	if !ok {
+		return nil, fmt.Errorf("secret key %q is not configured", id)
+	}
+
+	dataKey, err := open(kek, wrappedKey, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %v", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptData encrypts the data like Encrypt when a key is configured, and
+// returns it as is otherwise.
+func (k *Keyring) EncryptData(data, additionalData []byte) ([]byte, error) {
+	if !k.Enabled() {
+		return data, nil
+	}
+	encrypted, err := k.Encrypt(data, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encrypted), nil
+}
+
+// DecryptData decrypts data returned by EncryptData, data stored in
+// plaintext before a key was configured is returned as is.
+func (k *Keyring) DecryptData(data, additionalData []byte) ([]byte, error) {
+	if !IsEncrypted(string(data)) {
+		return data, nil
+	}
+	return k.Decrypt(string(data), additionalData)
+}
+
+func split(value string) (string, []byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, fmt.Errorf("not an encrypted secret")
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("malformed encrypted secret")
+	}
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted secret: %v", err)
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted secret: %v", err)
+	}
+	return parts[0], wrappedKey, ciphertext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext behind a random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], additionalData)
+}
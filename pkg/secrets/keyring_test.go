@@ -0,0 +1,179 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func testKeyring(t *testing.T, keys string) *Keyring {
+	t.Helper()
+
+	k, err := NewKeyring(keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	k := testKeyring(t, "2024-10="+testKey(t))
+	plaintext := []byte("access-token")
+
+	value, err := k.Encrypt(plaintext, []byte("subscription-1"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(value) || !strings.HasPrefix(value, prefix+"2024-10:") || strings.Contains(value, "access-token") {
+		t.Fatalf("unexpected encrypted value %q", value)
+	}
+	if again, _ := k.Encrypt(plaintext, []byte("subscription-1")); again == value {
+		t.Errorf("two encryptions gave the same value")
+	}
+
+	decrypted, err := k.Decrypt(value, []byte("subscription-1"))
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("got %q, want %q", decrypted, plaintext)
+	}
+}
+
+func TestDecryptChecksAdditionalData(t *testing.T) {
+	k := testKeyring(t, "2024-10="+testKey(t))
+
+	value, err := k.Encrypt([]byte("access-token"), []byte("subscription-1"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := k.Decrypt(value, []byte("subscription-2")); err == nil {
+		t.Errorf("a value was decrypted with another additional data")
+	}
+}
+
+func TestDecryptRejectsTamperedValues(t *testing.T) {
+	k := testKeyring(t, "2024-10="+testKey(t))
+
+	value, err := k.Encrypt([]byte("access-token"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	id, wrappedKey, ciphertext, err := split(value)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+
+	for _, tampered := range []string{
+		prefix + id + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext),
+		strings.Replace(value, "2024-10", "2025-01", 1),
+		strings.TrimPrefix(value, prefix),
+		prefix + "2024-10:abc",
+		prefix + "2024-10:!!:!!",
+	} {
+		if _, err := k.Decrypt(tampered, nil); err == nil {
+			t.Errorf("%q was decrypted", tampered)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	old := testKeyring(t, "2024-10="+oldKey)
+	value, err := old.Encrypt([]byte("access-token"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := testKeyring(t, "2025-01="+newKey+", 2024-10="+oldKey)
+	if rotated.Current(value) {
+		t.Errorf("a value of the old key is current")
+	}
+	decrypted, err := rotated.Decrypt(value, nil)
+	if err != nil || string(decrypted) != "access-token" {
+		t.Fatalf("the old key did not decrypt: %q, %v", decrypted, err)
+	}
+
+	reencrypted, err := rotated.Encrypt(decrypted, nil)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !rotated.Current(reencrypted) || !strings.HasPrefix(reencrypted, prefix+"2025-01:") {
+		t.Errorf("the new value is not encrypted with the first key: %q", reencrypted)
+	}
+
+	if _, err := testKeyring(t, "2025-01="+newKey).Decrypt(value, nil); err == nil {
+		t.Errorf("a value was decrypted once its key was removed")
+	}
+}
+
+func TestDisabledKeyring(t *testing.T) {
+	k := testKeyring(t, " , ")
+	if k.Enabled() {
+		t.Fatalf("a keyring without keys is enabled")
+	}
+	if _, err := k.Encrypt([]byte("access-token"), nil); err == nil {
+		t.Errorf("a keyring without keys encrypted")
+	}
+	if k.Current("access-token") {
+		t.Errorf("a plaintext value is current")
+	}
+
+	data, err := k.EncryptData([]byte("payload"), nil)
+	if err != nil || string(data) != "payload" {
+		t.Errorf("EncryptData without keys: got %q, %v", data, err)
+	}
+}
+
+func TestEncryptData(t *testing.T) {
+	k := testKeyring(t, "2024-10="+testKey(t))
+
+	data, err := k.EncryptData([]byte("payload"), []byte("id"))
+	if err != nil {
+		t.Fatalf("EncryptData: %v", err)
+	}
+	if !IsEncrypted(string(data)) {
+		t.Fatalf("the data is not encrypted: %q", data)
+	}
+	decrypted, err := k.DecryptData(data, []byte("id"))
+	if err != nil || string(decrypted) != "payload" {
+		t.Errorf("DecryptData: got %q, %v", decrypted, err)
+	}
+
+	// stored before a key was configured
+	plaintext, err := k.DecryptData([]byte("payload"), []byte("id"))
+	if err != nil || string(plaintext) != "payload" {
+		t.Errorf("DecryptData of plaintext: got %q, %v", plaintext, err)
+	}
+}
+
+func TestNewKeyringRejectsInvalidKeys(t *testing.T) {
+	key := testKey(t)
+	short := base64.StdEncoding.EncodeToString([]byte("too short"))
+
+	for _, keys := range []string{
+		key,
+		"=" + key,
+		"a:b=" + key,
+		"2024-10=not base64!",
+		"2024-10=" + short,
+		"2024-10=" + key + ",2024-10=" + key,
+	} {
+		if _, err := NewKeyring(keys); err == nil {
+			t.Errorf("%q was accepted", keys)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sentiment
+
+import "github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+
+const (
+	DefaultRatingWeight = 0.5
+	DefaultRatingScale  = 5
+)
+
+// Score sets the sentiment of a single feedback following the tenant's
+// settings, it returns whether a sentiment was found.
+//
+// The text is scored with the lexicon of the feedback's language, English
+// when the language is unknown. Feedback in a language without a lexicon is
+// only scored by its rating. When the feedback has both, the rating and the
+// text score are blended by the rating weight.
+func Score(settings *models.SentimentSettings, feedback *models.Feedback) bool {
+	if settings.Disabled {
+		return false
+	}
+
+	body := feedback.Text()
+	scorable := body != "" && (feedback.Language == "" || Supported(feedback.Language))
+
+	var text *Result
+	if scorable {
+		result := Analyze(body, feedback.Language)
+		if result.Words > 0 {
+			text = &result
+		}
+	}
+
+	var score float64
+	switch {
+	case feedback.Rating != nil && text != nil:
+		weight := DefaultRatingWeight
+		if settings.RatingWeight != nil {
+			weight = *settings.RatingWeight
+		}
+		score = round(weight*FromRating(*feedback.Rating, ratingScale(settings)) + (1-weight)*text.Score)
+	case feedback.Rating != nil:
+		score = FromRating(*feedback.Rating, ratingScale(settings))
+	case text != nil:
+		score = text.Score
+	case scorable:
+		// text without any opinion word
+		score = 0
+	default:
+		return false
+	}
+
+	feedback.SentimentScore = &score
+	feedback.Sentiment = Label(score)
+	return true
+}
+
+func ratingScale(settings *models.SentimentSettings) float64 {
+	if settings.RatingScale > 0 {
+		return settings.RatingScale
+	}
+	return DefaultRatingScale
+}
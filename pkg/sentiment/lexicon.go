@@ -0,0 +1,179 @@
+package sentiment
+
+// lexicon holds, per language, the valence of words from -4 (most negative)
+// to +4 (most positive), along with the words that negate or intensify the
+// word that follows them. The lists are tuned to product feedback rather
+// than to general prose: "crash" or "refund" weigh more here than they would
+// in a novel.
+type lexicon struct {
+	valence   map[string]float64
+	negations map[string]bool
+	boosters  map[string]float64 // positive increases, negative decreases the intensity
+	// contrast words shift the weight of the sentence to what follows, "but" in English
+	contrast map[string]bool
+}
+
+var lexicons = map[string]*lexicon{
+	"en": {
+		valence: map[string]float64{
+			"good": 1.9, "great": 3.1, "excellent": 3.2, "amazing": 2.8, "awesome": 3.1, "fantastic": 2.6,
+			"love": 3.2, "loved": 2.9, "loving": 2.9, "like": 1.5, "liked": 1.8, "nice": 1.8, "best": 3.2,
+			"perfect": 2.7, "wonderful": 2.7, "brilliant": 2.8, "helpful": 1.9, "useful": 1.9, "easy": 1.9,
+			"fast": 1.3, "quick": 1.2, "smooth": 1.4, "happy": 2.7, "glad": 2.0, "pleased": 1.9,
+			"satisfied": 1.8, "recommend": 1.5, "thanks": 1.9, "thank": 1.5, "beautiful": 2.9, "cool": 1.3,
+			"fixed": 1.1, "solved": 1.4, "works": 1.0, "working": 0.6, "reliable": 1.7, "intuitive": 1.8,
+			"friendly": 2.2, "impressive": 2.4, "enjoy": 2.2, "fun": 2.3, "improved": 1.6, "improvement": 1.4,
+			"clean": 1.5, "simple": 1.0, "stable": 1.3, "worth": 1.2, "appreciate": 2.0, "superb": 3.1,
+			"ok": 0.9, "okay": 0.9, "fine": 0.8,
+			"bad": -2.5, "terrible": -2.9, "awful": -3.0, "horrible": -3.1, "worst": -3.1, "poor": -2.1,
+			"hate": -2.7, "hated": -3.0, "annoying": -1.9, "annoyed": -1.8, "frustrating": -2.1, "frustrated": -2.0,
+			"useless": -1.9, "broken": -2.1, "broke": -1.8, "bug": -1.6, "bugs": -1.7, "buggy": -2.0,
+			"crash": -2.0, "crashes": -2.1, "crashing": -2.2, "crashed": -2.0, "freeze": -1.6, "freezes": -1.7,
+			"slow": -1.5, "laggy": -1.7, "lag": -1.4, "error": -1.4, "errors": -1.5, "fail": -2.2,
+			"fails": -2.1, "failed": -2.2, "failure": -2.3, "problem": -1.7, "problems": -1.7, "issue": -1.1,
+			"issues": -1.2, "disappointed": -2.2, "disappointing": -2.2, "disappointment": -2.3, "confusing": -1.3,
+			"difficult": -1.5, "hard": -0.6, "expensive": -1.3, "overpriced": -1.9, "scam": -3.0, "waste": -1.8,
+			"ridiculous": -2.0, "unusable": -2.5, "unacceptable": -2.6, "angry": -2.3, "sad": -2.1,
+			"wrong": -2.1, "missing": -1.2, "lost": -1.3, "refund": -1.2, "charged": -1.0, "cancel": -1.0,
+			"uninstall": -1.7, "uninstalled": -1.8, "stuck": -1.4, "ugly": -2.3, "painful": -2.2, "sucks": -1.5,
+			"garbage": -2.5, "trash": -2.1, "spam": -1.5, "unresponsive": -1.9, "rude": -2.0, "ignored": -1.6,
+		},
+		negations: setOf("not", "no", "never", "nothing", "nobody", "none", "neither", "nor", "cannot",
+			"dont", "doesnt", "didnt", "isnt", "wasnt", "arent", "werent", "wont", "cant", "couldnt",
+			"shouldnt", "wouldnt", "hasnt", "havent", "hadnt", "aint", "without"),
+		boosters: map[string]float64{
+			"very": 0.293, "really": 0.293, "extremely": 0.293, "so": 0.293, "totally": 0.293, "absolutely": 0.293,
+			"completely": 0.293, "incredibly": 0.293, "super": 0.293, "too": 0.2, "most": 0.293, "highly": 0.293,
+			"slightly": -0.293, "somewhat": -0.293, "barely": -0.293, "kinda": -0.293, "little": -0.293, "bit": -0.293,
+		},
+		contrast: setOf("but", "however", "although", "though", "yet"),
+	},
+
+	"es": {
+		valence: map[string]float64{
+			"bueno": 1.9, "buena": 1.9, "excelente": 3.2, "genial": 3.0, "increíble": 2.8, "fantástico": 2.6,
+			"encanta": 3.0, "gusta": 1.5, "mejor": 2.0, "perfecto": 2.7, "perfecta": 2.7, "útil": 1.9,
+			"fácil": 1.9, "rápido": 1.3, "rápida": 1.3, "gracias": 1.9, "feliz": 2.7, "contento": 2.0,
+			"recomiendo": 1.5, "funciona": 1.0, "maravilloso": 2.7, "bonito": 2.0, "estable": 1.3, "amable": 2.2,
+			"malo": -2.5, "mala": -2.5, "terrible": -2.9, "horrible": -3.1, "pésimo": -3.1, "peor": -3.0,
+			"odio": -2.7, "molesto": -1.9, "frustrante": -2.1, "inútil": -1.9, "roto": -2.1, "error": -1.4,
+			"errores": -1.5, "falla": -2.1, "fallo": -2.0, "cierra": -1.2, "lento": -1.5, "lenta": -1.5,
+			"problema": -1.7, "problemas": -1.7, "decepcionado": -2.2, "caro": -1.3, "estafa": -3.0,
+			"basura": -2.5, "reembolso": -1.2, "cobraron": -1.0, "difícil": -1.5, "confuso": -1.3, "triste": -2.1,
+		},
+		negations: setOf("no", "nunca", "jamás", "nada", "nadie", "ni", "sin", "tampoco"),
+		boosters: map[string]float64{
+			"muy": 0.293, "súper": 0.293, "totalmente": 0.293, "realmente": 0.293, "demasiado": 0.2,
+			"extremadamente": 0.293, "poco": -0.293, "algo": -0.293,
+		},
+		contrast: setOf("pero", "aunque"),
+	},
+
+	"fr": {
+		valence: map[string]float64{
+			"bon": 1.9, "bonne": 1.9, "excellent": 3.2, "excellente": 3.2, "génial": 3.0, "super": 2.5,
+			"incroyable": 2.8, "adore": 3.0, "aime": 1.9, "meilleur": 2.0, "parfait": 2.7, "parfaite": 2.7,
+			"utile": 1.9, "facile": 1.9, "rapide": 1.3, "merci": 1.9, "content": 2.0, "contente": 2.0,
+			"heureux": 2.7, "recommande": 1.5, "fonctionne": 1.0, "magnifique": 2.9, "pratique": 1.5, "agréable": 2.0,
+			"mauvais": -2.5, "mauvaise": -2.5, "terrible": -2.9, "horrible": -3.1, "nul": -2.5, "nulle": -2.5,
+			"pire": -3.0, "déteste": -2.7, "énervant": -1.9, "frustrant": -2.1, "inutile": -1.9, "cassé": -2.1,
+			"erreur": -1.4, "erreurs": -1.5, "plante": -2.0, "bug": -1.6, "bugs": -1.7, "lent": -1.5, "lente": -1.5,
+			"problème": -1.7, "problèmes": -1.7, "déçu": -2.2, "déçue": -2.2, "cher": -1.3, "arnaque": -3.0,
+			"remboursement": -1.2, "difficile": -1.5, "compliqué": -1.3, "triste": -2.1,
+		},
+		negations: setOf("ne", "pas", "jamais", "rien", "personne", "aucun", "aucune", "sans", "ni"),
+		boosters: map[string]float64{
+			"très": 0.293, "vraiment": 0.293, "trop": 0.2, "totalement": 0.293, "extrêmement": 0.293,
+			"tellement": 0.293, "peu": -0.293, "assez": -0.1,
+		},
+		contrast: setOf("mais", "cependant", "pourtant", "toutefois"),
+	},
+
+	"de": {
+		valence: map[string]float64{
+			"gut": 1.9, "gute": 1.9, "toll": 3.0, "super": 2.5, "ausgezeichnet": 3.2, "großartig": 3.1,
+			"genial": 3.0, "liebe": 3.0, "mag": 1.5, "beste": 3.1, "perfekt": 2.7, "hilfreich": 1.9,
+			"nützlich": 1.9, "einfach": 1.5, "schnell": 1.3, "danke": 1.9, "zufrieden": 1.9, "glücklich": 2.7,
+			"empfehlen": 1.5, "funktioniert": 1.0, "schön": 2.3, "freundlich": 2.2, "praktisch": 1.5, "stabil": 1.3,
+			"schlecht": -2.5, "schlechte": -2.5, "schrecklich": -3.0, "furchtbar": -3.0, "mies": -2.5, "schlimmste": -3.1,
+			"hasse": -2.7, "nervig": -1.9, "frustrierend": -2.1, "nutzlos": -1.9, "kaputt": -2.1, "fehler": -1.5,
+			"absturz": -2.0, "stürzt": -2.0, "abstürze": -2.1, "langsam": -1.5, "problem": -1.7, "probleme": -1.7,
+			"enttäuscht": -2.2, "enttäuschend": -2.2, "teuer": -1.3, "betrug": -3.0, "müll": -2.5,
+			"erstattung": -1.2, "schwierig": -1.5, "kompliziert": -1.3, "traurig": -2.1,
+		},
+		negations: setOf("nicht", "kein", "keine", "keinen", "keiner", "nie", "niemals", "nichts", "niemand", "ohne", "weder"),
+		boosters: map[string]float64{
+			"sehr": 0.293, "wirklich": 0.293, "total": 0.293, "extrem": 0.293, "echt": 0.293, "zu": 0.2,
+			"absolut": 0.293, "etwas": -0.293, "kaum": -0.293, "bisschen": -0.293,
+		},
+		contrast: setOf("aber", "jedoch", "doch", "obwohl"),
+	},
+
+	"it": {
+		valence: map[string]float64{
+			"buono": 1.9, "buona": 1.9, "ottimo": 3.0, "ottima": 3.0, "eccellente": 3.2, "fantastico": 2.6,
+			"bellissimo": 2.9, "adoro": 3.0, "piace": 1.5, "migliore": 2.0, "perfetto": 2.7, "perfetta": 2.7,
+			"utile": 1.9, "facile": 1.9, "veloce": 1.3, "grazie": 1.9, "felice": 2.7, "contento": 2.0,
+			"consiglio": 1.5, "funziona": 1.0, "bello": 2.3, "bella": 2.3, "gentile": 2.2, "stabile": 1.3,
+			"cattivo": -2.5, "pessimo": -3.1, "pessima": -3.1, "terribile": -2.9, "orribile": -3.1, "peggiore": -3.0,
+			"odio": -2.7, "fastidioso": -1.9, "frustrante": -2.1, "inutile": -1.9, "rotto": -2.1, "errore": -1.4,
+			"errori": -1.5, "crash": -2.0, "chiude": -1.2, "lento": -1.5, "lenta": -1.5, "problema": -1.7,
+			"problemi": -1.7, "deluso": -2.2, "delusa": -2.2, "caro": -1.3, "truffa": -3.0, "schifo": -2.8,
+			"rimborso": -1.2, "difficile": -1.5, "complicato": -1.3, "triste": -2.1,
+		},
+		negations: setOf("non", "mai", "niente", "nulla", "nessuno", "nessuna", "senza", "né"),
+		boosters: map[string]float64{
+			"molto": 0.293, "davvero": 0.293, "troppo": 0.2, "totalmente": 0.293, "estremamente": 0.293,
+			"super": 0.293, "poco": -0.293, "abbastanza": -0.1,
+		},
+		contrast: setOf("ma", "però", "tuttavia"),
+	},
+
+	"pt": {
+		valence: map[string]float64{
+			"bom": 1.9, "boa": 1.9, "ótimo": 3.0, "ótima": 3.0, "excelente": 3.2, "incrível": 2.8,
+			"maravilhoso": 2.7, "adoro": 3.0, "amei": 3.0, "gosto": 1.5, "melhor": 2.0, "perfeito": 2.7,
+			"útil": 1.9, "fácil": 1.9, "rápido": 1.3, "obrigado": 1.9, "obrigada": 1.9, "feliz": 2.7,
+			"satisfeito": 1.9, "recomendo": 1.5, "funciona": 1.0, "lindo": 2.3, "bonito": 2.0, "estável": 1.3,
+			"ruim": -2.5, "péssimo": -3.1, "péssima": -3.1, "terrível": -2.9, "horrível": -3.1, "pior": -3.0,
+			"odeio": -2.7, "chato": -1.9, "frustrante": -2.1, "inútil": -1.9, "quebrado": -2.1, "erro": -1.4,
+			"erros": -1.5, "trava": -1.8, "fecha": -1.2, "lento": -1.5, "lenta": -1.5, "problema": -1.7,
+			"problemas": -1.7, "decepcionado": -2.2, "caro": -1.3, "golpe": -3.0, "lixo": -2.5,
+			"reembolso": -1.2, "cobrado": -1.0, "difícil": -1.5, "confuso": -1.3, "triste": -2.1,
+		},
+		negations: setOf("não", "nunca", "jamais", "nada", "ninguém", "nem", "sem", "nenhum", "nenhuma"),
+		boosters: map[string]float64{
+			"muito": 0.293, "super": 0.293, "realmente": 0.293, "totalmente": 0.293, "demais": 0.2,
+			"extremamente": 0.293, "pouco": -0.293,
+		},
+		contrast: setOf("mas", "porém", "contudo", "embora"),
+	},
+
+	"nl": {
+		valence: map[string]float64{
+			"goed": 1.9, "goede": 1.9, "geweldig": 3.1, "uitstekend": 3.2, "super": 2.5, "fantastisch": 2.6,
+			"prachtig": 2.9, "dol": 2.5, "fijn": 1.9, "beste": 3.1, "perfect": 2.7, "handig": 1.7,
+			"nuttig": 1.9, "makkelijk": 1.9, "snel": 1.3, "bedankt": 1.9, "dank": 1.5, "blij": 2.5,
+			"tevreden": 1.9, "aanrader": 2.0, "werkt": 1.0, "mooi": 2.3, "vriendelijk": 2.2, "stabiel": 1.3,
+			"slecht": -2.5, "slechte": -2.5, "vreselijk": -3.0, "verschrikkelijk": -3.1, "waardeloos": -2.8,
+			"haat": -2.7, "irritant": -1.9, "frustrerend": -2.1, "nutteloos": -1.9, "kapot": -2.1, "fout": -1.5,
+			"fouten": -1.6, "crasht": -2.1, "traag": -1.5, "langzaam": -1.3, "probleem": -1.7, "problemen": -1.7,
+			"teleurgesteld": -2.2, "teleurstellend": -2.2, "duur": -1.3, "oplichting": -3.0, "rommel": -2.2,
+			"terugbetaling": -1.2, "moeilijk": -1.5, "verwarrend": -1.3, "jammer": -1.5,
+		},
+		negations: setOf("niet", "geen", "nooit", "niets", "niks", "niemand", "zonder", "noch"),
+		boosters: map[string]float64{
+			"heel": 0.293, "erg": 0.293, "zeer": 0.293, "echt": 0.293, "super": 0.293, "te": 0.2,
+			"totaal": 0.293, "enigszins": -0.293, "beetje": -0.293,
+		},
+		contrast: setOf("maar", "echter", "hoewel"),
+	},
+}
+
+func setOf(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
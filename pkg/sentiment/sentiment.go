@@ -0,0 +1,215 @@
+// Package sentiment is an offline sentiment scorer for feedback.
+//
+// It follows the approach of VADER: words are looked up in a per-language
+// lexicon of valences, adjusted for the negations and intensifiers preceding
+// them, for words in capitals and exclamation marks, and for contrast words
+// ("good, but slow" leans on "slow"). The sum is normalised to a compound
+// score between -1 and 1.
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// normalisation constant of the compound score, the one VADER uses
+	alpha = 15
+
+	// words up to this far back negate or intensify a word
+	window = 3
+
+	negationFactor = -0.74
+	capsBoost      = 0.733
+	exclaimBoost   = 0.292
+	maxExclaims    = 4
+
+	// the weight of the words before and after the last contrast word
+	beforeContrast = 0.5
+	afterContrast  = 1.5
+
+	// compound scores from which the text is positive or negative
+	PositiveThreshold = 0.05
+	NegativeThreshold = -0.05
+)
+
+const (
+	Positive = "positive"
+	Neutral  = "neutral"
+	Negative = "negative"
+)
+
+// Result is the compound score of a text, between -1 and 1, its label and
+// the number of lexicon words it was computed from.
+type Result struct {
+	Score float64 `json:"score"`
+	Label string  `json:"label"`
+	Words int     `json:"words"`
+}
+
+// Languages lists the languages with a lexicon.
+func Languages() []string {
+	return []string{"de", "en", "es", "fr", "it", "nl", "pt"}
+}
+
+// Supported reports whether there is a lexicon for the language.
+func Supported(language string) bool {
+	_, ok := lexicons[language]
+	return ok
+}
+
+// Label returns the label of a compound score.
+func Label(score float64) string {
+	switch {
+	case score >= PositiveThreshold:
+		return Positive
+	case score <= NegativeThreshold:
+		return Negative
+	default:
+		return Neutral
+	}
+}
+
+type token struct {
+	word string // lower-cased, apostrophes removed
+	caps bool   // written in capitals
+	// clause counts the punctuation before the word, negations and
+	// intensifiers do not reach past a comma or a full stop
+	clause int
+}
+
+// Analyze scores text with the lexicon of the language, English when the
+// language has no lexicon.
+func Analyze(text, language string) Result {
+	lex, ok := lexicons[language]
+	if !ok {
+		lex = lexicons["en"]
+	}
+
+	tokens, exclaims := tokenize(text)
+
+	// emphasis by capitals only counts when not everything is in capitals
+	mixedCase := false
+	for _, t := range tokens {
+		if !t.caps {
+			mixedCase = true
+			break
+		}
+	}
+
+	lastContrast := -1
+	for i, t := range tokens {
+		if lex.contrast[t.word] {
+			lastContrast = i
+		}
+	}
+
+	sum, words := 0.0, 0
+	for i, t := range tokens {
+		valence, ok := lex.valence[t.word]
+		if !ok {
+			continue
+		}
+		words++
+		sign := math.Copysign(1, valence)
+
+		if t.caps && mixedCase {
+			valence += sign * capsBoost
+		}
+
+		negated := false
+		for back := 1; back <= window && i-back >= 0 && tokens[i-back].clause == t.clause; back++ {
+			prev := tokens[i-back].word
+			if boost, ok := lex.boosters[prev]; ok {
+				// the further the intensifier, the weaker its effect
+				valence += sign * boost * (1 - 0.05*float64(back-1))
+			}
+			if lex.negations[prev] {
+				negated = true
+			}
+			if lex.contrast[prev] {
+				break
+			}
+		}
+		if negated {
+			valence *= negationFactor
+		}
+
+		if lastContrast >= 0 {
+			if i < lastContrast {
+				valence *= beforeContrast
+			} else {
+				valence *= afterContrast
+			}
+		}
+
+		sum += valence
+	}
+
+	if words == 0 {
+		return Result{Label: Neutral}
+	}
+
+	if sum != 0 {
+		sum += math.Copysign(float64(min(exclaims, maxExclaims))*exclaimBoost, sum)
+	}
+
+	score := round(sum / math.Sqrt(sum*sum+alpha))
+	return Result{Score: score, Label: Label(score), Words: words}
+}
+
+// FromRating maps a rating from 1 to scale onto a score between -1 and 1,
+// the middle of the scale being neutral.
+func FromRating(rating, scale float64) float64 {
+	if scale <= 1 {
+		return 0
+	}
+	score := 2*(rating-1)/(scale-1) - 1
+	return round(math.Max(-1, math.Min(1, score)))
+}
+
+// tokenize splits text into words and counts its exclamation marks.
+// Apostrophes are dropped so that "don't" and "dont" are the same word.
+func tokenize(text string) ([]token, int) {
+	var (
+		tokens   []token
+		exclaims int
+		clause   int
+		word     []rune
+	)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		s := string(word)
+		lower := strings.ToLower(s)
+		tokens = append(tokens, token{word: lower, caps: len(word) > 1 && s == strings.ToUpper(s) && s != lower, clause: clause})
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r):
+			word = append(word, r)
+		case r == '\'' || r == '’':
+			// inside a word, "n't" and "l'app" alike
+		case r == '!':
+			exclaims++
+			flush()
+			clause++
+		case unicode.IsPunct(r) && r != '-':
+			flush()
+			clause++
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens, exclaims
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
@@ -0,0 +1,148 @@
+package sentiment
+
+import (
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func TestAnalyzeLabels(t *testing.T) {
+	tests := []struct {
+		text     string
+		language string
+		want     string
+	}{
+		{"I love this app, it is great", "en", Positive},
+		{"The app crashes all the time, terrible", "en", Negative},
+		{"I opened the settings page yesterday", "en", Neutral},
+		{"This is not good", "en", Negative},
+		{"Not bad at all", "en", Positive},
+		{"The design is nice but the app is slow and buggy", "en", Negative},
+		{"J'adore cette application, elle est parfaite", "fr", Positive},
+		{"Application nulle, horrible", "fr", Negative},
+		// no lexicon, English is used
+		{"great app", "ja", Positive},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.text, tt.language); got.Label != tt.want {
+			t.Errorf("Analyze(%q, %s) = %+v, want %s", tt.text, tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeModifiers(t *testing.T) {
+	score := func(text string) float64 {
+		return Analyze(text, "en").Score
+	}
+
+	tests := []struct {
+		name            string
+		stronger, plain string
+	}{
+		{"intensifier", "the app is very good", "the app is good"},
+		{"capitals", "the app is GOOD", "the app is good"},
+		{"exclamation", "the app is good!!", "the app is good"},
+	}
+	for _, tt := range tests {
+		if score(tt.stronger) <= score(tt.plain) {
+			t.Errorf("%s: %q scored %v, not above %q at %v", tt.name, tt.stronger, score(tt.stronger), tt.plain, score(tt.plain))
+		}
+	}
+
+	if score("the app is slightly good") >= score("the app is good") {
+		t.Errorf("a dampener did not lower the score")
+	}
+	// a negation does not reach past a comma
+	if score("no, the app is good") <= 0 {
+		t.Errorf("a negation reached into the next clause")
+	}
+	// everything in capitals is not emphasis
+	if score("THE APP IS GOOD") != score("the app is good") {
+		t.Errorf("a text all in capitals was boosted")
+	}
+	if got := score("dont like it"); got != score("don't like it") || got >= 0 {
+		t.Errorf("don't and dont differ or are not negative: %v", got)
+	}
+}
+
+func TestAnalyzeBounds(t *testing.T) {
+	result := Analyze("great amazing awesome perfect wonderful excellent best love!!!!!!", "en")
+	if result.Score <= 0.9 || result.Score > 1 {
+		t.Errorf("got score %v, want close to 1", result.Score)
+	}
+	if result.Words != 8 {
+		t.Errorf("got %d words, want 8", result.Words)
+	}
+	if result := Analyze("", "en"); result.Score != 0 || result.Label != Neutral || result.Words != 0 {
+		t.Errorf("empty text: got %+v", result)
+	}
+}
+
+func TestFromRating(t *testing.T) {
+	tests := []struct {
+		rating, scale, want float64
+	}{
+		{1, 5, -1},
+		{3, 5, 0},
+		{5, 5, 1},
+		{4, 5, 0.5},
+		{10, 10, 1},
+		{7, 5, 1},
+		{3, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := FromRating(tt.rating, tt.scale); got != tt.want {
+			t.Errorf("FromRating(%v, %v) = %v, want %v", tt.rating, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for score, want := range map[float64]string{0.05: Positive, 0.04: Neutral, -0.04: Neutral, -0.05: Negative} {
+		if got := Label(score); got != want {
+			t.Errorf("Label(%v) = %s, want %s", score, got, want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	rating := func(r float64) *float64 { return &r }
+	weight := func(w float64) *float64 { return &w }
+
+	tests := []struct {
+		name     string
+		settings models.SentimentSettings
+		feedback models.Feedback
+		want     *float64
+	}{
+		{"rating only", models.SentimentSettings{}, models.Feedback{Rating: rating(5)}, rating(1)},
+		{"rating on a custom scale", models.SentimentSettings{RatingScale: 10}, models.Feedback{Rating: rating(1)}, rating(-1)},
+		{"text without opinion", models.SentimentSettings{}, models.Feedback{Content: map[string]interface{}{"text": "I opened the page"}}, rating(0)},
+		{"rating blended with the text", models.SentimentSettings{RatingWeight: weight(1)},
+			models.Feedback{Rating: rating(1), Content: map[string]interface{}{"text": "I love it"}}, rating(-1)},
+		{"language without lexicon, no rating", models.SentimentSettings{}, models.Feedback{Language: "ja", Content: map[string]interface{}{"text": "great"}}, nil},
+		{"disabled", models.SentimentSettings{Disabled: true}, models.Feedback{Rating: rating(5)}, nil},
+		{"nothing to score", models.SentimentSettings{}, models.Feedback{}, nil},
+	}
+	for _, tt := range tests {
+		found := Score(&tt.settings, &tt.feedback)
+		switch {
+		case tt.want == nil && found:
+			t.Errorf("%s: scored %v, want no score", tt.name, *tt.feedback.SentimentScore)
+		case tt.want != nil && !found:
+			t.Errorf("%s: not scored, want %v", tt.name, *tt.want)
+		case tt.want != nil && *tt.feedback.SentimentScore != *tt.want:
+			t.Errorf("%s: scored %v, want %v", tt.name, *tt.feedback.SentimentScore, *tt.want)
+		case tt.want != nil && tt.feedback.Sentiment != Label(*tt.want):
+			t.Errorf("%s: labelled %s, want %s", tt.name, tt.feedback.Sentiment, Label(*tt.want))
+		}
+	}
+
+	// by default the rating and the text weigh the same
+	feedback := models.Feedback{Rating: rating(5), Content: map[string]interface{}{"text": "terrible"}}
+	Score(&models.SentimentSettings{}, &feedback)
+	text := Analyze("terrible", "en").Score
+	if want := round(0.5*1 + 0.5*text); *feedback.SentimentScore != want {
+		t.Errorf("blended score %v, want %v", *feedback.SentimentScore, want)
+	}
+}
@@ -15,6 +15,8 @@ func CreateTables(ctx context.Context, dbpool *pgxpool.Pool) error {
 			api_key TEXT NOT NULL UNIQUE
 		);`,
 
+		`ALTER TABLE tenant ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}';`,
+
 		`CREATE TABLE IF NOT EXISTS feedback (
 			id TEXT NOT NULL,
 			tenant_id UUID NOT NULL,
@@ -32,6 +34,240 @@ func CreateTables(ctx context.Context, dbpool *pgxpool.Pool) error {
 			  ON DELETE CASCADE
 		);`,
 
+		// common record-level attributes
+		`ALTER TABLE feedback
+			ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS language_confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS url TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS rating DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS source_created_at TIMESTAMPTZ,
+			ADD COLUMN IF NOT EXISTS ingested_at TIMESTAMPTZ NOT NULL DEFAULT NOW();`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tenant_source_created_at ON feedback (tenant_id, source_created_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tenant_timestamp ON feedback (tenant_id, (COALESCE(source_created_at, created_at)) DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tenant_language ON feedback (tenant_id, language);`,
+
+		`CREATE TABLE IF NOT EXISTS feedback_tag (
+			tenant_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			origin TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT pk_feedback_tag PRIMARY KEY (tenant_id, source, feedback_id, tag),
+			CONSTRAINT fk_feedback
+			  FOREIGN KEY(feedback_id, tenant_id, source)
+			  REFERENCES feedback(id, tenant_id, source)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tag_tenant_tag ON feedback_tag (tenant_id, tag);`,
+
+		// near-duplicate detection
+		`ALTER TABLE feedback
+			ADD COLUMN IF NOT EXISTS minhash BYTEA,
+			ADD COLUMN IF NOT EXISTS duplicate_cluster_id UUID;`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tenant_duplicate_cluster ON feedback (tenant_id, duplicate_cluster_id);`,
+
+		`CREATE TABLE IF NOT EXISTS feedback_minhash_band (
+			tenant_id UUID NOT NULL,
+			band SMALLINT NOT NULL,
+			hash BIGINT NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			CONSTRAINT pk_feedback_minhash_band PRIMARY KEY (tenant_id, band, hash, source, feedback_id),
+			CONSTRAINT fk_feedback
+			  FOREIGN KEY(feedback_id, tenant_id, source)
+			  REFERENCES feedback(id, tenant_id, source)
+			  ON DELETE CASCADE
+		);`,
+
+		// sentiment, see pkg/sentiment
+		`ALTER TABLE feedback
+			ADD COLUMN IF NOT EXISTS sentiment_score DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS sentiment TEXT NOT NULL DEFAULT '';`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tenant_sentiment ON feedback (tenant_id, sentiment);`,
+
+		`CREATE TABLE IF NOT EXISTS tag_rule (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			name TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			conditions JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT fk_tenant
+			  FOREIGN KEY(tenant_id) 
+			  REFERENCES tenant(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_tag_rule_tenant ON tag_rule (tenant_id);`,
+
+		// the rule that added the tag, for tags with the rule origin
+		`ALTER TABLE feedback_tag ADD COLUMN IF NOT EXISTS rule_id UUID REFERENCES tag_rule(id) ON DELETE CASCADE;`,
+
+		// triage, notes and manual tags, kept apart from the ingested record
+		`CREATE TABLE IF NOT EXISTS feedback_triage (
+			tenant_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'new' CHECK (status IN ('new', 'reviewed', 'actioned', 'ignored')),
+			assignee TEXT NOT NULL DEFAULT '',
+			priority TEXT NOT NULL DEFAULT '' CHECK (priority IN ('', 'low', 'medium', 'high', 'urgent')),
+			updated_by TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT pk_feedback_triage PRIMARY KEY (tenant_id, source, feedback_id),
+			CONSTRAINT fk_feedback
+			  FOREIGN KEY(feedback_id, tenant_id, source)
+			  REFERENCES feedback(id, tenant_id, source)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_triage_tenant_status ON feedback_triage (tenant_id, status);`,
+		`CREATE INDEX IF NOT EXISTS idx_feedback_triage_tenant_assignee ON feedback_triage (tenant_id, assignee);`,
+
+		`CREATE TABLE IF NOT EXISTS feedback_note (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			author TEXT NOT NULL,
+			body TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT fk_feedback
+			  FOREIGN KEY(feedback_id, tenant_id, source)
+			  REFERENCES feedback(id, tenant_id, source)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_note_feedback ON feedback_note (tenant_id, source, feedback_id);`,
+
+		// topics, see pkg/topics
+		`CREATE TABLE IF NOT EXISTS topic (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			label TEXT NOT NULL,
+			keywords TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			last_seen_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT uq_topic_tenant_label UNIQUE (tenant_id, label),
+			CONSTRAINT fk_tenant
+			  FOREIGN KEY(tenant_id) 
+			  REFERENCES tenant(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS topic_member (
+			topic_id UUID NOT NULL REFERENCES topic(id) ON DELETE CASCADE,
+			tenant_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			score DOUBLE PRECISION NOT NULL DEFAULT 0,
+			CONSTRAINT pk_topic_member PRIMARY KEY (topic_id, tenant_id, source, feedback_id),
+			CONSTRAINT fk_feedback
+			  FOREIGN KEY(feedback_id, tenant_id, source)
+			  REFERENCES feedback(id, tenant_id, source)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_topic_member_feedback ON topic_member (tenant_id, source, feedback_id);`,
+
+		// hourly rollups of the feedback counts, see db.AnalyticsRepository
+		`CREATE TABLE IF NOT EXISTS feedback_rollup (
+			tenant_id UUID NOT NULL,
+			bucket TIMESTAMPTZ NOT NULL,
+			source TEXT NOT NULL,
+			source_type TEXT NOT NULL,
+			sub_source_id UUID NOT NULL,
+			language TEXT NOT NULL,
+			rating DOUBLE PRECISION,
+			sentiment TEXT NOT NULL,
+			count BIGINT NOT NULL,
+			CONSTRAINT fk_tenant
+			  FOREIGN KEY(tenant_id) 
+			  REFERENCES tenant(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_rollup_tenant_bucket ON feedback_rollup (tenant_id, bucket);`,
+
+		`CREATE TABLE IF NOT EXISTS feedback_tag_rollup (
+			tenant_id UUID NOT NULL,
+			bucket TIMESTAMPTZ NOT NULL,
+			source TEXT NOT NULL,
+			source_type TEXT NOT NULL,
+			sub_source_id UUID NOT NULL,
+			language TEXT NOT NULL,
+			rating DOUBLE PRECISION,
+			sentiment TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			count BIGINT NOT NULL,
+			CONSTRAINT fk_tenant
+			  FOREIGN KEY(tenant_id) 
+			  REFERENCES tenant(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_feedback_tag_rollup_tenant_bucket ON feedback_tag_rollup (tenant_id, bucket);`,
+
+		`CREATE TABLE IF NOT EXISTS feedback_rollup_state (
+			tenant_id UUID PRIMARY KEY REFERENCES tenant(id) ON DELETE CASCADE,
+			refreshed_until TIMESTAMPTZ NOT NULL,
+			refreshed_at TIMESTAMPTZ NOT NULL
+		);`,
+
+		// the hours whose rollups went stale: feedback updated in or out of
+		// them, deleted, or a tag removed
+		`CREATE TABLE IF NOT EXISTS feedback_rollup_stale (
+			tenant_id UUID NOT NULL REFERENCES tenant(id) ON DELETE CASCADE,
+			bucket TIMESTAMPTZ NOT NULL,
+			CONSTRAINT pk_feedback_rollup_stale PRIMARY KEY (tenant_id, bucket)
+		);`,
+
+		// the tenant is gone when its feedback is deleted with it
+		`CREATE OR REPLACE FUNCTION feedback_rollup_stale_hour() RETURNS TRIGGER AS $$
+		BEGIN
+			INSERT INTO feedback_rollup_stale (tenant_id, bucket)
+			SELECT OLD.tenant_id, date_trunc('hour', COALESCE(OLD.source_created_at, OLD.created_at), 'UTC')
+			WHERE EXISTS (SELECT 1 FROM tenant WHERE id = OLD.tenant_id)
+			ON CONFLICT DO NOTHING;
+			IF TG_OP = 'UPDATE' THEN
+				INSERT INTO feedback_rollup_stale (tenant_id, bucket)
+				VALUES (NEW.tenant_id, date_trunc('hour', COALESCE(NEW.source_created_at, NEW.created_at), 'UTC'))
+				ON CONFLICT DO NOTHING;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+
+		`DROP TRIGGER IF EXISTS feedback_rollup_stale ON feedback;`,
+		`CREATE TRIGGER feedback_rollup_stale
+			AFTER UPDATE OR DELETE ON feedback
+			FOR EACH ROW EXECUTE FUNCTION feedback_rollup_stale_hour();`,
+
+		// the feedback is gone, and its hour marked, when its tags are
+		// deleted with it
+		`CREATE OR REPLACE FUNCTION feedback_tag_rollup_stale_hour() RETURNS TRIGGER AS $$
+		BEGIN
+			INSERT INTO feedback_rollup_stale (tenant_id, bucket)
+			SELECT feedback.tenant_id, date_trunc('hour', COALESCE(feedback.source_created_at, feedback.created_at), 'UTC')
+			FROM feedback
+			WHERE feedback.tenant_id = OLD.tenant_id AND feedback.source = OLD.source AND feedback.id = OLD.feedback_id
+			ON CONFLICT DO NOTHING;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+
+		`DROP TRIGGER IF EXISTS feedback_tag_rollup_stale ON feedback_tag;`,
+		`CREATE TRIGGER feedback_tag_rollup_stale
+			AFTER DELETE ON feedback_tag
+			FOR EACH ROW EXECUTE FUNCTION feedback_tag_rollup_stale_hour();`,
+
 		`CREATE TABLE IF NOT EXISTS subscription (
 			id UUID PRIMARY KEY,
 			tenant_id UUID NOT NULL,
@@ -48,6 +284,136 @@ func CreateTables(ctx context.Context, dbpool *pgxpool.Pool) error {
 			  ON DELETE CASCADE
 		);`,
 
+		// the secret webhook calls of push subscriptions are verified with
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS webhook_secret TEXT NOT NULL DEFAULT '';`,
+
+		// set when the OAuth token of the subscription cannot be refreshed
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS needs_reauth BOOLEAN NOT NULL DEFAULT FALSE;`,
+
+		// set when the subscription is deleted but its feedback kept
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`CREATE INDEX IF NOT EXISTS idx_subscription_tenant ON subscription (tenant_id, created_at DESC);`,
+
+		// the health of the subscription's pulls, a failing subscription is
+		// not pulled before next_pull_at and disabled after too many failures
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS health TEXT NOT NULL DEFAULT 'healthy' CHECK (health IN ('healthy', 'degraded', 'failing', 'auth_error'));`,
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS consecutive_failures INT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS last_success_at TIMESTAMPTZ;`,
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS next_pull_at TIMESTAMPTZ;`,
+		`ALTER TABLE subscription ADD COLUMN IF NOT EXISTS disabled_reason TEXT NOT NULL DEFAULT '';`,
+
+		// webhook calls as received, processed by background workers
+		`CREATE TABLE IF NOT EXISTS webhook_inbox (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			subscription_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			headers JSONB,
+			query TEXT NOT NULL DEFAULT '',
+			body BYTEA NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'dead')),
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			locked_until TIMESTAMPTZ,
+			last_error TEXT NOT NULL DEFAULT '',
+			received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			processed_at TIMESTAMPTZ,
+			CONSTRAINT fk_subscription
+			  FOREIGN KEY(subscription_id)
+			  REFERENCES subscription(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_webhook_inbox_due ON webhook_inbox (status, next_attempt_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_inbox_tenant ON webhook_inbox (tenant_id, received_at DESC);`,
+
+		// the claim an entry is processed under, a worker whose lease expired
+		// cannot complete or fail it anymore
+		`ALTER TABLE webhook_inbox ADD COLUMN IF NOT EXISTS lease_token UUID;`,
+
+		// raw source payloads, gzip compressed, so feedback can be mapped again
+		`CREATE TABLE IF NOT EXISTS raw_payload (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			subscription_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			kind TEXT NOT NULL CHECK (kind IN ('pull', 'push')),
+			data BYTEA NOT NULL,
+			size INT NOT NULL,
+			received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CONSTRAINT fk_subscription
+			  FOREIGN KEY(subscription_id)
+			  REFERENCES subscription(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_raw_payload_tenant_source ON raw_payload (tenant_id, source, received_at, id);`,
+		`CREATE INDEX IF NOT EXISTS idx_raw_payload_received_at ON raw_payload (received_at);`,
+
+		`ALTER TABLE feedback ADD COLUMN IF NOT EXISTS raw_payload_id UUID REFERENCES raw_payload(id) ON DELETE SET NULL;`,
+
+		// payloads that failed to be ingested
+		`CREATE TABLE IF NOT EXISTS dead_letter (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			subscription_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			kind TEXT NOT NULL CHECK (kind IN ('pull', 'push')),
+			payload BYTEA NOT NULL,
+			stage TEXT NOT NULL CHECK (stage IN ('fetch', 'map', 'pipeline', 'store')),
+			error TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CONSTRAINT fk_subscription
+			  FOREIGN KEY(subscription_id)
+			  REFERENCES subscription(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_dead_letter_tenant ON dead_letter (tenant_id, created_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_dead_letter_last_attempt_at ON dead_letter (last_attempt_at);`,
+
+		// OAuth connect flows started, until their callback
+		`CREATE TABLE IF NOT EXISTS oauth_state (
+			state TEXT PRIMARY KEY,
+			subscription_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMPTZ NOT NULL,
+			CONSTRAINT fk_subscription
+			  FOREIGN KEY(subscription_id)
+			  REFERENCES subscription(id)
+			  ON DELETE CASCADE
+		);`,
+
+		// token buckets limiting the calls to the sources, shared by the replicas
+		`CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+			key TEXT PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS pii_vault (
+			id UUID PRIMARY KEY,
+			tenant_id UUID NOT NULL,
+			source TEXT NOT NULL,
+			feedback_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			replacement TEXT NOT NULL,
+			ciphertext BYTEA NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			CONSTRAINT fk_tenant
+			  FOREIGN KEY(tenant_id) 
+			  REFERENCES tenant(id)
+			  ON DELETE CASCADE
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_pii_vault_feedback ON pii_vault (tenant_id, source, feedback_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_pii_vault_replacement ON pii_vault (tenant_id, replacement);`,
+
 		// Insert a default tenant if not already exists
 		`INSERT INTO tenant (id, name, api_key)
 		 VALUES ('cb4d81c7-e1bf-4ca5-900f-665a0e3fc932', 'Default Tenant', '3eaa4c4a-2271-45b1-8df0-81a4bd64251b')
@@ -3,17 +3,20 @@ package server
 import (
 	"net/http"
 
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/config"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type Server struct {
 	Router *http.ServeMux
 	DBPool *pgxpool.Pool
+	Config *config.Config
 }
 
-func NewServer(dbpool *pgxpool.Pool) *Server {
+func NewServer(dbpool *pgxpool.Pool, cfg *config.Config) *Server {
 	return &Server{
 		Router: http.NewServeMux(),
 		DBPool: dbpool,
+		Config: cfg,
 	}
 }
@@ -4,17 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
 )
 
 type SubscriptionHandler struct {
-	service *SubscriptionService
+	service   *SubscriptionService
+	publicURL string
 }
 
-func NewSubscriptionHandler(service *SubscriptionService) *SubscriptionHandler {
-	return &SubscriptionHandler{service: service}
+func NewSubscriptionHandler(service *SubscriptionService, publicURL string) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service, publicURL: publicURL}
 }
 
 func (h *SubscriptionHandler) CreateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
@@ -34,7 +36,13 @@ func (h *SubscriptionHandler) CreateSubscriptionHandler(w http.ResponseWriter, r
 		return
 	}
 
+	if err := h.service.ValidateSubscription(&sub); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	sub.ID = uuid.New().String()
+	generatedSecret := sub.SubscriptionMode == models.SubscriptionModePush && sub.WebhookSecret == ""
 
 	ctx := r.Context()
 	if err := h.service.CreateSubscription(ctx, &sub); err != nil {
@@ -42,6 +50,219 @@ func (h *SubscriptionHandler) CreateSubscriptionHandler(w http.ResponseWriter, r
 		return
 	}
 
+	response := h.response(&sub)
+	if generatedSecret {
+		// the only time it is shown, the source needs it
+		response.WebhookSecret = sub.WebhookSecret
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(sub)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListSubscriptionsHandler lists the tenant's subscriptions, the last
+// created first, optionally filtered by source, mode, active and health.
+// Deleted ones are listed with include_deleted=true.
+func (h *SubscriptionHandler) ListSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &models.SubscriptionFilter{
+		TenantID:         query.Get("tenant_id"),
+		Source:           models.Source(query.Get("source")),
+		SubscriptionMode: models.SubscriptionMode(query.Get("mode")),
+		Health:           models.SubscriptionHealth(query.Get("health")),
+		IncludeDeleted:   query.Get("include_deleted") == "true",
+	}
+	if !validTenantID(w, filter.TenantID) {
+		return
+	}
+	if filter.SubscriptionMode != "" && filter.SubscriptionMode != models.SubscriptionModePush && filter.SubscriptionMode != models.SubscriptionModePull {
+		http.Error(w, "Mode must be 'push' or 'pull'", http.StatusBadRequest)
+		return
+	}
+	switch filter.Health {
+	case "", models.HealthHealthy, models.HealthDegraded, models.HealthFailing, models.HealthAuthError:
+	default:
+		http.Error(w, "Health must be 'healthy', 'degraded', 'failing' or 'auth_error'", http.StatusBadRequest)
+		return
+	}
+	if active := query.Get("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			http.Error(w, "Invalid active, expected true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Active = &value
+	}
+	for name, value := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if s := query.Get(name); s != "" {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 {
+				http.Error(w, fmt.Sprintf("Invalid %s, expected a non-negative integer", name), http.StatusBadRequest)
+				return
+			}
+			*value = i
+		}
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+
+	ctx := r.Context()
+	subs, err := h.service.ListSubscriptions(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list subscriptions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]*models.Subscription, len(subs))
+	for i, sub := range subs {
+		response[i] = h.response(sub)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSubscriptionHandler returns the tenant's subscription with the given
+// id, its secrets redacted.
+func (h *SubscriptionHandler) GetSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(h.response(sub))
+}
+
+// UpdateSubscriptionHandler replaces the configuration of the tenant's
+// subscription with the one in the body, {"configuration": {...}}. Secret
+// fields sent back redacted keep their value.
+func (h *SubscriptionHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update struct {
+		Configuration map[string]interface{} `json:"configuration"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	sub, ok := h.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.UpdateConfiguration(ctx, sub, update.Configuration); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(h.response(sub))
+}
+
+// PauseSubscriptionHandler stops pulling the tenant's subscription and
+// rejects its webhook calls, until it is resumed.
+func (h *SubscriptionHandler) PauseSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+// ResumeSubscriptionHandler resumes the tenant's paused subscription.
+func (h *SubscriptionHandler) ResumeSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+func (h *SubscriptionHandler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sub, ok := h.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.SetActive(ctx, sub.ID, active); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subscription: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sub.Active = active
+
+	json.NewEncoder(w).Encode(h.response(sub))
+}
+
+// DeleteSubscriptionHandler removes the tenant's subscription with its
+// feedback, or with soft=true deactivates it for good and keeps its
+// feedback.
+func (h *SubscriptionHandler) DeleteSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	soft := r.URL.Query().Get("soft") == "true"
+
+	sub, ok := h.subscriptionOf(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	deleted, err := h.service.DeleteSubscription(ctx, sub.ID, soft)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete subscription: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": sub.ID, "soft": soft, "feedback_deleted": deleted})
+}
+
+// subscriptionOf returns the subscription of the id and tenant_id query
+// parameters, deleted ones are not found.
+func (h *SubscriptionHandler) subscriptionOf(w http.ResponseWriter, r *http.Request) (*models.Subscription, bool) {
+	query := r.URL.Query()
+	tenantID, subscriptionID := query.Get("tenant_id"), query.Get("id")
+	if !validTenantID(w, tenantID) {
+		return nil, false
+	}
+	if subscriptionID == "" {
+		http.Error(w, "Subscription ID is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		http.Error(w, "Invalid Subscription ID format", http.StatusBadRequest)
+		return nil, false
+	}
+
+	ctx := r.Context()
+	sub, err := h.service.GetSubscription(ctx, subscriptionID)
+	if err != nil || sub.TenantID != tenantID || sub.DeletedAt != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return nil, false
+	}
+	return sub, true
+}
+
+// response returns the subscription as it is returned, without secrets.
+func (h *SubscriptionHandler) response(sub *models.Subscription) *models.Subscription {
+	response := h.service.Redact(sub)
+	if sub.SubscriptionMode == models.SubscriptionModePush {
+		response.WebhookURL = WebhookURL(h.publicURL, sub)
+	}
+	return response
+}
+
+func validTenantID(w http.ResponseWriter, tenantID string) bool {
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return false
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return false
+	}
+	return true
 }
@@ -0,0 +1,87 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/metrics"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	// failingAfter is the number of failures in a row a subscription is
+	// failing after, degraded before
+	failingAfter = 3
+
+	// pullBackoffMax caps the time a failing subscription waits before it is
+	// pulled again
+	pullBackoffMax = 24 * time.Hour
+
+	// maxLastErrorLength caps the error kept on the subscription
+	maxLastErrorLength = 1000
+)
+
+// unauthorizedError is the error of a call the source refused for its
+// credentials, e.g. a 401 or 403 *integrations.StatusError.
+type unauthorizedError interface {
+	Unauthorized() bool
+}
+
+// RecordPullSuccess marks the subscription as healthy, its failures are
+// forgotten.
+func (s *SubscriptionService) RecordPullSuccess(ctx context.Context, sub *models.Subscription) error {
+	return s.repo.RecordSuccess(ctx, sub.ID)
+}
+
+// RecordPullFailure records the failure of a pull of the subscription: it
+// is degraded, then failing after a few failures in a row, or has an auth
+// error when it must be connected again or the source refused its
+// credentials. Pulled every interval, it skips a number of pulls doubling
+// with each failure, and is disabled once it failed disableAfter times in a
+// row (never when 0).
+func (s *SubscriptionService) RecordPullFailure(ctx context.Context, sub *models.Subscription, pullErr error, interval time.Duration, disableAfter int) error {
+	lastError := pullErr.Error()
+	if len(lastError) > maxLastErrorLength {
+		lastError = strings.ToValidUTF8(lastError[:maxLastErrorLength], "")
+	}
+
+	failures, err := s.repo.RecordFailure(ctx, sub.ID, lastError)
+	if err != nil {
+		return err
+	}
+
+	var unauthorized unauthorizedError
+	health := models.HealthDegraded
+	switch {
+	case sub.NeedsReauth, errors.As(pullErr, &unauthorized) && unauthorized.Unauthorized():
+		health = models.HealthAuthError
+	case failures >= failingAfter:
+		health = models.HealthFailing
+	}
+
+	var disabledReason string
+	if disableAfter > 0 && failures >= disableAfter {
+		disabledReason = fmt.Sprintf("disabled after %d failed pulls in a row", failures)
+		metrics.IncCounter("subscription_auto_disabled_total", "Subscriptions disabled for failing too many times in a row.", metrics.Labels{"source": string(sub.Source)}, 1)
+		fmt.Printf("Subscription %s is %s: %s\n", sub.ID, disabledReason, lastError)
+	}
+
+	return s.repo.UpdateHealth(ctx, sub.ID, health, time.Now().UTC().Add(pullBackoff(failures, interval)), disabledReason)
+}
+
+// pullBackoff is the time a subscription pulled every interval that failed
+// failures times in a row waits before it is pulled again: it skips
+// 2^(failures-1)-1 pulls, as many as fit in pullBackoffMax at most. The
+// wait ends half an interval before the pull it resumes at, so the time
+// the failing pull took does not push it to the next one, and never lasts
+// more than pullBackoffMax.
+func pullBackoff(failures int, interval time.Duration) time.Duration {
+	skipped := time.Duration(0)
+	for i := 1; i < failures && (2*skipped+1)*interval <= pullBackoffMax; i++ {
+		skipped = 2*skipped + 1
+	}
+	return min(skipped*interval+interval/2, pullBackoffMax)
+}
@@ -0,0 +1,36 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPullBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		interval time.Duration
+		want     time.Duration
+	}{
+		// pulled at the next run
+		{1, 8 * time.Hour, 4 * time.Hour},
+		// skips 1, then 3 runs, which fill the 24h
+		{2, 8 * time.Hour, 12 * time.Hour},
+		{3, 8 * time.Hour, 24 * time.Hour},
+		{10, 8 * time.Hour, 24 * time.Hour},
+		// skips 1, 3, 7 and 15 runs, 31 would not fit
+		{2, time.Hour, 90 * time.Minute},
+		{5, time.Hour, 15*time.Hour + 30*time.Minute},
+		{10, time.Hour, 15*time.Hour + 30*time.Minute},
+		// runs longer than the cap are never skipped
+		{5, 48 * time.Hour, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got := pullBackoff(tt.failures, tt.interval)
+		if got != tt.want {
+			t.Errorf("pullBackoff(%d, %s) = %s, want %s", tt.failures, tt.interval, got, tt.want)
+		}
+		if got > pullBackoffMax {
+			t.Errorf("pullBackoff(%d, %s) = %s, more than %s", tt.failures, tt.interval, got, pullBackoffMax)
+		}
+	}
+}
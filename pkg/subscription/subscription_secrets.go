@@ -0,0 +1,239 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+)
+
+// RedactedValue replaces the secrets in the responses.
+const RedactedValue = "[REDACTED]"
+
+// webhookSecretField names the webhook secret in the additional data of
+// its encryption.
+const webhookSecretField = "webhook_secret"
+
+// SecretFields returns the configuration fields of the source's
+// subscriptions that hold secrets, as dotted paths, e.g. signature.secret.
+type SecretFields func(source models.Source) []string
+
+// SetSecretFields sets the secret fields of the sources. It is set once the
+// integrations, which depend on this service, are set up.
+func (s *SubscriptionService) SetSecretFields(fields SecretFields) {
+	s.secretFields = fields
+}
+
+// secretFieldsOf returns the source's secret fields and the OAuth tokens.
+func (s *SubscriptionService) secretFieldsOf(source models.Source) []string {
+	var fields []string
+	if s.secretFields != nil {
+		fields = append(fields, s.secretFields(source)...)
+	}
+	return append(fields, models.OAuthSecretFields...)
+}
+
+// Redact returns a copy of the subscription without its secrets, to be
+// returned or logged.
+func (s *SubscriptionService) Redact(sub *models.Subscription) *models.Subscription {
+	redacted := *sub
+	redacted.WebhookSecret = ""
+	redacted.Configuration = copyConfiguration(sub.Configuration)
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		if _, ok := getField(redacted.Configuration, field); ok {
+			setField(redacted.Configuration, field, RedactedValue)
+		}
+	}
+	return &redacted
+}
+
+// seal returns a copy of the subscription with its secrets encrypted, as
+// it is stored. Without a key a subscription holding secrets is not stored,
+// rather than stored in plaintext.
+func (s *SubscriptionService) seal(sub *models.Subscription) (*models.Subscription, error) {
+	if !s.keyring.Enabled() {
+		if field, ok := s.plaintextSecret(sub); ok {
+			return nil, fmt.Errorf("no secret key is configured to encrypt %s", field)
+		}
+		return sub, nil
+	}
+
+	sealed := *sub
+	sealed.Configuration = copyConfiguration(sub.Configuration)
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		value, ok := getField(sealed.Configuration, field)
+		if !ok || value == nil {
+			continue
+		}
+		if text, ok := value.(string); ok && secrets.IsEncrypted(text) {
+			continue
+		}
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %v", field, err)
+		}
+		encrypted, err := s.keyring.Encrypt(plaintext, secretAdditionalData(sub, field))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %v", field, err)
+		}
+		setField(sealed.Configuration, field, encrypted)
+	}
+
+	if sealed.WebhookSecret != "" && !secrets.IsEncrypted(sealed.WebhookSecret) {
+		encrypted, err := s.keyring.Encrypt([]byte(sealed.WebhookSecret), secretAdditionalData(sub, webhookSecretField))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %v", webhookSecretField, err)
+		}
+		sealed.WebhookSecret = encrypted
+	}
+
+	return &sealed, nil
+}
+
+// plaintextSecret returns the first secret of the subscription that is not
+// encrypted.
+func (s *SubscriptionService) plaintextSecret(sub *models.Subscription) (string, bool) {
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		value, ok := getField(sub.Configuration, field)
+		if !ok || value == nil {
+			continue
+		}
+		if text, ok := value.(string); !ok || !secrets.IsEncrypted(text) {
+			return field, true
+		}
+	}
+	if sub.WebhookSecret != "" && !secrets.IsEncrypted(sub.WebhookSecret) {
+		return webhookSecretField, true
+	}
+	return "", false
+}
+
+// open decrypts the secrets of the subscription as read from the database.
+// Secrets stored before a key was configured are read as they are.
+func (s *SubscriptionService) open(sub *models.Subscription) error {
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		value, ok := getField(sub.Configuration, field)
+		text, isString := value.(string)
+		if !ok || !isString || !secrets.IsEncrypted(text) {
+			continue
+		}
+		plaintext, err := s.keyring.Decrypt(text, secretAdditionalData(sub, field))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s of subscription %s: %v", field, sub.ID, err)
+		}
+		var decrypted interface{}
+		if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+			return fmt.Errorf("failed to decrypt %s of subscription %s: %v", field, sub.ID, err)
+		}
+		setField(sub.Configuration, field, decrypted)
+	}
+
+	if secrets.IsEncrypted(sub.WebhookSecret) {
+		plaintext, err := s.keyring.Decrypt(sub.WebhookSecret, secretAdditionalData(sub, webhookSecretField))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s of subscription %s: %v", webhookSecretField, sub.ID, err)
+		}
+		sub.WebhookSecret = string(plaintext)
+	}
+
+	return nil
+}
+
+// current reports whether every secret of the subscription is encrypted
+// with the active key.
+func (s *SubscriptionService) current(sub *models.Subscription) bool {
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		value, ok := getField(sub.Configuration, field)
+		if !ok || value == nil {
+			continue
+		}
+		if text, ok := value.(string); !ok || !s.keyring.Current(text) {
+			return false
+		}
+	}
+	return sub.WebhookSecret == "" || s.keyring.Current(sub.WebhookSecret)
+}
+
+// Reencrypt encrypts the secrets of every subscription with the active key:
+// the plaintext ones and the ones encrypted with an older key, which can be
+// removed from the configuration afterwards. It returns the number of
+// subscriptions scanned and encrypted again.
+func (s *SubscriptionService) Reencrypt(ctx context.Context, dryRun bool) (int, int, error) {
+	if !s.keyring.Enabled() {
+		return 0, 0, fmt.Errorf("no secret key is configured")
+	}
+
+	subs, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	reencrypted := 0
+	for _, sub := range subs {
+		if s.current(sub) {
+			continue
+		}
+		if err := s.open(sub); err != nil {
+			return len(subs), reencrypted, err
+		}
+		sealed, err := s.seal(sub)
+		if err != nil {
+			return len(subs), reencrypted, err
+		}
+		if !dryRun {
+			if err := s.repo.UpdateSecrets(ctx, sealed); err != nil {
+				return len(subs), reencrypted, err
+			}
+		}
+		reencrypted++
+	}
+
+	return len(subs), reencrypted, nil
+}
+
+// secretAdditionalData binds an encrypted secret to its subscription and
+// field, so it cannot be moved to another one.
+func secretAdditionalData(sub *models.Subscription, field string) []byte {
+	return []byte(sub.ID + "\x00" + field)
+}
+
+func copyConfiguration(configuration map[string]interface{}) map[string]interface{} {
+	if configuration == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(configuration))
+	for key, value := range configuration {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = copyConfiguration(nested)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+func getField(configuration map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	current := configuration
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = nested
+	}
+	value, ok := current[keys[len(keys)-1]]
+	return value, ok
+}
+
+// setField sets a field found by getField.
+func setField(configuration map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := configuration
+	for _, key := range keys[:len(keys)-1] {
+		current = current[key].(map[string]interface{})
+	}
+	current[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,99 @@
+package subscription
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+)
+
+func testService(t *testing.T, keys string) *SubscriptionService {
+	t.Helper()
+
+	keyring, err := secrets.NewKeyring(keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	s := NewSubscriptionService(nil, keyring)
+	s.SetSecretFields(func(source models.Source) []string {
+		return []string{"api_key", "signature.secret"}
+	})
+	return s
+}
+
+func testKeys(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return "2024-10=" + base64.StdEncoding.EncodeToString(key)
+}
+
+func TestSealWithoutKey(t *testing.T) {
+	s := testService(t, "")
+
+	tests := []struct {
+		name    string
+		sub     *models.Subscription
+		wantErr bool
+	}{
+		{"no secrets", &models.Subscription{Configuration: map[string]interface{}{"package_name": "app"}}, false},
+		{"credential", &models.Subscription{Configuration: map[string]interface{}{"api_key": "key"}}, true},
+		{"nested credential", &models.Subscription{Configuration: map[string]interface{}{
+			"signature": map[string]interface{}{"header": "X-Signature", "secret": "s"}}}, true},
+		{"webhook secret", &models.Subscription{WebhookSecret: "secret"}, true},
+		{"OAuth tokens", &models.Subscription{Configuration: map[string]interface{}{
+			models.OAuthConfigKey: map[string]interface{}{"access_token": "token"}}}, true},
+	}
+	for _, tt := range tests {
+		_, err := s.seal(tt.sub)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSealAndOpen(t *testing.T) {
+	s := testService(t, testKeys(t))
+	sub := &models.Subscription{
+		ID:            "3f2a1c4e-5b6d-4e7f-8a9b-0c1d2e3f4a5b",
+		WebhookSecret: "secret",
+		Configuration: map[string]interface{}{
+			"api_key":   "key",
+			"signature": map[string]interface{}{"header": "X-Signature", "secret": "s"},
+		},
+	}
+
+	sealed, err := s.seal(sub)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, ok := s.plaintextSecret(sealed); ok || !s.current(sealed) {
+		t.Fatalf("a secret was not encrypted: %+v", sealed)
+	}
+	if sub.Configuration["api_key"] != "key" || sub.WebhookSecret != "secret" {
+		t.Errorf("seal changed the subscription")
+	}
+	if signature := sealed.Configuration["signature"].(map[string]interface{}); signature["header"] != "X-Signature" {
+		t.Errorf("a field that is not a secret was encrypted: %v", signature)
+	}
+
+	if err := s.open(sealed); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if sealed.Configuration["api_key"] != "key" || sealed.WebhookSecret != "secret" {
+		t.Errorf("got %+v after open", sealed)
+	}
+	if signature := sealed.Configuration["signature"].(map[string]interface{}); signature["secret"] != "s" {
+		t.Errorf("got signature %v after open", signature)
+	}
+
+	redacted := s.Redact(sub)
+	if redacted.Configuration["api_key"] != RedactedValue || redacted.WebhookSecret != "" || sub.Configuration["api_key"] != "key" {
+		t.Errorf("got %+v redacted", redacted)
+	}
+}
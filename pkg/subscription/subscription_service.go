@@ -2,23 +2,83 @@ package subscription
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
 )
 
+// ConfigValidator checks a subscription before it is created, e.g. that
+// its source is supported and its configuration is valid.
+type ConfigValidator func(sub *models.Subscription) error
+
+// SubscriptionService keeps the subscriptions, their secrets (see
+// SetSecretFields) and webhook secret encrypted with the keyring.
 type SubscriptionService struct {
-	repo *db.SubscriptionRepository
+	repo         *db.SubscriptionRepository
+	keyring      *secrets.Keyring
+	validate     ConfigValidator
+	secretFields SecretFields
+}
+
+func NewSubscriptionService(repo *db.SubscriptionRepository, keyring *secrets.Keyring) *SubscriptionService {
+	return &SubscriptionService{repo: repo, keyring: keyring}
 }
 
-func NewSubscriptionService(repo *db.SubscriptionRepository) *SubscriptionService {
-	return &SubscriptionService{repo: repo}
+// SetConfigValidator sets the check of new subscriptions. It is set once the
+// integrations, which depend on this service, are set up.
+func (s *SubscriptionService) SetConfigValidator(validate ConfigValidator) {
+	s.validate = validate
+}
+
+// ValidateSubscription runs the configured check on the subscription.
+func (s *SubscriptionService) ValidateSubscription(sub *models.Subscription) error {
+	if s.validate == nil {
+		return nil
+	}
+	return s.validate(sub)
+}
+
+func (s *SubscriptionService) GetSubscription(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	sub, err := s.repo.Get(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.open(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
 }
 
 func (s *SubscriptionService) CreateSubscription(ctx context.Context, sub *models.Subscription) error {
-	if err := s.repo.Create(ctx, sub); err != nil {
+	if err := s.ValidateSubscription(sub); err != nil {
 		return err
 	}
+	if sub.SubscriptionMode == models.SubscriptionModePush && sub.WebhookSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %v", err)
+		}
+		sub.WebhookSecret = hex.EncodeToString(secret)
+	}
+	if sub.ID == "" {
+		// the secrets are bound to it
+		sub.ID = uuid.New().String()
+	}
+
+	sealed, err := s.seal(sub)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, sealed); err != nil {
+		return err
+	}
+	sub.CreatedAt, sub.LastPulled, sub.Health = sealed.CreatedAt, sealed.LastPulled, models.HealthHealthy
 
 	return nil
 }
@@ -29,7 +89,99 @@ func (s *SubscriptionService) GetAllActivePullSubscriptions(ctx context.Context)
 		return nil, err
 	}
 
-	return subscriptions, nil
+	opened := subscriptions[:0]
+	for _, sub := range subscriptions {
+		if err := s.open(sub); err != nil {
+			// the others can still be pulled
+			fmt.Printf("Skipping subscription: %v\n", err)
+			continue
+		}
+		opened = append(opened, sub)
+	}
+
+	return opened, nil
+}
+
+// ListSubscriptions returns the tenant's subscriptions matching the filter,
+// with their secrets still encrypted.
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context, filter *models.SubscriptionFilter) ([]*models.Subscription, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// serverManagedFields are the configuration fields set by the service
+// rather than the clients, e.g. the OAuth token set by the connect flow.
+var serverManagedFields = []string{models.OAuthConfigKey}
+
+// UpdateConfiguration replaces the configuration of the subscription once
+// validated. Secret fields left redacted keep their value, and the fields
+// the service manages keep their stored value.
+func (s *SubscriptionService) UpdateConfiguration(ctx context.Context, sub *models.Subscription, configuration map[string]interface{}) error {
+	if sub.DeletedAt != nil {
+		return fmt.Errorf("subscription %s is deleted", sub.ID)
+	}
+
+	updated := *sub
+	updated.Configuration = copyConfiguration(configuration)
+	for _, field := range s.secretFieldsOf(sub.Source) {
+		if value, ok := getField(updated.Configuration, field); !ok || value != RedactedValue {
+			continue
+		}
+		if current, ok := getField(sub.Configuration, field); ok {
+			setField(updated.Configuration, field, current)
+		} else {
+			return fmt.Errorf("%s is redacted but has no value", field)
+		}
+	}
+
+	if err := s.ValidateSubscription(&updated); err != nil {
+		return err
+	}
+	for _, field := range serverManagedFields {
+		delete(updated.Configuration, field)
+		if value, ok := sub.Configuration[field]; ok {
+			if updated.Configuration == nil {
+				updated.Configuration = map[string]interface{}{}
+			}
+			updated.Configuration[field] = value
+		}
+	}
+	if err := s.SaveConfiguration(ctx, &updated); err != nil {
+		return err
+	}
+	sub.Configuration = updated.Configuration
+	return nil
+}
+
+// SetActive pauses or resumes the subscription: paused subscriptions are
+// not pulled and their webhook calls are rejected.
+func (s *SubscriptionService) SetActive(ctx context.Context, subscriptionID string, active bool) error {
+	return s.repo.SetActive(ctx, subscriptionID, active)
+}
+
+// DeleteSubscription removes the subscription with its feedback, or when
+// soft only deactivates it for good and keeps its feedback. It returns the
+// number of feedback records removed.
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, subscriptionID string, soft bool) (int, error) {
+	if soft {
+		return 0, s.repo.SoftDelete(ctx, subscriptionID)
+	}
+	return s.repo.Delete(ctx, subscriptionID)
+}
+
+// SaveConfiguration saves the configuration of the subscription, its
+// secrets encrypted.
+func (s *SubscriptionService) SaveConfiguration(ctx context.Context, sub *models.Subscription) error {
+	sealed, err := s.seal(sub)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateSecrets(ctx, sealed)
+}
+
+// SetNeedsReauth flags the subscription as needing to be connected again
+// to its source, or clears the flag.
+func (s *SubscriptionService) SetNeedsReauth(ctx context.Context, subscriptionID string, needsReauth bool) error {
+	return s.repo.SetNeedsReauth(ctx, subscriptionID, needsReauth)
 }
 
 func (s *SubscriptionService) UpdateLastPulled(ctx context.Context, subscriptionID string) error {
@@ -39,3 +191,8 @@ func (s *SubscriptionService) UpdateLastPulled(ctx context.Context, subscription
 	}
 	return nil
 }
+
+// WebhookURL is the URL the source calls the push subscription at.
+func WebhookURL(publicURL string, sub *models.Subscription) string {
+	return fmt.Sprintf("%s/webhook/%s/%s", strings.TrimSuffix(publicURL, "/"), sub.Source, sub.ID)
+}
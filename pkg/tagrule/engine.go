@@ -0,0 +1,296 @@
+package tagrule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/sentiment"
+)
+
+var versionRe = regexp.MustCompile(`^v?\d+(\.\d+)*$`)
+
+// Engine applies a tenant's enabled tag rules to feedback.
+type Engine struct {
+	rules []*compiledRule
+}
+
+type compiledRule struct {
+	rule     *models.TagRule
+	patterns []*regexp.Regexp
+	sources  map[models.Source]bool
+}
+
+// NewEngine compiles the enabled rules.
+func NewEngine(rules []*models.TagRule) (*Engine, error) {
+	e := &Engine{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		compiled, err := compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("tag rule %s: %v", rule.ID, err)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// Validate checks that the rule can be applied.
+func Validate(rule *models.TagRule) error {
+	if strings.TrimSpace(rule.Tag) == "" {
+		return fmt.Errorf("tag is required")
+	}
+	c := rule.Conditions
+	if len(c.Keywords) == 0 && len(c.Patterns) == 0 && len(c.Sources) == 0 && len(c.Metadata) == 0 &&
+		len(c.Sentiments) == 0 && c.MinSentiment == nil && c.MaxSentiment == nil {
+		return fmt.Errorf("rule has no conditions")
+	}
+	_, err := compile(rule)
+	return err
+}
+
+func compile(rule *models.TagRule) (*compiledRule, error) {
+	compiled := &compiledRule{rule: rule}
+
+	for _, keyword := range rule.Conditions.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+		compiled.patterns = append(compiled.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(keyword)+`\b`))
+	}
+	for _, pattern := range rule.Conditions.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		compiled.patterns = append(compiled.patterns, re)
+	}
+
+	if len(rule.Conditions.Sources) > 0 {
+		compiled.sources = map[models.Source]bool{}
+		for _, source := range rule.Conditions.Sources {
+			compiled.sources[source] = true
+		}
+	}
+
+	for _, label := range rule.Conditions.Sentiments {
+		switch label {
+		case sentiment.Positive, sentiment.Neutral, sentiment.Negative:
+		default:
+			return nil, fmt.Errorf("invalid sentiment %q, expected positive, neutral or negative", label)
+		}
+	}
+
+	for _, cond := range rule.Conditions.Metadata {
+		if cond.Field == "" {
+			return nil, fmt.Errorf("metadata condition without a field")
+		}
+		switch cond.Op {
+		case "eq", "ne", "lt", "lte", "gt", "gte", "contains", "exists":
+		default:
+			return nil, fmt.Errorf("invalid metadata operator %q", cond.Op)
+		}
+		switch cond.Type {
+		case "", "version", "text":
+		case "number":
+			if _, err := strconv.ParseFloat(cond.Value, 64); err != nil && cond.Op != "exists" {
+				return nil, fmt.Errorf("metadata condition on %s: %q is not a number", cond.Field, cond.Value)
+			}
+		default:
+			return nil, fmt.Errorf("invalid metadata comparison type %q", cond.Type)
+		}
+	}
+
+	return compiled, nil
+}
+
+// Apply adds the tags of the rules the feedback matches, it returns whether
+// any did.
+func (e *Engine) Apply(feedback *models.Feedback) bool {
+	if len(e.rules) == 0 {
+		return false
+	}
+
+	// the text is only needed, and only extracted, for rules with patterns
+	var text *string
+	matched := false
+	for _, rule := range e.rules {
+		if rule.matches(feedback, func() string {
+			if text == nil {
+				t := feedback.Text()
+				text = &t
+			}
+			return *text
+		}) {
+			feedback.AddRuleTag(rule.rule.Tag, rule.rule.ID)
+			matched = true
+		}
+	}
+	return matched
+}
+
+func (r *compiledRule) matches(feedback *models.Feedback, text func() string) bool {
+	c := r.rule.Conditions
+
+	if r.sources != nil && !r.sources[feedback.Source] {
+		return false
+	}
+
+	if len(c.Sentiments) > 0 {
+		found := false
+		for _, label := range c.Sentiments {
+			if feedback.Sentiment == label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.MinSentiment != nil && (feedback.SentimentScore == nil || *feedback.SentimentScore < *c.MinSentiment) {
+		return false
+	}
+	if c.MaxSentiment != nil && (feedback.SentimentScore == nil || *feedback.SentimentScore > *c.MaxSentiment) {
+		return false
+	}
+
+	for _, cond := range c.Metadata {
+		if !matchMetadata(feedback.Metadata, &cond) {
+			return false
+		}
+	}
+
+	if len(r.patterns) > 0 {
+		t := text()
+		for _, re := range r.patterns {
+			if re.MatchString(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func matchMetadata(metadata map[string]interface{}, cond *models.MetadataCondition) bool {
+	value, ok := lookup(metadata, cond.Field)
+	if cond.Op == "exists" {
+		return ok
+	}
+	if !ok {
+		// a missing field is only different from anything
+		return cond.Op == "ne"
+	}
+
+	if cond.Op == "contains" {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(cond.Value))
+	}
+
+	cmp := compare(value, cond.Value, cond.Type)
+	switch cond.Op {
+	case "eq":
+		return cmp == 0
+	case "ne":
+		return cmp != 0
+	case "lt":
+		return cmp < 0
+	case "lte":
+		return cmp <= 0
+	case "gt":
+		return cmp > 0
+	case "gte":
+		return cmp >= 0
+	}
+	return false
+}
+
+// lookup returns the metadata value at the dotted path as text.
+func lookup(metadata map[string]interface{}, path string) (string, bool) {
+	var value interface{} = metadata
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// compare returns -1, 0 or 1 as a is before, equal to or after b.
+func compare(a, b, typ string) int {
+	if typ == "" {
+		typ = "text"
+		if versionRe.MatchString(strings.TrimSpace(a)) && versionRe.MatchString(strings.TrimSpace(b)) {
+			typ = "version"
+		}
+	}
+
+	switch typ {
+	case "number":
+		x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if errA == nil && errB == nil {
+			return compareFloats(x, y)
+		}
+	case "version":
+		if versionRe.MatchString(strings.TrimSpace(a)) && versionRe.MatchString(strings.TrimSpace(b)) {
+			return compareVersions(a, b)
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareVersions compares dotted versions segment by segment, missing
+// segments being 0: "3" == "3.0" < "3.0.1".
+func compareVersions(a, b string) int {
+	x := strings.Split(strings.TrimPrefix(strings.TrimSpace(a), "v"), ".")
+	y := strings.Split(strings.TrimPrefix(strings.TrimSpace(b), "v"), ".")
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var p, q float64
+		if i < len(x) {
+			p, _ = strconv.ParseFloat(x[i], 64)
+		}
+		if i < len(y) {
+			q, _ = strconv.ParseFloat(y[i], 64)
+		}
+		if c := compareFloats(p, q); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
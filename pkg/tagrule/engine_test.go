@@ -0,0 +1,159 @@
+package tagrule
+
+import (
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func testFeedback() *models.Feedback {
+	score := -0.6
+	return &models.Feedback{
+		Source:         models.SourceIntercom,
+		Content:        map[string]interface{}{"text": "The app crashes when I pay with my card"},
+		Sentiment:      "negative",
+		SentimentScore: &score,
+		Metadata: map[string]interface{}{
+			"app_version": "2.10.1",
+			"plan":        "Enterprise",
+			"seats":       float64(40),
+			"device":      map[string]interface{}{"os": "android"},
+		},
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions models.TagRuleConditions
+		want       bool
+	}{
+		{"keyword, any case", models.TagRuleConditions{Keywords: []string{"CRASHES"}}, true},
+		{"keyword, whole words only", models.TagRuleConditions{Keywords: []string{"crash"}}, false},
+		{"one of the keywords", models.TagRuleConditions{Keywords: []string{"refund", "card"}}, true},
+		{"pattern", models.TagRuleConditions{Patterns: []string{`pay(ment)? with`}}, true},
+		{"pattern is case sensitive", models.TagRuleConditions{Patterns: []string{`APP`}}, false},
+		{"source", models.TagRuleConditions{Sources: []models.Source{models.SourceIntercom}}, true},
+		{"other source", models.TagRuleConditions{Sources: []models.Source{models.SourceDiscourse}}, false},
+		{"sentiment", models.TagRuleConditions{Sentiments: []string{"negative", "neutral"}}, true},
+		{"other sentiment", models.TagRuleConditions{Sentiments: []string{"positive"}}, false},
+		{"max sentiment", models.TagRuleConditions{MaxSentiment: float(-0.5)}, true},
+		{"min sentiment", models.TagRuleConditions{MinSentiment: float(-0.5)}, false},
+		{"all conditions", models.TagRuleConditions{Keywords: []string{"card"}, Sources: []models.Source{models.SourceIntercom}, Sentiments: []string{"negative"}}, true},
+		{"all conditions, one fails", models.TagRuleConditions{Keywords: []string{"card"}, Sources: []models.Source{models.SourceDiscourse}}, false},
+		{"version", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "app_version", Op: "gte", Value: "2.9"}}}, true},
+		{"version as text", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "app_version", Op: "gte", Value: "2.9", Type: "text"}}}, false},
+		{"number", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "seats", Op: "gt", Value: "9", Type: "number"}}}, true},
+		{"text, any case", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "plan", Op: "eq", Value: "enterprise"}}}, true},
+		{"contains", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "plan", Op: "contains", Value: "PRISE"}}}, true},
+		{"nested field", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "device.os", Op: "eq", Value: "android"}}}, true},
+		{"exists", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "device.os", Op: "exists"}}}, true},
+		{"missing field exists", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "device.model", Op: "exists"}}}, false},
+		{"missing field ne", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "region", Op: "ne", Value: "eu"}}}, true},
+		{"missing field eq", models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "region", Op: "eq", Value: "eu"}}}, false},
+	}
+	for _, tt := range tests {
+		rule := &models.TagRule{ID: "rule", Tag: "tag", Enabled: true, Conditions: tt.conditions}
+		e, err := NewEngine([]*models.TagRule{rule})
+		if err != nil {
+			t.Fatalf("%s: NewEngine: %v", tt.name, err)
+		}
+		feedback := testFeedback()
+		if got := e.Apply(feedback); got != tt.want {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTags(t *testing.T) {
+	rules := []*models.TagRule{
+		{ID: "r1", Tag: "payments", Enabled: true, Conditions: models.TagRuleConditions{Keywords: []string{"card"}}},
+		{ID: "r2", Tag: "payments", Enabled: true, Conditions: models.TagRuleConditions{Keywords: []string{"pay"}}},
+		{ID: "r3", Tag: "crash", Enabled: true, Conditions: models.TagRuleConditions{Keywords: []string{"crashes"}}},
+		{ID: "r4", Tag: "disabled", Enabled: false, Conditions: models.TagRuleConditions{Keywords: []string{"app"}}},
+		{ID: "r5", Tag: "refund", Enabled: true, Conditions: models.TagRuleConditions{Keywords: []string{"refund"}}},
+	}
+	e, err := NewEngine(rules)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	feedback := testFeedback()
+	if !e.Apply(feedback) {
+		t.Fatalf("no rule matched")
+	}
+	want := []models.RuleTag{{Tag: "payments", RuleID: "r1"}, {Tag: "crash", RuleID: "r3"}}
+	if len(feedback.RuleTags) != len(want) {
+		t.Fatalf("got rule tags %+v, want %+v", feedback.RuleTags, want)
+	}
+	for i := range want {
+		if feedback.RuleTags[i] != want[i] {
+			t.Errorf("got rule tags %+v, want %+v", feedback.RuleTags, want)
+		}
+	}
+	if len(feedback.Tags) != 2 {
+		t.Errorf("got tags %v, want payments and crash", feedback.Tags)
+	}
+
+	empty, _ := NewEngine(nil)
+	if empty.Apply(testFeedback()) {
+		t.Errorf("an engine without rules matched")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.TagRule
+		wantErr bool
+	}{
+		{"valid", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Keywords: []string{"crash"}}}, false},
+		{"no tag", models.TagRule{Tag: " ", Conditions: models.TagRuleConditions{Keywords: []string{"crash"}}}, true},
+		{"no conditions", models.TagRule{Tag: "crash"}, true},
+		{"invalid pattern", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Patterns: []string{"("}}}, true},
+		{"invalid sentiment", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Sentiments: []string{"angry"}}}, true},
+		{"metadata without field", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Metadata: []models.MetadataCondition{{Op: "eq"}}}}, true},
+		{"invalid operator", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "plan", Op: "like"}}}}, true},
+		{"invalid type", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "plan", Op: "eq", Type: "date"}}}}, true},
+		{"not a number", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "seats", Op: "gt", Value: "many", Type: "number"}}}}, true},
+		{"exists on a number", models.TagRule{Tag: "crash", Conditions: models.TagRuleConditions{Metadata: []models.MetadataCondition{{Field: "seats", Op: "exists", Type: "number"}}}}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(&tt.rule); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	rule := &models.TagRule{ID: "bad", Tag: "crash", Enabled: true, Conditions: models.TagRuleConditions{Patterns: []string{"("}}}
+	if _, err := NewEngine([]*models.TagRule{rule}); err == nil {
+		t.Errorf("NewEngine accepted an invalid rule")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b, typ string
+		want      int
+	}{
+		{"2.10", "2.9", "", 1},
+		{"3", "3.0", "", 0},
+		{"3.0", "3.0.1", "", -1},
+		{"v1.2", "1.2", "", 0},
+		{"2.10", "2.9", "text", -1},
+		{"10", "9", "number", 1},
+		{"1e2", "100", "number", 0},
+		{"Pro", "pro", "", 0},
+		{"abc", "abd", "", -1},
+		// not numbers, compared as text
+		{"ten", "9", "number", 1},
+	}
+	for _, tt := range tests {
+		if got := compare(tt.a, tt.b, tt.typ); got != tt.want {
+			t.Errorf("compare(%q, %q, %q) = %d, want %d", tt.a, tt.b, tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tagrule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type TagRuleHandler struct {
+	service *TagRuleService
+}
+
+func NewTagRuleHandler(service *TagRuleService) *TagRuleHandler {
+	return &TagRuleHandler{service: service}
+}
+
+func (h *TagRuleHandler) CreateTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.TagRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if rule.TenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(rule.TenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+	if err := Validate(&rule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tag rule: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rule.ID = uuid.New().String()
+
+	ctx := r.Context()
+	if err := h.service.CreateTagRule(ctx, &rule); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *TagRuleHandler) GetTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ruleID := r.URL.Query().Get("id")
+	if ruleID == "" {
+		http.Error(w, "Tag rule ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	rule, err := h.service.GetTagRule(ctx, ruleID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve tag rule: %v", err), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *TagRuleHandler) UpdateTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.TagRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if rule.ID == "" {
+		http.Error(w, "Tag rule ID is required", http.StatusBadRequest)
+		return
+	}
+	if err := Validate(&rule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tag rule: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.UpdateTagRule(ctx, &rule); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *TagRuleHandler) DeleteTagRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ruleID := r.URL.Query().Get("id")
+	if ruleID == "" {
+		http.Error(w, "Tag rule ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.DeleteTagRule(ctx, ruleID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete tag rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagRuleHandler) ListTagRulesHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	rules, err := h.service.ListTagRules(ctx, tenantID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list tag rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// RetagHandler starts re-tagging the tenant's stored feedback with its
+// current rules. The job runs in the background, its outcome is logged.
+func (h *TagRuleHandler) RetagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenant_id")
+	if tenantID == "" {
+		http.Error(w, "Tenant ID is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		http.Error(w, "Invalid Tenant ID format", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		processed, tagged, err := h.service.Retag(context.Background(), tenantID)
+		if err != nil {
+			fmt.Printf("Re-tagging of tenant %s failed after %d records: %v\n", tenantID, processed, err)
+			return
+		}
+		fmt.Printf("Re-tagged %d feedback records of tenant %s, %d tagged by a rule\n", processed, tenantID, tagged)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
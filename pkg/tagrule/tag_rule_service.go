@@ -0,0 +1,96 @@
+package tagrule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const retagBatchSize = 500
+
+type TagRuleService struct {
+	repo         *db.TagRuleRepository
+	feedbackRepo *db.FeedbackRepository
+}
+
+func NewTagRuleService(repo *db.TagRuleRepository, feedbackRepo *db.FeedbackRepository) *TagRuleService {
+	return &TagRuleService{repo: repo, feedbackRepo: feedbackRepo}
+}
+
+func (s *TagRuleService) CreateTagRule(ctx context.Context, rule *models.TagRule) error {
+	if err := Validate(rule); err != nil {
+		return fmt.Errorf("invalid tag rule: %v", err)
+	}
+	return s.repo.Save(ctx, rule)
+}
+
+func (s *TagRuleService) GetTagRule(ctx context.Context, ruleID string) (*models.TagRule, error) {
+	return s.repo.Get(ctx, ruleID)
+}
+
+// UpdateTagRule updates the rule, the feedback it tagged before keeps its
+// tags until the tenant's feedback is re-tagged.
+func (s *TagRuleService) UpdateTagRule(ctx context.Context, rule *models.TagRule) error {
+	if err := Validate(rule); err != nil {
+		return fmt.Errorf("invalid tag rule: %v", err)
+	}
+	return s.repo.Update(ctx, rule)
+}
+
+func (s *TagRuleService) DeleteTagRule(ctx context.Context, ruleID string) error {
+	return s.repo.Delete(ctx, ruleID)
+}
+
+func (s *TagRuleService) ListTagRules(ctx context.Context, tenantID string) ([]*models.TagRule, error) {
+	return s.repo.ListByTenant(ctx, tenantID)
+}
+
+// Engine returns the engine applying the tenant's enabled rules.
+func (s *TagRuleService) Engine(ctx context.Context, tenantID string) (*Engine, error) {
+	rules, err := s.repo.ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(rules)
+}
+
+// Retag applies the tenant's current rules to all of its stored feedback,
+// replacing the tags added by rules before. It returns the number of
+// feedback records processed and the number of them now tagged by a rule.
+func (s *TagRuleService) Retag(ctx context.Context, tenantID string) (int, int, error) {
+	engine, err := s.Engine(ctx, tenantID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var (
+		processed, tagged int
+		afterSource       models.Source
+		afterID           string
+	)
+	for {
+		feedbacks, err := s.feedbackRepo.ListAfter(ctx, tenantID, afterSource, afterID, retagBatchSize)
+		if err != nil {
+			return processed, tagged, err
+		}
+		if len(feedbacks) == 0 {
+			return processed, tagged, nil
+		}
+
+		for _, feedback := range feedbacks {
+			feedback.RuleTags = nil
+			if engine.Apply(feedback) {
+				tagged++
+			}
+		}
+		if err := s.feedbackRepo.ReplaceRuleTags(ctx, feedbacks); err != nil {
+			return processed, tagged, err
+		}
+		processed += len(feedbacks)
+
+		last := feedbacks[len(feedbacks)-1]
+		afterSource, afterID = last.Source, last.ID
+	}
+}
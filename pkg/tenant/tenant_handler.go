@@ -3,6 +3,7 @@ package tenant
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -54,9 +55,16 @@ func (h *TenantHandler) GetTenantHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(tenant)
 }
 
+// UpdateTenantHandler updates the tenant with the fields of the request,
+// the ones left out are kept, see TenantService.UpdateTenant.
 func (h *TenantHandler) UpdateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
 	var tenant models.Tenant
-	if err := json.NewDecoder(r.Body).Decode(&tenant); err != nil {
+	if err := json.Unmarshal(body, &tenant); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
@@ -67,12 +75,13 @@ func (h *TenantHandler) UpdateTenantHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	ctx := r.Context()
-	if err := h.service.UpdateTenant(ctx, &tenant); err != nil {
+	updated, err := h.service.UpdateTenant(ctx, tenant.ID, body)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update tenant: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(tenant)
+	json.NewEncoder(w).Encode(updated)
 }
 
 func (h *TenantHandler) DeleteTenantHandler(w http.ResponseWriter, r *http.Request) {
@@ -2,20 +2,32 @@ package tenant
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
 	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/pipeline"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/redaction"
 )
 
 type TenantService struct {
-	repo *db.TenantRepository
+	repo    *db.TenantRepository
+	vault   *redaction.VaultService
+	hashKey []byte
 }
 
-func NewTenantService(repo *db.TenantRepository) *TenantService {
-	return &TenantService{repo: repo}
+// NewTenantService creates the service, the PII vault and the hash key are
+// the ones of the pipeline: the redaction settings needing them are rejected
+// when they are not configured.
+func NewTenantService(repo *db.TenantRepository, vault *redaction.VaultService, hashKey string) *TenantService {
+	return &TenantService{repo: repo, vault: vault, hashKey: []byte(hashKey)}
 }
 
 func (s *TenantService) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	if err := s.validateSettings(&tenant.Settings); err != nil {
+		return err
+	}
 	return s.repo.Save(ctx, tenant)
 }
 
@@ -23,10 +35,82 @@ func (s *TenantService) GetTenant(ctx context.Context, tenantID string) (*models
 	return s.repo.Get(ctx, tenantID)
 }
 
-func (s *TenantService) UpdateTenant(ctx context.Context, tenant *models.Tenant) error {
-	return s.repo.Update(ctx, tenant)
+// UpdateTenant applies the JSON update to the stored tenant: the fields it
+// leaves out keep their value, at every level of the settings, so an update
+// of the name or of one setting does not reset the others.
+func (s *TenantService) UpdateTenant(ctx context.Context, tenantID string, update []byte) (*models.Tenant, error) {
+	tenant, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := tenant.ApiKey
+	if err := json.Unmarshal(update, tenant); err != nil {
+		return nil, fmt.Errorf("invalid update: %v", err)
+	}
+	// neither is updated
+	tenant.ID, tenant.ApiKey = tenantID, apiKey
+
+	if err := s.validateSettings(&tenant.Settings); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
 }
 
 func (s *TenantService) DeleteTenant(ctx context.Context, tenantID string) error {
 	return s.repo.Delete(ctx, tenantID)
 }
+
+// CheckRedactionKeys checks that the stored tenants' redaction settings can
+// be applied with the configured keys, their feedback would be dropped
+// otherwise.
+func (s *TenantService) CheckRedactionKeys(ctx context.Context) error {
+	ids, err := s.repo.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		tenant, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.validateRedaction(&tenant.Settings.Redaction); err != nil {
+			return fmt.Errorf("tenant %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *TenantService) validateSettings(settings *models.TenantSettings) error {
+	if err := pipeline.ValidateSettings(&settings.Pipeline); err != nil {
+		return fmt.Errorf("invalid pipeline settings: %v", err)
+	}
+	if t := settings.Deduplication.Threshold; t < 0 || t > 1 {
+		return fmt.Errorf("invalid deduplication threshold: %v, expected a value between 0 and 1", t)
+	}
+	if w := settings.Sentiment.RatingWeight; w != nil && (*w < 0 || *w > 1) {
+		return fmt.Errorf("invalid sentiment rating weight: %v, expected a value between 0 and 1", *w)
+	}
+	if scale := settings.Sentiment.RatingScale; scale != 0 && scale <= 1 {
+		return fmt.Errorf("invalid sentiment rating scale: %v, expected a value above 1", scale)
+	}
+	if err := s.validateRedaction(&settings.Redaction); err != nil {
+		return fmt.Errorf("invalid redaction settings: %v", err)
+	}
+	return nil
+}
+
+func (s *TenantService) validateRedaction(settings *models.RedactionSettings) error {
+	if !settings.Enabled {
+		return nil
+	}
+	if _, err := redaction.NewRedactor("", settings, s.hashKey); err != nil {
+		return err
+	}
+	if settings.Vault && !s.vault.Enabled() {
+		return fmt.Errorf("the vault requires PII_VAULT_KEY to be set")
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package topics
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// keyphrases kept per feedback
+	phrasesPerDocument = 8
+
+	// a term shared by more than this share of the feedback says nothing
+	maxDocumentShare = 0.5
+
+	maxKeywords = 5
+)
+
+// Document is a feedback to cluster.
+type Document struct {
+	Text     string
+	Language string
+}
+
+// Options of the clustering, zero values take the defaults.
+type Options struct {
+	// MinSize is the number of feedback from which a term makes a topic, 3 by default
+	MinSize int
+	// MaxTopics returned, the largest first, 50 by default
+	MaxTopics int
+}
+
+// Cluster is a topic found among the documents: its label, the terms most
+// common among its members, and its members as indexes into the documents
+// with the weight of the label in each.
+type Cluster struct {
+	Label    string
+	Keywords []string
+	Members  []int
+	Scores   []float64
+}
+
+// Group clusters the documents into topics, a document being in at most one
+// topic.
+func Group(documents []Document, opts Options) []*Cluster {
+	if opts.MinSize <= 0 {
+		opts.MinSize = 3
+	}
+	if opts.MaxTopics <= 0 {
+		opts.MaxTopics = 50
+	}
+
+	// terms of each document, its keyphrases, with their RAKE score
+	terms := make([]map[string]float64, len(documents))
+	df := map[string]int{}
+	for i, doc := range documents {
+		terms[i] = map[string]float64{}
+		phrases := Keyphrases(doc.Text, doc.Language)
+		if len(phrases) > phrasesPerDocument {
+			phrases = phrases[:phrasesPerDocument]
+		}
+		for _, phrase := range phrases {
+			addTerm(terms[i], phrase.Phrase, phrase.Score)
+			// and its shorter phrases, "add dark mode" is also about "dark mode"
+			words := strings.Fields(phrase.Phrase)
+			for size := len(words) - 1; size >= 1; size-- {
+				for start := 0; start+size <= len(words); start++ {
+					addTerm(terms[i], strings.Join(words[start:start+size], " "), phrase.Score*float64(size)/float64(len(words)))
+				}
+			}
+		}
+		for term := range terms[i] {
+			df[term]++
+		}
+	}
+
+	n := float64(len(documents))
+	idf := func(term string) float64 {
+		return math.Log(1 + n/float64(df[term]))
+	}
+
+	// rank the terms shared by enough documents, phrases before single words
+	type candidate struct {
+		term  string
+		score float64
+	}
+	var candidates []candidate
+	for term, count := range df {
+		if count < opts.MinSize || float64(count) > maxDocumentShare*n {
+			continue
+		}
+		words := float64(len(strings.Fields(term)))
+		candidates = append(candidates, candidate{term, float64(count) * idf(term) * (1 + 0.5*(words-1))})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].term < candidates[j].term
+	})
+	rank := make(map[string]int, len(candidates))
+	for i, c := range candidates {
+		rank[c.term] = i
+	}
+
+	// each document joins the topic of its best ranked term
+	clusters := map[string]*Cluster{}
+	for i := range documents {
+		best, bestRank := "", len(candidates)
+		for term := range terms[i] {
+			if r, ok := rank[term]; ok && r < bestRank {
+				best, bestRank = term, r
+			}
+		}
+		if best == "" {
+			continue
+		}
+		c, ok := clusters[best]
+		if !ok {
+			c = &Cluster{Label: best}
+			clusters[best] = c
+		}
+		c.Members = append(c.Members, i)
+		c.Scores = append(c.Scores, round(terms[i][best]*idf(best)))
+	}
+
+	var result []*Cluster
+	for _, c := range clusters {
+		if len(c.Members) < opts.MinSize {
+			continue
+		}
+		c.Keywords = keywords(c, terms)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Members) != len(result[j].Members) {
+			return len(result[i].Members) > len(result[j].Members)
+		}
+		return result[i].Label < result[j].Label
+	})
+	if len(result) > opts.MaxTopics {
+		result = result[:opts.MaxTopics]
+	}
+	return result
+}
+
+func addTerm(terms map[string]float64, term string, score float64) {
+	if score > terms[term] {
+		terms[term] = score
+	}
+}
+
+// keywords returns the terms most shared by the members of the cluster,
+// besides its label and the words of its label.
+func keywords(c *Cluster, terms []map[string]float64) []string {
+	skip := map[string]bool{c.Label: true}
+	for _, word := range strings.Fields(c.Label) {
+		skip[word] = true
+	}
+
+	counts := map[string]int{}
+	for _, member := range c.Members {
+		for term := range terms[member] {
+			if !skip[term] {
+				counts[term]++
+			}
+		}
+	}
+
+	var shared []string
+	for term, count := range counts {
+		if count > 1 {
+			shared = append(shared, term)
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		if counts[shared[i]] != counts[shared[j]] {
+			return counts[shared[i]] > counts[shared[j]]
+		}
+		return shared[i] < shared[j]
+	})
+	if len(shared) > maxKeywords {
+		shared = shared[:maxKeywords]
+	}
+	return shared
+}
+
+func round(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
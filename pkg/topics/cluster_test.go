@@ -0,0 +1,99 @@
+package topics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testDocuments() []Document {
+	var documents []Document
+	for _, text := range []string{
+		"Please add dark mode to the settings",
+		"Login error on Android",
+		"Dark mode would be great",
+		"Getting a login error today",
+		"I want dark mode",
+		"Login error again",
+		"Thanks for the great support",
+	} {
+		documents = append(documents, Document{Text: text, Language: "en"})
+	}
+	return documents
+}
+
+func TestGroup(t *testing.T) {
+	clusters := Group(testDocuments(), Options{})
+	if len(clusters) != 2 {
+		t.Fatalf("got %d topics, want 2", len(clusters))
+	}
+
+	tests := []struct {
+		label   string
+		members []int
+	}{
+		{"dark mode", []int{0, 2, 4}},
+		{"login error", []int{1, 3, 5}},
+	}
+	for i, tt := range tests {
+		c := clusters[i]
+		if c.Label != tt.label || !reflect.DeepEqual(c.Members, tt.members) {
+			t.Errorf("topic %d: got %q with %v, want %q with %v", i, c.Label, c.Members, tt.label, tt.members)
+		}
+		if len(c.Scores) != len(c.Members) {
+			t.Errorf("%s: got %d scores for %d members", c.Label, len(c.Scores), len(c.Members))
+		}
+		for _, score := range c.Scores {
+			if score <= 0 {
+				t.Errorf("%s: got score %v", c.Label, score)
+			}
+		}
+	}
+}
+
+func TestGroupOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		labels []string
+	}{
+		{"defaults", Options{}, []string{"dark mode", "login error"}},
+		{"max topics", Options{MaxTopics: 1}, []string{"dark mode"}},
+		{"min size", Options{MinSize: 4}, nil},
+		// "great" is shared by two, one of which is about dark mode already
+		{"smaller min size", Options{MinSize: 2}, []string{"dark mode", "login error"}},
+	}
+	for _, tt := range tests {
+		var labels []string
+		for _, c := range Group(testDocuments(), tt.opts) {
+			labels = append(labels, c.Label)
+		}
+		if !reflect.DeepEqual(labels, tt.labels) {
+			t.Errorf("%s: got %q, want %q", tt.name, labels, tt.labels)
+		}
+	}
+}
+
+func TestGroupSkipsCommonTerms(t *testing.T) {
+	documents := []Document{
+		{Text: "dark mode please"},
+		{Text: "dark mode now"},
+		{Text: "want dark mode"},
+	}
+	if clusters := Group(documents, Options{}); len(clusters) != 0 {
+		t.Errorf("a term of every feedback made topic %q", clusters[0].Label)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	terms := []map[string]float64{
+		{"dark mode": 1, "dark": 1, "mode": 1, "setting": 1, "theme": 1},
+		{"dark mode": 1, "setting": 1, "theme": 1, "night": 1},
+		{"dark mode": 1, "setting": 1, "night": 1, "color": 1},
+	}
+	c := &Cluster{Label: "dark mode", Members: []int{0, 1, 2}}
+
+	want := []string{"setting", "night", "theme"}
+	if got := keywords(c, terms); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Package topics groups a tenant's feedback into topics named after the
+// keyphrases they share.
+//
+// Keyphrases are extracted from each feedback with RAKE: the text is split
+// into candidate phrases at stopwords and punctuation, and phrases are
+// scored by the co-occurrence degree of their words. Phrases and their words
+// are then weighted across the feedback of the window with TF-IDF, and each
+// feedback joins the topic of its best-ranked term shared by enough feedback.
+package topics
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// longer candidates are split, RAKE favours long phrases otherwise
+	maxPhraseWords = 3
+	minWordLength  = 3
+)
+
+// Keyphrase is a candidate phrase of a text and its RAKE score.
+type Keyphrase struct {
+	Phrase string
+	Score  float64
+}
+
+// Keyphrases returns the candidate keyphrases of text, best first.
+func Keyphrases(text, language string) []Keyphrase {
+	stop := stopwordsFor(language)
+
+	var (
+		phrases [][]string
+		current []string
+	)
+	split := func() {
+		if len(current) > 0 {
+			phrases = append(phrases, current)
+			current = nil
+		}
+	}
+
+	for _, word := range splitWords(text) {
+		if word == "" {
+			// punctuation
+			split()
+			continue
+		}
+		if stop[word] || len([]rune(word)) < minWordLength || !hasLetter(word) {
+			split()
+			continue
+		}
+		current = append(current, normalizeWord(word, language))
+		if len(current) == maxPhraseWords {
+			split()
+		}
+	}
+	split()
+
+	// word scores: degree (co-occurring words, itself included) over frequency
+	frequency := map[string]float64{}
+	degree := map[string]float64{}
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += float64(len(phrase))
+		}
+	}
+
+	scores := map[string]float64{}
+	for _, phrase := range phrases {
+		score := 0.0
+		for _, word := range phrase {
+			score += degree[word] / frequency[word]
+		}
+		key := strings.Join(phrase, " ")
+		if score > scores[key] {
+			scores[key] = score
+		}
+	}
+
+	keyphrases := make([]Keyphrase, 0, len(scores))
+	for phrase, score := range scores {
+		keyphrases = append(keyphrases, Keyphrase{Phrase: phrase, Score: score})
+	}
+	sort.Slice(keyphrases, func(i, j int) bool {
+		if keyphrases[i].Score != keyphrases[j].Score {
+			return keyphrases[i].Score > keyphrases[j].Score
+		}
+		return keyphrases[i].Phrase < keyphrases[j].Phrase
+	})
+	return keyphrases
+}
+
+// splitWords lower-cases text and splits it into words, punctuation ending a
+// phrase is returned as an empty word. Apostrophes are dropped.
+func splitWords(text string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		case r == '\'' || r == '’':
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '/':
+			flush()
+		default:
+			flush()
+			words = append(words, "")
+		}
+	}
+	flush()
+	return words
+}
+
+func hasLetter(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWord folds simple English plurals and gerunds so that "crash",
+// "crashes" and "crashing" are the same term.
+func normalizeWord(word, language string) string {
+	if language != "" && language != "en" {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ing") && len(word) > 6:
+		return strings.TrimSuffix(word, "ing")
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,74 @@
+package topics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyphrases(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		language string
+		want     []Keyphrase
+	}{
+		{"split at stopwords", "The app crashes when I upload large photos", "en",
+			[]Keyphrase{{"upload large photo", 9}, {"crash", 1}}},
+		{"split at punctuation", "Login fails, password reset", "en",
+			[]Keyphrase{{"login fail", 4}, {"password reset", 4}}},
+		{"at most three words", "upload large photos crashes daily", "en",
+			[]Keyphrase{{"upload large photo", 9}, {"crash daily", 4}}},
+		{"short words and numbers", "version 42 is ok", "en",
+			[]Keyphrase{{"version", 1}}},
+		{"repeated phrase", "slow sync. slow sync!", "en",
+			[]Keyphrase{{"slow sync", 4}}},
+		{"other language", "la aplicación falla siempre", "es",
+			[]Keyphrase{{"falla siempre", 4}}},
+		{"unknown language", "The app crashes", "xx",
+			[]Keyphrase{{"crashes", 1}}},
+		{"no words", "it is the app!", "en", []Keyphrase{}},
+	}
+	for _, tt := range tests {
+		if got := Keyphrases(tt.text, tt.language); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Don't crash—now!", []string{"dont", "crash", "", "now", ""}},
+		{"sign-in/out_flow", []string{"sign", "in", "out", "flow"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := splitWords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct {
+		word, language, want string
+	}{
+		{"crashing", "en", "crash"},
+		{"bring", "en", "bring"},
+		{"replies", "en", "reply"},
+		{"crashes", "en", "crash"},
+		{"boxes", "en", "box"},
+		{"bugs", "", "bug"},
+		{"status", "en", "status"},
+		{"class", "en", "class"},
+		{"analysis", "en", "analysis"},
+		{"crashes", "fr", "crashes"},
+	}
+	for _, tt := range tests {
+		if got := normalizeWord(tt.word, tt.language); got != tt.want {
+			t.Errorf("normalizeWord(%q, %q) = %q, want %q", tt.word, tt.language, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package topics
+
+import "strings"
+
+// stopwords split the text into candidate keyphrases, per language. Words
+// common in any feedback ("app", "please") are included too: they would
+// otherwise end up as topics of their own.
+var stopwords = map[string]map[string]bool{
+	"en": wordSet(`a about above after again against all also am an and any are as at be because been before being
+		below between both but by can could did do does doing down during each even ever every few for from further get
+		gets got had has have having he her here hers him his how however i if in into is it its itself just let like
+		me more most much my no nor not now of off on once only or other our ours out over own please really same she
+		should so some such than thank thanks that the their theirs them then there these they this those through to
+		too under until up upon us very via was way we well were what when where which while who whom why will with
+		within without would yet you your yours app application hi hello dear team one two use used using still always
+		never lot lots since make makes made thing things something anything know want wants need needs new time
+		today yesterday day days also would could dont doesnt didnt cant wont isnt im ive its thats theres`),
+	"es": wordSet(`a al algo ante antes aunque bajo bien cada como con contra cual cuando de del desde donde durante e
+		el ella ellas ellos en entre era es esa ese eso esta estaba estan este esto estos fue ha hace hasta hay la las
+		le les lo los mas me mi mis mucho muy nada ni no nos nosotros o otra otro para pero poco por porque que quien
+		se sea ser si sin sobre solo su sus tambien tan tanto te tengo tiene todo todos tu un una uno unos usted y ya yo
+		app aplicación aplicacion hola gracias favor`),
+	"fr": wordSet(`a afin ai aie alors au aucun aussi autre aux avec avoir bon car ce cela ces cet cette ceci chaque
+		comme comment dans de des donc dont du elle elles en encore est et etait été être fait faire il ils je la le les
+		leur lui ma mais me même mes moi mon ne ni nos notre nous on ou où par pas peu plus pour pourquoi quand que quel
+		quelle qui sa sans se ses si son sont sur ta te tes toi ton tous tout très tu un une vos votre vous y app
+		application bonjour merci`),
+	"de": wordSet(`aber alle als also am an auch auf aus bei bin bis bitte da damit dann das dass dem den der des die
+		dies diese doch dort du durch ein eine einem einen einer es für gibt hab habe haben hat hatte ich ihr im in ist
+		ja jetzt kann kein keine man mehr mein mich mir mit muss nach nicht noch nur ob oder ohne schon sehr sein sich
+		sie sind so über um und uns unter viel vom von vor war was weil wenn wer wie wir wird wo zu zum zur app danke
+		hallo`),
+	"it": wordSet(`a ad al alla alle anche ancora che chi ci come con da dal dalla dei del della delle di dove e è ed
+		gli ha hanno ho i il in io la le lei lo loro lui ma mi mia mio molto ne nei nel nella no noi non o per perché
+		più poi quando quello questa questo se si sia solo sono su sua suo tra tu tutto un una uno vi app applicazione
+		ciao grazie`),
+	"pt": wordSet(`a ao aos as até com como da das de do dos e é ela ele eles em entre era essa esse esta este eu foi
+		há isso já la mais mas me meu minha muito na não nas nem no nos nós o os ou para pela pelo por porque que quem
+		se sem ser seu sua só também tem tenho um uma você app aplicativo olá obrigado obrigada`),
+	"nl": wordSet(`aan al als bij daar dan dat de deze die dit door een en er had heb heeft het hij hoe ik in is je
+		kan maar me meer met mijn na naar niet nog nu of om omdat ook op over te tot u uit van veel voor was wat we
+		wel wie wij zal ze zij zijn zo app bedankt hallo`),
+}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// stopwordsFor returns the stopwords of the language, English when there
+// are none for it.
+func stopwordsFor(language string) map[string]bool {
+	if words, ok := stopwords[language]; ok {
+		return words
+	}
+	return stopwords["en"]
+}
@@ -0,0 +1,148 @@
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type TopicHandler struct {
+	service *TopicService
+}
+
+func NewTopicHandler(service *TopicService) *TopicHandler {
+	return &TopicHandler{service: service}
+}
+
+// RunTopicsHandler starts the topic job of a tenant over the from/to window,
+// the last 7 days by default. The job runs in the background, its outcome
+// is logged.
+func (h *TopicHandler) RunTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	tenantID, err := parseTenantID(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, to, err := parseWindow(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		topics, members, err := h.service.Run(context.Background(), tenantID, from, to)
+		if err != nil {
+			fmt.Printf("Failed to extract topics of tenant %s: %v\n", tenantID, err)
+			return
+		}
+		fmt.Printf("Extracted %d topics of tenant %s, covering %d feedback records\n", topics, tenantID, members)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// TrendingTopicsHandler reports the topics of the from/to window, the last 7
+// days by default, compared with the window before it. The report can be
+// restricted to a source and sub-source.
+func (h *TopicHandler) TrendingTopicsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tenantID, err := parseTenantID(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.TopicReportFilter{
+		TenantID:    tenantID,
+		Source:      models.Source(query.Get("source")),
+		SubSourceID: query.Get("sub_source_id"),
+	}
+	if filter.SubSourceID != "" {
+		if _, err := uuid.Parse(filter.SubSourceID); err != nil {
+			http.Error(w, "Invalid Sub Source ID format", http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.From, filter.To, err = parseWindow(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, err = parseCount(query, "limit"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Samples, err = parseCount(query, "samples"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	topics, err := h.service.Trending(ctx, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to report trending topics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(topics)
+}
+
+func parseTenantID(query url.Values) (string, error) {
+	tenantID := query.Get("tenant_id")
+	if tenantID == "" {
+		return "", fmt.Errorf("Tenant ID is required")
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		return "", fmt.Errorf("Invalid Tenant ID format")
+	}
+	return tenantID, nil
+}
+
+// parseWindow reads the RFC3339 from and to parameters, to defaulting to
+// now and from to DefaultWindow before to.
+func parseWindow(query url.Values) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	if value := query.Get("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid to, expected RFC3339 timestamp")
+		}
+		to = t
+	}
+	from := to.Add(-DefaultWindow)
+	if value := query.Get("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid from, expected RFC3339 timestamp")
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseCount(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("Invalid %s, expected a non-negative integer", name)
+	}
+	return i, nil
+}
@@ -0,0 +1,132 @@
+package topics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+const (
+	// DefaultWindow of the topic job and of the trending report
+	DefaultWindow = 7 * 24 * time.Hour
+
+	documentBatchSize = 1000
+	// the feedback of a window is clustered in memory, any beyond this is left out
+	maxDocuments = 50000
+
+	defaultReportLimit = 20
+	defaultSamples     = 3
+)
+
+type TopicService struct {
+	repo       *db.TopicRepository
+	tenantRepo *db.TenantRepository
+}
+
+func NewTopicService(repo *db.TopicRepository, tenantRepo *db.TenantRepository) *TopicService {
+	return &TopicService{repo: repo, tenantRepo: tenantRepo}
+}
+
+// Run extracts the topics of the tenant's feedback written within
+// [from, to) and stores their members, replacing the memberships of that
+// window. It returns the number of topics and of feedback in one.
+func (s *TopicService) Run(ctx context.Context, tenantID string, from, to time.Time) (int, int, error) {
+	var (
+		feedbacks   []*models.Feedback
+		afterSource models.Source
+		afterID     string
+	)
+	for len(feedbacks) < maxDocuments {
+		batch, err := s.repo.ListDocuments(ctx, tenantID, from, to, afterSource, afterID, documentBatchSize)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		feedbacks = append(feedbacks, batch...)
+		last := batch[len(batch)-1]
+		afterSource, afterID = last.Source, last.ID
+	}
+
+	documents := make([]Document, len(feedbacks))
+	for i, feedback := range feedbacks {
+		documents[i] = Document{Text: feedback.Text(), Language: feedback.Language}
+	}
+
+	var (
+		topics  []*models.Topic
+		members int
+	)
+	for _, cluster := range Group(documents, Options{}) {
+		topic := &models.Topic{Label: cluster.Label, Keywords: cluster.Keywords}
+		if topic.Keywords == nil {
+			topic.Keywords = []string{}
+		}
+		for i, index := range cluster.Members {
+			feedback := feedbacks[index]
+			topic.Members = append(topic.Members, models.TopicMember{Source: feedback.Source, FeedbackID: feedback.ID, Score: cluster.Scores[i]})
+		}
+		members += len(topic.Members)
+		topics = append(topics, topic)
+	}
+
+	if err := s.repo.ReplaceWindow(ctx, tenantID, from, to, topics); err != nil {
+		return 0, 0, err
+	}
+
+	return len(topics), members, nil
+}
+
+// RunAll runs the topic job of every tenant over the window ending now.
+func (s *TopicService) RunAll(ctx context.Context, window time.Duration) error {
+	tenantIDs, err := s.tenantRepo.ListIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-window)
+	for _, tenantID := range tenantIDs {
+		topics, members, err := s.Run(ctx, tenantID, from, to)
+		if err != nil {
+			fmt.Printf("Failed to extract topics of tenant %s: %v\n", tenantID, err)
+			continue
+		}
+		fmt.Printf("Extracted %d topics of tenant %s, covering %d feedback records\n", topics, tenantID, members)
+	}
+
+	return nil
+}
+
+// Trending reports the topics of the window with their change in volume
+// since the previous window, and sample feedback.
+func (s *TopicService) Trending(ctx context.Context, filter *models.TopicReportFilter) ([]*models.TrendingTopic, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultReportLimit
+	}
+	if filter.Samples <= 0 {
+		filter.Samples = defaultSamples
+	}
+
+	topics, err := s.repo.Trending(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, topic := range topics {
+		topic.Change = topic.Volume - topic.PreviousVolume
+		if topic.PreviousVolume > 0 {
+			percent := float64(topic.Change) / float64(topic.PreviousVolume) * 100
+			topic.ChangePercent = &percent
+		}
+		if topic.Samples, err = s.repo.Samples(ctx, topic.ID, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	return topics, nil
+}
@@ -0,0 +1,196 @@
+package triage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type TriageHandler struct {
+	service *TriageService
+}
+
+func NewTriageHandler(service *TriageService) *TriageHandler {
+	return &TriageHandler{service: service}
+}
+
+// SetTriageHandler sets the status, assignee and priority of a feedback.
+func (h *TriageHandler) SetTriageHandler(w http.ResponseWriter, r *http.Request) {
+	var triage models.FeedbackTriage
+	if err := json.NewDecoder(r.Body).Decode(&triage); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateKey(triage.TenantID, triage.Source, triage.FeedbackID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if triage.Status != "" && !ValidStatus(triage.Status) {
+		http.Error(w, "Status must be 'new', 'reviewed', 'actioned' or 'ignored'", http.StatusBadRequest)
+		return
+	}
+	if !ValidPriority(triage.Priority) {
+		http.Error(w, "Priority must be 'low', 'medium', 'high' or 'urgent'", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.SetTriage(ctx, &triage); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set feedback triage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(triage)
+}
+
+func (h *TriageHandler) GetTriageHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, source, feedbackID, err := feedbackKey(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	triage, err := h.service.GetTriage(ctx, tenantID, source, feedbackID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve feedback triage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(triage)
+}
+
+func (h *TriageHandler) AddNoteHandler(w http.ResponseWriter, r *http.Request) {
+	var note models.FeedbackNote
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateKey(note.TenantID, note.Source, note.FeedbackID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if note.Author == "" || note.Body == "" {
+		http.Error(w, "Author and body are required", http.StatusBadRequest)
+		return
+	}
+
+	note.ID = uuid.New().String()
+
+	ctx := r.Context()
+	if err := h.service.AddNote(ctx, &note); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add feedback note: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+func (h *TriageHandler) ListNotesHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, source, feedbackID, err := feedbackKey(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	notes, err := h.service.ListNotes(ctx, tenantID, source, feedbackID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list feedback notes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(notes)
+}
+
+func (h *TriageHandler) DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.URL.Query().Get("tenant_id")
+	noteID := r.URL.Query().Get("id")
+	if tenantID == "" || noteID == "" {
+		http.Error(w, "Tenant ID and note ID are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.DeleteNote(ctx, tenantID, noteID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete feedback note: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TriageHandler) AddTagHandler(w http.ResponseWriter, r *http.Request) {
+	var tag models.FeedbackTag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateKey(tag.TenantID, tag.Source, tag.FeedbackID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if tag.Tag == "" {
+		http.Error(w, "Tag is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.AddTag(ctx, &tag); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to tag feedback: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *TriageHandler) RemoveTagHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tenantID, source, feedbackID, err := feedbackKey(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tag := &models.FeedbackTag{TenantID: tenantID, Source: source, FeedbackID: feedbackID, Tag: query.Get("tag")}
+	if tag.Tag == "" {
+		http.Error(w, "Tag is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.RemoveTag(ctx, tag); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove feedback tag: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feedbackKey reads the key of a feedback from the tenant_id, source and id
+// query parameters.
+func feedbackKey(query url.Values) (string, models.Source, string, error) {
+	tenantID, source, feedbackID := query.Get("tenant_id"), models.Source(query.Get("source")), query.Get("id")
+	if err := validateKey(tenantID, source, feedbackID); err != nil {
+		return "", "", "", err
+	}
+	return tenantID, source, feedbackID, nil
+}
+
+func validateKey(tenantID string, source models.Source, feedbackID string) error {
+	if tenantID == "" || source == "" || feedbackID == "" {
+		return fmt.Errorf("Tenant ID, source and feedback ID are required")
+	}
+	if _, err := uuid.Parse(tenantID); err != nil {
+		return fmt.Errorf("Invalid Tenant ID format")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package triage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+type TriageService struct {
+	repo *db.TriageRepository
+}
+
+func NewTriageService(repo *db.TriageRepository) *TriageService {
+	return &TriageService{repo: repo}
+}
+
+// ValidStatus reports whether status is a triage status.
+func ValidStatus(status string) bool {
+	switch status {
+	case models.TriageStatusNew, models.TriageStatusReviewed, models.TriageStatusActioned, models.TriageStatusIgnored:
+		return true
+	}
+	return false
+}
+
+// ValidPriority reports whether priority is a triage priority, empty
+// meaning none.
+func ValidPriority(priority string) bool {
+	switch priority {
+	case "", models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+func (s *TriageService) SetTriage(ctx context.Context, triage *models.FeedbackTriage) error {
+	if triage.Status == "" {
+		triage.Status = models.TriageStatusNew
+	}
+	if !ValidStatus(triage.Status) {
+		return fmt.Errorf("invalid status %q", triage.Status)
+	}
+	if !ValidPriority(triage.Priority) {
+		return fmt.Errorf("invalid priority %q", triage.Priority)
+	}
+	return s.repo.SaveTriage(ctx, triage)
+}
+
+func (s *TriageService) GetTriage(ctx context.Context, tenantID string, source models.Source, feedbackID string) (*models.FeedbackTriage, error) {
+	return s.repo.GetTriage(ctx, tenantID, source, feedbackID)
+}
+
+func (s *TriageService) AddNote(ctx context.Context, note *models.FeedbackNote) error {
+	if strings.TrimSpace(note.Body) == "" {
+		return fmt.Errorf("note body is empty")
+	}
+	return s.repo.AddNote(ctx, note)
+}
+
+func (s *TriageService) ListNotes(ctx context.Context, tenantID string, source models.Source, feedbackID string) ([]*models.FeedbackNote, error) {
+	return s.repo.ListNotes(ctx, tenantID, source, feedbackID)
+}
+
+func (s *TriageService) DeleteNote(ctx context.Context, tenantID, noteID string) error {
+	return s.repo.DeleteNote(ctx, tenantID, noteID)
+}
+
+func (s *TriageService) AddTag(ctx context.Context, tag *models.FeedbackTag) error {
+	tag.Tag = strings.TrimSpace(tag.Tag)
+	if tag.Tag == "" {
+		return fmt.Errorf("tag is empty")
+	}
+	return s.repo.AddTag(ctx, tag)
+}
+
+func (s *TriageService) RemoveTag(ctx context.Context, tag *models.FeedbackTag) error {
+	return s.repo.RemoveTag(ctx, tag)
+}
@@ -1 +1,54 @@
 package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/server"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// testPool connects to the database of TEST_DATABASE_URL and creates the
+// tables, the test is skipped when it is not set. The tests write to it,
+// do not point it at a database in use.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if err := server.CreateTables(ctx, pool); err != nil {
+		t.Fatalf("failed to create the tables: %v", err)
+	}
+	return pool
+}
+
+// testSubscription creates a tenant with a push subscription and returns
+// their IDs, the tenant is deleted with everything it has after the test.
+func testSubscription(t *testing.T, pool *pgxpool.Pool) (string, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	tenantID, subscriptionID := uuid.New().String(), uuid.New().String()
+	if _, err := pool.Exec(ctx, `INSERT INTO tenant (id, name, api_key) VALUES ($1, 'Test Tenant', $2)`, tenantID, uuid.New().String()); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), `DELETE FROM tenant WHERE id = $1`, tenantID)
+	})
+	_, err := pool.Exec(ctx, `INSERT INTO subscription (id, tenant_id, sub_source_id, source, subscription_mode) VALUES ($1, $2, $3, 'webhook', 'push')`,
+		subscriptionID, tenantID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	return tenantID, subscriptionID
+}
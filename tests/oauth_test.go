@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/oauth"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// testOAuthService returns an OAuth service refreshing the Intercom tokens
+// at a server answering with the status and body, and an Intercom
+// subscription whose access token expired.
+func testOAuthService(t *testing.T, pool *pgxpool.Pool, status int, body string) (*oauth.OAuthService, *subscription.SubscriptionService, string) {
+	t.Helper()
+
+	subService := testSubscriptionService(t, pool)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	providers := map[models.Source]*oauth.Provider{models.SourceIntercom: {TokenURL: server.URL, ClientID: "client"}}
+
+	tenantID, _ := testSubscription(t, pool)
+	subscriptionID := uuid.New().String()
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	_, err := pool.Exec(context.Background(), `INSERT INTO subscription (id, tenant_id, sub_source_id, source, subscription_mode, configuration)
+		VALUES ($1, $2, $3, 'intercom', 'pull', $4)`, subscriptionID, tenantID, uuid.New().String(),
+		map[string]interface{}{models.OAuthConfigKey: map[string]interface{}{"access_token": "old-access", "refresh_token": "old-refresh", "expires_at": expired}})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	return oauth.NewOAuthService(nil, subService, providers, ""), subService, subscriptionID
+}
+
+func TestOAuthRefreshSavesToken(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	// the refresh token is not rotated
+	oauthService, subService, subscriptionID := testOAuthService(t, pool, http.StatusOK, `{"access_token": "new-access", "expires_in": 3600}`)
+
+	sub, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if err := oauthService.EnsureFresh(ctx, sub); err != nil {
+		t.Fatalf("EnsureFresh: %v", err)
+	}
+
+	stored, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	token, ok := oauth.TokenOf(stored)
+	if !ok || token.AccessToken != "new-access" || token.RefreshToken != "old-refresh" || token.ExpiresAt == nil || token.ExpiresAt.Before(time.Now()) {
+		t.Errorf("got stored token %+v, want the new access token and the old refresh token", token)
+	}
+	if stored.NeedsReauth {
+		t.Errorf("a refreshed subscription needs re-auth")
+	}
+
+	var raw string
+	if err := pool.QueryRow(ctx, `SELECT configuration::text FROM subscription WHERE id = $1`, subscriptionID).Scan(&raw); err != nil {
+		t.Fatalf("failed to read the configuration: %v", err)
+	}
+	if strings.Contains(raw, "new-access") || strings.Contains(raw, "old-refresh") {
+		t.Errorf("the tokens are stored in plaintext: %s", raw)
+	}
+}
+
+func TestOAuthRejectedRefreshNeedsReauth(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	oauthService, subService, subscriptionID := testOAuthService(t, pool, http.StatusBadRequest, `{"error": "invalid_grant"}`)
+
+	sub, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if err := oauthService.EnsureFresh(ctx, sub); err == nil || !sub.NeedsReauth {
+		t.Fatalf("got error %v and needs re-auth %v, want the subscription to need re-auth", err, sub.NeedsReauth)
+	}
+
+	stored, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if !stored.NeedsReauth {
+		t.Errorf("the stored subscription does not need re-auth")
+	}
+	if token, _ := oauth.TokenOf(stored); token == nil || token.AccessToken != "old-access" {
+		t.Errorf("got stored token %+v, want the old one kept", token)
+	}
+}
+
+func TestOAuthFailedRefreshIsRetried(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	oauthService, subService, subscriptionID := testOAuthService(t, pool, http.StatusServiceUnavailable, ``)
+
+	sub, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if err := oauthService.EnsureFresh(ctx, sub); err == nil || sub.NeedsReauth {
+		t.Fatalf("got error %v and needs re-auth %v, want a temporary error", err, sub.NeedsReauth)
+	}
+}
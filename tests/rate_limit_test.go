@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/integrations"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/ratelimit"
+)
+
+func TestRateLimiterWaitsForTokens(t *testing.T) {
+	pool := testPool(t)
+	limiter := ratelimit.NewRateLimiter(db.NewRateLimitRepository(pool))
+	ctx := context.Background()
+	key := "test:" + uuid.New().String()
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), `DELETE FROM rate_limit_bucket WHERE key = $1`, key)
+	})
+
+	limit := models.RateLimit{RequestsPerSecond: 10, Burst: 2}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(ctx, models.SourceDiscourse, key, limit); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	// the burst goes at once, the two calls after wait 100ms each
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("4 calls at 10/s with a burst of 2 took %s", elapsed)
+	}
+
+	limiter.Backoff(ctx, key, limit, time.Second)
+	start = time.Now()
+	if err := limiter.Wait(ctx, models.SourceDiscourse, key, limit); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("the call after a backoff of 1s waited %s", elapsed)
+	}
+}
+
+// A 429 with Retry-After holds the bucket back, the retry waits for it once.
+func TestSourceClientTooManyRequestsWaitsOnce(t *testing.T) {
+	pool := testPool(t)
+	limiter := ratelimit.NewRateLimiter(db.NewRateLimitRepository(pool))
+	credential := uuid.New().String()
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), `DELETE FROM rate_limit_bucket WHERE key = $1`, ratelimit.Key(models.SourceDiscourse, credential))
+	})
+
+	var (
+		mu    sync.Mutex
+		calls []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		first := len(calls) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	config := integrations.DefaultSourceClientConfig()
+	config.RateLimits = map[models.Source]models.RateLimit{models.SourceDiscourse: {RequestsPerSecond: 100, Burst: 10}}
+	client := integrations.NewSourceClient(config, limiter)
+	sub := &models.Subscription{Source: models.SourceDiscourse, Configuration: map[string]interface{}{}}
+
+	if _, err := client.Get(integrations.WithRateLimit(context.Background(), sub, credential), server.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if wait := calls[1].Sub(calls[0]); wait < 900*time.Millisecond || wait > 1800*time.Millisecond {
+		t.Errorf("the retry waited %s, want the Retry-After of 1s once", wait)
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/oauth"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/secrets"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/subscription"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// testSubscriptionService returns a subscription service encrypting with a
+// random key.
+func testSubscriptionService(t *testing.T, pool *pgxpool.Pool) *subscription.SubscriptionService {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyring, err := secrets.NewKeyring("test=" + base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return subscription.NewSubscriptionService(db.NewSubscriptionRepository(pool), keyring)
+}
+
+func TestUpdateConfigurationKeepsOAuthToken(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	subService := testSubscriptionService(t, pool)
+	_, subscriptionID := testSubscription(t, pool)
+
+	sub, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	// as the connect flow saves it
+	sub.Configuration = map[string]interface{}{
+		"text":                "$.comment",
+		models.OAuthConfigKey: map[string]interface{}{"access_token": "access", "refresh_token": "refresh"},
+	}
+	if err := subService.SaveConfiguration(ctx, sub); err != nil {
+		t.Fatalf("SaveConfiguration: %v", err)
+	}
+
+	if err := subService.UpdateConfiguration(ctx, sub, map[string]interface{}{"text": "$.body"}); err != nil {
+		t.Fatalf("UpdateConfiguration: %v", err)
+	}
+
+	stored, err := subService.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if stored.Configuration["text"] != "$.body" {
+		t.Errorf("got configuration %v, want the update", stored.Configuration)
+	}
+	if token, ok := oauth.TokenOf(stored); !ok || token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("got token %+v, want the one of the connect flow", token)
+	}
+}
+
+func TestResumeResetsFailures(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	repo := db.NewSubscriptionRepository(pool)
+	_, subscriptionID := testSubscription(t, pool)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.RecordFailure(ctx, subscriptionID, "unavailable"); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+	if err := repo.SetActive(ctx, subscriptionID, false); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	if err := repo.SetActive(ctx, subscriptionID, true); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+
+	failures, err := repo.RecordFailure(ctx, subscriptionID, "unavailable")
+	if err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	if failures != 1 {
+		t.Errorf("got %d failures after resuming, want 1", failures)
+	}
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/db"
+	"github.com/harish-dalal/feedback-ingestion-system/pkg/models"
+)
+
+// claimEntry claims the due entries and returns the one of the ID, the
+// others are left to expire.
+func claimEntry(t *testing.T, repo *db.WebhookInboxRepository, id string, lockedUntil time.Time, maxAttempts int) *models.WebhookInboxEntry {
+	t.Helper()
+
+	entries, err := repo.Claim(context.Background(), 100, lockedUntil, maxAttempts)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+func addEntry(t *testing.T, repo *db.WebhookInboxRepository, tenantID, subscriptionID string) *models.WebhookInboxEntry {
+	t.Helper()
+
+	entry := &models.WebhookInboxEntry{TenantID: tenantID, SubscriptionID: subscriptionID, Source: models.SourceWebhook, Body: []byte(`{"text": "hello"}`)}
+	if err := repo.Add(context.Background(), entry); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return entry
+}
+
+func TestInboxClaimIsLeased(t *testing.T) {
+	pool := testPool(t)
+	tenantID, subscriptionID := testSubscription(t, pool)
+	repo := db.NewWebhookInboxRepository(pool)
+	ctx := context.Background()
+
+	entry := addEntry(t, repo, tenantID, subscriptionID)
+
+	claimed := claimEntry(t, repo, entry.ID, time.Now().Add(time.Minute), 8)
+	if claimed == nil {
+		t.Fatalf("the entry was not claimed")
+	}
+	if claimed.Status != models.InboxProcessing || claimed.Attempts != 1 || claimed.LeaseToken == "" {
+		t.Fatalf("claimed entry: status %s, attempts %d, lease %q", claimed.Status, claimed.Attempts, claimed.LeaseToken)
+	}
+	if string(claimed.Body) != `{"text": "hello"}` {
+		t.Errorf("claimed body %q", claimed.Body)
+	}
+
+	// leased, no other worker gets it
+	if again := claimEntry(t, repo, entry.ID, time.Now().Add(time.Minute), 8); again != nil {
+		t.Fatalf("the leased entry was claimed again")
+	}
+
+	if err := repo.Complete(ctx, entry.ID, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, db.ErrLeaseLost) {
+		t.Fatalf("Complete with another lease: got %v, want ErrLeaseLost", err)
+	}
+	if err := repo.Complete(ctx, entry.ID, claimed.LeaseToken); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if err := repo.Complete(ctx, entry.ID, claimed.LeaseToken); !errors.Is(err, db.ErrLeaseLost) {
+		t.Fatalf("Complete of a done entry: got %v, want ErrLeaseLost", err)
+	}
+
+	stored, err := repo.Get(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != models.InboxDone || stored.ProcessedAt == nil {
+		t.Errorf("completed entry: status %s, processed at %v", stored.Status, stored.ProcessedAt)
+	}
+}
+
+func TestInboxExpiredLeaseIsClaimedAgain(t *testing.T) {
+	pool := testPool(t)
+	tenantID, subscriptionID := testSubscription(t, pool)
+	repo := db.NewWebhookInboxRepository(pool)
+	ctx := context.Background()
+
+	entry := addEntry(t, repo, tenantID, subscriptionID)
+
+	// the lease is over at once, as if the worker had died
+	first := claimEntry(t, repo, entry.ID, time.Now().Add(-time.Second), 8)
+	if first == nil {
+		t.Fatalf("the entry was not claimed")
+	}
+	second := claimEntry(t, repo, entry.ID, time.Now().Add(time.Minute), 8)
+	if second == nil {
+		t.Fatalf("the entry whose lease expired was not claimed again")
+	}
+	if second.Attempts != 2 || second.LeaseToken == first.LeaseToken {
+		t.Fatalf("claimed again: attempts %d, lease %q after %q", second.Attempts, second.LeaseToken, first.LeaseToken)
+	}
+
+	// the first worker finishing late changes nothing
+	if err := repo.Fail(ctx, entry.ID, first.LeaseToken, "late", time.Now(), true); !errors.Is(err, db.ErrLeaseLost) {
+		t.Fatalf("Fail with the expired lease: got %v, want ErrLeaseLost", err)
+	}
+	if err := repo.Complete(ctx, entry.ID, first.LeaseToken); !errors.Is(err, db.ErrLeaseLost) {
+		t.Fatalf("Complete with the expired lease: got %v, want ErrLeaseLost", err)
+	}
+
+	if err := repo.Fail(ctx, entry.ID, second.LeaseToken, "failed", time.Now().Add(time.Hour), false); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	stored, err := repo.Get(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != models.InboxPending || stored.LastError != "failed" || stored.Attempts != 2 {
+		t.Errorf("failed entry: status %s, last error %q, attempts %d", stored.Status, stored.LastError, stored.Attempts)
+	}
+}
+
+func TestInboxExpiredLeaseIsDeadAfterMaxAttempts(t *testing.T) {
+	pool := testPool(t)
+	tenantID, subscriptionID := testSubscription(t, pool)
+	repo := db.NewWebhookInboxRepository(pool)
+	ctx := context.Background()
+
+	entry := addEntry(t, repo, tenantID, subscriptionID)
+
+	const maxAttempts = 2
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		claimed := claimEntry(t, repo, entry.ID, time.Now().Add(-time.Second), maxAttempts)
+		if claimed == nil || claimed.Status != models.InboxProcessing || claimed.Attempts != attempt {
+			t.Fatalf("attempt %d: claimed %+v", attempt, claimed)
+		}
+	}
+
+	dead := claimEntry(t, repo, entry.ID, time.Now().Add(time.Minute), maxAttempts)
+	if dead == nil {
+		t.Fatalf("the entry out of attempts was not returned")
+	}
+	if dead.Status != models.InboxDead || dead.Attempts != maxAttempts || dead.LeaseToken != "" || dead.LastError == "" {
+		t.Fatalf("entry out of attempts: status %s, attempts %d, lease %q, last error %q", dead.Status, dead.Attempts, dead.LeaseToken, dead.LastError)
+	}
+	if again := claimEntry(t, repo, entry.ID, time.Now().Add(time.Minute), maxAttempts); again != nil {
+		t.Fatalf("the dead entry was claimed again")
+	}
+
+	stored, err := repo.Get(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != models.InboxDead {
+		t.Errorf("stored status %s, want dead", stored.Status)
+	}
+}
+
+func TestInboxPurgeDone(t *testing.T) {
+	pool := testPool(t)
+	tenantID, subscriptionID := testSubscription(t, pool)
+	repo := db.NewWebhookInboxRepository(pool)
+	ctx := context.Background()
+
+	done := addEntry(t, repo, tenantID, subscriptionID)
+	// claimed with it and left processing
+	processing := addEntry(t, repo, tenantID, subscriptionID)
+
+	claimed := claimEntry(t, repo, done.ID, time.Now().Add(time.Minute), 8)
+	if claimed == nil {
+		t.Fatalf("the entry was not claimed")
+	}
+	if err := repo.Complete(ctx, done.ID, claimed.LeaseToken); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	if _, err := repo.PurgeDone(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("PurgeDone: %v", err)
+	}
+	if _, err := repo.Get(ctx, done.ID); err != nil {
+		t.Fatalf("the entry done within the retention was purged: %v", err)
+	}
+
+	if _, err := repo.PurgeDone(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PurgeDone: %v", err)
+	}
+	if _, err := repo.Get(ctx, done.ID); err == nil {
+		t.Errorf("the done entry was not purged")
+	}
+	if _, err := repo.Get(ctx, processing.ID); err != nil {
+		t.Errorf("the entry being processed was purged: %v", err)
+	}
+}